@@ -3,56 +3,283 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	_ "github.com/lib/pq"
 	"github.com/valeriouberti/order-service-test/internal/api"
 	"github.com/valeriouberti/order-service-test/internal/api/handlers"
 	"github.com/valeriouberti/order-service-test/internal/config"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/eventbus"
+	"github.com/valeriouberti/order-service-test/internal/graphqlapi"
+	"github.com/valeriouberti/order-service-test/internal/grpcapi"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/metrics"
+	"github.com/valeriouberti/order-service-test/internal/outbox"
+	"github.com/valeriouberti/order-service-test/internal/payments"
+	"github.com/valeriouberti/order-service-test/internal/quote"
 	"github.com/valeriouberti/order-service-test/internal/repository"
 	"github.com/valeriouberti/order-service-test/internal/services"
+	"github.com/valeriouberti/order-service-test/internal/tracing"
+	"github.com/valeriouberti/order-service-test/internal/webhooks"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	// Load configuration
-	cfg := config.Load()
+	// Load configuration from defaults, config file, environment and flags
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "usage: order-service-test [flags] [serve | migrate | config print]\n\n%s", config.Usage())
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Configure structured logging
+	logger := logging.New(os.Stdout, cfg.LogLevel)
+	slog.SetDefault(logger)
+
+	// Dispatch subcommands; without arguments the API server is started
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	if command == "config" {
+		if err := runConfig(cfg, args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
 
 	// Connect to the database
+	db, err := openDB(cfg)
+	if err != nil {
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("connected to the database")
+
+	switch command {
+	case "migrate":
+		err = runMigrate(context.Background(), db, logger, args)
+	case "serve":
+		err = serve(cfg, logger, db)
+	default:
+		err = fmt.Errorf("unknown command %q, expected serve, migrate or config", command)
+	}
+
+	db.Close()
+	if err != nil {
+		logger.Error("command failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// runConfig implements the config subcommand.
+func runConfig(cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errors.New("usage: config print")
+	}
+	fmt.Print(cfg.Redacted())
+	return nil
+}
+
+// openDB opens the database, configures the connection pool and checks connectivity.
+func openDB(cfg *config.Config) (*sql.DB, error) {
 	db, err := sql.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return nil, err
 	}
-	defer db.Close()
 
 	// Set connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.ConnectionMaxAge) * time.Second)
 
 	// Test database connection
 	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// Order stream settings: events kept for Last-Event-ID resumption, events
+// buffered per client before it is disconnected, and the keep-alive interval.
+const (
+	streamHistory   = 1000
+	streamBuffer    = 64
+	streamHeartbeat = 15 * time.Second
+)
+
+// serve runs the HTTP and gRPC APIs until SIGINT or SIGTERM is received.
+func serve(cfg *config.Config, logger *slog.Logger, db *sql.DB) error {
+	// Initialize tracing
+	tp, shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.TracingFile)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	// Initialize metrics
+	m := metrics.New()
+	m.RegisterDBStats(db, "order_service")
+
+	// Initialize repositories, instrumented with metrics and tracing
+	productRepo := tracing.NewProductRepository(metrics.NewProductRepository(repository.NewProductRepo(db), m), tp)
+	orderRepo := tracing.NewOrderRepository(metrics.NewOrderRepository(repository.NewOrderRepo(db), m), tp)
+
+	// Initialize the event bus feeding order streams
+	bus := eventbus.New(streamHistory, streamBuffer)
+
+	// Initialize the quote token signer
+	quoteSecret := []byte(cfg.QuoteSecret)
+	if len(quoteSecret) == 0 {
+		logger.Warn("quote_secret is not set, quote tokens only verify on this instance until it restarts")
+		quoteSecret = make([]byte, 32)
+		if _, err := rand.Read(quoteSecret); err != nil {
+			return fmt.Errorf("failed to generate quote secret: %w", err)
+		}
 	}
-	log.Println("Connected to the database successfully")
+	quotes := quote.NewSigner(quoteSecret, time.Duration(cfg.QuoteTTL)*time.Second)
 
-	// Initialize repositories
-	productRepo := repository.NewProductRepo(db)
-	orderRepo := repository.NewOrderRepo(db)
+	// Initialize the payment gateway
+	gateway, err := newPaymentGateway(cfg)
+	if err != nil {
+		return err
+	}
+	if gateway.Name() == "fake" {
+		logger.Warn("payments are processed by the fake gateway, no money is moved")
+	}
 
 	// Initialize services
-	orderService := services.NewOrderService(orderRepo, productRepo)
+	orderService := tracing.NewOrderService(services.NewOrderService(orderRepo, productRepo, bus, quotes), tp)
+	paymentService := services.NewPaymentService(repository.NewPaymentRepo(db), orderRepo, gateway, bus)
+	refundService := services.NewRefundService(repository.NewCreditNoteRepo(db), orderRepo, gateway, bus)
+	shipmentService := services.NewShipmentService(repository.NewShipmentRepo(db), orderRepo, bus)
+	returnService := services.NewReturnService(repository.NewReturnRepo(db), orderRepo, refundService)
+	invoiceService, err := newInvoiceService(cfg, db)
+	if err != nil {
+		return err
+	}
+
+	// Start background workers; they are stopped after the HTTP server
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var workers sync.WaitGroup
+
+	// Start the outbox relay. Events always feed webhook deliveries and are
+	// optionally also written to the configured publisher.
+	webhookRepo := repository.NewWebhookRepo(db)
+	publishers := outbox.MultiPublisher{webhooks.NewEnqueuer(webhookRepo)}
+	if cfg.OutboxPublisher != "none" {
+		publisher, closePublisher, err := newPublisher(cfg)
+		if err != nil {
+			return err
+		}
+		defer closePublisher()
+		publishers = append(publishers, publisher)
+		if cfg.OutboxPublisher == "stdout" {
+			logger.Warn("outbox events are written to stdout, interleaved with the logs")
+		}
+	}
+
+	relay := outbox.NewRelay(repository.NewOutboxRepo(db), publishers,
+		cfg.OutboxBatchSize, time.Duration(cfg.OutboxInterval)*time.Second, logger)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		relay.Run(background)
+	}()
+
+	// Start the webhook dispatcher
+	dispatcher := webhooks.NewDispatcher(webhookRepo, webhooks.Config{
+		MaxAttempts: cfg.WebhookAttempts,
+		Backoff:     time.Duration(cfg.WebhookBackoff) * time.Second,
+		Timeout:     time.Duration(cfg.WebhookTimeout) * time.Second,
+		BatchSize:   cfg.OutboxBatchSize,
+		Interval:    time.Duration(cfg.WebhookInterval) * time.Second,
+	}, logger)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		dispatcher.Run(background)
+	}()
+
+	// Start the cart expiry worker
+	cartService := services.NewCartService(repository.NewCartRepo(db), productRepo, orderService,
+		time.Duration(cfg.CartTTL)*time.Second)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		cartService.RunExpiry(logging.NewContext(background, logger), time.Duration(cfg.CartExpiryInterval)*time.Second)
+	}()
+
+	// Start the import worker running queued import jobs
+	importService := services.NewImportService(repository.NewImportRepo(db), orderService, cfg.ImportSyncRows, cfg.ImportMaxRows)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		importService.RunJobs(logging.NewContext(background, logger), time.Duration(cfg.ImportInterval)*time.Second)
+	}()
+
+	// Start the refund recovery worker finishing interrupted refunds
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		refundService.RunRecovery(logging.NewContext(background, logger), time.Minute)
+	}()
 
 	// Initialize handlers
 	orderHandler := handlers.NewOrderHandler(orderService)
+	streamHandler := handlers.NewOrderStreamHandler(bus, streamHeartbeat)
+	cartHandler := handlers.NewCartHandler(cartService)
+	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	refundHandler := handlers.NewRefundHandler(refundService)
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceService)
+	returnHandler := handlers.NewReturnHandler(returnService)
+	shipmentHandler := handlers.NewShipmentHandler(shipmentService)
+	importHandler := handlers.NewImportHandler(importService)
+	reportHandler := handlers.NewReportHandler(services.NewReportService(repository.NewReportRepo(db)))
+	auditHandler := handlers.NewAuditHandler(services.NewAuditService(repository.NewAuditRepo(db)))
+	webhookHandler := handlers.NewWebhookHandler(services.NewWebhookService(webhookRepo))
+	graphqlHandler := graphqlapi.NewHandler(orderService, productRepo)
+	healthHandler := handlers.NewHealthHandler(db, time.Duration(cfg.ReadyTimeout)*time.Second)
 
 	// Initialize router
-	router := api.NewRouter(orderHandler)
+	router := api.NewRouter(api.Handlers{
+		Order:    orderHandler,
+		Stream:   streamHandler,
+		Cart:     cartHandler,
+		Payment:  paymentHandler,
+		Refund:   refundHandler,
+		Invoice:  invoiceHandler,
+		Return:   returnHandler,
+		Shipment: shipmentHandler,
+		Import:   importHandler,
+		Report:   reportHandler,
+		Audit:    auditHandler,
+		Webhook:  webhookHandler,
+		GraphQL:  graphqlHandler,
+		Health:   healthHandler,
+	}, cfg.AuditActorHeader, logger, m, tp)
 
 	// Configure HTTP server
 	srv := &http.Server{
@@ -63,28 +290,125 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Start server in a goroutine so it doesn't block graceful shutdown
+	// Configure gRPC server
+	grpcSrv, grpcHealth := grpcapi.NewServer(grpcapi.NewOrderServer(orderService, bus), cfg.AuditActorHeader, logger, tp)
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		return fmt.Errorf("failed to listen on gRPC port: %w", err)
+	}
+
+	// Start servers in goroutines so they don't block graceful shutdown
+	serverErr := make(chan error, 2)
 	go func() {
-		log.Printf("Server starting on port %s", cfg.ServerPort)
+		logger.Info("server starting", "port", cfg.ServerPort)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			serverErr <- err
+		}
+	}()
+	go func() {
+		logger.Info("grpc server starting", "port", cfg.GRPCPort)
+		if err := grpcSrv.Serve(grpcListener); err != nil {
+			serverErr <- fmt.Errorf("grpc: %w", err)
 		}
 	}()
 
 	// Wait for interrupt signal to gracefully shut down the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("failed to start server: %w", err)
+	case <-quit:
+	}
+	logger.Info("shutting down server")
+
+	// Fail readiness first and give load balancers time to stop sending traffic
+	healthHandler.MarkShuttingDown()
+	grpcHealth.Shutdown()
+	time.Sleep(time.Duration(cfg.DrainDelay) * time.Second)
 
 	// Create context with timeout for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Gracefully shutdown the server
+	// End open order streams, then gracefully shut down the servers
+	bus.Close()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+	stopGRPC(ctx, grpcSrv)
+
+	// Stop background workers
+	stopBackground()
+	workers.Wait()
+
+	// Flush pending spans
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("failed to shut down tracing", "error", err)
+	}
+
+	logger.Info("server exited properly")
+	return nil
+}
+
+// stopGRPC gracefully stops the gRPC server, closing any remaining
+// connections once ctx is done.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
 	}
+}
+
+// newPublisher creates the outbox publisher selected in the configuration.
+// The returned function releases any file it opened.
+func newPublisher(cfg *config.Config) (outbox.Publisher, func() error, error) {
+	switch cfg.OutboxPublisher {
+	case "file":
+		f, err := os.OpenFile(cfg.OutboxFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open outbox file: %w", err)
+		}
+		return outbox.NewWriterPublisher(f), f.Close, nil
+	default:
+		return outbox.NewWriterPublisher(os.Stdout), func() error { return nil }, nil
+	}
+}
 
-	log.Println("Server exited properly")
+// newInvoiceService creates the invoice service with the seller details and
+// fiscal year settings of the configuration.
+func newInvoiceService(cfg *config.Config, db *sql.DB) (*services.InvoiceService, error) {
+	location, err := time.LoadLocation(cfg.InvoiceTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice time zone: %w", err)
+	}
+
+	return services.NewInvoiceService(repository.NewInvoiceRepo(db), services.InvoiceSettings{
+		Seller: domain.InvoiceParty{
+			Name:    cfg.InvoiceSellerName,
+			VATID:   cfg.InvoiceSellerVATID,
+			Address: cfg.InvoiceSellerAddress,
+			Country: cfg.InvoiceSellerCountry,
+		},
+		Currency:        cfg.InvoiceCurrency,
+		Location:        location,
+		FiscalYearStart: time.Month(cfg.InvoiceFiscalStart),
+	}), nil
+}
+
+// newPaymentGateway creates the payment gateway selected in the configuration.
+func newPaymentGateway(cfg *config.Config) (services.PaymentGateway, error) {
+	switch cfg.PaymentGateway {
+	case "fake":
+		return payments.NewFakeGateway(), nil
+	default:
+		return nil, fmt.Errorf("unknown payment gateway %q", cfg.PaymentGateway)
+	}
 }
@@ -0,0 +1,36 @@
+// Package actor carries the identity of whoever makes a request, as
+// established by authentication, so that changes can be attributed to them.
+package actor
+
+import "context"
+
+// MaxLength bounds actors so they can't bloat logs or the audit log.
+const MaxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the given actor.
+func NewContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// FromContext returns the actor stored in ctx, or an empty string for
+// unauthenticated requests and background work.
+func FromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(contextKey{}).(string)
+	return actor
+}
+
+// Valid accepts non-empty actors of at most MaxLength printable ASCII
+// characters, spaces included.
+func Valid(actor string) bool {
+	if actor == "" || len(actor) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(actor); i++ {
+		if actor[i] < 0x20 || actor[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
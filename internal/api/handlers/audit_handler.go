@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type AuditHandler struct {
+	auditService services.AuditServiceInterface
+}
+
+func NewAuditHandler(auditService services.AuditServiceInterface) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditEntries handles HTTP GET requests listing the audit log, oldest
+// entry first. The optional entity_type, entity_id, action, actor and
+// request_id query parameters filter the entries, from and to (RFC 3339
+// times, to exclusive) bound their creation time, and limit and after_id
+// page through them like ListOrders.
+//
+// It returns a 400 Bad Request response for invalid numbers or times and a
+// 500 Internal Server Error response if the entries cannot be loaded.
+func (h *AuditHandler) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.AuditFilter{
+		EntityType: query.Get("entity_type"),
+		Action:     query.Get("action"),
+		Actor:      query.Get("actor"),
+		RequestID:  query.Get("request_id"),
+	}
+
+	for _, param := range []struct {
+		name string
+		dest *int64
+	}{
+		{"entity_id", &filter.EntityID},
+		{"after_id", &filter.AfterID},
+	} {
+		if raw := query.Get(param.name); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || id < 0 {
+				writeError(w, r, http.StatusBadRequest, "Invalid "+param.name)
+				return
+			}
+			*param.dest = id
+		}
+	}
+	for _, param := range []struct {
+		name string
+		dest *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		if raw := query.Get(param.name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, "Invalid "+param.name+", expected an RFC 3339 time")
+				return
+			}
+			*param.dest = t
+		}
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			writeError(w, r, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	response, err := h.auditService.ListAuditEntries(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) ListAuditEntries(ctx context.Context, filter domain.AuditFilter) (*domain.AuditListResponse, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AuditListResponse), args.Error(1)
+}
+
+func TestListAuditEntries(t *testing.T) {
+	t.Run("Passes the filters", func(t *testing.T) {
+		// Setup
+		mockService := new(MockAuditService)
+		handler := NewAuditHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/audit?entity_type=order&entity_id=7&action=order.updated&actor=alice"+
+			"&request_id=req-1&from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00%2B02:00&after_id=40&limit=2", nil)
+		w := httptest.NewRecorder()
+
+		mockService.On("ListAuditEntries", mock.Anything, domain.AuditFilter{
+			EntityType: "order",
+			EntityID:   7,
+			Action:     "order.updated",
+			Actor:      "alice",
+			RequestID:  "req-1",
+			From:       time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			To:         time.Date(2024, 5, 2, 0, 0, 0, 0, time.FixedZone("", 2*60*60)),
+			AfterID:    40,
+			Limit:      2,
+		}).Return(&domain.AuditListResponse{
+			Entries: []domain.AuditEntry{{
+				ID: 42, EntityType: "order", EntityID: 7, Action: "order.updated", Actor: "alice",
+				Before: []byte(`{"status":"pending"}`), After: []byte(`{"status":"paid"}`),
+			}},
+			NextAfterID: 42,
+		}, nil)
+
+		handler.ListAuditEntries(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"before":{"status":"pending"}`)
+		assert.Contains(t, w.Body.String(), `"next_after_id":42`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		for query, message := range map[string]string{
+			"entity_id=abc":   "Invalid entity_id",
+			"after_id=-1":     "Invalid after_id",
+			"from=2024-05-01": "Invalid from",
+			"limit=0":         "Invalid limit",
+		} {
+			// Setup
+			handler := NewAuditHandler(new(MockAuditService))
+
+			req := httptest.NewRequest("GET", "/api/audit?"+query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ListAuditEntries(w, req)
+
+			// Assertions
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+			assert.Contains(t, w.Body.String(), message, query)
+		}
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		// Setup
+		mockService := new(MockAuditService)
+		handler := NewAuditHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/audit", nil)
+		w := httptest.NewRecorder()
+
+		mockService.On("ListAuditEntries", mock.Anything, domain.AuditFilter{}).Return(nil, errors.New("db error"))
+
+		handler.ListAuditEntries(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
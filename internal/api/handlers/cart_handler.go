@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type CartHandler struct {
+	cartService services.CartServiceInterface
+}
+
+func NewCartHandler(cartService services.CartServiceInterface) *CartHandler {
+	return &CartHandler{
+		cartService: cartService,
+	}
+}
+
+// CreateCart handles HTTP POST requests creating an empty cart.
+// It returns 201 Created with the cart; its ID is used for every later call.
+func (h *CartHandler) CreateCart(w http.ResponseWriter, r *http.Request) {
+	// The body is optional; an empty one creates an anonymous cart
+	var req domain.CreateCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	cart, err := h.cartService.CreateCart(r.Context(), &req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cart)
+}
+
+// GetCart handles HTTP GET requests for a cart, priced with the current
+// product prices. Expired carts return 404 Not Found.
+func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	cart, err := h.cartService.GetCart(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// AddItem handles HTTP POST requests adding a product to a cart. Adding a
+// product already in the cart increases its quantity.
+func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	var item domain.CartItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	cart, err := h.cartService.AddItem(r.Context(), mux.Vars(r)["id"], item)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// UpdateItem handles HTTP PUT requests setting the quantity of a product
+// already in a cart.
+func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r, "productID")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req domain.UpdateCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	cart, err := h.cartService.UpdateItem(r.Context(), mux.Vars(r)["id"], domain.CartItem{ProductID: productID, Quantity: req.Quantity})
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// RemoveItem handles HTTP DELETE requests removing a product from a cart.
+// It returns the updated cart.
+func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r, "productID")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	cart, err := h.cartService.RemoveItem(r.Context(), mux.Vars(r)["id"], productID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// Checkout handles HTTP POST requests converting a cart into an order.
+// It returns 201 Created with the order. A cart can only be checked out once;
+// later attempts return 409 Conflict.
+func (h *CartHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	order, err := h.cartService.Checkout(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, order)
+}
+
+func (h *CartHandler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCart), errors.Is(err, domain.ErrProductNotFound):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrCartNotFound), errors.Is(err, domain.ErrCartItemNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrCartCheckedOut):
+		writeError(w, r, http.StatusConflict, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type MockCartService struct {
+	mock.Mock
+	services.CartServiceInterface
+}
+
+func (m *MockCartService) CreateCart(ctx context.Context, req *domain.CreateCartRequest) (*domain.CartResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CartResponse), args.Error(1)
+}
+
+func (m *MockCartService) UpdateItem(ctx context.Context, id string, item domain.CartItem) (*domain.CartResponse, error) {
+	args := m.Called(ctx, id, item)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CartResponse), args.Error(1)
+}
+
+func (m *MockCartService) Checkout(ctx context.Context, id string) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func TestCartHandler(t *testing.T) {
+	t.Run("Create cart without body", func(t *testing.T) {
+		// Setup
+		mockService := new(MockCartService)
+		handler := NewCartHandler(mockService)
+		mockService.On("CreateCart", mock.Anything, &domain.CreateCartRequest{}).
+			Return(&domain.CartResponse{CartID: "abc", Status: domain.CartStatusOpen}, nil)
+
+		req := httptest.NewRequest("POST", "/api/carts", nil)
+		w := httptest.NewRecorder()
+
+		handler.CreateCart(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.CartResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "abc", response.CartID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Update item takes the product from the path", func(t *testing.T) {
+		// Setup
+		mockService := new(MockCartService)
+		handler := NewCartHandler(mockService)
+		mockService.On("UpdateItem", mock.Anything, "abc", domain.CartItem{ProductID: 2, Quantity: 5}).
+			Return(&domain.CartResponse{CartID: "abc"}, nil)
+
+		req := httptest.NewRequest("PUT", "/api/carts/abc/items/2", strings.NewReader(`{"quantity": 5}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "abc", "productID": "2"})
+		w := httptest.NewRecorder()
+
+		handler.UpdateItem(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Checkout", func(t *testing.T) {
+		// Setup
+		mockService := new(MockCartService)
+		handler := NewCartHandler(mockService)
+		mockService.On("Checkout", mock.Anything, "abc").Return(&domain.OrderResponse{OrderID: 7}, nil)
+
+		req := httptest.NewRequest("POST", "/api/carts/abc/checkout", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "abc"})
+		w := httptest.NewRecorder()
+
+		handler.Checkout(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.OrderResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(7), response.OrderID)
+	})
+
+	t.Run("Checkout errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{domain.ErrCartCheckedOut, http.StatusConflict},
+			{domain.ErrCartNotFound, http.StatusNotFound},
+			{services.ErrInvalidCart, http.StatusBadRequest},
+			{domain.ErrProductNotFound, http.StatusBadRequest},
+		}
+		for _, c := range cases {
+			// Setup
+			mockService := new(MockCartService)
+			handler := NewCartHandler(mockService)
+			mockService.On("Checkout", mock.Anything, "abc").Return(nil, c.err)
+
+			req := httptest.NewRequest("POST", "/api/carts/abc/checkout", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "abc"})
+			w := httptest.NewRecorder()
+
+			handler.Checkout(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code, c.err.Error())
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// orderETag returns the entity tag of an order at the given version.
+func orderETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagVersion parses an If-Match header holding a single strong order ETag
+// and returns its version.
+func etagVersion(header string) (int, bool) {
+	tag := strings.TrimSpace(header)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// noneMatch reports whether an If-None-Match header matches etag, using the
+// weak comparison: a W/ prefix is ignored and "*" matches any ETag.
+func noneMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Health check statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// DBChecker is the subset of *sql.DB used by the readiness probe.
+type DBChecker interface {
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+}
+
+// CheckResult is the outcome of a single readiness check.
+type CheckResult struct {
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// HealthResponse is the JSON body returned by the liveness and readiness probes.
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type HealthHandler struct {
+	db           DBChecker
+	pingTimeout  time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(db DBChecker, pingTimeout time.Duration) *HealthHandler {
+	return &HealthHandler{
+		db:          db,
+		pingTimeout: pingTimeout,
+	}
+}
+
+// MarkShuttingDown makes the readiness probe fail from now on so that load
+// balancers stop routing new traffic while in-flight requests drain.
+func (h *HealthHandler) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Livez handles the liveness probe. It only reports that the process is able
+// to serve HTTP and never checks external dependencies.
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, HealthResponse{Status: StatusOK})
+}
+
+// Readyz handles the readiness probe. It reports 200 OK only when the service
+// is not shutting down, the database answers a ping within the configured
+// timeout and the connection pool is not saturated. Otherwise it returns
+// 503 Service Unavailable. Every check is reported in the body either way.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]CheckResult{
+		"shutdown": h.checkShutdown(),
+		"database": h.checkDatabase(r.Context()),
+		"pool":     h.checkPool(),
+	}
+
+	response := HealthResponse{Status: StatusOK, Checks: checks}
+	status := http.StatusOK
+	for _, check := range checks {
+		if check.Status != StatusOK {
+			response.Status = StatusFail
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	writeJSON(w, status, response)
+}
+
+func (h *HealthHandler) checkShutdown() CheckResult {
+	if h.shuttingDown.Load() {
+		return CheckResult{Status: StatusFail, Error: "shutting down"}
+	}
+	return CheckResult{Status: StatusOK}
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.pingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := h.db.PingContext(ctx)
+	details := map[string]any{
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		return CheckResult{Status: StatusFail, Error: err.Error(), Details: details}
+	}
+	return CheckResult{Status: StatusOK, Details: details}
+}
+
+// checkPool reports connection pool usage. The pool is considered saturated,
+// and the check fails, when every allowed connection is in use.
+func (h *HealthHandler) checkPool() CheckResult {
+	stats := h.db.Stats()
+
+	var saturation float64
+	if stats.MaxOpenConnections > 0 {
+		saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+
+	result := CheckResult{
+		Status: StatusOK,
+		Details: map[string]any{
+			"open":          stats.OpenConnections,
+			"in_use":        stats.InUse,
+			"idle":          stats.Idle,
+			"max_open":      stats.MaxOpenConnections,
+			"saturation":    saturation,
+			"wait_count":    stats.WaitCount,
+			"wait_duration": stats.WaitDuration.String(),
+		},
+	}
+
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+		result.Status = StatusFail
+		result.Error = "connection pool saturated"
+	}
+	return result
+}
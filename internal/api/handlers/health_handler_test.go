@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDB is a stub implementation of the DBChecker interface
+type fakeDB struct {
+	pingErr error
+	stats   sql.DBStats
+}
+
+func (f *fakeDB) PingContext(ctx context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeDB) Stats() sql.DBStats {
+	return f.stats
+}
+
+func TestLivez(t *testing.T) {
+	handler := NewHealthHandler(&fakeDB{pingErr: errors.New("connection refused")}, time.Second)
+
+	req := httptest.NewRequest("GET", "/livez", nil)
+	w := httptest.NewRecorder()
+
+	handler.Livez(w, req)
+
+	// Liveness does not depend on the database
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	readyz := func(handler *HealthHandler) (int, HealthResponse) {
+		req := httptest.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		handler.Readyz(w, req)
+
+		var response HealthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	t.Run("Ready", func(t *testing.T) {
+		handler := NewHealthHandler(&fakeDB{stats: sql.DBStats{MaxOpenConnections: 25, InUse: 5}}, time.Second)
+
+		code, response := readyz(handler)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, StatusOK, response.Status)
+		assert.Equal(t, StatusOK, response.Checks["database"].Status)
+		assert.Equal(t, StatusOK, response.Checks["shutdown"].Status)
+		assert.Equal(t, 0.2, response.Checks["pool"].Details["saturation"])
+	})
+
+	t.Run("Database unreachable", func(t *testing.T) {
+		handler := NewHealthHandler(&fakeDB{pingErr: errors.New("connection refused")}, time.Second)
+
+		code, response := readyz(handler)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, StatusFail, response.Status)
+		assert.Equal(t, StatusFail, response.Checks["database"].Status)
+		assert.Equal(t, "connection refused", response.Checks["database"].Error)
+	})
+
+	t.Run("Pool saturated", func(t *testing.T) {
+		handler := NewHealthHandler(&fakeDB{stats: sql.DBStats{MaxOpenConnections: 25, InUse: 25}}, time.Second)
+
+		code, response := readyz(handler)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, StatusFail, response.Checks["pool"].Status)
+	})
+
+	t.Run("Shutting down", func(t *testing.T) {
+		handler := NewHealthHandler(&fakeDB{}, time.Second)
+		handler.MarkShuttingDown()
+
+		code, response := readyz(handler)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, StatusFail, response.Checks["shutdown"].Status)
+		assert.Equal(t, StatusOK, response.Checks["database"].Status)
+	})
+}
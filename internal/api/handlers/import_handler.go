@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/orderimport"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+// importMaxBytes bounds the size of an import file.
+const importMaxBytes = 32 << 20
+
+// importFormats maps the accepted content types to import formats.
+var importFormats = map[string]string{
+	"text/csv":             domain.ImportFormatCSV,
+	"application/csv":      domain.ImportFormatCSV,
+	"application/x-ndjson": domain.ImportFormatNDJSON,
+	"application/ndjson":   domain.ImportFormatNDJSON,
+	"application/jsonl":    domain.ImportFormatNDJSON,
+}
+
+type ImportHandler struct {
+	importService services.ImportServiceInterface
+}
+
+func NewImportHandler(importService services.ImportServiceInterface) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// ImportOrders handles HTTP POST requests importing orders from a CSV or
+// NDJSON file sent as the body, in the format given by the Content-Type
+// header or the format query parameter. The mode query parameter selects
+// best_effort, the default, or all_or_nothing, and dry_run=true only
+// validates and prices the rows.
+//
+// Small files are imported at once and return 200 OK with the finished job
+// and a result per row. Larger ones return 202 Accepted with the queued job
+// and its URL in the Location header. It returns 400 Bad Request for
+// unreadable files or invalid parameters, 413 Request Entity Too Large for
+// files over 32 MiB and 415 Unsupported Media Type for other formats.
+func (h *ImportHandler) ImportOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := domain.ImportRequest{Format: query.Get("format"), Mode: query.Get("mode")}
+
+	if req.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		req.Format = importFormats[mediaType]
+	}
+	if req.Format != domain.ImportFormatCSV && req.Format != domain.ImportFormatNDJSON {
+		writeError(w, r, http.StatusUnsupportedMediaType, "Import files must be CSV or NDJSON")
+		return
+	}
+	if raw := query.Get("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid dry_run")
+			return
+		}
+		req.DryRun = dryRun
+	}
+
+	rows, err := orderimport.Parse(http.MaxBytesReader(w, r.Body, importMaxBytes), req.Format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "Import file is too large")
+			return
+		}
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Rows = rows
+
+	job, err := h.importService.ImportOrders(r.Context(), &req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	if job.Status == domain.ImportStatusQueued {
+		w.Header().Set("Location", "/api/orders/import/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// GetImportJob handles HTTP GET requests returning the progress of an
+// import job and the results of the rows processed so far.
+func (h *ImportHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.importService.GetImportJob(r.Context(), mux.Vars(r)["jobID"])
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (h *ImportHandler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidImport):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrImportJobNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
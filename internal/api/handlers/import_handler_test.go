@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type MockImportService struct {
+	mock.Mock
+	services.ImportServiceInterface
+}
+
+func (m *MockImportService) ImportOrders(ctx context.Context, req *domain.ImportRequest) (*domain.ImportJob, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ImportJob), args.Error(1)
+}
+
+func (m *MockImportService) GetImportJob(ctx context.Context, id string) (*domain.ImportJob, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ImportJob), args.Error(1)
+}
+
+func TestImportHandler(t *testing.T) {
+	t.Run("Import CSV", func(t *testing.T) {
+		// Setup
+		mockService := new(MockImportService)
+		handler := NewImportHandler(mockService)
+		mockService.On("ImportOrders", mock.Anything, mock.MatchedBy(func(req *domain.ImportRequest) bool {
+			return req.Format == domain.ImportFormatCSV && req.Mode == domain.ImportModeAllOrNothing && req.DryRun &&
+				len(req.Rows) == 1 && req.Rows[0].Order.Items[0] == domain.OrderItem{ProductID: 1, Quantity: 2}
+		})).Return(&domain.ImportJob{ID: "job", Status: domain.ImportStatusCompleted}, nil)
+
+		body := "product_id,quantity\n1,2\n"
+		req := httptest.NewRequest("POST", "/api/orders/import?mode=all_or_nothing&dry_run=true", strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+		w := httptest.NewRecorder()
+
+		handler.ImportOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response domain.ImportJob
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, domain.ImportStatusCompleted, response.Status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Queued import", func(t *testing.T) {
+		// Setup
+		mockService := new(MockImportService)
+		handler := NewImportHandler(mockService)
+		mockService.On("ImportOrders", mock.Anything, mock.MatchedBy(func(req *domain.ImportRequest) bool {
+			return req.Format == domain.ImportFormatNDJSON
+		})).Return(&domain.ImportJob{ID: "job", Status: domain.ImportStatusQueued}, nil)
+
+		body := `{"order": {"items": [{"product_id": 1, "quantity": 1}]}}`
+		req := httptest.NewRequest("POST", "/api/orders/import?format=ndjson", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.ImportOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "/api/orders/import/job", w.Header().Get("Location"))
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		cases := []struct {
+			url         string
+			contentType string
+			body        string
+			status      int
+		}{
+			{"/api/orders/import", "application/json", "{}", http.StatusUnsupportedMediaType},
+			{"/api/orders/import?dry_run=maybe", "text/csv", "product_id,quantity\n", http.StatusBadRequest},
+			{"/api/orders/import", "text/csv", "product_id\n1\n", http.StatusBadRequest},
+		}
+		for _, c := range cases {
+			// Setup
+			handler := NewImportHandler(new(MockImportService))
+			req := httptest.NewRequest("POST", c.url, strings.NewReader(c.body))
+			req.Header.Set("Content-Type", c.contentType)
+			w := httptest.NewRecorder()
+
+			handler.ImportOrders(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code, c.url)
+		}
+	})
+
+	t.Run("Invalid import", func(t *testing.T) {
+		// Setup
+		mockService := new(MockImportService)
+		handler := NewImportHandler(mockService)
+		mockService.On("ImportOrders", mock.Anything, mock.Anything).Return(nil, services.ErrInvalidImport)
+
+		req := httptest.NewRequest("POST", "/api/orders/import?mode=sometimes", strings.NewReader("product_id,quantity\n1,1\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+
+		handler.ImportOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Get unknown import job", func(t *testing.T) {
+		// Setup
+		mockService := new(MockImportService)
+		handler := NewImportHandler(mockService)
+		mockService.On("GetImportJob", mock.Anything, "missing").Return(nil, domain.ErrImportJobNotFound)
+
+		req := httptest.NewRequest("GET", "/api/orders/import/missing", nil)
+		req = mux.SetURLVars(req, map[string]string{"jobID": "missing"})
+		w := httptest.NewRecorder()
+
+		handler.GetImportJob(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
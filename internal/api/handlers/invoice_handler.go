@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/invoice"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+// Invoice representations, in order of preference when the client accepts
+// several equally.
+const (
+	contentTypeJSON = "application/json"
+	contentTypePDF  = "application/pdf"
+	contentTypeXML  = "application/xml"
+	contentTypeUBL  = "application/vnd.oasis.ubl+xml"
+)
+
+type InvoiceHandler struct {
+	invoiceService services.InvoiceServiceInterface
+}
+
+func NewInvoiceHandler(invoiceService services.InvoiceServiceInterface) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+	}
+}
+
+// GetInvoice handles HTTP GET requests returning the invoice of a paid
+// order, issuing it on the first request. The representation is chosen from
+// the Accept header: JSON, a PDF document, or UBL 2.1 XML for
+// application/xml and application/vnd.oasis.ubl+xml. Other media types
+// return 406 Not Acceptable and unpaid orders 409 Conflict.
+func (h *InvoiceHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Vary", "Accept")
+
+	contentType := negotiate(r.Header.Get("Accept"), contentTypeJSON, contentTypePDF, contentTypeXML, contentTypeUBL)
+	if contentType == "" {
+		writeError(w, r, http.StatusNotAcceptable, "Supported media types are application/json, application/pdf, application/xml and application/vnd.oasis.ubl+xml")
+		return
+	}
+
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	inv, err := h.invoiceService.GetInvoice(r.Context(), orderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrOrderNotFound):
+			writeError(w, r, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrOrderNotInvoiceable):
+			writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			writeError(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if contentType == contentTypeJSON {
+		writeJSON(w, http.StatusOK, inv)
+		return
+	}
+
+	// Render before writing so a failure still gets an error response
+	var body bytes.Buffer
+	extension := "xml"
+	if contentType == contentTypePDF {
+		extension = "pdf"
+		err = invoice.WritePDF(&body, inv)
+	} else {
+		err = invoice.WriteUBL(&body, inv)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to render invoice", "invoice", inv.Number, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to render invoice")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", "invoice-"+inv.Number+"."+extension))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
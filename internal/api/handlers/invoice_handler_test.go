@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type MockInvoiceService struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceService) GetInvoice(ctx context.Context, orderID int64) (*domain.Invoice, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invoice), args.Error(1)
+}
+
+func TestGetInvoice(t *testing.T) {
+	inv := &domain.Invoice{
+		Number:   "2024-000042",
+		OrderID:  1,
+		Currency: "EUR",
+		Seller:   domain.InvoiceParty{Name: "Shop"},
+		Lines:    []domain.InvoiceLine{{ProductID: 1, Description: "Product 1", Quantity: 2, UnitPrice: 2.0, Price: 4.0, VAT: 0.4, VATRate: 10}},
+		Price:    4.0,
+		VAT:      0.4,
+		Total:    4.4,
+		IssuedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	cases := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"JSON by default", "", "application/json", `"number":"2024-000042"`},
+		{"PDF", "application/pdf", "application/pdf", "%PDF-1.4"},
+		{"UBL", "application/xml", "application/xml", "<cbc:UBLVersionID>2.1</cbc:UBLVersionID>"},
+		{"UBL media type", "application/vnd.oasis.ubl+xml", "application/vnd.oasis.ubl+xml", "<Invoice"},
+		{"Preferred by quality", "application/json;q=0.5, application/pdf", "application/pdf", "%PDF"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// Setup
+			mockService := new(MockInvoiceService)
+			handler := NewInvoiceHandler(mockService)
+			mockService.On("GetInvoice", mock.Anything, int64(1)).Return(inv, nil)
+
+			req := httptest.NewRequest("GET", "/api/orders/1/invoice", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			if c.accept != "" {
+				req.Header.Set("Accept", c.accept)
+			}
+			w := httptest.NewRecorder()
+
+			handler.GetInvoice(w, req)
+
+			// Assertions
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, c.contentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			assert.Contains(t, w.Body.String(), c.body)
+		})
+	}
+
+	t.Run("Not acceptable", func(t *testing.T) {
+		// Setup
+		handler := NewInvoiceHandler(new(MockInvoiceService))
+		req := httptest.NewRequest("GET", "/api/orders/1/invoice", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+
+		handler.GetInvoice(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	})
+
+	t.Run("Unpaid order", func(t *testing.T) {
+		// Setup
+		mockService := new(MockInvoiceService)
+		handler := NewInvoiceHandler(mockService)
+		mockService.On("GetInvoice", mock.Anything, int64(1)).Return(nil, domain.ErrOrderNotInvoiceable)
+
+		req := httptest.NewRequest("GET", "/api/orders/1/invoice", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.GetInvoice(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var response ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, strings.Contains(response.Error, "paid"))
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "application/pdf"}
+
+	assert.Equal(t, "application/json", negotiate("", offers...))
+	assert.Equal(t, "application/json", negotiate("*/*", offers...))
+	assert.Equal(t, "application/pdf", negotiate("application/*;q=0.5, application/pdf", offers...))
+	assert.Equal(t, "application/pdf", negotiate("*/*;q=0.1, application/json;q=0", offers...))
+	assert.Equal(t, "", negotiate("text/html", offers...))
+}
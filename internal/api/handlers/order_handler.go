@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/orderexport"
 	"github.com/valeriouberti/order-service-test/internal/services"
 )
 
@@ -26,8 +32,11 @@ func NewOrderHandler(orderService services.OrderServiceInterface) *OrderHandler
 //
 // The handler expects a request body containing a JSON representation of domain.CreateOrderRequest.
 // Upon successful creation, it returns a 201 Created status with the order details.
-// In case of errors, it returns appropriate HTTP error codes:
-// - 400 Bad Request: For invalid JSON or orders with no items
+// In case of errors, it returns a JSON error body carrying the request ID with
+// appropriate HTTP error codes:
+// - 400 Bad Request: For invalid JSON, orders with no items, invalid quote
+// tokens or items that do not match the quote
+// - 409 Conflict: For expired quote tokens
 // - 500 Internal Server Error: For errors during order processing
 //
 // @param w http.ResponseWriter - The response writer to write the HTTP response
@@ -36,27 +45,74 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreateOrderRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Validate request
 	if len(req.Order.Items) == 0 {
-		http.Error(w, "Order must contain at least one item", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Order must contain at least one item")
 		return
 	}
 
 	// Process the order
 	response, err := h.orderService.CreateOrder(r.Context(), &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, quoteErrorStatus(err), err.Error())
 		return
 	}
 
 	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	w.Header().Set("ETag", orderETag(response.Version))
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// QuoteOrder handles HTTP POST requests pricing an order without placing it.
+// The body is the same as for CreateOrder. The response holds the priced
+// items and totals and a quote token; passing the token as quote_token to
+// CreateOrder before it expires places the order at the quoted prices.
+//
+// It returns a 400 Bad Request response for invalid JSON, orders with no
+// items or unknown products and a 500 Internal Server Error response if
+// pricing fails; internal errors are logged rather than returned.
+func (h *OrderHandler) QuoteOrder(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateOrderRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Order.Items) == 0 {
+		writeError(w, r, http.StatusBadRequest, "Order must contain at least one item")
+		return
+	}
+
+	response, err := h.orderService.QuoteOrder(r.Context(), &req)
+	if errors.Is(err, domain.ErrProductNotFound) {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to quote order", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to quote order")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// quoteErrorStatus maps quote errors returned by CreateOrder to client error
+// statuses; other errors are internal.
+func quoteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidQuote), errors.Is(err, domain.ErrQuoteMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrQuoteExpired):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 // GetOrder handles HTTP GET requests to retrieve order details by ID.
@@ -68,24 +124,271 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 // it returns a 404 Not Found response with the error message.
 // On success, it returns a 200 OK response with the order details as JSON.
 //
+// The response carries the order's version as its ETag. A request whose
+// If-None-Match header matches it gets a 304 Not Modified response without
+// a body.
+//
 // The response format is determined by the order service implementation.
 func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	// Parse order ID from URL
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
 		return
 	}
 
 	// Get the order
 	response, err := h.orderService.GetOrder(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, err.Error())
 		return
 	}
 
 	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	etag := orderETag(response.Version)
+	w.Header().Set("ETag", etag)
+	if noneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ListOrders handles HTTP GET requests listing orders, oldest first.
+// The optional customer_id and status query parameters filter the orders,
+// limit sets the page size and after_id continues from the next_after_id
+// returned with the previous page.
+//
+// It returns a 400 Bad Request response if limit or after_id are not valid
+// numbers and a 500 Internal Server Error response if the orders cannot be
+// loaded.
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	filter, err := orderFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.orderService.ListOrders(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// orderFilter parses the customer_id, status, limit and after_id query
+// parameters of the order listings.
+func orderFilter(query url.Values) (domain.OrderFilter, error) {
+	filter := domain.OrderFilter{
+		CustomerID: query.Get("customer_id"),
+		Status:     query.Get("status"),
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return filter, errors.New("Invalid limit")
+		}
+		filter.Limit = limit
+	}
+	if raw := query.Get("after_id"); raw != "" {
+		afterID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || afterID < 0 {
+			return filter, errors.New("Invalid after_id")
+		}
+		filter.AfterID = afterID
+	}
+
+	return filter, nil
+}
+
+// exportFlushRows is the number of orders written between flushes of an
+// export to the client.
+const exportFlushRows = 100
+
+// ExportOrders handles HTTP GET requests exporting orders, oldest first. It
+// takes the filters of ListOrders, but without a limit every matching order
+// is exported. The rows parameter selects one row per order (the default)
+// or one row per item, with the order columns repeated on each.
+//
+// The format parameter selects csv (the default), ndjson or parquet; without
+// it the format is negotiated from the Accept header. Rows are streamed from
+// a database cursor as they are read, so exports of any size neither hold
+// the orders in memory nor hit the server write timeout.
+//
+// It returns a 400 Bad Request response for invalid filters, formats or
+// rows, and 406 Not Acceptable if the Accept header allows none of the
+// formats. If the export fails after rows were sent the connection is
+// aborted, so clients never mistake a truncated export for a complete one.
+func (h *OrderHandler) ExportOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := orderFilter(query)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		w.Header().Set("Vary", "Accept")
+		switch negotiate(r.Header.Get("Accept"), "text/csv", "application/x-ndjson", "application/vnd.apache.parquet") {
+		case "text/csv":
+			format = orderexport.FormatCSV
+		case "application/x-ndjson":
+			format = orderexport.FormatNDJSON
+		case "application/vnd.apache.parquet":
+			format = orderexport.FormatParquet
+		default:
+			writeError(w, r, http.StatusNotAcceptable, "Supported media types are text/csv, application/x-ndjson and application/vnd.apache.parquet")
+			return
+		}
+	}
+	rows := query.Get("rows")
+	if rows == "" {
+		rows = orderexport.RowsOrder
+	}
+
+	out := &exportResponse{w: w}
+	export, err := orderexport.NewWriter(out, format, rows)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Exports outlive the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeError(w, r, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	w.Header().Set("Content-Type", orderexport.ContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"orders.%s\"", format))
+
+	var n int
+	err = h.orderService.ExportOrders(r.Context(), filter, func(order *domain.OrderResponse) error {
+		if err := export.Write(order); err != nil {
+			return err
+		}
+		if n++; n%exportFlushRows == 0 {
+			if err := export.Flush(); err != nil {
+				return err
+			}
+			if out.written {
+				return rc.Flush()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = export.Close()
+	}
+	if err != nil {
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			writeError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		logging.FromContext(r.Context()).Error("order export failed", "format", format, "orders", n, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportResponse records whether an export started writing its body, after
+// which errors can no longer be reported with a status code.
+type exportResponse struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (e *exportResponse) Write(p []byte) (int, error) {
+	e.written = true
+	return e.w.Write(p)
+}
+
+// UpdateOrder handles HTTP PATCH requests changing the lines of a pending
+// order. The body lists item changes: each item sets the quantity of its
+// product, adding products that are not in the order and removing products
+// whose quantity is zero. It returns 200 OK with the repriced order and its
+// new ETag.
+//
+// The If-Match header must carry the ETag of the order the changes were made
+// against, as returned by GetOrder. Without it the request fails with 428
+// Precondition Required, and if the order changed since with 412
+// Precondition Failed, so concurrent edits never overwrite each other.
+//
+// It returns a 400 Bad Request response for invalid JSON, invalid changes or
+// unknown products, 404 Not Found for unknown orders and 409 Conflict once
+// the order is no longer pending.
+func (h *OrderHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, r, http.StatusPreconditionRequired, "If-Match header is required")
+		return
+	}
+	version, ok := etagVersion(ifMatch)
+	if !ok {
+		writeError(w, r, http.StatusPreconditionFailed, domain.ErrVersionMismatch.Error())
+		return
+	}
+
+	var req domain.UpdateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Version = version
+
+	response, err := h.orderService.UpdateOrder(r.Context(), id, &req)
+	if err != nil {
+		writeError(w, r, updateErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", orderETag(response.Version))
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ListOrderRevisions handles HTTP GET requests returning the revision
+// history of an order, oldest first. Each revision holds the requested
+// changes and the items and totals before and after them.
+func (h *OrderHandler) ListOrderRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	revisions, err := h.orderService.ListOrderRevisions(r.Context(), id)
+	if err != nil {
+		writeError(w, r, updateErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+// updateErrorStatus maps errors returned when changing an order to statuses.
+func updateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidOrderUpdate), errors.Is(err, domain.ErrProductNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrOrderNotEditable):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
 }
@@ -5,14 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+	"github.com/valeriouberti/order-service-test/internal/services"
 )
 
 // MockOrderService is a mock implementation of the OrderServicer interface
@@ -28,6 +32,34 @@ func (m *MockOrderService) CreateOrder(ctx context.Context, req *domain.CreateOr
 	return args.Get(0).(*domain.OrderResponse), args.Error(1)
 }
 
+func (m *MockOrderService) CreateOrders(ctx context.Context, reqs []*domain.CreateOrderRequest) ([]*domain.OrderResponse, error) {
+	args := m.Called(ctx, reqs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) ExportOrders(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.OrderResponse) error) error {
+	args := m.Called(ctx, filter, fn)
+	if orders, ok := args.Get(0).([]*domain.OrderResponse); ok {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockOrderService) QuoteOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.QuoteResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.QuoteResponse), args.Error(1)
+}
+
 func (m *MockOrderService) GetOrder(ctx context.Context, id int64) (*domain.OrderResponse, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -36,6 +68,30 @@ func (m *MockOrderService) GetOrder(ctx context.Context, id int64) (*domain.Orde
 	return args.Get(0).(*domain.OrderResponse), args.Error(1)
 }
 
+func (m *MockOrderService) ListOrders(ctx context.Context, filter domain.OrderFilter) (*domain.OrderListResponse, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderListResponse), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrder(ctx context.Context, id int64, req *domain.UpdateOrderRequest) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) ListOrderRevisions(ctx context.Context, id int64) ([]domain.OrderRevision, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.OrderRevision), args.Error(1)
+}
+
 func TestCreateOrder(t *testing.T) {
 	// Setup
 	mockService := new(MockOrderService)
@@ -48,9 +104,7 @@ func TestCreateOrder(t *testing.T) {
 
 		// Create request body
 		reqBody := domain.CreateOrderRequest{
-			Order: struct {
-				Items []domain.OrderItem `json:"items"`
-			}{
+			Order: domain.OrderInput{
 				Items: []domain.OrderItem{
 					{ProductID: 1, Quantity: 2},
 					{ProductID: 2, Quantity: 3},
@@ -109,9 +163,7 @@ func TestCreateOrder(t *testing.T) {
 		mockService.Calls = nil
 		// Create request body with no items
 		reqBody := domain.CreateOrderRequest{
-			Order: struct {
-				Items []domain.OrderItem `json:"items"`
-			}{
+			Order: domain.OrderInput{
 				Items: []domain.OrderItem{},
 			},
 		}
@@ -164,9 +216,7 @@ func TestCreateOrder(t *testing.T) {
 
 		// Create request body
 		reqBody := domain.CreateOrderRequest{
-			Order: struct {
-				Items []domain.OrderItem `json:"items"`
-			}{
+			Order: domain.OrderInput{
 				Items: []domain.OrderItem{
 					{ProductID: 1, Quantity: 2},
 				},
@@ -296,4 +346,443 @@ func TestGetOrder(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 		assert.Contains(t, w.Body.String(), "Invalid order ID")
 	})
+
+	t.Run("Error body includes request ID", func(t *testing.T) {
+		// Reset mock
+		mockService.ExpectedCalls = nil
+		mockService.Calls = nil
+
+		// Create HTTP request carrying a request ID in its context
+		req := httptest.NewRequest("GET", "/orders/999", nil)
+		req = req.WithContext(requestid.NewContext(req.Context(), "req-123"))
+		req = mux.SetURLVars(req, map[string]string{"id": "999"})
+
+		// Create response recorder
+		w := httptest.NewRecorder()
+
+		// Set up mock to return error
+		mockService.On("GetOrder", mock.Anything, int64(999)).Return(nil, errors.New("order not found"))
+
+		// Call handler
+		handler.GetOrder(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "order not found", response.Error)
+		assert.Equal(t, "req-123", response.RequestID)
+	})
+}
+
+func TestListOrders(t *testing.T) {
+	t.Run("Passes filters to the service", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/orders?customer_id=cust-1&status=pending&limit=10&after_id=5", nil)
+		w := httptest.NewRecorder()
+
+		mockService.On("ListOrders", mock.Anything, domain.OrderFilter{
+			CustomerID: "cust-1",
+			Status:     "pending",
+			AfterID:    5,
+			Limit:      10,
+		}).Return(&domain.OrderListResponse{
+			Orders:      []domain.OrderResponse{{OrderID: 6, CustomerID: "cust-1", Status: "pending"}},
+			NextAfterID: 6,
+		}, nil)
+
+		handler.ListOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response domain.OrderListResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Orders, 1)
+		assert.Equal(t, int64(6), response.NextAfterID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		// Setup
+		handler := NewOrderHandler(new(MockOrderService))
+
+		req := httptest.NewRequest("GET", "/api/orders?limit=abc", nil)
+		w := httptest.NewRecorder()
+
+		handler.ListOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid limit")
+	})
+}
+
+func TestExportOrders(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	orders := []*domain.OrderResponse{
+		{OrderID: 1, CustomerID: "cust-1", Status: "paid", CreatedAt: createdAt, Items: []domain.OrderItem{
+			{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0},
+			{ProductID: 2, Quantity: 1, Price: 5.0, VAT: 0.5},
+		}},
+	}
+
+	t.Run("Exports one CSV row per item", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/orders/export?customer_id=cust-1&status=paid&rows=item", nil)
+		w := httptest.NewRecorder()
+
+		mockService.On("ExportOrders", mock.Anything, domain.OrderFilter{CustomerID: "cust-1", Status: "paid"}, mock.Anything).Return(orders, nil)
+
+		handler.ExportOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="orders.csv"`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "order_id,customer_id,status,created_at,product_id,quantity,price,vat\n"+
+			"1,cust-1,paid,2024-05-01T12:00:00Z,1,2,20,2\n"+
+			"1,cust-1,paid,2024-05-01T12:00:00Z,2,1,5,0.5\n", w.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Negotiates NDJSON from the Accept header", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/orders/export", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		w := httptest.NewRecorder()
+
+		mockService.On("ExportOrders", mock.Anything, domain.OrderFilter{}, mock.Anything).Return(orders, nil)
+
+		handler.ExportOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		var row map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &row))
+		assert.Equal(t, 2.0, row["items"])
+	})
+
+	t.Run("Unsupported media type", func(t *testing.T) {
+		// Setup
+		handler := NewOrderHandler(new(MockOrderService))
+
+		req := httptest.NewRequest("GET", "/api/orders/export", nil)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.ExportOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	})
+
+	t.Run("Invalid rows", func(t *testing.T) {
+		// Setup
+		handler := NewOrderHandler(new(MockOrderService))
+
+		req := httptest.NewRequest("GET", "/api/orders/export?rows=product", nil)
+		w := httptest.NewRecorder()
+
+		handler.ExportOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unsupported export")
+	})
+
+	t.Run("Failure before any row was sent", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/orders/export?format=ndjson", nil)
+		w := httptest.NewRecorder()
+
+		mockService.On("ExportOrders", mock.Anything, domain.OrderFilter{}, mock.Anything).Return(orders, errors.New("database error"))
+
+		handler.ExportOrders(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("Failure after rows were sent aborts the response", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/orders/export", nil)
+		w := httptest.NewRecorder()
+
+		many := make([]*domain.OrderResponse, exportFlushRows)
+		for i := range many {
+			many[i] = orders[0]
+		}
+		mockService.On("ExportOrders", mock.Anything, domain.OrderFilter{}, mock.Anything).Return(many, errors.New("database error"))
+
+		// Assertions
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() { handler.ExportOrders(w, req) })
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, w.Flushed)
+	})
+}
+
+func TestQuoteOrder(t *testing.T) {
+	t.Run("Successful quote", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		body := `{"order": {"items": [{"product_id": 1, "quantity": 2}]}}`
+		req := httptest.NewRequest("POST", "/api/orders/quote", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		mockService.On("QuoteOrder", mock.Anything, mock.AnythingOfType("*domain.CreateOrderRequest")).Return(&domain.QuoteResponse{
+			OrderPrice: 20.0,
+			OrderVAT:   2.0,
+			Items:      []domain.OrderItem{{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0}},
+			QuoteToken: "token",
+		}, nil)
+
+		handler.QuoteOrder(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response domain.QuoteResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 20.0, response.OrderPrice)
+		assert.Equal(t, "token", response.QuoteToken)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Unknown product", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		body := `{"order": {"items": [{"product_id": 99, "quantity": 1}]}}`
+		req := httptest.NewRequest("POST", "/api/orders/quote", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		mockService.On("QuoteOrder", mock.Anything, mock.Anything).
+			Return(nil, fmt.Errorf("product with ID 99 not found: %w", domain.ErrProductNotFound))
+
+		handler.QuoteOrder(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "product with ID 99 not found")
+	})
+
+	t.Run("Internal error is not echoed", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		body := `{"order": {"items": [{"product_id": 1, "quantity": 1}]}}`
+		req := httptest.NewRequest("POST", "/api/orders/quote", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		mockService.On("QuoteOrder", mock.Anything, mock.Anything).
+			Return(nil, errors.New("pq: connection refused"))
+
+		handler.QuoteOrder(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "Failed to quote order")
+		assert.NotContains(t, w.Body.String(), "pq:")
+	})
+
+	t.Run("Expired quote on order creation", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		body := `{"order": {"items": [{"product_id": 1, "quantity": 2}]}, "quote_token": "token"}`
+		req := httptest.NewRequest("POST", "/api/orders", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		mockService.On("CreateOrder", mock.Anything, mock.MatchedBy(func(req *domain.CreateOrderRequest) bool {
+			return req.QuoteToken == "token"
+		})).Return(nil, domain.ErrQuoteExpired)
+
+		handler.CreateOrder(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "quote expired")
+	})
+}
+
+func TestUpdateOrder(t *testing.T) {
+	t.Run("Successful update", func(t *testing.T) {
+		// Setup
+		mockService := new(MockOrderService)
+		handler := NewOrderHandler(mockService)
+
+		body := `{"items": [{"product_id": 1, "quantity": 3}, {"product_id": 2, "quantity": 0}]}`
+		req := httptest.NewRequest("PATCH", "/api/orders/1", bytes.NewBufferString(body))
+		req.Header.Set("If-Match", `"2"`)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		mockService.On("UpdateOrder", mock.Anything, int64(1), &domain.UpdateOrderRequest{Version: 2, Items: []domain.OrderItem{
+			{ProductID: 1, Quantity: 3},
+			{ProductID: 2, Quantity: 0},
+		}}).Return(&domain.OrderResponse{
+			OrderID:    1,
+			Status:     domain.OrderStatusPending,
+			Version:    3,
+			OrderPrice: 30.0,
+			OrderVAT:   3.0,
+			Items:      []domain.OrderItem{{ProductID: 1, Quantity: 3, Price: 30.0, VAT: 3.0}},
+		}, nil)
+
+		handler.UpdateOrder(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response domain.OrderResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 30.0, response.OrderPrice)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{domain.ErrOrderNotEditable, http.StatusConflict},
+			{domain.ErrVersionMismatch, http.StatusPreconditionFailed},
+			{domain.ErrOrderNotFound, http.StatusNotFound},
+			{services.ErrInvalidOrderUpdate, http.StatusBadRequest},
+			{errors.New("db down"), http.StatusInternalServerError},
+		}
+		for _, c := range cases {
+			// Setup
+			mockService := new(MockOrderService)
+			handler := NewOrderHandler(mockService)
+
+			req := httptest.NewRequest("PATCH", "/api/orders/1", bytes.NewBufferString(`{"items": [{"product_id": 1, "quantity": 1}]}`))
+			req.Header.Set("If-Match", `"1"`)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+
+			mockService.On("UpdateOrder", mock.Anything, int64(1), mock.Anything).Return(nil, c.err)
+
+			handler.UpdateOrder(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code, c.err.Error())
+		}
+	})
+
+	t.Run("If-Match is required", func(t *testing.T) {
+		// Setup
+		handler := NewOrderHandler(new(MockOrderService))
+
+		req := httptest.NewRequest("PATCH", "/api/orders/1", bytes.NewBufferString(`{"items": [{"product_id": 1, "quantity": 1}]}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.UpdateOrder(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	})
+
+	t.Run("Malformed If-Match never matches", func(t *testing.T) {
+		// Setup
+		handler := NewOrderHandler(new(MockOrderService))
+
+		req := httptest.NewRequest("PATCH", "/api/orders/1", bytes.NewBufferString(`{"items": [{"product_id": 1, "quantity": 1}]}`))
+		req.Header.Set("If-Match", `W/"1"`)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.UpdateOrder(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+}
+
+func TestGetOrderConditional(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		ifNoneMatch string
+		status      int
+	}{
+		{"No condition", "", http.StatusOK},
+		{"Current version", `"4"`, http.StatusNotModified},
+		{"Weak current version in a list", `"3", W/"4"`, http.StatusNotModified},
+		{"Stale version", `"3"`, http.StatusOK},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			// Setup
+			mockService := new(MockOrderService)
+			handler := NewOrderHandler(mockService)
+			mockService.On("GetOrder", mock.Anything, int64(1)).Return(&domain.OrderResponse{OrderID: 1, Version: 4}, nil)
+
+			req := httptest.NewRequest("GET", "/api/orders/1", nil)
+			if c.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", c.ifNoneMatch)
+			}
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+
+			handler.GetOrder(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code)
+			assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+			if c.status == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListOrderRevisions(t *testing.T) {
+	// Setup
+	mockService := new(MockOrderService)
+	handler := NewOrderHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/api/orders/1/revisions", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+
+	mockService.On("ListOrderRevisions", mock.Anything, int64(1)).Return([]domain.OrderRevision{
+		{Revision: 1, Changes: []domain.OrderItem{{ProductID: 1, Quantity: 3}}, PriceBefore: 20.0, PriceAfter: 30.0},
+	}, nil)
+
+	handler.ListOrderRevisions(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response []domain.OrderRevision
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response, 1)
+	assert.Equal(t, 1, response[0].Revision)
 }
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/eventbus"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+)
+
+type OrderStreamHandler struct {
+	bus       *eventbus.Bus
+	heartbeat time.Duration
+}
+
+func NewOrderStreamHandler(bus *eventbus.Bus, heartbeat time.Duration) *OrderStreamHandler {
+	return &OrderStreamHandler{
+		bus:       bus,
+		heartbeat: heartbeat,
+	}
+}
+
+// matchesOrderEvent reports whether the order snapshot carried by event
+// satisfies filter.
+func matchesOrderEvent(filter domain.OrderFilter, event domain.Event) bool {
+	if filter.CustomerID == "" && filter.Status == "" {
+		return true
+	}
+
+	var order domain.Order
+	if err := json.Unmarshal(event.Payload, &order); err != nil {
+		return false
+	}
+	return filter.Matches(&order)
+}
+
+// Stream handles HTTP GET requests opening a Server-Sent Events stream of
+// order changes. Each event carries the order snapshot as data, its type as
+// the event name and a bus sequence number as the ID.
+//
+// Clients may filter by the customer_id and status query parameters. A client
+// that reconnects with a Last-Event-ID header (or last_event_id query parameter)
+// first receives the recent events it missed. Comment lines are sent
+// periodically to keep idle connections open.
+//
+// Slow clients are disconnected instead of slowing down order creation; they
+// can reconnect and resume from their last event ID.
+func (h *OrderStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	filter := domain.OrderFilter{
+		CustomerID: r.URL.Query().Get("customer_id"),
+		Status:     r.URL.Query().Get("status"),
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var afterID int64
+	if lastEventID != "" {
+		var err error
+		if afterID, err = strconv.ParseInt(lastEventID, 10, 64); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	// Streams outlive the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		writeError(w, r, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	sub := h.bus.Subscribe(afterID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event domain.Event) error {
+		if !matchesOrderEvent(filter, event) {
+			return nil
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Payload); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// Tell the client how long to wait before reconnecting
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for _, event := range sub.Replay {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				if sub.Lagged() {
+					logging.FromContext(r.Context()).Warn("order stream client too slow, disconnecting")
+				}
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
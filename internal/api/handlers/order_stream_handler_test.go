@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/eventbus"
+)
+
+func orderEvent(t *testing.T, customerID, status string) domain.Event {
+	event, err := domain.NewOrderEvent(domain.EventOrderCreated, &domain.Order{ID: 1, CustomerID: customerID, Status: status})
+	require.NoError(t, err)
+	return *event
+}
+
+// readEvent reads the next SSE frame carrying an ID, skipping comments and
+// retry hints.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	frame := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if frame["id"] != "" {
+				return frame
+			}
+			frame = map[string]string{}
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ": "); ok && key != "" {
+			frame[key] = value
+		}
+	}
+}
+
+func TestOrderStream(t *testing.T) {
+	openStream := func(t *testing.T, bus *eventbus.Bus, query string, header http.Header) *bufio.Reader {
+		server := httptest.NewServer(http.HandlerFunc(NewOrderStreamHandler(bus, time.Hour).Stream))
+		t.Cleanup(server.Close)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/orders/stream"+query, nil)
+		require.NoError(t, err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		// Wait for the subscription before publishing
+		require.Eventually(t, func() bool { return bus.Subscribers() == 1 }, time.Second, time.Millisecond)
+		return bufio.NewReader(resp.Body)
+	}
+
+	t.Run("Streams live events", func(t *testing.T) {
+		// Setup
+		bus := eventbus.New(10, 10)
+		stream := openStream(t, bus, "", nil)
+
+		bus.Publish(orderEvent(t, "cust-1", domain.OrderStatusPending))
+
+		// Assertions
+		frame := readEvent(t, stream)
+		assert.Equal(t, "1", frame["id"])
+		assert.Equal(t, domain.EventOrderCreated, frame["event"])
+
+		var order domain.Order
+		require.NoError(t, json.Unmarshal([]byte(frame["data"]), &order))
+		assert.Equal(t, "cust-1", order.CustomerID)
+	})
+
+	t.Run("Filters by customer and status", func(t *testing.T) {
+		// Setup
+		bus := eventbus.New(10, 10)
+		stream := openStream(t, bus, "?customer_id=cust-2&status=pending", nil)
+
+		bus.Publish(orderEvent(t, "cust-1", domain.OrderStatusPending))
+		bus.Publish(orderEvent(t, "cust-2", "paid"))
+		bus.Publish(orderEvent(t, "cust-2", domain.OrderStatusPending))
+
+		// Assertions
+		frame := readEvent(t, stream)
+		assert.Equal(t, "3", frame["id"])
+	})
+
+	t.Run("Resumes after Last-Event-ID", func(t *testing.T) {
+		// Setup
+		bus := eventbus.New(10, 10)
+		bus.Publish(orderEvent(t, "cust-1", domain.OrderStatusPending))
+		bus.Publish(orderEvent(t, "cust-1", domain.OrderStatusPending))
+
+		stream := openStream(t, bus, "", http.Header{"Last-Event-Id": {"1"}})
+		bus.Publish(orderEvent(t, "cust-1", domain.OrderStatusPending))
+
+		// Assertions
+		assert.Equal(t, "2", readEvent(t, stream)["id"])
+		assert.Equal(t, "3", readEvent(t, stream)["id"])
+	})
+
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		// Setup
+		handler := NewOrderStreamHandler(eventbus.New(10, 10), time.Hour)
+		req := httptest.NewRequest("GET", "/api/orders/stream?last_event_id=abc", nil)
+		w := httptest.NewRecorder()
+
+		handler.Stream(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
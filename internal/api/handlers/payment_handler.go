@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type PaymentHandler struct {
+	paymentService services.PaymentServiceInterface
+}
+
+func NewPaymentHandler(paymentService services.PaymentServiceInterface) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+	}
+}
+
+// CreatePayment handles HTTP POST requests authorizing a payment for an
+// order, and capturing it when the body sets capture. It returns 201 Created
+// with the payment. Declined payments return 402 Payment Required and
+// payments above the order balance 409 Conflict.
+func (h *PaymentHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	var req domain.CreatePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	payment, err := h.paymentService.CreatePayment(r.Context(), orderID, &req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, payment)
+}
+
+// ListPayments handles HTTP GET requests returning the payments of an order
+// reconciled against the order total.
+func (h *PaymentHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	summary, err := h.paymentService.ListPayments(r.Context(), orderID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// CapturePayment handles HTTP POST requests capturing an authorized payment.
+func (h *PaymentHandler) CapturePayment(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.paymentService.CapturePayment)
+}
+
+// VoidPayment handles HTTP POST requests voiding an authorized payment.
+func (h *PaymentHandler) VoidPayment(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.paymentService.VoidPayment)
+}
+
+// transition runs an operation on the payment identified by the request path.
+func (h *PaymentHandler) transition(w http.ResponseWriter, r *http.Request, operation func(ctx context.Context, orderID, paymentID int64) (*domain.Payment, error)) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+	paymentID, err := pathID(r, "paymentID")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	payment, err := operation(r.Context(), orderID, paymentID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payment)
+}
+
+func (h *PaymentHandler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPayment):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrPaymentDeclined):
+		writeError(w, r, http.StatusPaymentRequired, err.Error())
+	case errors.Is(err, domain.ErrOrderNotFound), errors.Is(err, domain.ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrPaymentExceedsBalance), errors.Is(err, domain.ErrOrderNotPayable),
+		errors.Is(err, domain.ErrPaymentStatus):
+		writeError(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrPaymentGateway):
+		writeError(w, r, http.StatusBadGateway, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
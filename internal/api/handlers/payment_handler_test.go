@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type MockPaymentService struct {
+	mock.Mock
+	services.PaymentServiceInterface
+}
+
+func (m *MockPaymentService) CreatePayment(ctx context.Context, orderID int64, req *domain.CreatePaymentRequest) (*domain.Payment, error) {
+	args := m.Called(ctx, orderID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Payment), args.Error(1)
+}
+
+func (m *MockPaymentService) CapturePayment(ctx context.Context, orderID, paymentID int64) (*domain.Payment, error) {
+	args := m.Called(ctx, orderID, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Payment), args.Error(1)
+}
+
+func TestPaymentHandler(t *testing.T) {
+	t.Run("Create payment", func(t *testing.T) {
+		// Setup
+		mockService := new(MockPaymentService)
+		handler := NewPaymentHandler(mockService)
+		mockService.On("CreatePayment", mock.Anything, int64(1), &domain.CreatePaymentRequest{Amount: 10.0, PaymentMethod: "card", Capture: true}).
+			Return(&domain.Payment{ID: 3, OrderID: 1, Amount: 10.0, Status: domain.PaymentStatusCaptured}, nil)
+
+		body := `{"amount": 10.0, "payment_method": "card", "capture": true}`
+		req := httptest.NewRequest("POST", "/api/orders/1/payments", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.CreatePayment(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.Payment
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, domain.PaymentStatusCaptured, response.Status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Create payment errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{domain.ErrPaymentDeclined, http.StatusPaymentRequired},
+			{domain.ErrPaymentExceedsBalance, http.StatusConflict},
+			{domain.ErrOrderNotPayable, http.StatusConflict},
+			{domain.ErrOrderNotFound, http.StatusNotFound},
+			{services.ErrInvalidPayment, http.StatusBadRequest},
+			{services.ErrPaymentGateway, http.StatusBadGateway},
+		}
+		for _, c := range cases {
+			// Setup
+			mockService := new(MockPaymentService)
+			handler := NewPaymentHandler(mockService)
+			mockService.On("CreatePayment", mock.Anything, int64(1), mock.Anything).Return(nil, c.err)
+
+			req := httptest.NewRequest("POST", "/api/orders/1/payments", bytes.NewBufferString(`{"amount": 10.0}`))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+
+			handler.CreatePayment(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code, c.err.Error())
+		}
+	})
+
+	t.Run("Capture payment", func(t *testing.T) {
+		// Setup
+		mockService := new(MockPaymentService)
+		handler := NewPaymentHandler(mockService)
+		mockService.On("CapturePayment", mock.Anything, int64(1), int64(3)).Return(nil, domain.ErrPaymentStatus)
+
+		req := httptest.NewRequest("POST", "/api/orders/1/payments/3/capture", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1", "paymentID": "3"})
+		w := httptest.NewRecorder()
+
+		handler.CapturePayment(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type RefundHandler struct {
+	refundService services.RefundServiceInterface
+}
+
+func NewRefundHandler(refundService services.RefundServiceInterface) *RefundHandler {
+	return &RefundHandler{
+		refundService: refundService,
+	}
+}
+
+// CreateRefund handles HTTP POST requests refunding order lines or an
+// amount. An empty body refunds everything captured and not yet refunded.
+// It returns 201 Created with the credit note; refunds above the captured
+// amount or the ordered quantities return 409 Conflict.
+func (h *RefundHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	var req domain.CreateRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	note, err := h.refundService.CreateRefund(r.Context(), orderID, &req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+// ListCreditNotes handles HTTP GET requests returning the credit notes of an
+// order.
+func (h *RefundHandler) ListCreditNotes(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	notes, err := h.refundService.ListCreditNotes(r.Context(), orderID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notes)
+}
+
+// GetCreditNote handles HTTP GET requests returning a credit note of an
+// order.
+func (h *RefundHandler) GetCreditNote(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+	noteID, err := pathID(r, "creditNoteID")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid credit note ID")
+		return
+	}
+
+	note, err := h.refundService.GetCreditNote(r.Context(), orderID, noteID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (h *RefundHandler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRefund):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrOrderNotFound), errors.Is(err, domain.ErrCreditNoteNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrRefundExceedsCaptured), errors.Is(err, domain.ErrRefundExceedsQuantity):
+		writeError(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrPaymentGateway):
+		writeError(w, r, http.StatusBadGateway, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type MockRefundService struct {
+	mock.Mock
+	services.RefundServiceInterface
+}
+
+func (m *MockRefundService) CreateRefund(ctx context.Context, orderID int64, req *domain.CreateRefundRequest) (*domain.CreditNote, error) {
+	args := m.Called(ctx, orderID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CreditNote), args.Error(1)
+}
+
+func (m *MockRefundService) GetCreditNote(ctx context.Context, orderID, id int64) (*domain.CreditNote, error) {
+	args := m.Called(ctx, orderID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CreditNote), args.Error(1)
+}
+
+func TestRefundHandler(t *testing.T) {
+	t.Run("Create refund", func(t *testing.T) {
+		// Setup
+		mockService := new(MockRefundService)
+		handler := NewRefundHandler(mockService)
+		mockService.On("CreateRefund", mock.Anything, int64(1), &domain.CreateRefundRequest{
+			Items:  []domain.OrderItem{{ProductID: 2, Quantity: 1}},
+			Reason: "damaged",
+		}).Return(&domain.CreditNote{ID: 7, Number: "CN-1-1", OrderID: 1, Status: domain.CreditNoteStatusIssued, Amount: 27.5}, nil)
+
+		body := `{"items": [{"product_id": 2, "quantity": 1}], "reason": "damaged"}`
+		req := httptest.NewRequest("POST", "/api/orders/1/refunds", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.CreateRefund(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.CreditNote
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "CN-1-1", response.Number)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Empty body refunds everything", func(t *testing.T) {
+		// Setup
+		mockService := new(MockRefundService)
+		handler := NewRefundHandler(mockService)
+		mockService.On("CreateRefund", mock.Anything, int64(1), &domain.CreateRefundRequest{}).
+			Return(&domain.CreditNote{ID: 7, OrderID: 1}, nil)
+
+		req := httptest.NewRequest("POST", "/api/orders/1/refunds", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.CreateRefund(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Create refund errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{services.ErrInvalidRefund, http.StatusBadRequest},
+			{domain.ErrOrderNotFound, http.StatusNotFound},
+			{domain.ErrRefundExceedsCaptured, http.StatusConflict},
+			{domain.ErrRefundExceedsQuantity, http.StatusConflict},
+			{services.ErrPaymentGateway, http.StatusBadGateway},
+		}
+		for _, c := range cases {
+			// Setup
+			mockService := new(MockRefundService)
+			handler := NewRefundHandler(mockService)
+			mockService.On("CreateRefund", mock.Anything, int64(1), mock.Anything).Return(nil, c.err)
+
+			req := httptest.NewRequest("POST", "/api/orders/1/refunds", bytes.NewBufferString(`{"amount": 5.0}`))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+
+			handler.CreateRefund(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code, c.err.Error())
+		}
+	})
+
+	t.Run("Get missing credit note", func(t *testing.T) {
+		// Setup
+		mockService := new(MockRefundService)
+		handler := NewRefundHandler(mockService)
+		mockService.On("GetCreditNote", mock.Anything, int64(1), int64(7)).Return(nil, domain.ErrCreditNoteNotFound)
+
+		req := httptest.NewRequest("GET", "/api/orders/1/credit-notes/7", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1", "creditNoteID": "7"})
+		w := httptest.NewRecorder()
+
+		handler.GetCreditNote(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
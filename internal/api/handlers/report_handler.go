@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+// reportCacheControl lets clients reuse a report for five minutes. Reports
+// are business figures, so shared caches must not store them.
+const reportCacheControl = "private, max-age=300"
+
+type ReportHandler struct {
+	reportService services.ReportServiceInterface
+}
+
+func NewReportHandler(reportService services.ReportServiceInterface) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// SalesReport handles HTTP GET requests for the sales report: order counts,
+// revenue, VAT, refunds and average order value per day, week or month.
+// The from, to, tz, granularity and status query parameters are optional.
+//
+// It returns a 400 Bad Request response for invalid parameters. Reports are
+// cacheable: they carry an ETag and a 304 Not Modified response is returned
+// when the If-None-Match header matches it.
+func (h *ReportHandler) SalesReport(w http.ResponseWriter, r *http.Request) {
+	req, ok := reportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.SalesReport(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeReport(w, r, report)
+}
+
+// TopProducts handles HTTP GET requests ranking the products sold by
+// quantity or revenue (by parameter), with the parameters of SalesReport
+// but granularity and a limit on the number of products.
+func (h *ReportHandler) TopProducts(w http.ResponseWriter, r *http.Request) {
+	req, ok := reportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.TopProducts(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeReport(w, r, report)
+}
+
+// reportRequest reads the query parameters of a report, writing a 400 Bad
+// Request response if the limit is not a number.
+func reportRequest(w http.ResponseWriter, r *http.Request) (*domain.ReportRequest, bool) {
+	query := r.URL.Query()
+	req := &domain.ReportRequest{
+		From:        query.Get("from"),
+		To:          query.Get("to"),
+		TimeZone:    query.Get("tz"),
+		Granularity: query.Get("granularity"),
+		Statuses:    query.Get("status"),
+		By:          query.Get("by"),
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid limit")
+			return nil, false
+		}
+		req.Limit = limit
+	}
+
+	return req, true
+}
+
+// writeReport writes a report as JSON with caching headers. The ETag is a
+// digest of the body, so it changes whenever the figures do.
+func writeReport(w http.ResponseWriter, r *http.Request, report any) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	digest := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(digest[:16]) + `"`
+
+	w.Header().Set("Cache-Control", reportCacheControl)
+	w.Header().Set("ETag", etag)
+	if noneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(body, '\n'))
+}
+
+func (h *ReportHandler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReport):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
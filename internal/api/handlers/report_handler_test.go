@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type MockReportService struct {
+	mock.Mock
+}
+
+func (m *MockReportService) SalesReport(ctx context.Context, req *domain.ReportRequest) (*domain.SalesReport, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SalesReport), args.Error(1)
+}
+
+func (m *MockReportService) TopProducts(ctx context.Context, req *domain.ReportRequest) (*domain.TopProductsReport, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.TopProductsReport), args.Error(1)
+}
+
+func TestSalesReport(t *testing.T) {
+	report := &domain.SalesReport{
+		From: "2024-05-01", To: "2024-05-01", TimeZone: "Europe/Rome", Granularity: "day",
+		Periods: []domain.SalesPeriod{{Period: "2024-05-01", SalesTotals: domain.SalesTotals{Orders: 2, Revenue: 30.0}}},
+		Total:   domain.SalesTotals{Orders: 2, Revenue: 30.0, AverageOrderValue: 15.0},
+	}
+
+	t.Run("Passes the parameters and returns a cacheable report", func(t *testing.T) {
+		// Setup
+		mockService := new(MockReportService)
+		handler := NewReportHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/reports/sales?from=2024-05-01&to=2024-05-01&tz=Europe/Rome&granularity=day&status=paid", nil)
+		w := httptest.NewRecorder()
+
+		mockService.On("SalesReport", mock.Anything, &domain.ReportRequest{
+			From: "2024-05-01", To: "2024-05-01", TimeZone: "Europe/Rome", Granularity: "day", Statuses: "paid",
+		}).Return(report, nil)
+
+		handler.SalesReport(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, max-age=300", w.Header().Get("Cache-Control"))
+		assert.NotEmpty(t, w.Header().Get("ETag"))
+
+		var response domain.SalesReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, *report, response)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Not modified", func(t *testing.T) {
+		// Setup
+		mockService := new(MockReportService)
+		handler := NewReportHandler(mockService)
+		mockService.On("SalesReport", mock.Anything, mock.Anything).Return(report, nil)
+
+		w := httptest.NewRecorder()
+		handler.SalesReport(w, httptest.NewRequest("GET", "/api/reports/sales", nil))
+		etag := w.Header().Get("ETag")
+
+		req := httptest.NewRequest("GET", "/api/reports/sales", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+
+		handler.SalesReport(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		// Setup
+		mockService := new(MockReportService)
+		handler := NewReportHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/reports/sales?granularity=year", nil)
+		w := httptest.NewRecorder()
+
+		mockService.On("SalesReport", mock.Anything, mock.Anything).Return(nil,
+			fmt.Errorf("%w: granularity must be day, week or month", services.ErrInvalidReport))
+
+		handler.SalesReport(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "granularity must be")
+	})
+}
+
+func TestTopProductsReport(t *testing.T) {
+	t.Run("Passes the ranking and limit", func(t *testing.T) {
+		// Setup
+		mockService := new(MockReportService)
+		handler := NewReportHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/reports/top-products?by=revenue&limit=5", nil)
+		w := httptest.NewRecorder()
+
+		mockService.On("TopProducts", mock.Anything, &domain.ReportRequest{By: "revenue", Limit: 5}).Return(&domain.TopProductsReport{
+			By:       "revenue",
+			Products: []domain.ProductSales{{ProductID: 5, Name: "Product 5", Quantity: 2, Revenue: 20.0, VAT: 2.0}},
+		}, nil)
+
+		handler.TopProducts(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"product_id":5`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		// Setup
+		handler := NewReportHandler(new(MockReportService))
+
+		req := httptest.NewRequest("GET", "/api/reports/top-products?limit=ten", nil)
+		w := httptest.NewRecorder()
+
+		handler.TopProducts(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid limit")
+	})
+}
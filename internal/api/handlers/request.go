@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// pathID parses the named route variable as a 64-bit integer ID.
+func pathID(r *http.Request, name string) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+}
+
+// negotiate returns the offered media type the Accept header prefers, or ""
+// if it accepts none of them. Each offer takes the quality of the most
+// specific media range matching it; ties go to the earlier offer. A missing
+// header accepts the first offer.
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		quality, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			mediaRange, params, _ := strings.Cut(part, ";")
+			mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+
+			s := -1
+			switch {
+			case mediaRange == offer:
+				s = 2
+			case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*")):
+				s = 1
+			case mediaRange == "*/*":
+				s = 0
+			}
+			if s <= specificity {
+				continue
+			}
+			specificity, quality = s, 1.0
+			for _, param := range strings.Split(params, ";") {
+				key, value, _ := strings.Cut(param, "=")
+				if strings.TrimSpace(key) == "q" {
+					if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+						quality = q
+					}
+				}
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+
+	return best
+}
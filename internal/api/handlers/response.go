@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+// ErrorResponse is the JSON body returned for every failed request.
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeJSON encodes v as JSON with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error body that carries the request ID so clients
+// can quote it when reporting a failure.
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeJSON(w, status, ErrorResponse{
+		Error:     message,
+		RequestID: requestid.FromContext(r.Context()),
+	})
+}
+
+// NotFound writes a JSON error for requests matching no route.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, "Not found")
+}
+
+// MethodNotAllowed writes a JSON error for requests to a route that does
+// not accept their method.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+}
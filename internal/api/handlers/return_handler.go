@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type ReturnHandler struct {
+	returnService services.ReturnServiceInterface
+}
+
+func NewReturnHandler(returnService services.ReturnServiceInterface) *ReturnHandler {
+	return &ReturnHandler{
+		returnService: returnService,
+	}
+}
+
+// CreateReturn handles HTTP POST requests opening a return for quantities
+// of order lines. It returns 201 Created with the return; quantities above
+// what is left to return return 409 Conflict.
+func (h *ReturnHandler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	var req domain.CreateReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ret, err := h.returnService.CreateReturn(r.Context(), orderID, &req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ret)
+}
+
+// ListReturns handles HTTP GET requests returning the returns of an order.
+func (h *ReturnHandler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	returns, err := h.returnService.ListReturns(r.Context(), orderID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, returns)
+}
+
+// GetReturn handles HTTP GET requests returning a return of an order.
+func (h *ReturnHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	h.operate(w, r, h.returnService.GetReturn)
+}
+
+// ApproveReturn handles HTTP POST requests approving a requested return.
+func (h *ReturnHandler) ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	h.operate(w, r, h.returnService.ApproveReturn)
+}
+
+// RejectReturn handles HTTP POST requests rejecting a return that has not
+// been received.
+func (h *ReturnHandler) RejectReturn(w http.ResponseWriter, r *http.Request) {
+	h.operate(w, r, h.returnService.RejectReturn)
+}
+
+// ReceiveReturn handles HTTP POST requests recording the receipt of the
+// goods of an approved return.
+func (h *ReturnHandler) ReceiveReturn(w http.ResponseWriter, r *http.Request) {
+	h.operate(w, r, h.returnService.ReceiveReturn)
+}
+
+// RefundReturn handles HTTP POST requests refunding a received return.
+func (h *ReturnHandler) RefundReturn(w http.ResponseWriter, r *http.Request) {
+	h.operate(w, r, h.returnService.RefundReturn)
+}
+
+// operate runs an operation on the return identified by the request path.
+func (h *ReturnHandler) operate(w http.ResponseWriter, r *http.Request, operation func(ctx context.Context, orderID, id int64) (*domain.Return, error)) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+	returnID, err := pathID(r, "returnID")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid return ID")
+		return
+	}
+
+	ret, err := operation(r.Context(), orderID, returnID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (h *ReturnHandler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReturn), errors.Is(err, services.ErrInvalidRefund):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrOrderNotFound), errors.Is(err, domain.ErrReturnNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrReturnExceedsQuantity), errors.Is(err, domain.ErrReturnStatus),
+		errors.Is(err, domain.ErrOrderNotReturnable), errors.Is(err, domain.ErrRefundExceedsCaptured),
+		errors.Is(err, domain.ErrRefundExceedsQuantity):
+		writeError(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrPaymentGateway):
+		writeError(w, r, http.StatusBadGateway, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
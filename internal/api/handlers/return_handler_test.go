@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type MockReturnService struct {
+	mock.Mock
+	services.ReturnServiceInterface
+}
+
+func (m *MockReturnService) CreateReturn(ctx context.Context, orderID int64, req *domain.CreateReturnRequest) (*domain.Return, error) {
+	args := m.Called(ctx, orderID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Return), args.Error(1)
+}
+
+func (m *MockReturnService) ReceiveReturn(ctx context.Context, orderID, id int64) (*domain.Return, error) {
+	args := m.Called(ctx, orderID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Return), args.Error(1)
+}
+
+func TestReturnHandler(t *testing.T) {
+	t.Run("Create return", func(t *testing.T) {
+		// Setup
+		mockService := new(MockReturnService)
+		handler := NewReturnHandler(mockService)
+		mockService.On("CreateReturn", mock.Anything, int64(1), &domain.CreateReturnRequest{
+			Items:  []domain.OrderItem{{ProductID: 2, Quantity: 1}},
+			Reason: "wrong size",
+		}).Return(&domain.Return{ID: 4, OrderID: 1, Status: domain.ReturnStatusRequested}, nil)
+
+		body := `{"items": [{"product_id": 2, "quantity": 1}], "reason": "wrong size"}`
+		req := httptest.NewRequest("POST", "/api/orders/1/returns", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.CreateReturn(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.Return
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, domain.ReturnStatusRequested, response.Status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Create return errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{services.ErrInvalidReturn, http.StatusBadRequest},
+			{domain.ErrOrderNotFound, http.StatusNotFound},
+			{domain.ErrReturnExceedsQuantity, http.StatusConflict},
+			{domain.ErrOrderNotReturnable, http.StatusConflict},
+		}
+		for _, c := range cases {
+			// Setup
+			mockService := new(MockReturnService)
+			handler := NewReturnHandler(mockService)
+			mockService.On("CreateReturn", mock.Anything, int64(1), mock.Anything).Return(nil, c.err)
+
+			req := httptest.NewRequest("POST", "/api/orders/1/returns", bytes.NewBufferString(`{"items": [{"product_id": 2, "quantity": 5}]}`))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+
+			handler.CreateReturn(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code, c.err.Error())
+		}
+	})
+
+	t.Run("Receive return in wrong status", func(t *testing.T) {
+		// Setup
+		mockService := new(MockReturnService)
+		handler := NewReturnHandler(mockService)
+		mockService.On("ReceiveReturn", mock.Anything, int64(1), int64(4)).Return(nil, domain.ErrReturnStatus)
+
+		req := httptest.NewRequest("POST", "/api/orders/1/returns/4/receive", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1", "returnID": "4"})
+		w := httptest.NewRecorder()
+
+		handler.ReceiveReturn(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
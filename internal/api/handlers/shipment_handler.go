@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type ShipmentHandler struct {
+	shipmentService services.ShipmentServiceInterface
+}
+
+func NewShipmentHandler(shipmentService services.ShipmentServiceInterface) *ShipmentHandler {
+	return &ShipmentHandler{
+		shipmentService: shipmentService,
+	}
+}
+
+// CreateShipment handles HTTP POST requests recording a shipment of order
+// lines. It returns 201 Created with the shipment; quantities above what is
+// left to ship return 409 Conflict.
+func (h *ShipmentHandler) CreateShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	var req domain.CreateShipmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	shipment, err := h.shipmentService.CreateShipment(r.Context(), orderID, &req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, shipment)
+}
+
+// ListShipments handles HTTP GET requests returning the shipments of an
+// order.
+func (h *ShipmentHandler) ListShipments(w http.ResponseWriter, r *http.Request) {
+	orderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	shipments, err := h.shipmentService.ListShipments(r.Context(), orderID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, shipments)
+}
+
+func (h *ShipmentHandler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidShipment):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrOrderNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrShipmentExceedsQuantity), errors.Is(err, domain.ErrOrderNotShippable),
+		errors.Is(err, domain.ErrDuplicateShipment):
+		writeError(w, r, http.StatusConflict, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
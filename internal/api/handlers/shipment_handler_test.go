@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type MockShipmentService struct {
+	mock.Mock
+}
+
+func (m *MockShipmentService) CreateShipment(ctx context.Context, orderID int64, req *domain.CreateShipmentRequest) (*domain.Shipment, error) {
+	args := m.Called(ctx, orderID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Shipment), args.Error(1)
+}
+
+func (m *MockShipmentService) ListShipments(ctx context.Context, orderID int64) ([]domain.Shipment, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Shipment), args.Error(1)
+}
+
+func TestShipmentHandler(t *testing.T) {
+	t.Run("Create shipment", func(t *testing.T) {
+		// Setup
+		mockService := new(MockShipmentService)
+		handler := NewShipmentHandler(mockService)
+		mockService.On("CreateShipment", mock.Anything, int64(1), &domain.CreateShipmentRequest{
+			Carrier:        "UPS",
+			TrackingNumber: "1Z999",
+			Items:          []domain.OrderItem{{ProductID: 2, Quantity: 1}},
+		}).Return(&domain.Shipment{ID: 5, OrderID: 1, Carrier: "UPS", TrackingNumber: "1Z999"}, nil)
+
+		body := `{"carrier": "UPS", "tracking_number": "1Z999", "items": [{"product_id": 2, "quantity": 1}]}`
+		req := httptest.NewRequest("POST", "/api/orders/1/shipments", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.CreateShipment(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.Shipment
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "1Z999", response.TrackingNumber)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Create shipment errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+		}{
+			{services.ErrInvalidShipment, http.StatusBadRequest},
+			{domain.ErrOrderNotFound, http.StatusNotFound},
+			{domain.ErrShipmentExceedsQuantity, http.StatusConflict},
+			{domain.ErrOrderNotShippable, http.StatusConflict},
+			{domain.ErrDuplicateShipment, http.StatusConflict},
+		}
+		for _, c := range cases {
+			// Setup
+			mockService := new(MockShipmentService)
+			handler := NewShipmentHandler(mockService)
+			mockService.On("CreateShipment", mock.Anything, int64(1), mock.Anything).Return(nil, c.err)
+
+			req := httptest.NewRequest("POST", "/api/orders/1/shipments", bytes.NewBufferString(`{"carrier": "UPS", "tracking_number": "1Z999"}`))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+
+			handler.CreateShipment(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code, c.err.Error())
+		}
+	})
+
+	t.Run("List shipments", func(t *testing.T) {
+		// Setup
+		mockService := new(MockShipmentService)
+		handler := NewShipmentHandler(mockService)
+		mockService.On("ListShipments", mock.Anything, int64(1)).Return([]domain.Shipment{{ID: 5}, {ID: 6}}, nil)
+
+		req := httptest.NewRequest("GET", "/api/orders/1/shipments", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.ListShipments(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response []domain.Shipment
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response, 2)
+	})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type WebhookHandler struct {
+	webhookService services.WebhookServiceInterface
+}
+
+func NewWebhookHandler(webhookService services.WebhookServiceInterface) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateSubscription handles HTTP POST requests to register a webhook endpoint.
+// It returns 201 Created with the subscription, including its signing secret,
+// which is not returned by any other endpoint.
+// Invalid URLs or unknown event types return 400 Bad Request.
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	sub, err := h.webhookService.CreateSubscription(r.Context(), &req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+// ListSubscriptions handles HTTP GET requests listing every webhook subscription.
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subs)
+}
+
+// GetSubscription handles HTTP GET requests for a single webhook subscription.
+func (h *WebhookHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	sub, err := h.webhookService.GetSubscription(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sub)
+}
+
+// DeleteSubscription handles HTTP DELETE requests removing a webhook
+// subscription and its pending deliveries. It returns 204 No Content.
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(r.Context(), id); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries handles HTTP GET requests returning the delivery log of a
+// subscription: the most recent deliveries with their status, number of
+// attempts, the outcome of the latest attempt and the history of every
+// attempt.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// Redeliver handles HTTP POST requests that schedule a delivery to be sent
+// again immediately. It returns 202 Accepted; the dispatcher sends it shortly after.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	deliveryID, err := pathID(r, "deliveryID")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	if err := h.webhookService.Redeliver(r.Context(), id, deliveryID); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *WebhookHandler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrWebhookNotFound), errors.Is(err, domain.ErrDeliveryNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
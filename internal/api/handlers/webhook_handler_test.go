@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+type MockWebhookService struct {
+	mock.Mock
+	services.WebhookServiceInterface
+}
+
+func (m *MockWebhookService) CreateSubscription(ctx context.Context, req *domain.CreateWebhookRequest) (*domain.WebhookSubscription, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteSubscription(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) ListDeliveries(ctx context.Context, subscriptionID int64) ([]domain.WebhookDelivery, error) {
+	args := m.Called(ctx, subscriptionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID int64) error {
+	args := m.Called(ctx, subscriptionID, deliveryID)
+	return args.Error(0)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	t.Run("Create subscription", func(t *testing.T) {
+		// Setup
+		mockService := new(MockWebhookService)
+		handler := NewWebhookHandler(mockService)
+		mockService.On("CreateSubscription", mock.Anything, &domain.CreateWebhookRequest{
+			URL:        "https://partner.example.com/hooks",
+			EventTypes: []string{domain.EventOrderPaid},
+		}).Return(&domain.WebhookSubscription{
+			ID: 1, URL: "https://partner.example.com/hooks", EventTypes: []string{domain.EventOrderPaid}, Secret: "s3cret", Active: true,
+		}, nil)
+
+		body := `{"url": "https://partner.example.com/hooks", "event_types": ["order.paid"]}`
+		req := httptest.NewRequest("POST", "/api/webhooks", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.CreateSubscription(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.WebhookSubscription
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(1), response.ID)
+		assert.Equal(t, "s3cret", response.Secret)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Create subscription errors", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			body   string
+			err    error
+			status int
+		}{
+			{"Invalid body", `{"url":`, nil, http.StatusBadRequest},
+			{"Invalid subscription", `{"url": "ftp://partner.example.com"}`, services.ErrInvalidWebhook, http.StatusBadRequest},
+		}
+		for _, c := range cases {
+			// Setup
+			mockService := new(MockWebhookService)
+			handler := NewWebhookHandler(mockService)
+			if c.err != nil {
+				mockService.On("CreateSubscription", mock.Anything, mock.Anything).Return(nil, c.err)
+			}
+
+			req := httptest.NewRequest("POST", "/api/webhooks", bytes.NewBufferString(c.body))
+			w := httptest.NewRecorder()
+
+			handler.CreateSubscription(w, req)
+
+			// Assertions
+			assert.Equal(t, c.status, w.Code, c.name)
+		}
+	})
+
+	t.Run("Delete subscription", func(t *testing.T) {
+		// Setup
+		mockService := new(MockWebhookService)
+		handler := NewWebhookHandler(mockService)
+		mockService.On("DeleteSubscription", mock.Anything, int64(1)).Return(nil)
+
+		req := httptest.NewRequest("DELETE", "/api/webhooks/1", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.DeleteSubscription(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Delete unknown subscription", func(t *testing.T) {
+		// Setup
+		mockService := new(MockWebhookService)
+		handler := NewWebhookHandler(mockService)
+		mockService.On("DeleteSubscription", mock.Anything, int64(9)).Return(domain.ErrWebhookNotFound)
+
+		req := httptest.NewRequest("DELETE", "/api/webhooks/9", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "9"})
+		w := httptest.NewRecorder()
+
+		handler.DeleteSubscription(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("List deliveries with their history", func(t *testing.T) {
+		// Setup
+		mockService := new(MockWebhookService)
+		handler := NewWebhookHandler(mockService)
+		attemptedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		mockService.On("ListDeliveries", mock.Anything, int64(1)).Return([]domain.WebhookDelivery{{
+			ID: 7, SubscriptionID: 1, EventType: domain.EventOrderPaid, Status: domain.DeliveryDelivered, Attempts: 2,
+			LastStatusCode: 200,
+			History: []domain.DeliveryAttempt{
+				{StatusCode: 503, AttemptedAt: attemptedAt},
+				{StatusCode: 200, AttemptedAt: attemptedAt.Add(time.Minute)},
+			},
+		}}, nil)
+
+		req := httptest.NewRequest("GET", "/api/webhooks/1/deliveries", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		handler.ListDeliveries(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response []domain.WebhookDelivery
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response, 1)
+		require.Len(t, response[0].History, 2)
+		assert.Equal(t, 503, response[0].History[0].StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("List deliveries with an invalid ID", func(t *testing.T) {
+		// Setup
+		handler := NewWebhookHandler(new(MockWebhookService))
+
+		req := httptest.NewRequest("GET", "/api/webhooks/abc/deliveries", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "abc"})
+		w := httptest.NewRecorder()
+
+		handler.ListDeliveries(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Redeliver", func(t *testing.T) {
+		// Setup
+		mockService := new(MockWebhookService)
+		handler := NewWebhookHandler(mockService)
+		mockService.On("Redeliver", mock.Anything, int64(1), int64(7)).Return(nil)
+
+		req := httptest.NewRequest("POST", "/api/webhooks/1/deliveries/7/redeliver", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1", "deliveryID": "7"})
+		w := httptest.NewRecorder()
+
+		handler.Redeliver(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Redeliver unknown delivery", func(t *testing.T) {
+		// Setup
+		mockService := new(MockWebhookService)
+		handler := NewWebhookHandler(mockService)
+		mockService.On("Redeliver", mock.Anything, int64(1), int64(8)).Return(domain.ErrDeliveryNotFound)
+
+		req := httptest.NewRequest("POST", "/api/webhooks/1/deliveries/8/redeliver", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1", "deliveryID": "8"})
+		w := httptest.NewRecorder()
+
+		handler.Redeliver(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/actor"
+)
+
+// Actor stores the actor named by the given request header in the request
+// context. The service does not authenticate requests itself: the header
+// must be set by an authenticating proxy in front of it, which also strips
+// it from client requests. With an empty header name no actor is recorded.
+func Actor(header string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if header == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if name := r.Header.Get(header); actor.Valid(name) {
+				r = r.WithContext(actor.NewContext(r.Context(), name))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valeriouberti/order-service-test/internal/actor"
+)
+
+func TestActor(t *testing.T) {
+	var ctxActor string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxActor = actor.FromContext(r.Context())
+	})
+
+	t.Run("Header names the actor", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/api/orders/1", nil)
+		req.Header.Set("X-Auth-Request-User", "alice@example.com")
+
+		Actor("X-Auth-Request-User")(next).ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "alice@example.com", ctxActor)
+	})
+
+	t.Run("Invalid header is ignored", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/api/orders/1", nil)
+		req.Header.Set("X-Auth-Request-User", "alice\x00")
+
+		Actor("X-Auth-Request-User")(next).ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Empty(t, ctxActor)
+	})
+
+	t.Run("Disabled without a header name", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/api/orders/1", nil)
+		req.Header.Set("X-Auth-Request-User", "alice@example.com")
+
+		Actor("")(next).ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Empty(t, ctxActor)
+	})
+}
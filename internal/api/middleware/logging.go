@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+// Logging returns a middleware that attaches a request-scoped logger to the
+// request context and records one structured log line per request with the
+// method, route template, status, latency and response size.
+func Logging(logger *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// Normally set by the RequestID middleware; generate one if it is missing
+			ctx := r.Context()
+			id := requestid.FromContext(ctx)
+			if id == "" {
+				id = requestid.New()
+				ctx = requestid.NewContext(ctx, id)
+			}
+
+			reqLogger := logger.With("request_id", id)
+			ctx = logging.NewContext(ctx, reqLogger)
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			reqLogger.LogAttrs(ctx, levelForStatus(rw.status), "http request",
+				slog.String("method", r.Method),
+				slog.String("route", routeTemplate(r)),
+				slog.Int("status", rw.status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("bytes", rw.bytes),
+			)
+		})
+	}
+}
+
+// levelForStatus logs server errors at error level and everything else at info.
+func levelForStatus(status int) slog.Level {
+	if status >= http.StatusInternalServerError {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
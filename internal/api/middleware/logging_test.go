@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, "debug")
+
+	var ctxRequestID string
+	var ctxLogger *slog.Logger
+
+	r := mux.NewRouter()
+	r.Use(Logging(logger))
+	r.HandleFunc("/api/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctxRequestID = requestid.FromContext(r.Context())
+		ctxLogger = logging.FromContext(r.Context())
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("order not found"))
+	}).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/api/orders/42", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The handler sees a request ID and a request-scoped logger
+	assert.NotEmpty(t, ctxRequestID)
+	assert.NotSame(t, slog.Default(), ctxLogger)
+
+	// One log line is written with the request attributes
+	var entry map[string]any
+	err := json.Unmarshal(buf.Bytes(), &entry)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "http request", entry["msg"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/api/orders/{id}", entry["route"])
+	assert.Equal(t, float64(http.StatusNotFound), entry["status"])
+	assert.Equal(t, float64(len("order not found")), entry["bytes"])
+	assert.Equal(t, ctxRequestID, entry["request_id"])
+	assert.Contains(t, entry, "latency")
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/valeriouberti/order-service-test/internal/metrics"
+)
+
+// Metrics returns a middleware that counts requests and observes their
+// latency per route template, method and status code.
+func Metrics(m *metrics.Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			labels := []string{routeTemplate(r), r.Method, strconv.Itoa(rw.status)}
+			m.HTTPRequests.WithLabelValues(labels...).Inc()
+			m.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		})
+	}
+}
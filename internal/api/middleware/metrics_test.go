@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeriouberti/order-service-test/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+
+	r := mux.NewRouter()
+	r.Use(Metrics(m))
+	r.HandleFunc("/api/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "999" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}).Methods("GET")
+	r.Handle("/metrics", m.Handler()).Methods("GET")
+
+	for _, path := range []string{"/api/orders/1", "/api/orders/2", "/api/orders/999"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// Requests are labelled by route template, not by raw path
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/api/orders/{id}", "GET", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/api/orders/{id}", "GET", "404")))
+
+	// The metrics endpoint serves the Prometheus text format
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, w.Body.String(), `order_service_http_requests_total{method="GET",route="/api/orders/{id}",status="200"} 2`)
+}
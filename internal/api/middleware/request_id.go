@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+// RequestIDHeader is the header used to receive and echo correlation IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID accepts an incoming X-Request-ID header or generates a new ID,
+// echoes it in the response headers and stores it in the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
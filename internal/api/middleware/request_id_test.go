@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+	var ctxRequestID string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxRequestID = requestid.FromContext(r.Context())
+	}))
+
+	t.Run("Incoming header is propagated", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/orders/1", nil)
+		req.Header.Set(RequestIDHeader, "client-abc-123")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, "client-abc-123", ctxRequestID)
+		assert.Equal(t, "client-abc-123", w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("Missing header generates an ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/orders/1", nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Len(t, ctxRequestID, 32)
+		assert.Equal(t, ctxRequestID, w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("Invalid header is replaced", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/orders/1", nil)
+		req.Header.Set(RequestIDHeader, strings.Repeat("x", requestid.MaxLength+1))
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Len(t, ctxRequestID, 32)
+		assert.Equal(t, ctxRequestID, w.Header().Get(RequestIDHeader))
+	})
+}
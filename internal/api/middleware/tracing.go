@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+	"github.com/valeriouberti/order-service-test/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing returns a middleware that continues the W3C trace context sent by the
+// client, if any, and wraps each request in a server span named after its route.
+func Tracing(tp trace.TracerProvider, propagator propagation.TextMapPropagator) mux.MiddlewareFunc {
+	tracer := tp.Tracer(tracing.InstrumentationName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := routeTemplate(r)
+			ctx, span := tracer.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+					attribute.String("request.id", requestid.FromContext(r.Context())),
+				),
+			)
+			defer span.End()
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+			if rw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rw.status))
+			}
+		})
+	}
+}
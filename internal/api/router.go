@@ -1,24 +1,134 @@
 package api
 
 import (
+	"log/slog"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/valeriouberti/order-service-test/internal/api/handlers"
+	"github.com/valeriouberti/order-service-test/internal/api/middleware"
+	"github.com/valeriouberti/order-service-test/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func NewRouter(orderHandler *handlers.OrderHandler) *mux.Router {
+// Handlers are the handlers serving the routes of the API.
+type Handlers struct {
+	Order    *handlers.OrderHandler
+	Stream   *handlers.OrderStreamHandler
+	Cart     *handlers.CartHandler
+	Payment  *handlers.PaymentHandler
+	Refund   *handlers.RefundHandler
+	Invoice  *handlers.InvoiceHandler
+	Return   *handlers.ReturnHandler
+	Shipment *handlers.ShipmentHandler
+	Import   *handlers.ImportHandler
+	Report   *handlers.ReportHandler
+	Audit    *handlers.AuditHandler
+	Webhook  *handlers.WebhookHandler
+	GraphQL  http.Handler
+	Health   *handlers.HealthHandler
+}
+
+// NewRouter routes the API to h behind the request ID, actor, logging,
+// metrics and tracing middleware. The actor of audited changes is read from
+// the actorHeader request header.
+func NewRouter(h Handlers, actorHeader string, logger *slog.Logger, m *metrics.Metrics, tp trace.TracerProvider) *mux.Router {
 	r := mux.NewRouter()
 
+	// Register middleware
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Actor(actorHeader))
+	r.Use(middleware.Logging(logger))
+	r.Use(middleware.Metrics(m))
+	r.Use(middleware.Tracing(tp, otel.GetTextMapPropagator()))
+
+	// mux only runs the middleware of matched routes, so unmatched requests
+	// get their request ID and logging here
+	unmatched := func(handler http.HandlerFunc) http.Handler {
+		return middleware.RequestID(middleware.Logging(logger)(handler))
+	}
+	r.NotFoundHandler = unmatched(handlers.NotFound)
+	r.MethodNotAllowedHandler = unmatched(handlers.MethodNotAllowed)
+
 	// Define API routes
-	r.HandleFunc("/api/orders", orderHandler.CreateOrder).Methods("POST")
-	r.HandleFunc("/api/orders/{id}", orderHandler.GetOrder).Methods("GET")
+	r.HandleFunc("/api/orders", h.Order.CreateOrder).Methods("POST")
+	r.HandleFunc("/api/orders", h.Order.ListOrders).Methods("GET")
+	r.HandleFunc("/api/orders/quote", h.Order.QuoteOrder).Methods("POST")
+	r.HandleFunc("/api/orders/export", h.Order.ExportOrders).Methods("GET")
+	r.HandleFunc("/api/orders/import", h.Import.ImportOrders).Methods("POST")
+	r.HandleFunc("/api/orders/import/{jobID}", h.Import.GetImportJob).Methods("GET")
+	// Registered before /api/orders/{id} so "stream" is not taken for an ID
+	r.HandleFunc("/api/orders/stream", h.Stream.Stream).Methods("GET")
+	r.HandleFunc("/api/orders/{id}", h.Order.GetOrder).Methods("GET")
+	r.HandleFunc("/api/orders/{id}", h.Order.UpdateOrder).Methods("PATCH")
+	r.HandleFunc("/api/orders/{id}/revisions", h.Order.ListOrderRevisions).Methods("GET")
+
+	// Payments
+	r.HandleFunc("/api/orders/{id}/payments", h.Payment.CreatePayment).Methods("POST")
+	r.HandleFunc("/api/orders/{id}/payments", h.Payment.ListPayments).Methods("GET")
+	r.HandleFunc("/api/orders/{id}/payments/{paymentID}/capture", h.Payment.CapturePayment).Methods("POST")
+	r.HandleFunc("/api/orders/{id}/payments/{paymentID}/void", h.Payment.VoidPayment).Methods("POST")
+
+	// Refunds and credit notes
+	r.HandleFunc("/api/orders/{id}/refunds", h.Refund.CreateRefund).Methods("POST")
+	r.HandleFunc("/api/orders/{id}/credit-notes", h.Refund.ListCreditNotes).Methods("GET")
+	r.HandleFunc("/api/orders/{id}/credit-notes/{creditNoteID}", h.Refund.GetCreditNote).Methods("GET")
+
+	// Invoices
+	r.HandleFunc("/api/orders/{id}/invoice", h.Invoice.GetInvoice).Methods("GET")
+
+	// Shipments
+	r.HandleFunc("/api/orders/{id}/shipments", h.Shipment.CreateShipment).Methods("POST")
+	r.HandleFunc("/api/orders/{id}/shipments", h.Shipment.ListShipments).Methods("GET")
 
-	// Add health check endpoint
+	// Returns
+	r.HandleFunc("/api/orders/{id}/returns", h.Return.CreateReturn).Methods("POST")
+	r.HandleFunc("/api/orders/{id}/returns", h.Return.ListReturns).Methods("GET")
+	r.HandleFunc("/api/orders/{id}/returns/{returnID}", h.Return.GetReturn).Methods("GET")
+	r.HandleFunc("/api/orders/{id}/returns/{returnID}/approve", h.Return.ApproveReturn).Methods("POST")
+	r.HandleFunc("/api/orders/{id}/returns/{returnID}/reject", h.Return.RejectReturn).Methods("POST")
+	r.HandleFunc("/api/orders/{id}/returns/{returnID}/receive", h.Return.ReceiveReturn).Methods("POST")
+	r.HandleFunc("/api/orders/{id}/returns/{returnID}/refund", h.Return.RefundReturn).Methods("POST")
+
+	// Shopping carts
+	r.HandleFunc("/api/carts", h.Cart.CreateCart).Methods("POST")
+	r.HandleFunc("/api/carts/{id}", h.Cart.GetCart).Methods("GET")
+	r.HandleFunc("/api/carts/{id}/items", h.Cart.AddItem).Methods("POST")
+	r.HandleFunc("/api/carts/{id}/items/{productID}", h.Cart.UpdateItem).Methods("PUT")
+	r.HandleFunc("/api/carts/{id}/items/{productID}", h.Cart.RemoveItem).Methods("DELETE")
+	r.HandleFunc("/api/carts/{id}/checkout", h.Cart.Checkout).Methods("POST")
+
+	// Sales reports
+	r.HandleFunc("/api/reports/sales", h.Report.SalesReport).Methods("GET")
+	r.HandleFunc("/api/reports/top-products", h.Report.TopProducts).Methods("GET")
+
+	// Audit log
+	r.HandleFunc("/api/audit", h.Audit.ListAuditEntries).Methods("GET")
+
+	// GraphQL API
+	r.Handle("/graphql", h.GraphQL).Methods("POST")
+
+	// Webhook subscriptions
+	r.HandleFunc("/api/webhooks", h.Webhook.CreateSubscription).Methods("POST")
+	r.HandleFunc("/api/webhooks", h.Webhook.ListSubscriptions).Methods("GET")
+	r.HandleFunc("/api/webhooks/{id}", h.Webhook.GetSubscription).Methods("GET")
+	r.HandleFunc("/api/webhooks/{id}", h.Webhook.DeleteSubscription).Methods("DELETE")
+	r.HandleFunc("/api/webhooks/{id}/deliveries", h.Webhook.ListDeliveries).Methods("GET")
+	r.HandleFunc("/api/webhooks/{id}/deliveries/{deliveryID}/redeliver", h.Webhook.Redeliver).Methods("POST")
+
+	// Add health check endpoints
+	r.HandleFunc("/livez", h.Health.Livez).Methods("GET")
+	r.HandleFunc("/readyz", h.Health.Readyz).Methods("GET")
+
+	// Kept for existing probes; equivalent to /livez
 	r.HandleFunc("/health-check", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	}).Methods("GET")
 
+	// Expose Prometheus metrics
+	r.Handle("/metrics", m.Handler()).Methods("GET")
+
 	return r
 }
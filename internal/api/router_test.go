@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/api/handlers"
+	"github.com/valeriouberti/order-service-test/internal/api/middleware"
+	"github.com/valeriouberti/order-service-test/internal/metrics"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestNewRouterUnmatched(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := NewRouter(Handlers{}, "", logger, metrics.New(), noop.NewTracerProvider())
+
+	t.Run("Unknown path returns a JSON 404 with the request ID", func(t *testing.T) {
+		// Setup
+		req := httptest.NewRequest("GET", "/api/unknown", nil)
+		req.Header.Set(middleware.RequestIDHeader, "client-abc-123")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "client-abc-123", w.Header().Get(middleware.RequestIDHeader))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var response handlers.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, handlers.ErrorResponse{Error: "Not found", RequestID: "client-abc-123"}, response)
+	})
+
+	t.Run("Wrong method returns a JSON 405 with a generated request ID", func(t *testing.T) {
+		// Setup
+		req := httptest.NewRequest("DELETE", "/api/orders", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		requestID := w.Header().Get(middleware.RequestIDHeader)
+		assert.Len(t, requestID, 32)
+
+		var response handlers.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, requestID, response.RequestID)
+	})
+}
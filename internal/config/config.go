@@ -2,58 +2,530 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	DatabaseURL      string
-	ServerPort       string
-	Environment      string
-	LogLevel         string
-	ConnectionMaxAge int
-	MaxOpenConns     int
-	MaxIdleConns     int
-}
-
-// Load loads configuration from environment variables with sensible defaults
-func Load() *Config {
-	// Default port is 9090 as required by the problem statement
-	port := getEnv("PORT", "9090")
-
-	// Database configuration
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "5432")
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "postgres")
-	dbName := getEnv("DB_NAME", "order_service")
-	dbSSLMode := getEnv("DB_SSLMODE", "disable")
-
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		dbUser, dbPassword, dbHost, dbPort, dbName, dbSSLMode)
-
-	// Connection pool settings
-	maxOpenConns, _ := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25"))
-	maxIdleConns, _ := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "5"))
-	connMaxAge, _ := strconv.Atoi(getEnv("DB_CONN_MAX_AGE", "300"))
+	DatabaseURL          string
+	ServerPort           string
+	GRPCPort             string
+	Environment          string
+	LogLevel             string
+	DBHost               string
+	DBPort               string
+	DBUser               string
+	DBPassword           string
+	DBName               string
+	DBSSLMode            string
+	ConnectionMaxAge     int
+	MaxOpenConns         int
+	MaxIdleConns         int
+	TracingExporter      string
+	TracingFile          string
+	ReadyTimeout         int
+	DrainDelay           int
+	OutboxPublisher      string
+	OutboxFile           string
+	OutboxBatchSize      int
+	OutboxInterval       int
+	WebhookAttempts      int
+	WebhookBackoff       int
+	WebhookTimeout       int
+	WebhookInterval      int
+	QuoteSecret          string
+	QuoteTTL             int
+	CartTTL              int
+	CartExpiryInterval   int
+	PaymentGateway       string
+	InvoiceSellerName    string
+	InvoiceSellerVATID   string
+	InvoiceSellerAddress string
+	InvoiceSellerCountry string
+	InvoiceCurrency      string
+	InvoiceTimezone      string
+	InvoiceFiscalStart   int
+	ImportSyncRows       int
+	ImportMaxRows        int
+	ImportInterval       int
+	AuditActorHeader     string
+}
+
+// setting describes a single configuration value and where it can be set:
+// the key in the config file, the environment variable and the CLI flag.
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	value  value
+}
+
+// value is a typed configuration field that can be set from a raw string.
+type value interface {
+	Set(raw string) error
+	String() string
+}
+
+type stringValue struct{ p *string }
+
+func (v stringValue) Set(raw string) error { *v.p = raw; return nil }
+func (v stringValue) String() string       { return *v.p }
+
+type intValue struct{ p *int }
+
+func (v intValue) Set(raw string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", raw)
+	}
+	*v.p = n
+	return nil
+}
+
+func (v intValue) String() string { return strconv.Itoa(*v.p) }
+
+// flagName converts a setting key such as db_max_open_conns into db-max-open-conns.
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.key, "_", "-")
+}
 
+// settings lists every configuration value in the order they are printed.
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "port", env: "PORT", usage: "HTTP port to listen on", value: stringValue{&c.ServerPort}},
+		{key: "grpc_port", env: "GRPC_PORT", usage: "gRPC port to listen on", value: stringValue{&c.GRPCPort}},
+		{key: "env", env: "ENV", usage: "application environment", value: stringValue{&c.Environment}},
+		{key: "log_level", env: "LOG_LEVEL", usage: "log level: debug, info, warn or error", value: stringValue{&c.LogLevel}},
+		{key: "db_host", env: "DB_HOST", usage: "database host", value: stringValue{&c.DBHost}},
+		{key: "db_port", env: "DB_PORT", usage: "database port", value: stringValue{&c.DBPort}},
+		{key: "db_user", env: "DB_USER", usage: "database user", value: stringValue{&c.DBUser}},
+		{key: "db_password", env: "DB_PASSWORD", usage: "database password", secret: true, value: stringValue{&c.DBPassword}},
+		{key: "db_name", env: "DB_NAME", usage: "database name", value: stringValue{&c.DBName}},
+		{key: "db_sslmode", env: "DB_SSLMODE", usage: "database SSL mode", value: stringValue{&c.DBSSLMode}},
+		{key: "db_max_open_conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum open database connections", value: intValue{&c.MaxOpenConns}},
+		{key: "db_max_idle_conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum idle database connections", value: intValue{&c.MaxIdleConns}},
+		{key: "db_conn_max_age", env: "DB_CONN_MAX_AGE", usage: "maximum database connection lifetime in seconds", value: intValue{&c.ConnectionMaxAge}},
+		{key: "ready_timeout", env: "READY_TIMEOUT", usage: "readiness database ping timeout in seconds", value: intValue{&c.ReadyTimeout}},
+		{key: "shutdown_drain_delay", env: "SHUTDOWN_DRAIN_DELAY", usage: "seconds to fail readiness before shutting down", value: intValue{&c.DrainDelay}},
+		{key: "tracing_exporter", env: "TRACING_EXPORTER", usage: "trace exporter: none, otlp, stdout or file", value: stringValue{&c.TracingExporter}},
+		{key: "tracing_file", env: "TRACING_FILE", usage: "file spans are written to with the file exporter", value: stringValue{&c.TracingFile}},
+		{key: "outbox_publisher", env: "OUTBOX_PUBLISHER", usage: "outbox event publisher: none, stdout or file", value: stringValue{&c.OutboxPublisher}},
+		{key: "outbox_file", env: "OUTBOX_FILE", usage: "file events are appended to with the file publisher", value: stringValue{&c.OutboxFile}},
+		{key: "outbox_batch_size", env: "OUTBOX_BATCH_SIZE", usage: "maximum outbox events published per batch", value: intValue{&c.OutboxBatchSize}},
+		{key: "outbox_poll_interval", env: "OUTBOX_POLL_INTERVAL", usage: "seconds between outbox polls", value: intValue{&c.OutboxInterval}},
+		{key: "webhook_max_attempts", env: "WEBHOOK_MAX_ATTEMPTS", usage: "failed attempts before a webhook delivery is dead-lettered", value: intValue{&c.WebhookAttempts}},
+		{key: "webhook_backoff", env: "WEBHOOK_BACKOFF", usage: "seconds before the first webhook retry, doubled after each failure", value: intValue{&c.WebhookBackoff}},
+		{key: "webhook_timeout", env: "WEBHOOK_TIMEOUT", usage: "webhook request timeout in seconds", value: intValue{&c.WebhookTimeout}},
+		{key: "webhook_poll_interval", env: "WEBHOOK_POLL_INTERVAL", usage: "seconds between polls for due webhook deliveries", value: intValue{&c.WebhookInterval}},
+		{key: "quote_secret", env: "QUOTE_SECRET", usage: "key signing quote tokens, random per process when empty", secret: true, value: stringValue{&c.QuoteSecret}},
+		{key: "quote_ttl", env: "QUOTE_TTL", usage: "seconds a quote token stays valid", value: intValue{&c.QuoteTTL}},
+		{key: "cart_ttl", env: "CART_TTL", usage: "seconds an open cart is kept after its last change", value: intValue{&c.CartTTL}},
+		{key: "cart_expiry_interval", env: "CART_EXPIRY_INTERVAL", usage: "seconds between runs deleting expired carts", value: intValue{&c.CartExpiryInterval}},
+		{key: "payment_gateway", env: "PAYMENT_GATEWAY", usage: "payment gateway: fake", value: stringValue{&c.PaymentGateway}},
+		{key: "invoice_seller_name", env: "INVOICE_SELLER_NAME", usage: "seller name printed on invoices", value: stringValue{&c.InvoiceSellerName}},
+		{key: "invoice_seller_vat_id", env: "INVOICE_SELLER_VAT_ID", usage: "seller VAT identification number printed on invoices", value: stringValue{&c.InvoiceSellerVATID}},
+		{key: "invoice_seller_address", env: "INVOICE_SELLER_ADDRESS", usage: "seller address printed on invoices", value: stringValue{&c.InvoiceSellerAddress}},
+		{key: "invoice_seller_country", env: "INVOICE_SELLER_COUNTRY", usage: "seller ISO 3166-1 alpha-2 country code", value: stringValue{&c.InvoiceSellerCountry}},
+		{key: "invoice_currency", env: "INVOICE_CURRENCY", usage: "ISO 4217 currency code of invoices", value: stringValue{&c.InvoiceCurrency}},
+		{key: "invoice_timezone", env: "INVOICE_TIMEZONE", usage: "time zone of invoice dates and fiscal years", value: stringValue{&c.InvoiceTimezone}},
+		{key: "invoice_fiscal_year_start", env: "INVOICE_FISCAL_YEAR_START", usage: "month the fiscal year starts in, 1 to 12", value: intValue{&c.InvoiceFiscalStart}},
+		{key: "import_sync_rows", env: "IMPORT_SYNC_ROWS", usage: "largest order import run within the request, in rows", value: intValue{&c.ImportSyncRows}},
+		{key: "import_max_rows", env: "IMPORT_MAX_ROWS", usage: "largest order import accepted, in rows", value: intValue{&c.ImportMaxRows}},
+		{key: "import_poll_interval", env: "IMPORT_POLL_INTERVAL", usage: "seconds between polls for queued import jobs", value: intValue{&c.ImportInterval}},
+		{key: "audit_actor_header", env: "AUDIT_ACTOR_HEADER", usage: "request header naming the authenticated actor, set by a proxy; empty disables it", value: stringValue{&c.AuditActorHeader}},
+	}
+}
+
+// defaults returns the configuration used when nothing else is set.
+func defaults() *Config {
 	return &Config{
-		DatabaseURL:      dbURL,
-		ServerPort:       port,
-		Environment:      getEnv("ENV", "development"),
-		LogLevel:         getEnv("LOG_LEVEL", "info"),
-		ConnectionMaxAge: connMaxAge,
-		MaxOpenConns:     maxOpenConns,
-		MaxIdleConns:     maxIdleConns,
+		// Default port is 9090 as required by the problem statement
+		ServerPort:         "9090",
+		GRPCPort:           "9091",
+		Environment:        "development",
+		LogLevel:           "info",
+		DBHost:             "localhost",
+		DBPort:             "5432",
+		DBUser:             "postgres",
+		DBPassword:         "postgres",
+		DBName:             "order_service",
+		DBSSLMode:          "disable",
+		MaxOpenConns:       25,
+		MaxIdleConns:       5,
+		ConnectionMaxAge:   300,
+		ReadyTimeout:       2,
+		DrainDelay:         5,
+		TracingExporter:    "none",
+		TracingFile:        "traces.json",
+		OutboxPublisher:    "none",
+		OutboxFile:         "events.ndjson",
+		OutboxBatchSize:    100,
+		OutboxInterval:     1,
+		WebhookAttempts:    10,
+		WebhookBackoff:     30,
+		WebhookTimeout:     10,
+		WebhookInterval:    1,
+		QuoteTTL:           900,
+		CartTTL:            604800,
+		CartExpiryInterval: 300,
+		PaymentGateway:     "fake",
+		InvoiceSellerName:  "Order Service",
+		InvoiceCurrency:    "EUR",
+		InvoiceTimezone:    "UTC",
+		InvoiceFiscalStart: 1,
+		ImportSyncRows:     100,
+		ImportMaxRows:      10000,
+		ImportInterval:     1,
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence:
+// built-in defaults, an optional YAML config file, environment variables and
+// command line flags. The config file is selected with the -config flag or the
+// CONFIG_FILE environment variable.
+//
+// args are the command line arguments without the program name. Flags must
+// come before any subcommand; the remaining positional arguments are returned.
+//
+// Every invalid value is reported, not just the first one, in a single joined error.
+func Load(args []string) (*Config, []string, error) {
+	cfg := defaults()
+	settings := cfg.settings()
+
+	// Register one flag per setting. Values are only recorded here and applied
+	// after the file and environment so that flags take precedence.
+	fs := flag.NewFlagSet("order-service-test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file")
+	flagValues := map[string]*rawValue{}
+	for _, s := range settings {
+		raw := &rawValue{}
+		flagValues[s.key] = raw
+		fs.Var(raw, s.flagName(), s.usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+
+	// Config file
+	if *configFile != "" {
+		errs = append(errs, cfg.loadFile(*configFile, settings)...)
+	}
+
+	// Environment variables
+	for _, s := range settings {
+		if raw, ok := os.LookupEnv(s.env); ok {
+			if err := s.value.Set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+
+	// Command line flags
+	for _, s := range settings {
+		if raw := flagValues[s.key]; raw.set {
+			if err := s.value.Set(raw.value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", s.flagName(), err))
+			}
+		}
+	}
+
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	cfg.DatabaseURL = cfg.databaseURL()
+
+	return cfg, fs.Args(), nil
+}
+
+// Usage returns the help text describing every flag and environment variable.
+func Usage() string {
+	var b strings.Builder
+	b.WriteString("Flags:\n  -config string\n\tpath to a YAML config file (env CONFIG_FILE)\n")
+	for _, s := range defaults().settings() {
+		if s.secret {
+			fmt.Fprintf(&b, "  -%s value\n\t%s (env %s)\n", s.flagName(), s.usage, s.env)
+			continue
+		}
+		fmt.Fprintf(&b, "  -%s value\n\t%s (env %s, default %q)\n", s.flagName(), s.usage, s.env, s.value.String())
+	}
+	return b.String()
+}
+
+// rawValue records a flag value without interpreting it.
+type rawValue struct {
+	value string
+	set   bool
+}
+
+func (v *rawValue) Set(raw string) error { v.value, v.set = raw, true; return nil }
+func (v *rawValue) String() string       { return v.value }
+
+// loadFile applies the top-level keys of a YAML file. Unknown keys and
+// non-scalar values are reported as errors together with their line number.
+func (c *Config) loadFile(path string, settings []setting) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config file: %w", err)}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return []error{fmt.Errorf("config file %s: expected a mapping of settings", path)}
+	}
+
+	byKey := map[string]setting{}
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	var errs []error
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i], root.Content[i+1]
+
+		s, ok := byKey[key.Value]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s:%d: unknown setting %q", path, key.Line, key.Value))
+			continue
+		}
+		if val.Kind != yaml.ScalarNode {
+			errs = append(errs, fmt.Errorf("%s:%d: %s must be a scalar value", path, val.Line, key.Value))
+			continue
+		}
+		if err := s.value.Set(val.Value); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %s: %w", path, val.Line, key.Value, err))
+		}
+	}
+
+	return errs
+}
+
+// validate checks the semantic constraints of every setting.
+func (c *Config) validate() []error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+	}
+
+	if !validPort(c.ServerPort) {
+		invalid("port", "must be a number between 1 and 65535, got %q", c.ServerPort)
+	}
+	if !validPort(c.GRPCPort) {
+		invalid("grpc_port", "must be a number between 1 and 65535, got %q", c.GRPCPort)
+	} else if c.GRPCPort == c.ServerPort {
+		invalid("grpc_port", "must differ from port, both are %q", c.GRPCPort)
+	}
+	if !oneOf(strings.ToLower(c.LogLevel), "debug", "info", "warn", "warning", "error") {
+		invalid("log_level", "must be one of debug, info, warn, error, got %q", c.LogLevel)
+	}
+	if c.Environment == "" {
+		invalid("env", "must not be empty")
+	}
+	if c.DBHost == "" {
+		invalid("db_host", "must not be empty")
+	}
+	if !validPort(c.DBPort) {
+		invalid("db_port", "must be a number between 1 and 65535, got %q", c.DBPort)
+	}
+	if c.DBUser == "" {
+		invalid("db_user", "must not be empty")
+	}
+	if c.DBName == "" {
+		invalid("db_name", "must not be empty")
+	}
+	if !oneOf(c.DBSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full") {
+		invalid("db_sslmode", "must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q", c.DBSSLMode)
+	}
+	if c.MaxOpenConns < 1 {
+		invalid("db_max_open_conns", "must be at least 1, got %d", c.MaxOpenConns)
+	}
+	if c.MaxIdleConns < 0 {
+		invalid("db_max_idle_conns", "must not be negative, got %d", c.MaxIdleConns)
+	} else if c.MaxOpenConns >= 1 && c.MaxIdleConns > c.MaxOpenConns {
+		invalid("db_max_idle_conns", "must not exceed db_max_open_conns (%d), got %d", c.MaxOpenConns, c.MaxIdleConns)
+	}
+	if c.ConnectionMaxAge < 0 {
+		invalid("db_conn_max_age", "must not be negative, got %d", c.ConnectionMaxAge)
+	}
+	if c.ReadyTimeout < 1 {
+		invalid("ready_timeout", "must be at least 1, got %d", c.ReadyTimeout)
+	}
+	if c.DrainDelay < 0 {
+		invalid("shutdown_drain_delay", "must not be negative, got %d", c.DrainDelay)
+	}
+	if !oneOf(c.TracingExporter, "none", "otlp", "stdout", "file") {
+		invalid("tracing_exporter", "must be one of none, otlp, stdout, file, got %q", c.TracingExporter)
+	}
+	if c.TracingExporter == "file" && c.TracingFile == "" {
+		invalid("tracing_file", "must be set when tracing_exporter is file")
+	}
+	if !oneOf(c.OutboxPublisher, "none", "stdout", "file") {
+		invalid("outbox_publisher", "must be one of none, stdout, file, got %q", c.OutboxPublisher)
+	}
+	if c.OutboxPublisher == "file" && c.OutboxFile == "" {
+		invalid("outbox_file", "must be set when outbox_publisher is file")
+	}
+	if c.OutboxBatchSize < 1 {
+		invalid("outbox_batch_size", "must be at least 1, got %d", c.OutboxBatchSize)
+	}
+	if c.OutboxInterval < 1 {
+		invalid("outbox_poll_interval", "must be at least 1, got %d", c.OutboxInterval)
+	}
+	if c.WebhookAttempts < 1 {
+		invalid("webhook_max_attempts", "must be at least 1, got %d", c.WebhookAttempts)
+	}
+	if c.WebhookBackoff < 1 {
+		invalid("webhook_backoff", "must be at least 1, got %d", c.WebhookBackoff)
+	}
+	if c.WebhookTimeout < 1 {
+		invalid("webhook_timeout", "must be at least 1, got %d", c.WebhookTimeout)
+	}
+	if c.WebhookInterval < 1 {
+		invalid("webhook_poll_interval", "must be at least 1, got %d", c.WebhookInterval)
+	}
+	if c.QuoteSecret != "" && len(c.QuoteSecret) < 32 {
+		invalid("quote_secret", "must be at least 32 characters, got %d", len(c.QuoteSecret))
+	}
+	if c.QuoteTTL < 1 {
+		invalid("quote_ttl", "must be at least 1, got %d", c.QuoteTTL)
+	}
+	if c.CartTTL < 1 {
+		invalid("cart_ttl", "must be at least 1, got %d", c.CartTTL)
+	}
+	if c.CartExpiryInterval < 1 {
+		invalid("cart_expiry_interval", "must be at least 1, got %d", c.CartExpiryInterval)
+	}
+	if !oneOf(c.PaymentGateway, "fake") {
+		invalid("payment_gateway", "must be fake, got %q", c.PaymentGateway)
+	}
+	if c.InvoiceSellerName == "" {
+		invalid("invoice_seller_name", "must not be empty")
+	}
+	if c.InvoiceSellerCountry != "" && !upperLetters(c.InvoiceSellerCountry, 2) {
+		invalid("invoice_seller_country", "must be a two-letter uppercase country code, got %q", c.InvoiceSellerCountry)
+	}
+	if !upperLetters(c.InvoiceCurrency, 3) {
+		invalid("invoice_currency", "must be a three-letter uppercase currency code, got %q", c.InvoiceCurrency)
+	}
+	if _, err := time.LoadLocation(c.InvoiceTimezone); err != nil {
+		invalid("invoice_timezone", "must be a time zone name such as Europe/Rome, got %q", c.InvoiceTimezone)
+	}
+	if c.InvoiceFiscalStart < 1 || c.InvoiceFiscalStart > 12 {
+		invalid("invoice_fiscal_year_start", "must be a month between 1 and 12, got %d", c.InvoiceFiscalStart)
+	}
+	if c.ImportSyncRows < 0 {
+		invalid("import_sync_rows", "must not be negative, got %d", c.ImportSyncRows)
+	}
+	if c.ImportMaxRows < 1 {
+		invalid("import_max_rows", "must be at least 1, got %d", c.ImportMaxRows)
+	}
+	if c.ImportInterval < 1 {
+		invalid("import_poll_interval", "must be at least 1, got %d", c.ImportInterval)
+	}
+	if c.AuditActorHeader != "" && !headerName(c.AuditActorHeader) {
+		invalid("audit_actor_header", "must be a header name such as X-Auth-Request-User, got %q", c.AuditActorHeader)
+	}
+
+	return errs
+}
+
+// Redacted renders the effective configuration as YAML with secrets masked.
+// The output uses the config file keys so it can be saved and loaded back.
+func (c *Config) Redacted() string {
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		val := s.value.String()
+		if s.secret && val != "" {
+			val = "******"
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: val}
+		if _, isInt := s.value.(intValue); !isInt {
+			node.Tag = "!!str"
+		}
+		mapping.Content = append(mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: s.key},
+			node,
+		)
+	}
+
+	out, err := yaml.Marshal(mapping)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+// databaseURL builds the Postgres connection URL, escaping credentials.
+func (c *Config) databaseURL() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     c.DBHost + ":" + c.DBPort,
+		Path:     "/" + c.DBName,
+		RawQuery: "sslmode=" + url.QueryEscape(c.DBSSLMode),
+	}
+	return u.String()
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// upperLetters reports whether value is n uppercase ASCII letters, the form
+// of ISO country and currency codes.
+func upperLetters(value string, n int) bool {
+	if len(value) != n {
+		return false
+	}
+	for _, c := range value {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
 	}
+	return true
 }
 
-// getEnv gets environment variable or returns default value
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// headerName reports whether value is made of the letters, digits and
+// hyphens of a conventional HTTP header name.
+func headerName(value string) bool {
+	for _, c := range value {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
 	}
-	return defaultValue
+	return value != ""
 }
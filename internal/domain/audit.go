@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Audited entity types
+const (
+	AuditEntityOrder   = "order"
+	AuditEntityPayment = "payment"
+	AuditEntityReturn  = "return"
+	AuditEntityProduct = "product"
+)
+
+// Audit actions
+const (
+	AuditActionOrderCreated     = "order.created"
+	AuditActionOrderUpdated     = "order.updated"
+	AuditActionPaymentCreated   = "payment.created"
+	AuditActionPaymentUpdated   = "payment.updated"
+	AuditActionReturnCreated    = "return.created"
+	AuditActionReturnUpdated    = "return.updated"
+	AuditActionProductRestocked = "product.restocked"
+)
+
+const (
+	DefaultAuditListLimit = 50
+	MaxAuditListLimit     = 500
+)
+
+// AuditEntry records a change to an entity: who made it, in which request,
+// and the fields that changed. Before and After hold the JSON fields of the
+// entity that differ, with their values before and after the change; Before
+// is null when the entity was created.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int64           `json:"entity_id"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter selects the audit entries returned by a listing. Empty fields
+// match any entry; From and To bound the creation time, To exclusive.
+// Entries are listed by ascending ID; AfterID is the keyset cursor returned
+// by the previous page.
+type AuditFilter struct {
+	EntityType string
+	EntityID   int64
+	Action     string
+	Actor      string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	AfterID    int64
+	Limit      int
+}
+
+type AuditListResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	// NextAfterID is the cursor of the next page, zero on the last page
+	NextAfterID int64 `json:"next_after_id,omitempty"`
+}
+
+// AuditDiff compares the JSON representations of an entity before and after
+// a change and returns the top-level fields that differ, with their values
+// on each side. A field missing on one side, such as an omitted empty value,
+// is only listed on the other. A nil before is a creation: the diff is null
+// before and every field after.
+func AuditDiff(before, after any) (json.RawMessage, json.RawMessage, error) {
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if before == nil {
+		diff, err := json.Marshal(afterFields)
+		return nil, diff, err
+	}
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name, value := range beforeFields {
+		if other, ok := afterFields[name]; ok && bytes.Equal(value, other) {
+			delete(beforeFields, name)
+			delete(afterFields, name)
+		}
+	}
+
+	beforeDiff, err := json.Marshal(beforeFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterDiff, err := json.Marshal(afterFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeDiff, afterDiff, nil
+}
+
+// jsonFields returns the top-level fields of the JSON object v encodes to.
+func jsonFields(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
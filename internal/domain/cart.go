@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Cart statuses
+const (
+	CartStatusOpen       = "open"
+	CartStatusCheckedOut = "checked_out"
+)
+
+var (
+	ErrCartNotFound     = errors.New("cart not found")
+	ErrCartItemNotFound = errors.New("cart item not found")
+	ErrCartCheckedOut   = errors.New("cart already checked out")
+)
+
+// Cart is a server-side basket that is converted into an order at checkout.
+// Open carts expire after a period without changes.
+type Cart struct {
+	ID         string
+	CustomerID string
+	Status     string
+	OrderID    int64
+	Items      []CartItem
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+}
+
+// Expired reports whether an open cart has passed its expiry time.
+func (c *Cart) Expired(now time.Time) bool {
+	return c.Status == CartStatusOpen && !now.Before(c.ExpiresAt)
+}
+
+type CartItem struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int   `json:"quantity"`
+}
+
+// Request and response structures
+type CreateCartRequest struct {
+	CustomerID string `json:"customer_id,omitempty"`
+}
+
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// CartResponse is a cart priced with the current product prices.
+type CartResponse struct {
+	CartID     string      `json:"cart_id"`
+	CustomerID string      `json:"customer_id,omitempty"`
+	Status     string      `json:"status"`
+	OrderID    int64       `json:"order_id,omitempty"`
+	CartPrice  float64     `json:"cart_price"`
+	CartVAT    float64     `json:"cart_vat"`
+	Items      []OrderItem `json:"items"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
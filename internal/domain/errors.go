@@ -0,0 +1,14 @@
+package domain
+
+import "errors"
+
+// Sentinel errors returned by the repositories and services.
+var (
+	ErrOrderNotFound    = errors.New("order not found")
+	ErrProductNotFound  = errors.New("product not found")
+	ErrInvalidQuote     = errors.New("invalid quote token")
+	ErrQuoteExpired     = errors.New("quote expired")
+	ErrQuoteMismatch    = errors.New("order items do not match the quote")
+	ErrOrderNotEditable = errors.New("order can no longer be modified")
+	ErrVersionMismatch  = errors.New("order was modified by another request")
+)
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types published for orders
+const (
+	EventOrderCreated  = "order.created"
+	EventOrderUpdated  = "order.updated"
+	EventOrderPaid     = "order.paid"
+	EventOrderRefunded = "order.refunded"
+	EventOrderShipped  = "order.shipped"
+)
+
+// Aggregate types that events refer to
+const (
+	AggregateOrder = "order"
+)
+
+// Event is a domain event recorded in the outbox and published to other systems.
+type Event struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   int64           `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	RequestID     string          `json:"request_id,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// NewOrderEvent builds an event of the given type carrying a snapshot of order.
+func NewOrderEvent(eventType string, order *Order) (*Event, error) {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		Type:          eventType,
+		AggregateType: AggregateOrder,
+		AggregateID:   order.ID,
+		Payload:       payload,
+		RequestID:     order.RequestID,
+	}, nil
+}
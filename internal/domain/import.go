@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Import modes. Best effort creates every valid order on its own; all or
+// nothing creates the orders in a single transaction, and none of them if a
+// row is invalid.
+const (
+	ImportModeBestEffort   = "best_effort"
+	ImportModeAllOrNothing = "all_or_nothing"
+)
+
+// Import file formats
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// Import job statuses
+const (
+	ImportStatusQueued    = "queued"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// Import row outcomes. Valid rows passed a dry run; skipped rows were valid
+// but not created because another row of an all or nothing import failed.
+const (
+	ImportRowCreated = "created"
+	ImportRowValid   = "valid"
+	ImportRowFailed  = "failed"
+	ImportRowSkipped = "skipped"
+)
+
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// ImportRow is one order read from an import file. Line is the line of the
+// file the order starts on and Error is set when the row could not be read.
+type ImportRow struct {
+	Line  int        `json:"line"`
+	Ref   string     `json:"ref,omitempty"`
+	Order OrderInput `json:"order"`
+	Error string     `json:"error,omitempty"`
+}
+
+// ImportRequest imports the orders of a parsed file.
+type ImportRequest struct {
+	Format string
+	Mode   string
+	DryRun bool
+	Rows   []ImportRow
+}
+
+// ImportResult is the outcome of one row of an import.
+type ImportResult struct {
+	Line    int     `json:"line"`
+	Ref     string  `json:"ref,omitempty"`
+	Status  string  `json:"status"`
+	OrderID int64   `json:"order_id,omitempty"`
+	Price   float64 `json:"price,omitempty"`
+	VAT     float64 `json:"vat,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// ImportJob tracks an import. Processed counts the rows with a result;
+// Succeeded counts the created rows, or the valid ones in a dry run.
+type ImportJob struct {
+	ID         string         `json:"id"`
+	Status     string         `json:"status"`
+	Format     string         `json:"format"`
+	Mode       string         `json:"mode"`
+	DryRun     bool           `json:"dry_run"`
+	Total      int            `json:"total"`
+	Processed  int            `json:"processed"`
+	Succeeded  int            `json:"succeeded"`
+	Failed     int            `json:"failed"`
+	Error      string         `json:"error,omitempty"`
+	Results    []ImportResult `json:"results"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Actor      string         `json:"actor,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvoiceNotFound     = errors.New("invoice not found")
+	ErrOrderNotInvoiceable = errors.New("order must be paid to be invoiced")
+)
+
+// Invoice is the invoice of a paid order. Invoices are numbered without gaps
+// within their fiscal year; Number is the fiscal year and Sequence, e.g.
+// 2024-000042. Its lines are the order lines at the price and VAT they
+// were charged.
+type Invoice struct {
+	ID         int64         `json:"id"`
+	Number     string        `json:"number"`
+	FiscalYear int           `json:"fiscal_year"`
+	Sequence   int           `json:"sequence"`
+	OrderID    int64         `json:"order_id"`
+	CustomerID string        `json:"customer_id,omitempty"`
+	Currency   string        `json:"currency"`
+	Seller     InvoiceParty  `json:"seller"`
+	Lines      []InvoiceLine `json:"lines"`
+	Price      float64       `json:"price"`
+	VAT        float64       `json:"vat"`
+	Total      float64       `json:"total"`
+	IssuedAt   time.Time     `json:"issued_at"`
+}
+
+// InvoiceLine is an invoiced order line. Price and VAT are the line totals;
+// UnitPrice is Price divided by Quantity.
+type InvoiceLine struct {
+	ProductID   int64   `json:"product_id"`
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Price       float64 `json:"price"`
+	VAT         float64 `json:"vat"`
+	VATRate     float64 `json:"vat_rate"`
+}
+
+// InvoiceParty identifies the seller on invoices. Country is an ISO 3166-1
+// alpha-2 code.
+type InvoiceParty struct {
+	Name    string `json:"name"`
+	VATID   string `json:"vat_id,omitempty"`
+	Address string `json:"address,omitempty"`
+	Country string `json:"country,omitempty"`
+}
@@ -2,6 +2,22 @@ package domain
 
 import "time"
 
+// Order statuses
+const (
+	OrderStatusPending          = "pending"
+	OrderStatusPartiallyPaid    = "partially_paid"
+	OrderStatusPaid             = "paid"
+	OrderStatusPartiallyShipped = "partially_shipped"
+	OrderStatusShipped          = "shipped"
+	OrderStatusRefunded         = "refunded"
+)
+
+// IsPaidStatus reports whether an order in status has been paid in full:
+// it is paid, or has been shipped since it was paid.
+func IsPaidStatus(status string) bool {
+	return status == OrderStatusPaid || status == OrderStatusPartiallyShipped || status == OrderStatusShipped
+}
+
 type OrderItem struct {
 	ProductID int64   `json:"product_id"`
 	Quantity  int     `json:"quantity"`
@@ -9,24 +25,115 @@ type OrderItem struct {
 	VAT       float64 `json:"vat,omitempty"`
 }
 
+// Order is a customer order. RefundedPrice and RefundedVAT sum the issued
+// credit notes of the order.
 type Order struct {
-	ID        int64       `json:"order_id"`
-	Items     []OrderItem `json:"items"`
-	Price     float64     `json:"order_price,omitempty"`
-	VAT       float64     `json:"order_vat,omitempty"`
-	CreatedAt time.Time   `json:"created_at,omitempty"`
+	ID            int64       `json:"order_id"`
+	CustomerID    string      `json:"customer_id,omitempty"`
+	Status        string      `json:"status"`
+	Items         []OrderItem `json:"items"`
+	Price         float64     `json:"order_price,omitempty"`
+	VAT           float64     `json:"order_vat,omitempty"`
+	RefundedPrice float64     `json:"refunded_price,omitempty"`
+	RefundedVAT   float64     `json:"refunded_vat,omitempty"`
+	RequestID     string      `json:"request_id,omitempty"`
+	CartID        string      `json:"-"`
+	Version       int         `json:"version"`
+	CreatedAt     time.Time   `json:"created_at,omitempty"`
 }
 
 // Request and response structures
+type OrderInput struct {
+	CustomerID string      `json:"customer_id,omitempty"`
+	Items      []OrderItem `json:"items"`
+}
+
 type CreateOrderRequest struct {
-	Order struct {
-		Items []OrderItem `json:"items"`
-	} `json:"order"`
+	Order OrderInput `json:"order"`
+	// QuoteToken, when set, makes CreateOrder charge the quoted prices
+	QuoteToken string `json:"quote_token,omitempty"`
+	// CartID is set by the cart checkout; the cart is closed in the same
+	// transaction as the order is created
+	CartID string `json:"-"`
 }
 
+// OrderResponse is the API representation of an order. RefundedPrice and
+// RefundedVAT sum its issued credit notes and NetPrice and NetVAT are the
+// totals net of them.
 type OrderResponse struct {
-	OrderID    int64       `json:"order_id"`
+	OrderID       int64       `json:"order_id"`
+	CustomerID    string      `json:"customer_id,omitempty"`
+	Status        string      `json:"status"`
+	OrderPrice    float64     `json:"order_price"`
+	OrderVAT      float64     `json:"order_vat"`
+	RefundedPrice float64     `json:"refunded_price,omitempty"`
+	RefundedVAT   float64     `json:"refunded_vat,omitempty"`
+	NetPrice      float64     `json:"net_price"`
+	NetVAT        float64     `json:"net_vat"`
+	RequestID     string      `json:"request_id,omitempty"`
+	Version       int         `json:"version"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Items         []OrderItem `json:"items"`
+}
+
+// UpdateOrderRequest changes the lines of a pending order. Each item sets the
+// quantity of a product: products not in the order are added, a zero
+// quantity removes the product and products not listed are left unchanged.
+type UpdateOrderRequest struct {
+	Items []OrderItem `json:"items"`
+	// Version is the version of the order the changes were made against;
+	// the update fails with ErrVersionMismatch if the order changed since
+	Version int `json:"-"`
+}
+
+// OrderRevision records one modification of the lines of an order: the
+// requested changes and the items and totals before and after it.
+type OrderRevision struct {
+	Revision    int         `json:"revision"`
+	Version     int         `json:"version"`
+	Changes     []OrderItem `json:"changes"`
+	ItemsBefore []OrderItem `json:"items_before"`
+	ItemsAfter  []OrderItem `json:"items_after"`
+	PriceBefore float64     `json:"price_before"`
+	VATBefore   float64     `json:"vat_before"`
+	PriceAfter  float64     `json:"price_after"`
+	VATAfter    float64     `json:"vat_after"`
+	RequestID   string      `json:"request_id,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// QuoteResponse is the priced breakdown of an order that was not placed.
+type QuoteResponse struct {
 	OrderPrice float64     `json:"order_price"`
 	OrderVAT   float64     `json:"order_vat"`
 	Items      []OrderItem `json:"items"`
+	QuoteToken string      `json:"quote_token,omitempty"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+}
+
+// Order listing limits
+const (
+	DefaultOrderListLimit = 50
+	MaxOrderListLimit     = 500
+)
+
+// OrderFilter selects the orders returned by a listing. Orders are listed by
+// ascending ID; AfterID is the keyset cursor returned by the previous page.
+type OrderFilter struct {
+	CustomerID string
+	Status     string
+	AfterID    int64
+	Limit      int
+}
+
+// Matches reports whether order satisfies the customer and status filters.
+func (f OrderFilter) Matches(order *Order) bool {
+	return (f.CustomerID == "" || order.CustomerID == f.CustomerID) &&
+		(f.Status == "" || order.Status == f.Status)
+}
+
+type OrderListResponse struct {
+	Orders []OrderResponse `json:"orders"`
+	// NextAfterID is the cursor of the next page, zero on the last page
+	NextAfterID int64 `json:"next_after_id,omitempty"`
 }
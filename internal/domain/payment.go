@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Payment statuses. A payment is pending while the gateway is asked to
+// authorize it; authorized payments are then captured or voided.
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusCaptured   = "captured"
+	PaymentStatusVoided     = "voided"
+	PaymentStatusFailed     = "failed"
+)
+
+var (
+	ErrPaymentNotFound       = errors.New("payment not found")
+	ErrPaymentDeclined       = errors.New("payment declined")
+	ErrPaymentExceedsBalance = errors.New("payment exceeds the order balance")
+	ErrPaymentStatus         = errors.New("payment is not in a state that allows this operation")
+	ErrOrderNotPayable       = errors.New("order does not accept payments")
+)
+
+// Payment is an amount authorized, and later captured, against an order.
+// An order can be paid with several payments.
+type Payment struct {
+	ID            int64     `json:"id"`
+	OrderID       int64     `json:"order_id"`
+	Amount        float64   `json:"amount"`
+	Status        string    `json:"status"`
+	Provider      string    `json:"provider"`
+	Reference     string    `json:"reference,omitempty"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	RequestID     string    `json:"request_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CreatePaymentRequest authorizes a payment for an order. A zero Amount pays
+// the remaining balance; Capture captures the payment right after it is
+// authorized.
+type CreatePaymentRequest struct {
+	Amount        float64 `json:"amount,omitempty"`
+	PaymentMethod string  `json:"payment_method"`
+	Capture       bool    `json:"capture,omitempty"`
+}
+
+// AuthorizationRequest is sent to the payment gateway to authorize a payment.
+type AuthorizationRequest struct {
+	OrderID       int64
+	PaymentID     int64
+	Amount        float64
+	PaymentMethod string
+}
+
+// PaymentSummary reconciles the payments of an order against its total,
+// Price plus VAT. Balance is the amount neither authorized nor captured yet.
+type PaymentSummary struct {
+	OrderID    int64     `json:"order_id"`
+	OrderTotal float64   `json:"order_total"`
+	Authorized float64   `json:"authorized"`
+	Captured   float64   `json:"captured"`
+	Balance    float64   `json:"balance"`
+	Payments   []Payment `json:"payments"`
+}
+
+// Cents converts an amount to whole cents, so that amounts are compared and
+// summed without floating point drift.
+func Cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromCents converts whole cents back to an amount.
+func FromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
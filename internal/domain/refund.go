@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Credit note statuses. A credit note is issued once every refund it is
+// made of has succeeded with the payment gateway, and partially issued
+// when only some of them did: its amount is then what was refunded.
+const (
+	CreditNoteStatusPending         = "pending"
+	CreditNoteStatusIssued          = "issued"
+	CreditNoteStatusPartiallyIssued = "partially_issued"
+	CreditNoteStatusFailed          = "failed"
+)
+
+// IsIssuedCreditNote reports whether a credit note in status refunded money
+// and so counts toward the refunded totals of its order.
+func IsIssuedCreditNote(status string) bool {
+	return status == CreditNoteStatusIssued || status == CreditNoteStatusPartiallyIssued
+}
+
+// Refund statuses
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+var (
+	ErrCreditNoteNotFound    = errors.New("credit note not found")
+	ErrRefundExceedsCaptured = errors.New("refund exceeds the captured amount")
+	ErrRefundExceedsQuantity = errors.New("refund exceeds the ordered quantity")
+)
+
+// CreditNote documents money given back for an order, either for order
+// lines or for an amount. Amount is Price plus VAT; it is refunded through
+// one refund per captured payment it is taken from.
+type CreditNote struct {
+	ID        int64       `json:"id"`
+	Number    string      `json:"number"`
+	OrderID   int64       `json:"order_id"`
+	Status    string      `json:"status"`
+	Amount    float64     `json:"amount"`
+	Price     float64     `json:"price"`
+	VAT       float64     `json:"vat"`
+	Reason    string      `json:"reason,omitempty"`
+	Items     []OrderItem `json:"items,omitempty"`
+	Refunds   []Refund    `json:"refunds"`
+	RequestID string      `json:"request_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Refund is the part of a credit note returned on one payment.
+type Refund struct {
+	ID            int64   `json:"id"`
+	PaymentID     int64   `json:"payment_id"`
+	Amount        float64 `json:"amount"`
+	Status        string  `json:"status"`
+	Reference     string  `json:"reference,omitempty"`
+	FailureReason string  `json:"failure_reason,omitempty"`
+	// PaymentReference is the gateway reference of the refunded payment
+	PaymentReference string `json:"-"`
+}
+
+// CreateRefundRequest refunds part of an order. Items refunds quantities of
+// order lines at the price they were charged; Amount refunds an amount,
+// VAT included. Without either, everything captured and not yet refunded
+// is refunded.
+type CreateRefundRequest struct {
+	Items  []OrderItem `json:"items,omitempty"`
+	Amount float64     `json:"amount,omitempty"`
+	Reason string      `json:"reason,omitempty"`
+}
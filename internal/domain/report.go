@@ -0,0 +1,99 @@
+package domain
+
+import "time"
+
+// Report granularities: the length of the periods of a sales report. Weeks
+// start on Monday.
+const (
+	ReportGranularityDay   = "day"
+	ReportGranularityWeek  = "week"
+	ReportGranularityMonth = "month"
+)
+
+// Rankings of the top products report
+const (
+	ReportByQuantity = "quantity"
+	ReportByRevenue  = "revenue"
+)
+
+// SalesStatuses are the statuses of the orders reported as sales unless a
+// report asks for others: paid orders, and refunded ones, which count net of
+// their refunds.
+var SalesStatuses = []string{OrderStatusPaid, OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusRefunded}
+
+// ReportRequest holds the raw parameters of a report. From and To are
+// inclusive dates (YYYY-MM-DD) in TimeZone, an IANA time zone name; Statuses
+// is a comma-separated list of order statuses.
+type ReportRequest struct {
+	From        string
+	To          string
+	TimeZone    string
+	Granularity string
+	Statuses    string
+	By          string
+	Limit       int
+}
+
+// ReportFilter selects the orders of a report: those created in [From, To)
+// with one of Statuses. Periods are computed in the time zone of Location.
+type ReportFilter struct {
+	From        time.Time
+	To          time.Time
+	Location    *time.Location
+	Granularity string
+	Statuses    []string
+}
+
+// SalesTotals aggregates orders. The amounts are sums of the totals of the
+// order API: GrossPrice and GrossVAT of order_price and order_vat, Revenue
+// and VAT of net_price and net_vat, after refunds. AverageOrderValue is
+// Revenue per order, rounded to the cent.
+type SalesTotals struct {
+	Orders            int64   `json:"orders"`
+	GrossPrice        float64 `json:"gross_price"`
+	GrossVAT          float64 `json:"gross_vat"`
+	RefundedPrice     float64 `json:"refunded_price"`
+	RefundedVAT       float64 `json:"refunded_vat"`
+	Revenue           float64 `json:"revenue"`
+	VAT               float64 `json:"vat"`
+	AverageOrderValue float64 `json:"average_order_value"`
+}
+
+// SalesPeriod aggregates the orders created in a period, which is identified
+// by its first day.
+type SalesPeriod struct {
+	Period string `json:"period"`
+	SalesTotals
+}
+
+// SalesReport aggregates orders by period. Periods without orders are
+// included with zero totals.
+type SalesReport struct {
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	TimeZone    string        `json:"time_zone"`
+	Granularity string        `json:"granularity"`
+	Statuses    []string      `json:"statuses"`
+	Periods     []SalesPeriod `json:"periods"`
+	Total       SalesTotals   `json:"total"`
+}
+
+// ProductSales aggregates the order lines of a product. Revenue and VAT are
+// the sums of the line totals, before refunds.
+type ProductSales struct {
+	ProductID int64   `json:"product_id"`
+	Name      string  `json:"name"`
+	Quantity  int64   `json:"quantity"`
+	Revenue   float64 `json:"revenue"`
+	VAT       float64 `json:"vat"`
+}
+
+// TopProductsReport ranks the products sold in a date range.
+type TopProductsReport struct {
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	TimeZone string         `json:"time_zone"`
+	Statuses []string       `json:"statuses"`
+	By       string         `json:"by"`
+	Products []ProductSales `json:"products"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Return statuses. A return is requested by the customer, approved or
+// rejected, then received and finally refunded. It can be rejected until
+// the goods are received.
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusReceived  = "received"
+	ReturnStatusRefunded  = "refunded"
+	ReturnStatusRejected  = "rejected"
+)
+
+var (
+	ErrReturnNotFound        = errors.New("return not found")
+	ErrReturnStatus          = errors.New("return status does not allow this operation")
+	ErrReturnExceedsQuantity = errors.New("return exceeds the ordered quantity")
+	ErrOrderNotReturnable    = errors.New("order must be paid to return goods")
+)
+
+// Return is a return merchandise authorization (RMA) for quantities of
+// order lines. CreditNoteID is the credit note refunding it once it is
+// refunded.
+type Return struct {
+	ID           int64       `json:"id"`
+	OrderID      int64       `json:"order_id"`
+	Status       string      `json:"status"`
+	Reason       string      `json:"reason,omitempty"`
+	Items        []OrderItem `json:"items"`
+	CreditNoteID *int64      `json:"credit_note_id,omitempty"`
+	RequestID    string      `json:"request_id,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// CreateReturnRequest opens a return for quantities of order lines; only
+// ProductID and Quantity of the items are used.
+type CreateReturnRequest struct {
+	Items  []OrderItem `json:"items"`
+	Reason string      `json:"reason,omitempty"`
+}
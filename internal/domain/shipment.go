@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrShipmentExceedsQuantity = errors.New("shipment exceeds the quantity left to ship")
+	ErrOrderNotShippable       = errors.New("order must be paid to be shipped")
+	ErrDuplicateShipment       = errors.New("a shipment with this carrier and tracking number already exists")
+)
+
+// Shipment is a parcel sent for an order, holding quantities of its lines.
+type Shipment struct {
+	ID             int64       `json:"id"`
+	OrderID        int64       `json:"order_id"`
+	Carrier        string      `json:"carrier"`
+	TrackingNumber string      `json:"tracking_number"`
+	Items          []OrderItem `json:"items"`
+	RequestID      string      `json:"request_id,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// CreateShipmentRequest records a shipment. Only ProductID and Quantity of
+// the items are used; without items, everything not shipped yet is shipped.
+type CreateShipmentRequest struct {
+	Carrier        string      `json:"carrier"`
+	TrackingNumber string      `json:"tracking_number"`
+	Items          []OrderItem `json:"items,omitempty"`
+}
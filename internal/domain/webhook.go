@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrDeliveryLeaseLost is returned when recording an attempt of a
+	// delivery that was claimed again or redelivered meanwhile.
+	ErrDeliveryLeaseLost = errors.New("webhook delivery lease lost")
+)
+
+// WebhookSubscription is a partner endpoint that receives order events.
+// An empty EventTypes list subscribes to every event type.
+type WebhookSubscription struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret,omitempty"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event queued for one subscription, together with
+// the outcome of its latest attempt. History lists every attempt, oldest
+// first, including those made before it was redelivered.
+type WebhookDelivery struct {
+	ID             int64             `json:"id"`
+	SubscriptionID int64             `json:"subscription_id"`
+	EventID        int64             `json:"event_id"`
+	EventType      string            `json:"event_type"`
+	Payload        json.RawMessage   `json:"payload"`
+	Status         string            `json:"status"`
+	Attempts       int               `json:"attempts"`
+	NextAttemptAt  time.Time         `json:"next_attempt_at"`
+	LastStatusCode int               `json:"last_status_code,omitempty"`
+	LastError      string            `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time        `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	History        []DeliveryAttempt `json:"history"`
+
+	// Target of the delivery, loaded for the dispatcher only
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// DeliveryAttempt is an attempt in the history of a delivery. StatusCode is
+// zero when no response was received.
+type DeliveryAttempt struct {
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// WebhookAttempt is the outcome of sending a delivery once. LeasedUntil is
+// the next attempt time set when the delivery was claimed, which identifies
+// the claim the attempt was made under.
+type WebhookAttempt struct {
+	DeliveryID    int64
+	StatusCode    int
+	Error         string
+	Status        string
+	NextAttemptAt time.Time
+	LeasedUntil   time.Time
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"`
+}
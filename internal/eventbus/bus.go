@@ -0,0 +1,146 @@
+package eventbus
+
+import (
+	"sync"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// Bus is an in-process publish/subscribe hub for domain events.
+//
+// Publish never blocks: each subscriber has a bounded buffer and a subscriber
+// that falls behind is disconnected rather than slowing down the publisher.
+// The bus keeps a bounded history of recent events so that subscribers can
+// resume after a disconnect from the last event ID they received.
+//
+// Event IDs are assigned by the bus, increase monotonically and are only
+// meaningful within a single process.
+type Bus struct {
+	mu         sync.Mutex
+	lastID     int64
+	history    []domain.Event
+	maxHistory int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+	closed     bool
+}
+
+// Subscription receives the events published after it was created.
+type Subscription struct {
+	// Replay holds the buffered events published after the requested
+	// last event ID, oldest first. They precede anything sent on Events.
+	Replay []domain.Event
+	// Events receives live events. It is closed when the subscription is
+	// closed or when the subscriber falls behind.
+	Events <-chan domain.Event
+
+	bus    *Bus
+	ch     chan domain.Event
+	lagged bool
+	closed bool
+}
+
+func New(maxHistory, bufferSize int) *Bus {
+	return &Bus{
+		maxHistory: maxHistory,
+		bufferSize: bufferSize,
+		subs:       map[*Subscription]struct{}{},
+	}
+}
+
+// Publish assigns the next ID to event, records it in the history and
+// delivers it to every subscriber without blocking.
+func (b *Bus) Publish(event domain.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.maxHistory {
+		b.history = b.history[len(b.history)-b.maxHistory:]
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			// The subscriber's buffer is full: drop it so it can resume later
+			sub.lagged = true
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. When lastEventID is positive, the
+// buffered events published after it are returned in Replay.
+func (b *Bus) Subscribe(lastEventID int64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan domain.Event, b.bufferSize)
+	sub := &Subscription{Events: ch, bus: b, ch: ch}
+
+	if lastEventID > 0 {
+		for _, event := range b.history {
+			if event.ID > lastEventID {
+				sub.Replay = append(sub.Replay, event)
+			}
+		}
+	}
+
+	if b.closed {
+		// Let the caller end its stream right away
+		sub.closed = true
+		close(ch)
+		return sub
+	}
+
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close ends every subscription, so that open streams finish and a server
+// can shut down; later subscriptions are closed immediately. Events
+// published afterwards are still recorded in the history.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// Subscribers returns the number of active subscriptions.
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close unregisters the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// Lagged reports whether the subscription was dropped for falling behind.
+func (s *Subscription) Lagged() bool {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.lagged
+}
+
+// remove must be called with b.mu held.
+func (b *Bus) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(b.subs, sub)
+	close(sub.ch)
+}
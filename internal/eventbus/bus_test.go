@@ -0,0 +1,99 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+func event(aggregateID int64) domain.Event {
+	return domain.Event{Type: domain.EventOrderCreated, AggregateType: domain.AggregateOrder, AggregateID: aggregateID}
+}
+
+func TestBus(t *testing.T) {
+	t.Run("Subscribers receive published events with increasing IDs", func(t *testing.T) {
+		bus := New(10, 10)
+		sub := bus.Subscribe(0)
+		defer sub.Close()
+
+		bus.Publish(event(1))
+		bus.Publish(event(2))
+
+		first, second := <-sub.Events, <-sub.Events
+		assert.Equal(t, int64(1), first.ID)
+		assert.Equal(t, int64(1), first.AggregateID)
+		assert.Equal(t, int64(2), second.ID)
+		assert.Empty(t, sub.Replay)
+	})
+
+	t.Run("Resume replays events after the last event ID", func(t *testing.T) {
+		bus := New(3, 10)
+		for i := int64(1); i <= 5; i++ {
+			bus.Publish(event(i))
+		}
+
+		sub := bus.Subscribe(3)
+		defer sub.Close()
+
+		require.Len(t, sub.Replay, 2)
+		assert.Equal(t, int64(4), sub.Replay[0].ID)
+		assert.Equal(t, int64(5), sub.Replay[1].ID)
+
+		// Only the bounded history can be replayed
+		old := bus.Subscribe(1)
+		defer old.Close()
+		assert.Len(t, old.Replay, 3)
+	})
+
+	t.Run("Slow subscribers are dropped without blocking the publisher", func(t *testing.T) {
+		bus := New(10, 2)
+		slow := bus.Subscribe(0)
+		fast := bus.Subscribe(0)
+		defer fast.Close()
+
+		for i := int64(1); i <= 3; i++ {
+			bus.Publish(event(i))
+			<-fast.Events
+		}
+
+		assert.True(t, slow.Lagged())
+		assert.False(t, fast.Lagged())
+		assert.Equal(t, 1, bus.Subscribers())
+
+		// The slow subscriber drains its buffer, then its channel is closed
+		<-slow.Events
+		<-slow.Events
+		_, open := <-slow.Events
+		assert.False(t, open)
+	})
+
+	t.Run("Close is idempotent", func(t *testing.T) {
+		bus := New(10, 2)
+		sub := bus.Subscribe(0)
+
+		sub.Close()
+		sub.Close()
+
+		assert.Equal(t, 0, bus.Subscribers())
+		bus.Publish(event(1))
+	})
+
+	t.Run("Closing the bus ends every subscription", func(t *testing.T) {
+		bus := New(10, 2)
+		sub := bus.Subscribe(0)
+
+		bus.Close()
+
+		_, open := <-sub.Events
+		assert.False(t, open)
+		assert.False(t, sub.Lagged())
+		assert.Equal(t, 0, bus.Subscribers())
+
+		late := bus.Subscribe(0)
+		_, open = <-late.Events
+		assert.False(t, open)
+		late.Close()
+	})
+}
@@ -0,0 +1,51 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+)
+
+// Error codes reported in the extensions of GraphQL errors
+const (
+	CodeBadUserInput = "BAD_USER_INPUT"
+	CodeNotFound     = "NOT_FOUND"
+	CodeInternal     = "INTERNAL"
+)
+
+// resolverError is a GraphQL error carrying a machine readable code.
+type resolverError struct {
+	message string
+	code    string
+}
+
+func (e *resolverError) Error() string {
+	return e.message
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func badUserInput(message string) error {
+	return &resolverError{message: message, code: CodeBadUserInput}
+}
+
+// toError maps errors returned by the services to GraphQL errors.
+// Unexpected errors are logged and reported without their details.
+func toError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrOrderNotFound):
+		return &resolverError{message: err.Error(), code: CodeNotFound}
+	case errors.Is(err, domain.ErrProductNotFound),
+		errors.Is(err, domain.ErrInvalidQuote),
+		errors.Is(err, domain.ErrQuoteExpired),
+		errors.Is(err, domain.ErrQuoteMismatch):
+		return badUserInput(err.Error())
+	default:
+		logging.FromContext(ctx).Error("graphql resolver failed", "error", err)
+		return &resolverError{message: "internal error", code: CodeInternal}
+	}
+}
@@ -0,0 +1,73 @@
+// Package graphqlapi serves orders, their items and products over GraphQL.
+package graphqlapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+//go:embed schema.graphql
+var Schema string
+
+// Query limits protecting the service from expensive requests. Every order
+// list is a query of its own, and Order.customer.orders nests lists inside
+// lists, so their number is limited per request rather than by depth alone.
+const (
+	maxDepth      = 10
+	maxBodyBytes  = 1 << 20
+	maxOrderLists = 10
+)
+
+// Handler executes GraphQL requests POSTed as JSON.
+type Handler struct {
+	schema      *graphql.Schema
+	productRepo repository.ProductRepository
+}
+
+// NewHandler parses the schema and binds it to the resolvers. It panics if
+// the schema and resolvers do not match, which is a programming error.
+func NewHandler(orderService services.OrderServiceInterface, productRepo repository.ProductRepository) *Handler {
+	resolver := &Resolver{orderService: orderService, productRepo: productRepo}
+	return &Handler{
+		schema:      graphql.MustParseSchema(Schema, resolver, graphql.MaxDepth(maxDepth)),
+		productRepo: productRepo,
+	}
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP executes the query in the request body. Following the GraphQL
+// over HTTP convention, query errors are reported in the errors field of a
+// 200 OK response; only malformed requests get a 400 Bad Request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil || req.Query == "" {
+		writeResponse(w, http.StatusBadRequest, &graphql.Response{
+			Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("invalid GraphQL request body")},
+		})
+		return
+	}
+
+	// Product lookups are batched per request
+	ctx := withProductLoader(r.Context(), newProductLoader(h.productRepo))
+	ctx = withOrderListBudget(ctx, maxOrderLists)
+
+	response := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	writeResponse(w, http.StatusOK, response)
+}
+
+func writeResponse(w http.ResponseWriter, status int, response *graphql.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,322 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// MockOrderService is a mock implementation of services.OrderServiceInterface
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) CreateOrders(ctx context.Context, reqs []*domain.CreateOrderRequest) ([]*domain.OrderResponse, error) {
+	args := m.Called(ctx, reqs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) ExportOrders(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.OrderResponse) error) error {
+	args := m.Called(ctx, filter, fn)
+	if orders, ok := args.Get(0).([]*domain.OrderResponse); ok {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockOrderService) QuoteOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.QuoteResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.QuoteResponse), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrder(ctx context.Context, id int64) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) ListOrders(ctx context.Context, filter domain.OrderFilter) (*domain.OrderListResponse, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderListResponse), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrder(ctx context.Context, id int64, req *domain.UpdateOrderRequest) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) ListOrderRevisions(ctx context.Context, id int64) ([]domain.OrderRevision, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.OrderRevision), args.Error(1)
+}
+
+// MockProductRepository is a mock implementation of repository.ProductRepository
+type MockProductRepository struct {
+	mock.Mock
+}
+
+func (m *MockProductRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetByIDs(ctx context.Context, ids []int64) ([]domain.Product, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, handler *Handler, query string, variables map[string]interface{}) graphqlResponse {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response graphqlResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestOrderQuery(t *testing.T) {
+	t.Run("Products are loaded in one batch", func(t *testing.T) {
+		// Setup
+		orderService := new(MockOrderService)
+		productRepo := new(MockProductRepository)
+		handler := NewHandler(orderService, productRepo)
+
+		orderService.On("GetOrder", mock.Anything, int64(1)).Return(&domain.OrderResponse{
+			OrderID:    1,
+			CustomerID: "cust-1",
+			Status:     domain.OrderStatusPending,
+			OrderPrice: 35.0,
+			OrderVAT:   3.5,
+			Items: []domain.OrderItem{
+				{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0},
+				{ProductID: 2, Quantity: 3, Price: 15.0, VAT: 1.5},
+				{ProductID: 1, Quantity: 1, Price: 10.0, VAT: 1.0},
+			},
+		}, nil)
+		productRepo.On("GetByIDs", mock.Anything, mock.MatchedBy(func(ids []int64) bool {
+			return assert.ElementsMatch(t, []int64{1, 2}, ids)
+		})).Return([]domain.Product{
+			{ID: 1, Name: "Product 1", Price: 10.0, VAT: 1.0},
+			{ID: 2, Name: "Product 2", Price: 5.0, VAT: 0.5},
+		}, nil).Once()
+
+		response := execute(t, handler, `{
+			order(id: "1") {
+				id status total
+				customer { id }
+				items { quantity product { id name } }
+			}
+		}`, nil)
+
+		// Assertions
+		require.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"order": {
+			"id": "1", "status": "pending", "total": 38.5,
+			"customer": {"id": "cust-1"},
+			"items": [
+				{"quantity": 2, "product": {"id": "1", "name": "Product 1"}},
+				{"quantity": 3, "product": {"id": "2", "name": "Product 2"}},
+				{"quantity": 1, "product": {"id": "1", "name": "Product 1"}}
+			]
+		}}`, string(response.Data))
+		productRepo.AssertExpectations(t)
+		productRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Missing order is null", func(t *testing.T) {
+		// Setup
+		orderService := new(MockOrderService)
+		handler := NewHandler(orderService, new(MockProductRepository))
+
+		orderService.On("GetOrder", mock.Anything, int64(999)).Return(nil, domain.ErrOrderNotFound)
+
+		response := execute(t, handler, `{ order(id: "999") { id } }`, nil)
+
+		// Assertions
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"order": null}`, string(response.Data))
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		// Setup
+		handler := NewHandler(new(MockOrderService), new(MockProductRepository))
+
+		response := execute(t, handler, `{ order(id: "abc") { id } }`, nil)
+
+		// Assertions
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, CodeBadUserInput, response.Errors[0].Extensions["code"])
+	})
+}
+
+func TestOrdersQuery(t *testing.T) {
+	// Setup
+	orderService := new(MockOrderService)
+	handler := NewHandler(orderService, new(MockProductRepository))
+
+	orderService.On("ListOrders", mock.Anything, domain.OrderFilter{CustomerID: "cust-1", Status: "pending", AfterID: 3, Limit: 2}).
+		Return(&domain.OrderListResponse{
+			Orders:      []domain.OrderResponse{{OrderID: 4}, {OrderID: 5}},
+			NextAfterID: 5,
+		}, nil)
+
+	response := execute(t, handler, `{
+		customer(id: "cust-1") {
+			orders(status: "pending", first: 2, after: "3") { nodes { id } nextCursor }
+		}
+	}`, nil)
+
+	// Assertions
+	require.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"customer": {"orders": {"nodes": [{"id": "4"}, {"id": "5"}], "nextCursor": "5"}}}`, string(response.Data))
+	orderService.AssertExpectations(t)
+}
+
+func TestNestedOrderListsAreLimited(t *testing.T) {
+	// Setup
+	orderService := new(MockOrderService)
+	handler := NewHandler(orderService, new(MockProductRepository))
+
+	orders := make([]domain.OrderResponse, 20)
+	for i := range orders {
+		orders[i] = domain.OrderResponse{OrderID: int64(i + 1), CustomerID: "cust-1"}
+	}
+	orderService.On("ListOrders", mock.Anything, mock.Anything).
+		Return(&domain.OrderListResponse{Orders: orders}, nil)
+
+	response := execute(t, handler, `{
+		orders { nodes { customer { orders { nodes { id } } } } }
+	}`, nil)
+
+	// Assertions
+	require.NotEmpty(t, response.Errors)
+	assert.Equal(t, "a query may list orders at most 10 times", response.Errors[0].Message)
+	assert.Equal(t, CodeBadUserInput, response.Errors[0].Extensions["code"])
+	orderService.AssertNumberOfCalls(t, "ListOrders", maxOrderLists)
+}
+
+func TestCreateOrderMutation(t *testing.T) {
+	mutation := `mutation($input: CreateOrderInput!) { createOrder(input: $input) { id price vat } }`
+
+	t.Run("Successful order creation", func(t *testing.T) {
+		// Setup
+		orderService := new(MockOrderService)
+		handler := NewHandler(orderService, new(MockProductRepository))
+
+		orderService.On("CreateOrder", mock.Anything, &domain.CreateOrderRequest{
+			Order: domain.OrderInput{
+				CustomerID: "cust-1",
+				Items:      []domain.OrderItem{{ProductID: 1, Quantity: 2}},
+			},
+		}).Return(&domain.OrderResponse{OrderID: 7, OrderPrice: 20.0, OrderVAT: 2.0}, nil)
+
+		response := execute(t, handler, mutation, map[string]interface{}{
+			"input": map[string]interface{}{
+				"customerId": "cust-1",
+				"items":      []interface{}{map[string]interface{}{"productId": "1", "quantity": 2}},
+			},
+		})
+
+		// Assertions
+		require.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"createOrder": {"id": "7", "price": 20, "vat": 2}}`, string(response.Data))
+		orderService.AssertExpectations(t)
+	})
+
+	t.Run("Unknown product", func(t *testing.T) {
+		// Setup
+		orderService := new(MockOrderService)
+		handler := NewHandler(orderService, new(MockProductRepository))
+
+		orderService.On("CreateOrder", mock.Anything, mock.Anything).Return(nil, domain.ErrProductNotFound)
+
+		response := execute(t, handler, mutation, map[string]interface{}{
+			"input": map[string]interface{}{
+				"items": []interface{}{map[string]interface{}{"productId": "9", "quantity": 1}},
+			},
+		})
+
+		// Assertions
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, CodeBadUserInput, response.Errors[0].Extensions["code"])
+	})
+
+	t.Run("Empty order items", func(t *testing.T) {
+		// Setup
+		handler := NewHandler(new(MockOrderService), new(MockProductRepository))
+
+		response := execute(t, handler, mutation, map[string]interface{}{
+			"input": map[string]interface{}{"items": []interface{}{}},
+		})
+
+		// Assertions
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Message, "at least one item")
+	})
+}
+
+func TestInvalidRequestBody(t *testing.T) {
+	handler := NewHandler(new(MockOrderService), new(MockProductRepository))
+
+	req := httptest.NewRequest("POST", "/graphql", bytes.NewBufferString("not json"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,80 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+type productLoaderKey struct{}
+
+// productLoader batches and caches the product lookups of one request, so
+// that resolving the product of every item costs a single query.
+type productLoader = dataloader.Interface[int64, *domain.Product]
+
+func newProductLoader(repo repository.ProductRepository) productLoader {
+	batch := func(ctx context.Context, ids []int64) []*dataloader.Result[*domain.Product] {
+		results := make([]*dataloader.Result[*domain.Product], len(ids))
+
+		products, err := repo.GetByIDs(ctx, ids)
+		if err != nil {
+			for i := range results {
+				results[i] = &dataloader.Result[*domain.Product]{Error: err}
+			}
+			return results
+		}
+
+		byID := make(map[int64]*domain.Product, len(products))
+		for i := range products {
+			byID[products[i].ID] = &products[i]
+		}
+
+		// Results must be in the order of ids
+		for i, id := range ids {
+			if product, ok := byID[id]; ok {
+				results[i] = &dataloader.Result[*domain.Product]{Data: product}
+			} else {
+				results[i] = &dataloader.Result[*domain.Product]{
+					Error: fmt.Errorf("product with ID %d not found: %w", id, domain.ErrProductNotFound),
+				}
+			}
+		}
+		return results
+	}
+
+	return dataloader.NewBatchedLoader(batch)
+}
+
+func withProductLoader(ctx context.Context, loader productLoader) context.Context {
+	return context.WithValue(ctx, productLoaderKey{}, loader)
+}
+
+func productLoaderFromContext(ctx context.Context) productLoader {
+	loader, _ := ctx.Value(productLoaderKey{}).(productLoader)
+	return loader
+}
+
+type orderListBudgetKey struct{}
+
+// orderListBudget counts down the order lists a request may still resolve.
+// Fields are resolved concurrently, so it is decremented atomically.
+type orderListBudget struct {
+	remaining atomic.Int64
+}
+
+func withOrderListBudget(ctx context.Context, n int64) context.Context {
+	budget := &orderListBudget{}
+	budget.remaining.Store(n)
+	return context.WithValue(ctx, orderListBudgetKey{}, budget)
+}
+
+// takeOrderList reports whether the request may resolve one more order
+// list. Requests without a budget are not limited.
+func takeOrderList(ctx context.Context) bool {
+	budget, ok := ctx.Value(orderListBudgetKey{}).(*orderListBudget)
+	return !ok || budget.remaining.Add(-1) >= 0
+}
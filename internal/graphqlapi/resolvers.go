@@ -0,0 +1,289 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+	"github.com/valeriouberti/order-service-test/internal/services"
+)
+
+// Resolver is the root resolver of the schema.
+type Resolver struct {
+	orderService services.OrderServiceInterface
+	productRepo  repository.ProductRepository
+}
+
+func parseID(id graphql.ID) (int64, error) {
+	n, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, badUserInput("invalid ID " + strconv.Quote(string(id)))
+	}
+	return n, nil
+}
+
+func formatID(id int64) graphql.ID {
+	return graphql.ID(strconv.FormatInt(id, 10))
+}
+
+type orderArgs struct {
+	ID graphql.ID
+}
+
+// Order resolves a single order. A missing order resolves to null.
+func (r *Resolver) Order(ctx context.Context, args orderArgs) (*orderResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := r.orderService.GetOrder(ctx, id)
+	if errors.Is(err, domain.ErrOrderNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toError(ctx, err)
+	}
+
+	return &orderResolver{root: r, order: order}, nil
+}
+
+type pageArgs struct {
+	Status *string
+	First  *int32
+	After  *graphql.ID
+}
+
+type ordersArgs struct {
+	CustomerID *string
+	pageArgs
+}
+
+// Orders resolves a page of orders.
+func (r *Resolver) Orders(ctx context.Context, args ordersArgs) (*orderConnectionResolver, error) {
+	var customerID string
+	if args.CustomerID != nil {
+		customerID = *args.CustomerID
+	}
+	return r.listOrders(ctx, customerID, args.pageArgs)
+}
+
+// Customer resolves a customer. Customers are only known through their
+// orders, so any ID resolves to a customer, possibly without orders.
+func (r *Resolver) Customer(args struct{ ID graphql.ID }) *customerResolver {
+	return &customerResolver{root: r, id: string(args.ID)}
+}
+
+type createOrderArgs struct {
+	Input struct {
+		CustomerID *string
+		Items      []struct {
+			ProductID graphql.ID
+			Quantity  int32
+		}
+		QuoteToken *string
+	}
+}
+
+// CreateOrder creates an order through the order service, like POST /api/orders.
+func (r *Resolver) CreateOrder(ctx context.Context, args createOrderArgs) (*orderResolver, error) {
+	if len(args.Input.Items) == 0 {
+		return nil, badUserInput("order must contain at least one item")
+	}
+
+	req := &domain.CreateOrderRequest{}
+	if args.Input.CustomerID != nil {
+		req.Order.CustomerID = *args.Input.CustomerID
+	}
+	if args.Input.QuoteToken != nil {
+		req.QuoteToken = *args.Input.QuoteToken
+	}
+	for _, item := range args.Input.Items {
+		productID, err := parseID(item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		req.Order.Items = append(req.Order.Items, domain.OrderItem{ProductID: productID, Quantity: int(item.Quantity)})
+	}
+
+	order, err := r.orderService.CreateOrder(ctx, req)
+	if err != nil {
+		return nil, toError(ctx, err)
+	}
+
+	return &orderResolver{root: r, order: order}, nil
+}
+
+func (r *Resolver) listOrders(ctx context.Context, customerID string, args pageArgs) (*orderConnectionResolver, error) {
+	if !takeOrderList(ctx) {
+		return nil, badUserInput(fmt.Sprintf("a query may list orders at most %d times", maxOrderLists))
+	}
+
+	filter := domain.OrderFilter{CustomerID: customerID}
+	if args.Status != nil {
+		filter.Status = *args.Status
+	}
+	if args.First != nil {
+		if *args.First < 1 {
+			return nil, badUserInput("first must be positive")
+		}
+		filter.Limit = int(*args.First)
+	}
+	if args.After != nil {
+		afterID, err := parseID(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		filter.AfterID = afterID
+	}
+
+	list, err := r.orderService.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, toError(ctx, err)
+	}
+
+	return &orderConnectionResolver{root: r, list: list}, nil
+}
+
+type orderResolver struct {
+	root  *Resolver
+	order *domain.OrderResponse
+}
+
+func (o *orderResolver) ID() graphql.ID {
+	return formatID(o.order.OrderID)
+}
+
+func (o *orderResolver) Customer() *customerResolver {
+	if o.order.CustomerID == "" {
+		return nil
+	}
+	return &customerResolver{root: o.root, id: o.order.CustomerID}
+}
+
+func (o *orderResolver) Status() string {
+	return o.order.Status
+}
+
+func (o *orderResolver) Price() float64 {
+	return o.order.OrderPrice
+}
+
+func (o *orderResolver) VAT() float64 {
+	return o.order.OrderVAT
+}
+
+func (o *orderResolver) Total() float64 {
+	return o.order.OrderPrice + o.order.OrderVAT
+}
+
+func (o *orderResolver) RequestID() *string {
+	if o.order.RequestID == "" {
+		return nil
+	}
+	return &o.order.RequestID
+}
+
+func (o *orderResolver) Items() []*orderItemResolver {
+	items := make([]*orderItemResolver, 0, len(o.order.Items))
+	for _, item := range o.order.Items {
+		items = append(items, &orderItemResolver{root: o.root, item: item})
+	}
+	return items
+}
+
+type orderItemResolver struct {
+	root *Resolver
+	item domain.OrderItem
+}
+
+func (i *orderItemResolver) ProductID() graphql.ID {
+	return formatID(i.item.ProductID)
+}
+
+// Product resolves the product through the request's product loader, so the
+// products of all items in a response are fetched together.
+func (i *orderItemResolver) Product(ctx context.Context) (*productResolver, error) {
+	var product *domain.Product
+	var err error
+	if loader := productLoaderFromContext(ctx); loader != nil {
+		product, err = loader.Load(ctx, i.item.ProductID)()
+	} else {
+		product, err = i.root.productRepo.GetByID(ctx, i.item.ProductID)
+	}
+	if err != nil {
+		return nil, toError(ctx, err)
+	}
+
+	return &productResolver{product: product}, nil
+}
+
+func (i *orderItemResolver) Quantity() int32 {
+	return int32(i.item.Quantity)
+}
+
+func (i *orderItemResolver) Price() float64 {
+	return i.item.Price
+}
+
+func (i *orderItemResolver) VAT() float64 {
+	return i.item.VAT
+}
+
+type productResolver struct {
+	product *domain.Product
+}
+
+func (p *productResolver) ID() graphql.ID {
+	return formatID(p.product.ID)
+}
+
+func (p *productResolver) Name() string {
+	return p.product.Name
+}
+
+func (p *productResolver) Price() float64 {
+	return p.product.Price
+}
+
+func (p *productResolver) VAT() float64 {
+	return p.product.VAT
+}
+
+type customerResolver struct {
+	root *Resolver
+	id   string
+}
+
+func (c *customerResolver) ID() graphql.ID {
+	return graphql.ID(c.id)
+}
+
+func (c *customerResolver) Orders(ctx context.Context, args pageArgs) (*orderConnectionResolver, error) {
+	return c.root.listOrders(ctx, c.id, args)
+}
+
+type orderConnectionResolver struct {
+	root *Resolver
+	list *domain.OrderListResponse
+}
+
+func (c *orderConnectionResolver) Nodes() []*orderResolver {
+	nodes := make([]*orderResolver, 0, len(c.list.Orders))
+	for i := range c.list.Orders {
+		nodes = append(nodes, &orderResolver{root: c.root, order: &c.list.Orders[i]})
+	}
+	return nodes
+}
+
+func (c *orderConnectionResolver) NextCursor() *graphql.ID {
+	if c.list.NextAfterID == 0 {
+		return nil
+	}
+	cursor := formatID(c.list.NextAfterID)
+	return &cursor
+}
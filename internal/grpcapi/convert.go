@@ -0,0 +1,53 @@
+package grpcapi
+
+import (
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/grpcapi/orderv1"
+)
+
+func fromProtoItems(items []*orderv1.OrderItem) []domain.OrderItem {
+	result := make([]domain.OrderItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, domain.OrderItem{
+			ProductID: item.GetProductId(),
+			Quantity:  int(item.GetQuantity()),
+		})
+	}
+	return result
+}
+
+func toProtoOrder(order *domain.OrderResponse) *orderv1.OrderResponse {
+	items := make([]*orderv1.OrderItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, &orderv1.OrderItem{
+			ProductId: item.ProductID,
+			Quantity:  int32(item.Quantity),
+			Price:     item.Price,
+			Vat:       item.VAT,
+		})
+	}
+
+	return &orderv1.OrderResponse{
+		OrderId:    order.OrderID,
+		CustomerId: order.CustomerID,
+		Status:     order.Status,
+		OrderPrice: order.OrderPrice,
+		OrderVat:   order.OrderVAT,
+		RequestId:  order.RequestID,
+		Items:      items,
+	}
+}
+
+// orderResponse maps an order snapshot carried by an event to the shape
+// returned by the other methods.
+func orderResponse(order *domain.Order) *domain.OrderResponse {
+	return &domain.OrderResponse{
+		OrderID:    order.ID,
+		CustomerID: order.CustomerID,
+		Status:     order.Status,
+		OrderPrice: order.Price,
+		OrderVAT:   order.VAT,
+		RequestID:  order.RequestID,
+		Items:      order.Items,
+	}
+}
@@ -0,0 +1,34 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus maps errors returned by the services to gRPC status errors.
+// Unexpected errors are logged and reported as Internal without leaking
+// their details.
+func toStatus(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrProductNotFound),
+		errors.Is(err, domain.ErrInvalidQuote),
+		errors.Is(err, domain.ErrQuoteMismatch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrQuoteExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		logging.FromContext(ctx).Error("grpc request failed", "error", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
@@ -0,0 +1,36 @@
+// Package grpcapi serves the order API over gRPC for internal services,
+// alongside the REST API.
+package grpcapi
+
+import (
+	"log/slog"
+
+	"github.com/valeriouberti/order-service-test/internal/grpcapi/orderv1"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewServer creates a gRPC server exposing orders together with the standard
+// health service and server reflection. Calls are traced with tp and logged
+// with their request ID. The actor of a call is read from the actorMetadata
+// key, set by an authenticating proxy; empty disables it. The returned health
+// server reports SERVING until its Shutdown method is called.
+func NewServer(orders *OrderServer, actorMetadata string, logger *slog.Logger, tp trace.TracerProvider) (*grpc.Server, *health.Server) {
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithTracerProvider(tp))),
+		grpc.ChainUnaryInterceptor(UnaryInterceptor(logger, actorMetadata)),
+		grpc.ChainStreamInterceptor(StreamInterceptor(logger, actorMetadata)),
+	)
+
+	orderv1.RegisterOrderServiceServer(srv, orders)
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthServer)
+	reflection.Register(srv)
+
+	return srv, healthServer
+}
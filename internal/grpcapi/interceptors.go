@@ -0,0 +1,97 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/actor"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadata is the metadata key used to receive and echo correlation
+// IDs, the gRPC equivalent of the X-Request-ID header.
+const RequestIDMetadata = "x-request-id"
+
+// requestContext accepts an incoming request ID or generates a new one,
+// echoes it in the response header and attaches it with a request-scoped
+// logger to ctx. The actor named by the actorMetadata key, if any, is
+// attached too, like the HTTP Actor middleware does.
+func requestContext(ctx context.Context, logger *slog.Logger, actorMetadata string) (context.Context, *slog.Logger) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadata); len(values) > 0 {
+			id = values[0]
+		}
+		if actorMetadata != "" {
+			if values := md.Get(actorMetadata); len(values) > 0 && actor.Valid(values[0]) {
+				ctx = actor.NewContext(ctx, values[0])
+			}
+		}
+	}
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadata, id))
+
+	reqLogger := logger.With("request_id", id)
+	ctx = requestid.NewContext(ctx, id)
+	return logging.NewContext(ctx, reqLogger), reqLogger
+}
+
+// logRequest records one structured log line per call, mirroring the HTTP
+// logging middleware.
+func logRequest(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelError
+	}
+
+	logger.LogAttrs(ctx, level, "grpc request",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	)
+}
+
+// UnaryInterceptor assigns a request ID, request-scoped logger and actor to
+// each unary call and logs its outcome.
+func UnaryInterceptor(logger *slog.Logger, actorMetadata string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, reqLogger := requestContext(ctx, logger, actorMetadata)
+
+		resp, err := handler(ctx, req)
+		logRequest(ctx, reqLogger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamInterceptor is the streaming counterpart of UnaryInterceptor.
+func StreamInterceptor(logger *slog.Logger, actorMetadata string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, reqLogger := requestContext(ss.Context(), logger, actorMetadata)
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		logRequest(ctx, reqLogger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,147 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/eventbus"
+	"github.com/valeriouberti/order-service-test/internal/grpcapi/orderv1"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/services"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OrderServer implements the orderv1.OrderService gRPC service on top of the
+// same order service used by the REST handlers.
+type OrderServer struct {
+	orderv1.UnimplementedOrderServiceServer
+
+	orderService services.OrderServiceInterface
+	bus          *eventbus.Bus
+}
+
+func NewOrderServer(orderService services.OrderServiceInterface, bus *eventbus.Bus) *OrderServer {
+	return &OrderServer{
+		orderService: orderService,
+		bus:          bus,
+	}
+}
+
+// CreateOrder prices and persists a new order. Like the REST handler it
+// rejects orders without items with InvalidArgument.
+func (s *OrderServer) CreateOrder(ctx context.Context, req *orderv1.CreateOrderRequest) (*orderv1.OrderResponse, error) {
+	if len(req.GetOrder().GetItems()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order must contain at least one item")
+	}
+
+	response, err := s.orderService.CreateOrder(ctx, &domain.CreateOrderRequest{
+		Order: domain.OrderInput{
+			CustomerID: req.GetOrder().GetCustomerId(),
+			Items:      fromProtoItems(req.GetOrder().GetItems()),
+		},
+		QuoteToken: req.GetQuoteToken(),
+	})
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return toProtoOrder(response), nil
+}
+
+// GetOrder returns a single order, or NotFound if it does not exist.
+func (s *OrderServer) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.OrderResponse, error) {
+	if req.GetOrderId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid order ID")
+	}
+
+	response, err := s.orderService.GetOrder(ctx, req.GetOrderId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return toProtoOrder(response), nil
+}
+
+// ListOrders returns a page of orders matching the request filters.
+func (s *OrderServer) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	if req.GetLimit() < 0 || req.GetAfterId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and after_id must not be negative")
+	}
+
+	response, err := s.orderService.ListOrders(ctx, domain.OrderFilter{
+		CustomerID: req.GetCustomerId(),
+		Status:     req.GetStatus(),
+		AfterID:    req.GetAfterId(),
+		Limit:      int(req.GetLimit()),
+	})
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	orders := make([]*orderv1.OrderResponse, 0, len(response.Orders))
+	for i := range response.Orders {
+		orders = append(orders, toProtoOrder(&response.Orders[i]))
+	}
+
+	return &orderv1.ListOrdersResponse{
+		Orders:      orders,
+		NextAfterId: response.NextAfterID,
+	}, nil
+}
+
+// WatchOrders streams order events matching the request filters until the
+// client cancels. Events published after last_event_id that are still in the
+// bus history are sent first. A client that falls behind is disconnected with
+// ResourceExhausted and can resume from the last event ID it received.
+func (s *OrderServer) WatchOrders(req *orderv1.WatchOrdersRequest, stream orderv1.OrderService_WatchOrdersServer) error {
+	ctx := stream.Context()
+	filter := domain.OrderFilter{
+		CustomerID: req.GetCustomerId(),
+		Status:     req.GetStatus(),
+	}
+
+	sub := s.bus.Subscribe(req.GetLastEventId())
+	defer sub.Close()
+
+	send := func(event domain.Event) error {
+		var order domain.Order
+		if err := json.Unmarshal(event.Payload, &order); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed order event", "event_id", event.ID, "error", err)
+			return nil
+		}
+		if !filter.Matches(&order) {
+			return nil
+		}
+
+		return stream.Send(&orderv1.OrderEvent{
+			Id:    event.ID,
+			Type:  event.Type,
+			Order: toProtoOrder(orderResponse(&order)),
+		})
+	}
+
+	for _, event := range sub.Replay {
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.Events:
+			if !ok {
+				if sub.Lagged() {
+					return status.Error(codes.ResourceExhausted, "client too slow, resume from the last event ID")
+				}
+				return status.Error(codes.Unavailable, "server shutting down, resume from the last event ID")
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
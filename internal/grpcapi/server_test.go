@@ -0,0 +1,326 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/actor"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/eventbus"
+	"github.com/valeriouberti/order-service-test/internal/grpcapi/orderv1"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// MockOrderService is a mock implementation of services.OrderServiceInterface
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) CreateOrders(ctx context.Context, reqs []*domain.CreateOrderRequest) ([]*domain.OrderResponse, error) {
+	args := m.Called(ctx, reqs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) ExportOrders(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.OrderResponse) error) error {
+	args := m.Called(ctx, filter, fn)
+	if orders, ok := args.Get(0).([]*domain.OrderResponse); ok {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockOrderService) QuoteOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.QuoteResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.QuoteResponse), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrder(ctx context.Context, id int64) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) ListOrders(ctx context.Context, filter domain.OrderFilter) (*domain.OrderListResponse, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderListResponse), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrder(ctx context.Context, id int64, req *domain.UpdateOrderRequest) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) ListOrderRevisions(ctx context.Context, id int64) ([]domain.OrderRevision, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.OrderRevision), args.Error(1)
+}
+
+// newClient serves the order service on an in-memory listener and returns a client for it
+func newClient(t *testing.T, service *MockOrderService, bus *eventbus.Bus) orderv1.OrderServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	srv, _ := NewServer(NewOrderServer(service, bus), "x-auth-request-user", slog.New(slog.NewTextHandler(io.Discard, nil)), noop.NewTracerProvider())
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return orderv1.NewOrderServiceClient(conn)
+}
+
+func TestCreateOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Successful order creation", func(t *testing.T) {
+		// Setup
+		service := new(MockOrderService)
+		client := newClient(t, service, eventbus.New(10, 10))
+
+		service.On("CreateOrder", mock.Anything, &domain.CreateOrderRequest{
+			Order: domain.OrderInput{
+				CustomerID: "cust-1",
+				Items:      []domain.OrderItem{{ProductID: 1, Quantity: 2}},
+			},
+		}).Return(&domain.OrderResponse{
+			OrderID:    1,
+			CustomerID: "cust-1",
+			Status:     domain.OrderStatusPending,
+			OrderPrice: 20.0,
+			OrderVAT:   2.0,
+			Items:      []domain.OrderItem{{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0}},
+		}, nil)
+
+		var header metadata.MD
+		resp, err := client.CreateOrder(ctx, &orderv1.CreateOrderRequest{
+			Order: &orderv1.OrderInput{
+				CustomerId: "cust-1",
+				Items:      []*orderv1.OrderItem{{ProductId: 1, Quantity: 2}},
+			},
+		}, grpc.Header(&header))
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), resp.OrderId)
+		assert.Equal(t, domain.OrderStatusPending, resp.Status)
+		assert.Equal(t, 20.0, resp.OrderPrice)
+		assert.Len(t, resp.Items, 1)
+		assert.NotEmpty(t, header.Get(RequestIDMetadata))
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Empty order items", func(t *testing.T) {
+		// Setup
+		client := newClient(t, new(MockOrderService), eventbus.New(10, 10))
+
+		_, err := client.CreateOrder(ctx, &orderv1.CreateOrderRequest{Order: &orderv1.OrderInput{}})
+
+		// Assertions
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Product not found", func(t *testing.T) {
+		// Setup
+		service := new(MockOrderService)
+		client := newClient(t, service, eventbus.New(10, 10))
+
+		service.On("CreateOrder", mock.Anything, mock.Anything).
+			Return(nil, fmt.Errorf("product with ID 9 not found: %w", domain.ErrProductNotFound))
+
+		_, err := client.CreateOrder(ctx, &orderv1.CreateOrderRequest{
+			Order: &orderv1.OrderInput{Items: []*orderv1.OrderItem{{ProductId: 9, Quantity: 1}}},
+		})
+
+		// Assertions
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestGetOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Order not found", func(t *testing.T) {
+		// Setup
+		service := new(MockOrderService)
+		client := newClient(t, service, eventbus.New(10, 10))
+
+		service.On("GetOrder", mock.Anything, int64(999)).Return(nil, domain.ErrOrderNotFound)
+
+		_, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: 999})
+
+		// Assertions
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Request ID is propagated", func(t *testing.T) {
+		// Setup
+		service := new(MockOrderService)
+		client := newClient(t, service, eventbus.New(10, 10))
+
+		service.On("GetOrder", mock.Anything, int64(1)).Return(&domain.OrderResponse{OrderID: 1}, nil)
+
+		var header metadata.MD
+		ctx := metadata.AppendToOutgoingContext(ctx, RequestIDMetadata, "req-123")
+		_, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: 1}, grpc.Header(&header))
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, []string{"req-123"}, header.Get(RequestIDMetadata))
+	})
+
+	t.Run("Actor is read from metadata", func(t *testing.T) {
+		// Setup
+		service := new(MockOrderService)
+		client := newClient(t, service, eventbus.New(10, 10))
+
+		service.On("GetOrder", mock.MatchedBy(func(ctx context.Context) bool {
+			return actor.FromContext(ctx) == "alice"
+		}), int64(1)).Return(&domain.OrderResponse{OrderID: 1}, nil)
+
+		ctx := metadata.AppendToOutgoingContext(ctx, "X-Auth-Request-User", "alice")
+		_, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: 1})
+
+		// Assertions
+		require.NoError(t, err)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Unexpected errors are not leaked", func(t *testing.T) {
+		// Setup
+		service := new(MockOrderService)
+		client := newClient(t, service, eventbus.New(10, 10))
+
+		service.On("GetOrder", mock.Anything, int64(1)).Return(nil, fmt.Errorf("connection refused"))
+
+		_, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: 1})
+
+		// Assertions
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.NotContains(t, err.Error(), "connection refused")
+	})
+}
+
+func TestListOrders(t *testing.T) {
+	// Setup
+	service := new(MockOrderService)
+	client := newClient(t, service, eventbus.New(10, 10))
+
+	service.On("ListOrders", mock.Anything, domain.OrderFilter{Status: "pending", AfterID: 4, Limit: 2}).
+		Return(&domain.OrderListResponse{
+			Orders:      []domain.OrderResponse{{OrderID: 5}, {OrderID: 6}},
+			NextAfterID: 6,
+		}, nil)
+
+	resp, err := client.ListOrders(context.Background(), &orderv1.ListOrdersRequest{Status: "pending", AfterId: 4, Limit: 2})
+
+	// Assertions
+	require.NoError(t, err)
+	assert.Len(t, resp.Orders, 2)
+	assert.Equal(t, int64(6), resp.NextAfterId)
+	service.AssertExpectations(t)
+}
+
+func TestWatchOrders(t *testing.T) {
+	publish := func(t *testing.T, bus *eventbus.Bus, id int64, customerID string) {
+		event, err := domain.NewOrderEvent(domain.EventOrderCreated, &domain.Order{ID: id, CustomerID: customerID, Status: domain.OrderStatusPending})
+		require.NoError(t, err)
+		bus.Publish(*event)
+	}
+
+	t.Run("Replays and streams matching events", func(t *testing.T) {
+		// Setup
+		bus := eventbus.New(10, 10)
+		client := newClient(t, new(MockOrderService), bus)
+
+		publish(t, bus, 1, "cust-1")
+		publish(t, bus, 2, "cust-1")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream, err := client.WatchOrders(ctx, &orderv1.WatchOrdersRequest{CustomerId: "cust-1", LastEventId: 1})
+		require.NoError(t, err)
+
+		// The replayed event proves the subscription exists before publishing
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), event.Id)
+		assert.Equal(t, int64(2), event.Order.OrderId)
+
+		publish(t, bus, 3, "cust-2")
+		publish(t, bus, 4, "cust-1")
+
+		// Assertions
+		event, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), event.Id)
+		assert.Equal(t, domain.EventOrderCreated, event.Type)
+		assert.Equal(t, "cust-1", event.Order.CustomerId)
+	})
+
+	t.Run("Closing the bus ends the stream", func(t *testing.T) {
+		// Setup
+		bus := eventbus.New(10, 10)
+		client := newClient(t, new(MockOrderService), bus)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := client.WatchOrders(ctx, &orderv1.WatchOrdersRequest{})
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return bus.Subscribers() == 1 }, time.Second, time.Millisecond)
+		bus.Close()
+
+		_, err = stream.Recv()
+
+		// Assertions
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
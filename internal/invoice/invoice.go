@@ -0,0 +1,50 @@
+// Package invoice renders invoices as PDF documents and UBL 2.1 XML.
+package invoice
+
+import (
+	"math"
+	"sort"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// taxSubtotal is the taxable amount and VAT of the lines sharing a VAT
+// rate, in cents.
+type taxSubtotal struct {
+	rate    float64
+	taxable int64
+	vat     int64
+}
+
+// taxSubtotals groups the invoice lines by VAT rate, highest rate first.
+func taxSubtotals(inv *domain.Invoice) []taxSubtotal {
+	byRate := map[float64]*taxSubtotal{}
+	var subtotals []*taxSubtotal
+	for _, line := range inv.Lines {
+		subtotal, ok := byRate[line.VATRate]
+		if !ok {
+			subtotal = &taxSubtotal{rate: line.VATRate}
+			byRate[line.VATRate] = subtotal
+			subtotals = append(subtotals, subtotal)
+		}
+		subtotal.taxable += domain.Cents(line.Price)
+		subtotal.vat += domain.Cents(line.VAT)
+	}
+	sort.Slice(subtotals, func(i, j int) bool { return subtotals[i].rate > subtotals[j].rate })
+
+	result := make([]taxSubtotal, len(subtotals))
+	for i, subtotal := range subtotals {
+		result[i] = *subtotal
+	}
+	return result
+}
+
+// VATRate returns the VAT rate of a line in percent, rounded to one
+// decimal. Order lines store VAT amounts, not rates, so the rate is derived
+// from them.
+func VATRate(price, vat float64) float64 {
+	if domain.Cents(price) == 0 {
+		return 0
+	}
+	return math.Round(vat/price*1000) / 10
+}
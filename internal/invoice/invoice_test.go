@@ -0,0 +1,134 @@
+package invoice
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+func testInvoice() *domain.Invoice {
+	return &domain.Invoice{
+		Number:     "2024-000042",
+		FiscalYear: 2024,
+		Sequence:   42,
+		OrderID:    7,
+		CustomerID: "cust-42",
+		Currency:   "EUR",
+		Seller:     domain.InvoiceParty{Name: "Shop (Italia) S.r.l.", VATID: "IT12345678901", Address: "Via Roma 1, Milano", Country: "IT"},
+		Lines: []domain.InvoiceLine{
+			{ProductID: 1, Description: "Caffè", Quantity: 2, UnitPrice: 10.0, Price: 20.0, VAT: 2.0, VATRate: 10},
+			{ProductID: 2, Description: "Book", Quantity: 3, UnitPrice: 5.0, Price: 15.0, VAT: 0.6, VATRate: 4},
+			{ProductID: 3, Description: "Mug", Quantity: 1, UnitPrice: 8.0, Price: 8.0, VAT: 0.8, VATRate: 10},
+		},
+		Price:    43.0,
+		VAT:      3.4,
+		Total:    46.4,
+		IssuedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWriteUBL(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteUBL(&buf, testInvoice())
+
+	// Assertions
+	require.NoError(t, err)
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, xml.Header))
+	assert.Contains(t, out, `<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"`)
+	assert.Contains(t, out, `<cbc:UBLVersionID>2.1</cbc:UBLVersionID>`)
+	assert.Contains(t, out, `<cbc:ID>2024-000042</cbc:ID>`)
+	assert.Contains(t, out, `<cbc:IssueDate>2024-05-01</cbc:IssueDate>`)
+	assert.Contains(t, out, `<cbc:CompanyID>IT12345678901</cbc:CompanyID>`)
+	assert.Contains(t, out, `<cbc:TaxAmount currencyID="EUR">3.40</cbc:TaxAmount>`)
+	assert.Contains(t, out, `<cbc:PayableAmount currencyID="EUR">46.40</cbc:PayableAmount>`)
+	assert.Contains(t, out, `<cbc:InvoicedQuantity unitCode="C62">3</cbc:InvoicedQuantity>`)
+
+	// One subtotal per rate, the 10% lines together
+	var doc struct {
+		Subtotals []struct {
+			Taxable string `xml:"TaxableAmount"`
+			Tax     string `xml:"TaxAmount"`
+			Percent string `xml:"TaxCategory>Percent"`
+		} `xml:"TaxTotal>TaxSubtotal"`
+		Lines []struct {
+			ID string `xml:"ID"`
+		} `xml:"InvoiceLine"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.Subtotals, 2)
+	assert.Equal(t, "28.00", doc.Subtotals[0].Taxable)
+	assert.Equal(t, "2.80", doc.Subtotals[0].Tax)
+	assert.Equal(t, "10", doc.Subtotals[0].Percent)
+	assert.Equal(t, "4", doc.Subtotals[1].Percent)
+	assert.Len(t, doc.Lines, 3)
+}
+
+func TestWritePDF(t *testing.T) {
+	t.Run("Single page", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := WritePDF(&buf, testInvoice())
+
+		// Assertions
+		require.NoError(t, err)
+		out := buf.String()
+		assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+		assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+		assert.Contains(t, out, "/Count 1")
+		assert.Contains(t, out, "(Invoice 2024-000042) Tj")
+		assert.Contains(t, out, `(Shop \(Italia\) S.r.l.) Tj`)
+		assert.Contains(t, out, `(Caff\350) Tj`)
+		assert.Contains(t, out, "(46.40) Tj")
+	})
+
+	t.Run("Cross-reference offsets point at the objects", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WritePDF(&buf, testInvoice()))
+		out := buf.String()
+
+		xref := out[strings.Index(out, "xref\n"):]
+		entries := strings.Split(xref, "\n")[3:]
+		for i := 1; i <= 6; i++ {
+			var offset int
+			_, err := fmt.Sscanf(entries[i-1], "%d", &offset)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj", i)), "object %d", i)
+		}
+	})
+
+	t.Run("Long invoices continue on further pages", func(t *testing.T) {
+		inv := testInvoice()
+		for len(inv.Lines) < 100 {
+			inv.Lines = append(inv.Lines, inv.Lines[0])
+		}
+		var buf bytes.Buffer
+
+		err := WritePDF(&buf, inv)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "/Count 3")
+		assert.Contains(t, buf.String(), "(Page 3) Tj")
+	})
+}
+
+func TestEscapePDF(t *testing.T) {
+	assert.Equal(t, `a\(b\)\\c`, escapePDF(`a(b)\c`))
+	assert.Equal(t, `\351 ?`, escapePDF("é €"))
+}
+
+func TestVATRate(t *testing.T) {
+	assert.Equal(t, 10.0, VATRate(20.0, 2.0))
+	assert.Equal(t, 10.1, VATRate(4.25, 0.43))
+	assert.Equal(t, 5.5, VATRate(10.0, 0.55))
+	assert.Equal(t, 0.0, VATRate(0, 0))
+}
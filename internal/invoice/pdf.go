@@ -0,0 +1,251 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// A4 page size and layout in PDF points.
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	margin       = 50.0
+	lineHeight   = 16.0
+	bodySize     = 10.0
+	headingSize  = 18.0
+	linesPerPage = 38
+)
+
+// Columns of the line table: the left edge of the description and the
+// right edges of the numeric columns.
+const (
+	colDescription = margin
+	colQuantity    = 300.0
+	colUnitPrice   = 370.0
+	colPrice       = 440.0
+	colRate        = 490.0
+	colVAT         = pageWidth - margin
+)
+
+// pdfPage accumulates the content stream of a page.
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+// text draws s with its left edge at x.
+func (p *pdfPage) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(size), num(x), num(y), escapePDF(s))
+}
+
+// textRight draws s with its right edge at x.
+func (p *pdfPage) textRight(x, y, size float64, bold bool, s string) {
+	p.text(x-textWidth(s, size), y, size, bold, s)
+}
+
+// rule draws a horizontal line across the page at y.
+func (p *pdfPage) rule(y float64) {
+	fmt.Fprintf(&p.content, "0.5 w %s %s m %s %s l S\n", num(margin), num(y), num(pageWidth-margin), num(y))
+}
+
+// WritePDF writes the invoice as a PDF document using the standard
+// Helvetica fonts, which every PDF reader provides, so nothing needs to be
+// embedded. Long invoices continue on further pages.
+func WritePDF(w io.Writer, inv *domain.Invoice) error {
+	var pages []*pdfPage
+	var page *pdfPage
+	y := 0.0
+
+	newPage := func() {
+		page = &pdfPage{}
+		pages = append(pages, page)
+		y = pageHeight - margin
+
+		page.text(margin, y-headingSize, headingSize, true, "Invoice "+inv.Number)
+		if len(pages) > 1 {
+			page.textRight(pageWidth-margin, y-headingSize, bodySize, false, fmt.Sprintf("Page %d", len(pages)))
+		}
+		y -= headingSize + lineHeight
+	}
+	tableHeader := func() {
+		page.text(colDescription, y, bodySize, true, "Description")
+		page.textRight(colQuantity, y, bodySize, true, "Qty")
+		page.textRight(colUnitPrice, y, bodySize, true, "Unit price")
+		page.textRight(colPrice, y, bodySize, true, "Net")
+		page.textRight(colRate, y, bodySize, true, "VAT %")
+		page.textRight(colVAT, y, bodySize, true, "VAT")
+		page.rule(y - 5)
+		y -= lineHeight + 4
+	}
+
+	newPage()
+
+	// Seller and invoice details
+	seller := []string{inv.Seller.Name, inv.Seller.Address, inv.Seller.Country}
+	if inv.Seller.VATID != "" {
+		seller = append(seller, "VAT ID "+inv.Seller.VATID)
+	}
+	details := []string{
+		"Issue date: " + inv.IssuedAt.Format("2006-01-02"),
+		"Order: " + strconv.FormatInt(inv.OrderID, 10),
+	}
+	if inv.CustomerID != "" {
+		details = append(details, "Customer: "+inv.CustomerID)
+	}
+	top := y
+	for _, s := range seller {
+		if s != "" {
+			page.text(margin, y, bodySize, false, s)
+			y -= lineHeight
+		}
+	}
+	bottom := y
+	y = top
+	for _, s := range details {
+		page.textRight(pageWidth-margin, y, bodySize, false, s)
+		y -= lineHeight
+	}
+	y = min(y, bottom) - lineHeight
+
+	tableHeader()
+	for i, line := range inv.Lines {
+		if i > 0 && i%linesPerPage == 0 {
+			newPage()
+			tableHeader()
+		}
+		page.text(colDescription, y, bodySize, false, truncate(line.Description, colQuantity-colDescription-40, bodySize))
+		page.textRight(colQuantity, y, bodySize, false, strconv.Itoa(line.Quantity))
+		page.textRight(colUnitPrice, y, bodySize, false, formatCents(domain.Cents(line.UnitPrice)))
+		page.textRight(colPrice, y, bodySize, false, formatCents(domain.Cents(line.Price)))
+		page.textRight(colRate, y, bodySize, false, strconv.FormatFloat(line.VATRate, 'f', -1, 64))
+		page.textRight(colVAT, y, bodySize, false, formatCents(domain.Cents(line.VAT)))
+		y -= lineHeight
+	}
+
+	// Totals, on a new page if they do not fit
+	subtotals := taxSubtotals(inv)
+	if y-float64(len(subtotals)+4)*lineHeight < margin {
+		newPage()
+	}
+	page.rule(y + lineHeight - 5)
+	total := func(label, value string, bold bool) {
+		page.textRight(colRate, y, bodySize, bold, label)
+		page.textRight(colVAT, y, bodySize, bold, value)
+		y -= lineHeight
+	}
+	total("Net total", formatCents(domain.Cents(inv.Price)), false)
+	for _, subtotal := range subtotals {
+		total(fmt.Sprintf("VAT %s%% on %s", strconv.FormatFloat(subtotal.rate, 'f', -1, 64), formatCents(subtotal.taxable)), formatCents(subtotal.vat), false)
+	}
+	total("Total "+inv.Currency, formatCents(domain.Cents(inv.Price)+domain.Cents(inv.VAT)), true)
+
+	return writePDFDocument(w, pages)
+}
+
+// writePDFDocument writes the pages as a PDF 1.4 file: the catalog, the
+// page tree, the two fonts, then a page object and content stream per page,
+// followed by the cross-reference table.
+func writePDFDocument(w io.Writer, pages []*pdfPage) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 to 4; pages start at object 5, two objects each
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(pageWidth), num(pageHeight), 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// escapePDF encodes s as the content of a PDF string in WinAnsiEncoding.
+// Characters outside Latin-1 are replaced with a question mark.
+func escapePDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff:
+			b.WriteByte('?')
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "\\%03o", r)
+		}
+	}
+	return b.String()
+}
+
+// helveticaWidths are the widths of the Helvetica glyphs that differ from
+// the average width, in thousandths of the font size. Numbers are set in
+// Helvetica, whose digits all have the same width, so amounts align.
+var helveticaWidths = map[rune]float64{
+	' ': 278, '.': 278, ',': 278, ':': 278, '-': 333, '%': 889, '(': 333, ')': 333,
+	'i': 222, 'l': 222, 'j': 222, 't': 278, 'f': 278, 'r': 333, 'I': 278,
+	'm': 833, 'w': 722, 'M': 833, 'W': 944,
+}
+
+// textWidth estimates the width of s in points. It is exact for amounts
+// and close enough elsewhere to right-align labels.
+func textWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		if w, ok := helveticaWidths[r]; ok {
+			width += w
+		} else {
+			width += 556
+		}
+	}
+	return width * size / 1000
+}
+
+// truncate shortens s with an ellipsis to fit in width points.
+func truncate(s string, width, size float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// num formats a coordinate without trailing zeros.
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
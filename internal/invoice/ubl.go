@@ -0,0 +1,235 @@
+package invoice
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// UBL 2.1 namespaces and the identifiers of the invoice profile.
+const (
+	ublInvoiceNS   = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	ublAggregateNS = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	ublBasicNS     = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+
+	ublCustomizationID   = "urn:cen.eu:en16931:2017"
+	ublCommercialInvoice = "380"
+	// ublUnitCode is the UN/ECE rec 20 code for "one", i.e. pieces
+	ublUnitCode = "C62"
+)
+
+type ublInvoice struct {
+	XMLName              xml.Name         `xml:"Invoice"`
+	Xmlns                string           `xml:"xmlns,attr"`
+	XmlnsCac             string           `xml:"xmlns:cac,attr"`
+	XmlnsCbc             string           `xml:"xmlns:cbc,attr"`
+	UBLVersionID         string           `xml:"cbc:UBLVersionID"`
+	CustomizationID      string           `xml:"cbc:CustomizationID"`
+	ID                   string           `xml:"cbc:ID"`
+	IssueDate            string           `xml:"cbc:IssueDate"`
+	InvoiceTypeCode      string           `xml:"cbc:InvoiceTypeCode"`
+	DocumentCurrencyCode string           `xml:"cbc:DocumentCurrencyCode"`
+	OrderReference       ublReference     `xml:"cac:OrderReference"`
+	Supplier             ublSupplier      `xml:"cac:AccountingSupplierParty"`
+	Customer             ublCustomer      `xml:"cac:AccountingCustomerParty"`
+	TaxTotal             ublTaxTotal      `xml:"cac:TaxTotal"`
+	MonetaryTotal        ublMonetaryTotal `xml:"cac:LegalMonetaryTotal"`
+	Lines                []ublLine        `xml:"cac:InvoiceLine"`
+}
+
+type ublReference struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type ublAmount struct {
+	Currency string `xml:"currencyID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublSupplier struct {
+	Party ublParty `xml:"cac:Party"`
+}
+
+type ublCustomer struct {
+	Party ublParty `xml:"cac:Party"`
+}
+
+type ublParty struct {
+	Identification *ublReference   `xml:"cac:PartyIdentification,omitempty"`
+	Address        *ublAddress     `xml:"cac:PostalAddress,omitempty"`
+	TaxScheme      *ublPartyTax    `xml:"cac:PartyTaxScheme,omitempty"`
+	LegalEntity    *ublLegalEntity `xml:"cac:PartyLegalEntity,omitempty"`
+}
+
+type ublAddress struct {
+	StreetName string      `xml:"cbc:StreetName,omitempty"`
+	Country    *ublCountry `xml:"cac:Country,omitempty"`
+}
+
+type ublCountry struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode"`
+}
+
+type ublPartyTax struct {
+	CompanyID string       `xml:"cbc:CompanyID"`
+	TaxScheme ublReference `xml:"cac:TaxScheme"`
+}
+
+type ublLegalEntity struct {
+	RegistrationName string `xml:"cbc:RegistrationName"`
+}
+
+type ublTaxTotal struct {
+	TaxAmount ublAmount        `xml:"cbc:TaxAmount"`
+	Subtotals []ublTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type ublTaxSubtotal struct {
+	TaxableAmount ublAmount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     ublAmount      `xml:"cbc:TaxAmount"`
+	TaxCategory   ublTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type ublTaxCategory struct {
+	ID        string       `xml:"cbc:ID"`
+	Percent   string       `xml:"cbc:Percent"`
+	TaxScheme ublReference `xml:"cac:TaxScheme"`
+}
+
+type ublMonetaryTotal struct {
+	LineExtensionAmount ublAmount `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount  ublAmount `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount  ublAmount `xml:"cbc:TaxInclusiveAmount"`
+	PayableAmount       ublAmount `xml:"cbc:PayableAmount"`
+}
+
+type ublLine struct {
+	ID                  string      `xml:"cbc:ID"`
+	InvoicedQuantity    ublQuantity `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount ublAmount   `xml:"cbc:LineExtensionAmount"`
+	Item                ublItem     `xml:"cac:Item"`
+	Price               ublPrice    `xml:"cac:Price"`
+}
+
+type ublQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    int    `xml:",chardata"`
+}
+
+type ublItem struct {
+	Name                  string         `xml:"cbc:Name"`
+	SellersItemID         ublReference   `xml:"cac:SellersItemIdentification"`
+	ClassifiedTaxCategory ublTaxCategory `xml:"cac:ClassifiedTaxCategory"`
+}
+
+type ublPrice struct {
+	PriceAmount ublAmount `xml:"cbc:PriceAmount"`
+}
+
+// WriteUBL writes the invoice as a UBL 2.1 Invoice document following the
+// EN 16931 data model. VAT is reported per rate; lines with VAT use the
+// standard rate category S and lines without it the zero rate category Z.
+func WriteUBL(w io.Writer, inv *domain.Invoice) error {
+	amount := func(cents int64) ublAmount {
+		return ublAmount{Currency: inv.Currency, Value: formatCents(cents)}
+	}
+	vat := ublReference{ID: "VAT"}
+
+	doc := ublInvoice{
+		Xmlns:                ublInvoiceNS,
+		XmlnsCac:             ublAggregateNS,
+		XmlnsCbc:             ublBasicNS,
+		UBLVersionID:         "2.1",
+		CustomizationID:      ublCustomizationID,
+		ID:                   inv.Number,
+		IssueDate:            inv.IssuedAt.Format("2006-01-02"),
+		InvoiceTypeCode:      ublCommercialInvoice,
+		DocumentCurrencyCode: inv.Currency,
+		OrderReference:       ublReference{ID: strconv.FormatInt(inv.OrderID, 10)},
+		Supplier:             ublSupplier{Party: sellerParty(inv.Seller)},
+		Customer:             ublCustomer{Party: customerParty(inv.CustomerID)},
+	}
+
+	price, tax := domain.Cents(inv.Price), domain.Cents(inv.VAT)
+	doc.TaxTotal.TaxAmount = amount(tax)
+	for _, subtotal := range taxSubtotals(inv) {
+		doc.TaxTotal.Subtotals = append(doc.TaxTotal.Subtotals, ublTaxSubtotal{
+			TaxableAmount: amount(subtotal.taxable),
+			TaxAmount:     amount(subtotal.vat),
+			TaxCategory:   taxCategory(subtotal.rate, vat),
+		})
+	}
+	doc.MonetaryTotal = ublMonetaryTotal{
+		LineExtensionAmount: amount(price),
+		TaxExclusiveAmount:  amount(price),
+		TaxInclusiveAmount:  amount(price + tax),
+		PayableAmount:       amount(price + tax),
+	}
+
+	for i, line := range inv.Lines {
+		doc.Lines = append(doc.Lines, ublLine{
+			ID:                  strconv.Itoa(i + 1),
+			InvoicedQuantity:    ublQuantity{UnitCode: ublUnitCode, Value: line.Quantity},
+			LineExtensionAmount: amount(domain.Cents(line.Price)),
+			Item: ublItem{
+				Name:                  line.Description,
+				SellersItemID:         ublReference{ID: strconv.FormatInt(line.ProductID, 10)},
+				ClassifiedTaxCategory: taxCategory(line.VATRate, vat),
+			},
+			Price: ublPrice{PriceAmount: amount(domain.Cents(line.UnitPrice))},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode UBL invoice: %w", err)
+	}
+	return enc.Close()
+}
+
+func sellerParty(seller domain.InvoiceParty) ublParty {
+	party := ublParty{LegalEntity: &ublLegalEntity{RegistrationName: seller.Name}}
+	if seller.Address != "" || seller.Country != "" {
+		party.Address = &ublAddress{StreetName: seller.Address}
+		if seller.Country != "" {
+			party.Address.Country = &ublCountry{IdentificationCode: seller.Country}
+		}
+	}
+	if seller.VATID != "" {
+		party.TaxScheme = &ublPartyTax{CompanyID: seller.VATID, TaxScheme: ublReference{ID: "VAT"}}
+	}
+	return party
+}
+
+// customerParty identifies the buyer by the order's customer ID, the only
+// buyer detail the service keeps.
+func customerParty(customerID string) ublParty {
+	if customerID == "" {
+		return ublParty{}
+	}
+	return ublParty{Identification: &ublReference{ID: customerID}}
+}
+
+func taxCategory(rate float64, scheme ublReference) ublTaxCategory {
+	category := "S"
+	if rate == 0 {
+		category = "Z"
+	}
+	return ublTaxCategory{ID: category, Percent: strconv.FormatFloat(rate, 'f', -1, 64), TaxScheme: scheme}
+}
+
+// formatCents formats an amount in cents with two decimals.
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "order_service"
+
+// Metrics holds the Prometheus collectors exposed by the service.
+// Each instance owns its own registry so tests can create isolated instances.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	HTTPRequests        *prometheus.CounterVec
+	HTTPRequestDuration *prometheus.HistogramVec
+	OrdersCreated       prometheus.Counter
+	OrdersValue         prometheus.Counter
+	ProductsNotFound    prometheus.Counter
+	QueryDuration       *prometheus.HistogramVec
+}
+
+// New creates and registers all service collectors together with the
+// standard Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		OrdersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_created_total",
+			Help:      "Total number of orders created.",
+		}),
+		OrdersValue: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_value_total",
+			Help:      "Total value of created orders, VAT included.",
+		}),
+		ProductsNotFound: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "products_not_found_total",
+			Help:      "Total number of product lookups that found no product.",
+		}),
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Repository query latency by repository, method and outcome.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"repository", "method", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequests,
+		m.HTTPRequestDuration,
+		m.OrdersCreated,
+		m.OrdersValue,
+		m.ProductsNotFound,
+		m.QueryDuration,
+	)
+
+	return m
+}
+
+// RegisterDBStats exposes the connection pool statistics reported by db.Stats().
+func (m *Metrics) RegisterDBStats(db *sql.DB, dbName string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler returns the HTTP handler serving the metrics in Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
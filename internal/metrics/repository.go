@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// orderRepository decorates a repository.OrderRepository with query latency
+// and order creation metrics.
+type orderRepository struct {
+	next    repository.OrderRepository
+	metrics *Metrics
+}
+
+// NewOrderRepository wraps next so every call is measured.
+func NewOrderRepository(next repository.OrderRepository, m *Metrics) repository.OrderRepository {
+	return &orderRepository{next: next, metrics: m}
+}
+
+func (r *orderRepository) Create(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	start := time.Now()
+	created, err := r.next.Create(ctx, order)
+	r.metrics.observeQuery("order", "Create", start, err)
+
+	if err == nil {
+		r.metrics.OrdersCreated.Inc()
+		r.metrics.OrdersValue.Add(created.Price + created.VAT)
+	}
+
+	return created, err
+}
+
+func (r *orderRepository) CreateBatch(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error) {
+	start := time.Now()
+	created, err := r.next.CreateBatch(ctx, orders)
+	r.metrics.observeQuery("order", "CreateBatch", start, err)
+
+	if err == nil {
+		for _, order := range created {
+			r.metrics.OrdersCreated.Inc()
+			r.metrics.OrdersValue.Add(order.Price + order.VAT)
+		}
+	}
+
+	return created, err
+}
+
+func (r *orderRepository) GetByID(ctx context.Context, id int64) (*domain.Order, error) {
+	start := time.Now()
+	order, err := r.next.GetByID(ctx, id)
+	r.metrics.observeQuery("order", "GetByID", start, err)
+	return order, err
+}
+
+func (r *orderRepository) List(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, error) {
+	start := time.Now()
+	orders, err := r.next.List(ctx, filter)
+	r.metrics.observeQuery("order", "List", start, err)
+	return orders, err
+}
+
+func (r *orderRepository) Export(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.Order) error) error {
+	start := time.Now()
+	err := r.next.Export(ctx, filter, fn)
+	r.metrics.observeQuery("order", "Export", start, err)
+	return err
+}
+
+func (r *orderRepository) Update(ctx context.Context, id int64, version int, update func(order *domain.Order) (*domain.OrderRevision, error)) (*domain.Order, error) {
+	start := time.Now()
+	order, err := r.next.Update(ctx, id, version, update)
+	r.metrics.observeQuery("order", "Update", start, err)
+	return order, err
+}
+
+func (r *orderRepository) ListRevisions(ctx context.Context, orderID int64) ([]domain.OrderRevision, error) {
+	start := time.Now()
+	revisions, err := r.next.ListRevisions(ctx, orderID)
+	r.metrics.observeQuery("order", "ListRevisions", start, err)
+	return revisions, err
+}
+
+// productRepository decorates a repository.ProductRepository with query
+// latency and product-not-found metrics.
+type productRepository struct {
+	next    repository.ProductRepository
+	metrics *Metrics
+}
+
+// NewProductRepository wraps next so every call is measured.
+func NewProductRepository(next repository.ProductRepository, m *Metrics) repository.ProductRepository {
+	return &productRepository{next: next, metrics: m}
+}
+
+func (r *productRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
+	start := time.Now()
+	product, err := r.next.GetByID(ctx, id)
+	r.metrics.observeQuery("product", "GetByID", start, err)
+
+	if errors.Is(err, domain.ErrProductNotFound) {
+		r.metrics.ProductsNotFound.Inc()
+	}
+
+	return product, err
+}
+
+func (r *productRepository) GetByIDs(ctx context.Context, ids []int64) ([]domain.Product, error) {
+	start := time.Now()
+	products, err := r.next.GetByIDs(ctx, ids)
+	r.metrics.observeQuery("product", "GetByIDs", start, err)
+	return products, err
+}
+
+// observeQuery records the latency of a repository call. Not-found results
+// are reported separately from real failures.
+func (m *Metrics) observeQuery(repo, method string, start time.Time, err error) {
+	outcome := "success"
+	switch {
+	case errors.Is(err, domain.ErrOrderNotFound), errors.Is(err, domain.ErrProductNotFound):
+		outcome = "not_found"
+	case err != nil:
+		outcome = "error"
+	}
+	m.QueryDuration.WithLabelValues(repo, method, outcome).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type MockOrderRepository struct {
+	mock.Mock
+}
+
+func (m *MockOrderRepository) Create(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	args := m.Called(ctx, order)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) CreateBatch(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error) {
+	args := m.Called(ctx, orders)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) Export(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.Order) error) error {
+	args := m.Called(ctx, filter, fn)
+	if orders, ok := args.Get(0).([]*domain.Order); ok {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockOrderRepository) GetByID(ctx context.Context, id int64) (*domain.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) List(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) Update(ctx context.Context, id int64, version int, update func(order *domain.Order) (*domain.OrderRevision, error)) (*domain.Order, error) {
+	args := m.Called(ctx, id, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) ListRevisions(ctx context.Context, orderID int64) ([]domain.OrderRevision, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.OrderRevision), args.Error(1)
+}
+
+type MockProductRepository struct {
+	mock.Mock
+}
+
+func (m *MockProductRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetByIDs(ctx context.Context, ids []int64) ([]domain.Product, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func TestOrderRepositoryMetrics(t *testing.T) {
+	m := New()
+	mockRepo := new(MockOrderRepository)
+	repo := NewOrderRepository(mockRepo, m)
+	ctx := context.Background()
+
+	order := &domain.Order{Price: 35.0, VAT: 3.5}
+	mockRepo.On("Create", ctx, order).Return(&domain.Order{ID: 1, Price: 35.0, VAT: 3.5}, nil).Once()
+	mockRepo.On("Create", ctx, order).Return(nil, errors.New("db down")).Once()
+	mockRepo.On("GetByID", ctx, int64(999)).Return(nil, domain.ErrOrderNotFound)
+
+	_, err := repo.Create(ctx, order)
+	assert.NoError(t, err)
+	_, err = repo.Create(ctx, order)
+	assert.Error(t, err)
+	_, err = repo.GetByID(ctx, 999)
+	assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+
+	// Only successful inserts are counted
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.OrdersCreated))
+	assert.Equal(t, 38.5, testutil.ToFloat64(m.OrdersValue))
+
+	// Each call is observed under its own outcome
+	assert.Equal(t, 3, testutil.CollectAndCount(m.QueryDuration))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductRepositoryMetrics(t *testing.T) {
+	m := New()
+	mockRepo := new(MockProductRepository)
+	repo := NewProductRepository(mockRepo, m)
+	ctx := context.Background()
+
+	mockRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1}, nil)
+	mockRepo.On("GetByID", ctx, int64(999)).Return(nil, domain.ErrProductNotFound)
+
+	_, err := repo.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	_, err = repo.GetByID(ctx, 999)
+	assert.ErrorIs(t, err, domain.ErrProductNotFound)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.ProductsNotFound))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.QueryDuration))
+	mockRepo.AssertExpectations(t)
+}
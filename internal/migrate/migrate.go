@@ -0,0 +1,307 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockKey identifies the Postgres advisory lock held while migrating, so that
+// replicas starting at the same time apply migrations one after the other.
+const lockKey int64 = 0x6f72646572 // "order"
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Load reads all NNN_name.up.sql / NNN_name.down.sql pairs from fsys and
+// returns them ordered by version. Every migration must have both files.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %03d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrator applies embedded migrations and records them in schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+func New(db *sql.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// Latest returns the highest known migration version, or 0 if there are none.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return m.revert(ctx, conn, m.migrations[i])
+			}
+		}
+
+		m.logger.Info("no migrations to revert")
+		return nil
+	})
+}
+
+// To migrates the schema up or down until exactly the migrations with a
+// version lower than or equal to target are applied.
+func (m *Migrator) To(ctx context.Context, target int64) error {
+	if target != 0 && !m.known(target) {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		up, down := plan(m.migrations, applied, target)
+		for _, migration := range down {
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+		}
+		for _, migration := range up {
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+		}
+
+		if len(up) == 0 && len(down) == 0 {
+			m.logger.Info("schema is up to date", "version", target)
+		}
+		return nil
+	})
+}
+
+// Status reports every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+// plan returns the migrations to apply, in ascending order, and the
+// migrations to revert, in descending order, to reach the target version.
+func plan(migrations []Migration, applied map[int64]time.Time, target int64) (up, down []Migration) {
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[migrations[i].Version]; ok && migrations[i].Version > target {
+			down = append(down, migrations[i])
+		}
+	}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= target {
+			up = append(up, migration)
+		}
+	}
+	return up, down
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock.
+// Session-level advisory locks belong to a connection, so all work must use conn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	query := `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version BIGINT PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+        )
+    `
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// apply runs the up script of a migration and records it in the same transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	err := inTx(ctx, conn, migration.Up,
+		`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+		migration.Version, migration.Name)
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %03d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	m.logger.Info("applied migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+// revert runs the down script of a migration and removes its record in the same transaction.
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	err := inTx(ctx, conn, migration.Down,
+		`DELETE FROM schema_migrations WHERE version = $1`,
+		migration.Version)
+	if err != nil {
+		return fmt.Errorf("failed to revert migration %03d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	m.logger.Info("reverted migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
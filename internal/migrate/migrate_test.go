@@ -0,0 +1,113 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valeriouberti/order-service-test/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Embedded migrations are paired and ordered", func(t *testing.T) {
+		loaded, err := Load(migrations.FS)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, loaded)
+		for i, migration := range loaded {
+			assert.Equal(t, int64(i+1), migration.Version)
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+		}
+	})
+
+	t.Run("Missing down file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"001_init.up.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+		}
+
+		_, err := Load(fsys)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "must have both up and down files")
+	})
+
+	t.Run("Duplicate version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"001_a.down.sql": {Data: []byte("SELECT 1;")},
+			"001_b.up.sql":   {Data: []byte("SELECT 1;")},
+			"001_b.down.sql": {Data: []byte("SELECT 1;")},
+		}
+
+		_, err := Load(fsys)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is used by both")
+	})
+
+	t.Run("Other files are ignored", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"002_b.up.sql":   {Data: []byte("SELECT 2;")},
+			"002_b.down.sql": {Data: []byte("SELECT 2;")},
+			"001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"001_a.down.sql": {Data: []byte("SELECT 1;")},
+			"migrations.go":  {Data: []byte("package migrations")},
+		}
+
+		loaded, err := Load(fsys)
+
+		assert.NoError(t, err)
+		assert.Len(t, loaded, 2)
+		assert.Equal(t, "a", loaded[0].Name)
+		assert.Equal(t, "b", loaded[1].Name)
+	})
+}
+
+func TestPlan(t *testing.T) {
+	all := []Migration{{Version: 1}, {Version: 2}, {Version: 3}, {Version: 4}}
+	now := time.Now()
+
+	versions := func(migrations []Migration) []int64 {
+		var v []int64
+		for _, m := range migrations {
+			v = append(v, m.Version)
+		}
+		return v
+	}
+
+	t.Run("Up from empty", func(t *testing.T) {
+		up, down := plan(all, map[int64]time.Time{}, 4)
+
+		assert.Equal(t, []int64{1, 2, 3, 4}, versions(up))
+		assert.Empty(t, down)
+	})
+
+	t.Run("Down to an older version", func(t *testing.T) {
+		applied := map[int64]time.Time{1: now, 2: now, 3: now, 4: now}
+
+		up, down := plan(all, applied, 2)
+
+		assert.Empty(t, up)
+		assert.Equal(t, []int64{4, 3}, versions(down))
+	})
+
+	t.Run("Fills gaps up to the target", func(t *testing.T) {
+		applied := map[int64]time.Time{1: now, 3: now}
+
+		up, down := plan(all, applied, 3)
+
+		assert.Equal(t, []int64{2}, versions(up))
+		assert.Empty(t, down)
+	})
+
+	t.Run("Down to zero", func(t *testing.T) {
+		applied := map[int64]time.Time{1: now, 2: now}
+
+		up, down := plan(all, applied, 0)
+
+		assert.Empty(t, up)
+		assert.Equal(t, []int64{2, 1}, versions(down))
+	})
+}
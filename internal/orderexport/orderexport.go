@@ -0,0 +1,239 @@
+// Package orderexport writes orders as CSV, NDJSON or Parquet, with one row
+// per order or one row per order item.
+//
+// Writers stream: CSV and NDJSON rows are written as they come and Parquet
+// rows are buffered one row group at a time, so exports of any size use a
+// bounded amount of memory.
+package orderexport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Row granularities: one row per order, or one per order item.
+const (
+	RowsOrder = "order"
+	RowsItem  = "item"
+)
+
+// ErrUnsupported is returned for unknown formats and row granularities.
+var ErrUnsupported = errors.New("unsupported export")
+
+// ContentTypes maps the export formats to their media types.
+var ContentTypes = map[string]string{
+	FormatCSV:     "text/csv; charset=utf-8",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+// kind is the type of the values of a column.
+type kind int
+
+const (
+	kindInt kind = iota
+	kindFloat
+	kindString
+	kindTime
+)
+
+type column struct {
+	name string
+	kind kind
+}
+
+// orderColumns are the columns of rows per order; the totals are those of
+// the order API.
+var orderColumns = []column{
+	{"order_id", kindInt},
+	{"customer_id", kindString},
+	{"status", kindString},
+	{"created_at", kindTime},
+	{"items", kindInt},
+	{"order_price", kindFloat},
+	{"order_vat", kindFloat},
+	{"refunded_price", kindFloat},
+	{"refunded_vat", kindFloat},
+	{"net_price", kindFloat},
+	{"net_vat", kindFloat},
+	{"version", kindInt},
+	{"request_id", kindString},
+}
+
+// itemColumns are the columns of rows per item.
+var itemColumns = []column{
+	{"order_id", kindInt},
+	{"customer_id", kindString},
+	{"status", kindString},
+	{"created_at", kindTime},
+	{"product_id", kindInt},
+	{"quantity", kindInt},
+	{"price", kindFloat},
+	{"vat", kindFloat},
+}
+
+// Writer writes orders as rows of an export.
+type Writer interface {
+	// Write adds the rows of an order.
+	Write(order *domain.OrderResponse) error
+	// Flush writes the buffered rows out, except for Parquet rows, which are
+	// written a row group at a time.
+	Flush() error
+	// Close writes the buffered rows and the end of the file. It does not
+	// close the underlying writer.
+	Close() error
+}
+
+// NewWriter returns a Writer of the given format and row granularity.
+func NewWriter(w io.Writer, format, rows string) (Writer, error) {
+	var columns []column
+	var rowsOf func(order *domain.OrderResponse) [][]any
+	switch rows {
+	case RowsOrder:
+		columns, rowsOf = orderColumns, orderRows
+	case RowsItem:
+		columns, rowsOf = itemColumns, itemRows
+	default:
+		return nil, fmt.Errorf("%w: rows must be %s or %s", ErrUnsupported, RowsOrder, RowsItem)
+	}
+
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns, rowsOf)
+	case FormatNDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(w), columns: columns, rowsOf: rowsOf}, nil
+	case FormatParquet:
+		return newParquetWriter(w, columns, rowsOf), nil
+	default:
+		return nil, fmt.Errorf("%w: format must be %s, %s or %s", ErrUnsupported, FormatCSV, FormatNDJSON, FormatParquet)
+	}
+}
+
+// orderRows returns the row of an order, in orderColumns order.
+func orderRows(order *domain.OrderResponse) [][]any {
+	return [][]any{{
+		order.OrderID, order.CustomerID, order.Status, order.CreatedAt, int64(len(order.Items)),
+		order.OrderPrice, order.OrderVAT, order.RefundedPrice, order.RefundedVAT, order.NetPrice, order.NetVAT,
+		int64(order.Version), order.RequestID,
+	}}
+}
+
+// itemRows returns a row per item of an order, in itemColumns order.
+func itemRows(order *domain.OrderResponse) [][]any {
+	rows := make([][]any, len(order.Items))
+	for i, item := range order.Items {
+		rows[i] = []any{
+			order.OrderID, order.CustomerID, order.Status, order.CreatedAt,
+			item.ProductID, int64(item.Quantity), item.Price, item.VAT,
+		}
+	}
+	return rows
+}
+
+// formatValue formats a value as CSV text.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return v.(string)
+	}
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	rowsOf func(order *domain.OrderResponse) [][]any
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []column, rowsOf func(order *domain.OrderResponse) [][]any) (*csvWriter, error) {
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+
+	cw := &csvWriter{w: csv.NewWriter(w), rowsOf: rowsOf, record: make([]string, len(columns))}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(order *domain.OrderResponse) error {
+	for _, row := range cw.rowsOf(order) {
+		for i, v := range row {
+			cw.record[i] = formatValue(v)
+		}
+		if err := cw.w.Write(cw.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	return cw.Flush()
+}
+
+// ndjsonWriter writes each row as a JSON object keyed by column name, with
+// the keys in column order.
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns []column
+	rowsOf  func(order *domain.OrderResponse) [][]any
+}
+
+func (nw *ndjsonWriter) Write(order *domain.OrderResponse) error {
+	for _, row := range nw.rowsOf(order) {
+		nw.w.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				nw.w.WriteByte(',')
+			}
+			if t, ok := v.(time.Time); ok {
+				v = t.UTC()
+			}
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(nw.w, "%q:%s", nw.columns[i].name, value)
+		}
+		if _, err := nw.w.WriteString("}\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (nw *ndjsonWriter) Flush() error {
+	return nw.w.Flush()
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.w.Flush()
+}
@@ -0,0 +1,238 @@
+package orderexport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+var createdAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func testOrders() []*domain.OrderResponse {
+	return []*domain.OrderResponse{
+		{
+			OrderID: 1, CustomerID: "c1", Status: domain.OrderStatusPaid, CreatedAt: createdAt,
+			OrderPrice: 35.0, OrderVAT: 3.5, NetPrice: 35.0, NetVAT: 3.5, Version: 2,
+			Items: []domain.OrderItem{
+				{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0},
+				{ProductID: 2, Quantity: 3, Price: 15.0, VAT: 1.5},
+			},
+		},
+		{
+			OrderID: 2, Status: domain.OrderStatusPending, CreatedAt: createdAt.Add(time.Minute),
+			OrderPrice: 10.0, OrderVAT: 1.0, NetPrice: 10.0, NetVAT: 1.0, Version: 1, RequestID: "req, \"1\"",
+			Items: []domain.OrderItem{{ProductID: 1, Quantity: 1, Price: 10.0, VAT: 1.0}},
+		},
+	}
+}
+
+func export(t *testing.T, format, rows string) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, rows)
+	require.NoError(t, err)
+	for _, order := range testOrders() {
+		require.NoError(t, w.Write(order))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	t.Run("One row per order", func(t *testing.T) {
+		out := export(t, FormatCSV, RowsOrder)
+
+		// Assertions
+		assert.Equal(t, "order_id,customer_id,status,created_at,items,order_price,order_vat,refunded_price,refunded_vat,net_price,net_vat,version,request_id\n"+
+			"1,c1,paid,2024-05-01T12:00:00Z,2,35,3.5,0,0,35,3.5,2,\n"+
+			"2,,pending,2024-05-01T12:01:00Z,1,10,1,0,0,10,1,1,\"req, \"\"1\"\"\"\n", string(out))
+	})
+
+	t.Run("One row per item", func(t *testing.T) {
+		out := export(t, FormatCSV, RowsItem)
+
+		// Assertions
+		assert.Equal(t, "order_id,customer_id,status,created_at,product_id,quantity,price,vat\n"+
+			"1,c1,paid,2024-05-01T12:00:00Z,1,2,20,2\n"+
+			"1,c1,paid,2024-05-01T12:00:00Z,2,3,15,1.5\n"+
+			"2,,pending,2024-05-01T12:01:00Z,1,1,10,1\n", string(out))
+	})
+}
+
+func TestNDJSON(t *testing.T) {
+	out := export(t, FormatNDJSON, RowsItem)
+
+	// Assertions
+	lines := bytes.Split(bytes.TrimSuffix(out, []byte("\n")), []byte("\n"))
+	require.Len(t, lines, 3)
+	assert.Equal(t, `{"order_id":1,"customer_id":"c1","status":"paid","created_at":"2024-05-01T12:00:00Z","product_id":2,"quantity":3,"price":15,"vat":1.5}`, string(lines[1]))
+}
+
+func TestUnsupported(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xlsx", RowsOrder)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = NewWriter(&bytes.Buffer{}, FormatCSV, "product")
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestParquet(t *testing.T) {
+	out := export(t, FormatParquet, RowsItem)
+
+	// The file starts and ends with the magic, preceded by the footer length
+	require.True(t, bytes.HasPrefix(out, []byte("PAR1")))
+	require.True(t, bytes.HasSuffix(out, []byte("PAR1")))
+	footerLength := int(binary.LittleEndian.Uint32(out[len(out)-8:]))
+	footer := out[len(out)-8-footerLength : len(out)-8]
+
+	r := &thriftReader{buf: footer}
+	metadata := r.readStruct()
+	require.Empty(t, r.buf, "trailing footer bytes")
+
+	// Assertions
+	assert.Equal(t, int64(3), metadata[3])
+	schema := metadata[2].([]any)
+	require.Len(t, schema, len(itemColumns)+1)
+	assert.Equal(t, int64(len(itemColumns)), schema[0].(map[int16]any)[5])
+	for i, c := range itemColumns {
+		assert.Equal(t, []byte(c.name), schema[i+1].(map[int16]any)[4])
+	}
+	assert.Equal(t, int64(parquetTimestampMillis), schema[4].(map[int16]any)[6])
+
+	groups := metadata[4].([]any)
+	require.Len(t, groups, 1)
+	chunks := groups[0].(map[int16]any)[1].([]any)
+	require.Len(t, chunks, len(itemColumns))
+
+	// Read the values of the order_id, created_at, product_id and price columns back
+	column := func(i int) (map[int16]any, []byte) {
+		meta := chunks[i].(map[int16]any)[3].(map[int16]any)
+		offset := meta[9].(int64)
+		page := &thriftReader{buf: out[offset:]}
+		header := page.readStruct()
+		assert.Equal(t, int64(3), header[5].(map[int16]any)[1])
+		return meta, page.buf[:header[3].(int64)]
+	}
+	int64s := func(data []byte) []int64 {
+		values := make([]int64, len(data)/8)
+		for i := range values {
+			values[i] = int64(binary.LittleEndian.Uint64(data[i*8:]))
+		}
+		return values
+	}
+
+	meta, data := column(0)
+	assert.Equal(t, int64(parquetInt64), meta[1])
+	assert.Equal(t, [][]byte{[]byte("order_id")}, toBytes(meta[3]))
+	assert.Equal(t, []int64{1, 1, 2}, int64s(data))
+
+	_, data = column(3)
+	assert.Equal(t, createdAt.UnixMilli(), int64s(data)[0])
+
+	_, data = column(4)
+	assert.Equal(t, []int64{1, 2, 1}, int64s(data))
+
+	_, data = column(6)
+	assert.Equal(t, 15.0, math.Float64frombits(binary.LittleEndian.Uint64(data[8:])))
+
+	_, data = column(2)
+	assert.Equal(t, []byte("\x04\x00\x00\x00paid"), data[:8])
+}
+
+func TestParquetRowGroups(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatParquet, RowsOrder)
+	require.NoError(t, err)
+	order := testOrders()[0]
+	for i := 0; i < parquetRowGroup+1; i++ {
+		require.NoError(t, w.Write(order))
+	}
+	require.NoError(t, w.Close())
+
+	out := buf.Bytes()
+	footerLength := int(binary.LittleEndian.Uint32(out[len(out)-8:]))
+	metadata := (&thriftReader{buf: out[len(out)-8-footerLength : len(out)-8]}).readStruct()
+
+	// Assertions
+	assert.Equal(t, int64(parquetRowGroup+1), metadata[3])
+	groups := metadata[4].([]any)
+	require.Len(t, groups, 2)
+	assert.Equal(t, int64(1), groups[1].(map[int16]any)[3])
+}
+
+func toBytes(v any) [][]byte {
+	var out [][]byte
+	for _, e := range v.([]any) {
+		out = append(out, e.([]byte))
+	}
+	return out
+}
+
+// thriftReader decodes the Thrift compact protocol types written by
+// thriftWriter: structs become maps by field ID, lists slices, integers
+// int64 and binaries byte slices.
+type thriftReader struct {
+	buf []byte
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := map[int16]any{}
+	var last int16
+	for {
+		header := r.buf[0]
+		r.buf = r.buf[1:]
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.read(header & 0x0f)
+		last = id
+	}
+}
+
+func (r *thriftReader) read(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := r.uvarint()
+		v := r.buf[:n]
+		r.buf = r.buf[n:]
+		return v
+	case thriftList:
+		header := r.buf[0]
+		r.buf = r.buf[1:]
+		n := uint64(header >> 4)
+		if n == 15 {
+			n = r.uvarint()
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.read(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	default:
+		panic("unexpected thrift type")
+	}
+}
@@ -0,0 +1,320 @@
+package orderexport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// The Parquet files are written with the subset of the format every reader
+// supports: required columns, one uncompressed PLAIN data page per column
+// chunk and the file metadata encoded with the Thrift compact protocol.
+// Integers are INT64, amounts DOUBLE, strings UTF8 byte arrays and times
+// TIMESTAMP_MILLIS.
+
+// parquetRowGroup is the number of rows buffered before a row group is written.
+const parquetRowGroup = 10000
+
+const parquetMagic = "PAR1"
+
+// Parquet physical types, converted types and enums
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetRequired     = 0
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// columnChunk locates a written column chunk for the file metadata.
+type columnChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type parquetWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	columns []column
+	rowsOf  func(order *domain.OrderResponse) [][]any
+
+	// PLAIN encoded values of the current row group, per column
+	values    []bytes.Buffer
+	rows      int64
+	totalRows int64
+	groups    [][]columnChunk
+	groupRows []int64
+	err       error
+}
+
+func newParquetWriter(w io.Writer, columns []column, rowsOf func(order *domain.OrderResponse) [][]any) *parquetWriter {
+	pw := &parquetWriter{
+		w:       bufio.NewWriter(w),
+		columns: columns,
+		rowsOf:  rowsOf,
+		values:  make([]bytes.Buffer, len(columns)),
+	}
+	pw.write([]byte(parquetMagic))
+	return pw
+}
+
+// write writes p to the file, keeping track of the offset.
+func (pw *parquetWriter) write(p []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	pw.err = err
+}
+
+func (pw *parquetWriter) Write(order *domain.OrderResponse) error {
+	for _, row := range pw.rowsOf(order) {
+		for i, v := range row {
+			buf := &pw.values[i]
+			switch v := v.(type) {
+			case int64:
+				binary.Write(buf, binary.LittleEndian, v)
+			case float64:
+				binary.Write(buf, binary.LittleEndian, math.Float64bits(v))
+			case time.Time:
+				binary.Write(buf, binary.LittleEndian, v.UnixMilli())
+			case string:
+				binary.Write(buf, binary.LittleEndian, uint32(len(v)))
+				buf.WriteString(v)
+			}
+		}
+		pw.rows++
+
+		if pw.rows == parquetRowGroup {
+			pw.writeRowGroup()
+		}
+	}
+	return pw.err
+}
+
+// writeRowGroup writes the buffered rows as a row group.
+func (pw *parquetWriter) writeRowGroup() {
+	if pw.rows == 0 {
+		return
+	}
+
+	chunks := make([]columnChunk, len(pw.columns))
+	for i := range pw.columns {
+		data := pw.values[i].Bytes()
+
+		var header thriftWriter
+		header.beginStruct()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.structField(5)
+		header.i32(1, int32(pw.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunks[i] = columnChunk{offset: pw.offset, size: int64(header.buf.Len() + len(data)), values: pw.rows}
+		pw.write(header.buf.Bytes())
+		pw.write(data)
+		pw.values[i].Reset()
+	}
+
+	pw.groups = append(pw.groups, chunks)
+	pw.groupRows = append(pw.groupRows, pw.rows)
+	pw.totalRows += pw.rows
+	pw.rows = 0
+}
+
+func (pw *parquetWriter) Flush() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+func (pw *parquetWriter) Close() error {
+	pw.writeRowGroup()
+
+	footer := pw.metadata()
+	pw.write(footer)
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	pw.write(length[:])
+	pw.write([]byte(parquetMagic))
+
+	return pw.Flush()
+}
+
+// metadata encodes the FileMetaData of the file.
+func (pw *parquetWriter) metadata() []byte {
+	var t thriftWriter
+	t.beginStruct()
+	t.i32(1, 1)
+
+	// Schema: the root followed by one leaf per column
+	t.list(2, thriftStruct, len(pw.columns)+1)
+	t.beginStruct()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.endStruct()
+	for _, c := range pw.columns {
+		t.beginStruct()
+		t.i32(1, parquetType(c.kind))
+		t.i32(3, parquetRequired)
+		t.binary(4, c.name)
+		switch c.kind {
+		case kindString:
+			t.i32(6, parquetUTF8)
+		case kindTime:
+			t.i32(6, parquetTimestampMillis)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, pw.totalRows)
+
+	t.list(4, thriftStruct, len(pw.groups))
+	for g, chunks := range pw.groups {
+		t.beginStruct()
+		t.list(1, thriftStruct, len(chunks))
+		var size int64
+		for i, chunk := range chunks {
+			size += chunk.size
+			t.beginStruct()
+			t.i64(2, chunk.offset)
+			t.structField(3)
+			t.i32(1, parquetType(pw.columns[i].kind))
+			t.list(2, thriftI32, 2)
+			t.varint(parquetPlain)
+			t.varint(parquetRLE)
+			t.list(3, thriftBinary, 1)
+			t.bytes(pw.columns[i].name)
+			t.i32(4, parquetUncompressed)
+			t.i64(5, chunk.values)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, size)
+		t.i64(3, pw.groupRows[g])
+		t.endStruct()
+	}
+
+	t.binary(6, "order-service-test")
+	t.endStruct()
+	return t.buf.Bytes()
+}
+
+// parquetType returns the physical type of a column kind.
+func parquetType(k kind) int32 {
+	switch k {
+	case kindFloat:
+		return parquetDouble
+	case kindString:
+		return parquetByteArray
+	default:
+		return parquetInt64
+	}
+}
+
+// thriftWriter encodes structs with the Thrift compact protocol.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last field ID written in each open struct, for the field ID deltas
+	last []int16
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	*last = id
+}
+
+// varint writes a zigzag encoded integer.
+func (t *thriftWriter) varint(v int64) {
+	t.uvarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (t *thriftWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+// bytes writes a length-prefixed string, as a binary field or list element.
+func (t *thriftWriter) bytes(s string) {
+	t.uvarint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.bytes(s)
+}
+
+// list writes the header of a list field of n elements of type elem.
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.uvarint(uint64(n))
+	}
+}
+
+// structField opens a struct field; it is closed by endStruct.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.beginStruct()
+}
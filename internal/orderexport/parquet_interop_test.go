@@ -0,0 +1,78 @@
+//go:build parquetinterop
+
+package orderexport
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// The Parquet writer is hand-written, so its files are also read back with
+// an independent implementation of the format. Run with
+//
+//	go test -mod=mod -tags parquetinterop ./internal/orderexport
+
+type parquetItemRow struct {
+	OrderID    int64   `parquet:"name=order_id, type=INT64"`
+	CustomerID string  `parquet:"name=customer_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Status     string  `parquet:"name=status, type=BYTE_ARRAY, convertedtype=UTF8"`
+	CreatedAt  int64   `parquet:"name=created_at, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	ProductID  int64   `parquet:"name=product_id, type=INT64"`
+	Quantity   int64   `parquet:"name=quantity, type=INT64"`
+	Price      float64 `parquet:"name=price, type=DOUBLE"`
+	VAT        float64 `parquet:"name=vat, type=DOUBLE"`
+}
+
+func TestParquetInterop(t *testing.T) {
+	t.Run("Rows read back by another reader", func(t *testing.T) {
+		// Setup
+		file, err := buffer.NewBufferFile(export(t, FormatParquet, RowsItem))
+		require.NoError(t, err)
+		pr, err := reader.NewParquetReader(file, new(parquetItemRow), 1)
+		require.NoError(t, err)
+		defer pr.ReadStop()
+
+		rows := make([]parquetItemRow, pr.GetNumRows())
+		err = pr.Read(&rows)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, []parquetItemRow{
+			{OrderID: 1, CustomerID: "c1", Status: "paid", CreatedAt: createdAt.UnixMilli(), ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0},
+			{OrderID: 1, CustomerID: "c1", Status: "paid", CreatedAt: createdAt.UnixMilli(), ProductID: 2, Quantity: 3, Price: 15.0, VAT: 1.5},
+			{OrderID: 2, Status: "pending", CreatedAt: createdAt.Add(time.Minute).UnixMilli(), ProductID: 1, Quantity: 1, Price: 10.0, VAT: 1.0},
+		}, rows)
+	})
+
+	t.Run("Row groups read back by another reader", func(t *testing.T) {
+		// Setup
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, FormatParquet, RowsItem)
+		require.NoError(t, err)
+		order := testOrders()[1]
+		for i := 0; i < parquetRowGroup+1; i++ {
+			require.NoError(t, w.Write(order))
+		}
+		require.NoError(t, w.Close())
+
+		file, err := buffer.NewBufferFile(buf.Bytes())
+		require.NoError(t, err)
+		pr, err := reader.NewParquetReader(file, new(parquetItemRow), 1)
+		require.NoError(t, err)
+		defer pr.ReadStop()
+
+		rows := make([]parquetItemRow, pr.GetNumRows())
+		err = pr.Read(&rows)
+
+		// Assertions
+		require.NoError(t, err)
+		require.Len(t, rows, parquetRowGroup+1)
+		assert.Equal(t, rows[0], rows[parquetRowGroup])
+	})
+}
@@ -0,0 +1,160 @@
+// Package orderimport reads orders to import from CSV and NDJSON files.
+//
+// A CSV file starts with a header naming its columns, in any order:
+// product_id and quantity are required, order_ref and customer_id are
+// optional. Every record is one item; records sharing an order_ref form a
+// single order, and records without one are an order each.
+//
+// An NDJSON file holds one order per line, in the body format of
+// POST /api/orders with an optional ref:
+//
+//	{"ref": "A-1", "order": {"customer_id": "c1", "items": [{"product_id": 1, "quantity": 2}]}}
+//
+// Rows that cannot be read are returned with their Error set, so that the
+// rest of the file can still be imported.
+package orderimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// ErrInvalidFile is returned for files that cannot be read at all.
+var ErrInvalidFile = errors.New("invalid import file")
+
+// maxLine bounds the length of an NDJSON line.
+const maxLine = 1 << 20
+
+// Parse reads the orders of a file in the given format.
+func Parse(r io.Reader, format string) ([]domain.ImportRow, error) {
+	switch format {
+	case domain.ImportFormatCSV:
+		return ParseCSV(r)
+	case domain.ImportFormatNDJSON:
+		return ParseNDJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidFile, format)
+	}
+}
+
+// ParseCSV reads the orders of a CSV file.
+func ParseCSV(r io.Reader) ([]domain.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"product_id", "quantity"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column", ErrInvalidFile, name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []domain.ImportRow
+	refs := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		// Find the order of the record, starting a new one if needed
+		ref := field(record, "order_ref")
+		customerID := field(record, "customer_id")
+		index, grouped := refs[ref]
+		if ref == "" || !grouped {
+			index = len(rows)
+			rows = append(rows, domain.ImportRow{Line: line, Ref: ref, Order: domain.OrderInput{CustomerID: customerID}})
+			if ref != "" {
+				refs[ref] = index
+			}
+		}
+		row := &rows[index]
+		if row.Error != "" {
+			continue
+		}
+
+		if err != nil {
+			row.Error = fmt.Sprintf("line %d: wrong number of fields", line)
+			continue
+		}
+		if customerID != "" && customerID != row.Order.CustomerID {
+			row.Error = fmt.Sprintf("line %d: customer_id differs from line %d", line, row.Line)
+			continue
+		}
+		productID, err := strconv.ParseInt(field(record, "product_id"), 10, 64)
+		if err != nil {
+			row.Error = fmt.Sprintf("line %d: invalid product_id", line)
+			continue
+		}
+		quantity, err := strconv.Atoi(field(record, "quantity"))
+		if err != nil {
+			row.Error = fmt.Sprintf("line %d: invalid quantity", line)
+			continue
+		}
+		row.Order.Items = append(row.Order.Items, domain.OrderItem{ProductID: productID, Quantity: quantity})
+	}
+
+	return rows, nil
+}
+
+// ndjsonRow is the JSON form of an NDJSON line.
+type ndjsonRow struct {
+	Ref   string            `json:"ref"`
+	Order domain.OrderInput `json:"order"`
+}
+
+// ParseNDJSON reads the orders of an NDJSON file. Blank lines are ignored.
+func ParseNDJSON(r io.Reader) ([]domain.ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+
+	var rows []domain.ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var input ndjsonRow
+		if err := json.Unmarshal(text, &input); err != nil {
+			rows = append(rows, domain.ImportRow{Line: line, Error: fmt.Sprintf("line %d: invalid JSON", line)})
+			continue
+		}
+		rows = append(rows, domain.ImportRow{Line: line, Ref: input.Ref, Order: input.Order})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	return rows, nil
+}
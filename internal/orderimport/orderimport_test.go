@@ -0,0 +1,76 @@
+package orderimport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+func TestParseCSV(t *testing.T) {
+	t.Run("Groups lines by order reference", func(t *testing.T) {
+		file := "quantity,product_id,order_ref,customer_id\n" +
+			"2,1,A-1,c1\n" +
+			"1,3,,c2\n" +
+			"3,2,A-1,\n"
+
+		rows, err := ParseCSV(strings.NewReader(file))
+
+		// Assertions
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, domain.ImportRow{Line: 2, Ref: "A-1", Order: domain.OrderInput{
+			CustomerID: "c1",
+			Items:      []domain.OrderItem{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 3}},
+		}}, rows[0])
+		assert.Equal(t, domain.ImportRow{Line: 3, Order: domain.OrderInput{
+			CustomerID: "c2",
+			Items:      []domain.OrderItem{{ProductID: 3, Quantity: 1}},
+		}}, rows[1])
+	})
+
+	t.Run("Invalid lines fail their order", func(t *testing.T) {
+		file := "order_ref,customer_id,product_id,quantity\n" +
+			"A-1,c1,x,1\n" +
+			"A-1,c1,2,1\n" +
+			"A-2,c1,2\n" +
+			"A-3,c1,2,1\n" +
+			"A-3,c2,2,1\n"
+
+		rows, err := ParseCSV(strings.NewReader(file))
+
+		// Assertions
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, "line 2: invalid product_id", rows[0].Error)
+		assert.Equal(t, "line 4: wrong number of fields", rows[1].Error)
+		assert.Equal(t, "line 6: customer_id differs from line 5", rows[2].Error)
+	})
+
+	t.Run("Required columns", func(t *testing.T) {
+		_, err := ParseCSV(strings.NewReader("order_ref,product_id\nA-1,1\n"))
+
+		// Assertions
+		assert.ErrorIs(t, err, ErrInvalidFile)
+		assert.Contains(t, err.Error(), "missing quantity column")
+	})
+}
+
+func TestParseNDJSON(t *testing.T) {
+	file := `{"ref": "A-1", "order": {"customer_id": "c1", "items": [{"product_id": 1, "quantity": 2}]}}
+
+{"ref": "A-2", "order": `
+
+	rows, err := ParseNDJSON(strings.NewReader(file))
+
+	// Assertions
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, domain.ImportRow{Line: 1, Ref: "A-1", Order: domain.OrderInput{
+		CustomerID: "c1",
+		Items:      []domain.OrderItem{{ProductID: 1, Quantity: 2}},
+	}}, rows[0])
+	assert.Equal(t, domain.ImportRow{Line: 3, Error: "line 3: invalid JSON"}, rows[1])
+}
@@ -0,0 +1,21 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// MultiPublisher publishes each event to every wrapped publisher in turn.
+// It stops at the first error so the relay retries the event; publishers that
+// already succeeded will see it again, as allowed by at-least-once delivery.
+type MultiPublisher []Publisher
+
+func (m MultiPublisher) Publish(ctx context.Context, event domain.Event) error {
+	for _, p := range m {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// Publisher delivers domain events to other systems. Implementations must be
+// safe to call repeatedly with the same event: delivery is at-least-once.
+type Publisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+// WriterPublisher writes each event as one JSON line to an io.Writer,
+// typically stdout or a file.
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+func (p *WriterPublisher) Publish(ctx context.Context, event domain.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
+
+// MemoryPublisher keeps published events in memory. It is intended for tests.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []domain.Event
+	err    error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, event domain.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+// FailWith makes subsequent Publish calls return err; pass nil to recover.
+func (p *MemoryPublisher) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Events returns a copy of the events published so far.
+func (p *MemoryPublisher) Events() []domain.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.Event(nil), p.events...)
+}
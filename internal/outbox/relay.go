@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// Relay polls the outbox table and publishes pending events at-least-once.
+type Relay struct {
+	repo      repository.OutboxRepository
+	publisher Publisher
+	batchSize int
+	interval  time.Duration
+	logger    *slog.Logger
+}
+
+func NewRelay(repo repository.OutboxRepository, publisher Publisher, batchSize int, interval time.Duration, logger *slog.Logger) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		batchSize: batchSize,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run publishes pending events every interval until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Warn("outbox relay failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain publishes batches until the outbox is empty or an error occurs.
+// It returns the total number of events published.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		published, err := r.repo.ProcessBatch(ctx, r.batchSize, r.publisher.Publish)
+		total += published
+		if err != nil {
+			return total, err
+		}
+		if published < r.batchSize {
+			if total > 0 {
+				r.logger.Debug("outbox events published", "count", total)
+			}
+			return total, nil
+		}
+	}
+}
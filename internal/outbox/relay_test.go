@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// memoryOutbox is an in-memory implementation of repository.OutboxRepository
+// following the same batch semantics as the Postgres implementation.
+type memoryOutbox struct {
+	events    []domain.Event
+	published map[int64]bool
+	attempts  map[int64]int
+}
+
+func newMemoryOutbox(n int) *memoryOutbox {
+	o := &memoryOutbox{published: map[int64]bool{}, attempts: map[int64]int{}}
+	for i := 1; i <= n; i++ {
+		o.events = append(o.events, domain.Event{
+			ID:            int64(i),
+			Type:          domain.EventOrderCreated,
+			AggregateType: domain.AggregateOrder,
+			AggregateID:   int64(100 + i),
+			Payload:       json.RawMessage(`{}`),
+		})
+	}
+	return o
+}
+
+func (o *memoryOutbox) ProcessBatch(ctx context.Context, limit int, publish func(context.Context, domain.Event) error) (int, error) {
+	published := 0
+	for _, event := range o.events {
+		if published == limit {
+			break
+		}
+		if o.published[event.ID] {
+			continue
+		}
+		o.attempts[event.ID]++
+		if err := publish(ctx, event); err != nil {
+			return published, err
+		}
+		o.published[event.ID] = true
+		published++
+	}
+	return published, nil
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRelayDrain(t *testing.T) {
+	t.Run("Publishes every pending event in order", func(t *testing.T) {
+		repo := newMemoryOutbox(5)
+		publisher := NewMemoryPublisher()
+		relay := NewRelay(repo, publisher, 2, 0, discardLogger())
+
+		published, err := relay.Drain(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 5, published)
+
+		events := publisher.Events()
+		require.Len(t, events, 5)
+		for i, event := range events {
+			assert.Equal(t, int64(i+1), event.ID)
+		}
+	})
+
+	t.Run("Failed events are retried on the next drain", func(t *testing.T) {
+		repo := newMemoryOutbox(3)
+		publisher := NewMemoryPublisher()
+		relay := NewRelay(repo, publisher, 10, 0, discardLogger())
+
+		publisher.FailWith(errors.New("broker unavailable"))
+		published, err := relay.Drain(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, published)
+		assert.Empty(t, publisher.Events())
+
+		publisher.FailWith(nil)
+		published, err = relay.Drain(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, published)
+		assert.Equal(t, 2, repo.attempts[1])
+		assert.Len(t, publisher.Events(), 3)
+	})
+}
+
+func TestWriterPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewWriterPublisher(&buf)
+
+	event := domain.Event{
+		ID:            1,
+		Type:          domain.EventOrderCreated,
+		AggregateType: domain.AggregateOrder,
+		AggregateID:   42,
+		Payload:       json.RawMessage(`{"order_id":42}`),
+		RequestID:     "req-123",
+	}
+
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	require.NoError(t, publisher.Publish(context.Background(), event))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var decoded domain.Event
+	require.NoError(t, json.Unmarshal(lines[0], &decoded))
+	assert.Equal(t, "order.created", decoded.Type)
+	assert.Equal(t, int64(42), decoded.AggregateID)
+	assert.Equal(t, "req-123", decoded.RequestID)
+	assert.JSONEq(t, `{"order_id":42}`, string(decoded.Payload))
+}
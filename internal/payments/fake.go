@@ -0,0 +1,120 @@
+// Package payments contains the payment gateway implementations.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// Payment methods that make the fake gateway fail. Every other payment
+// method is approved.
+const (
+	FakeMethodDeclined = "fake_declined"
+	FakeMethodError    = "fake_error"
+)
+
+// ErrFakeUnavailable is returned by the fake gateway for FakeMethodError.
+var ErrFakeUnavailable = errors.New("fake gateway unavailable")
+
+// fakeAuthorization is the state the fake gateway keeps per authorization.
+type fakeAuthorization struct {
+	amount   int64
+	captured int64
+	refunded int64
+	voided   bool
+}
+
+// FakeGateway is a deterministic in-memory payment gateway for local
+// development and tests. Its outcome depends only on the payment method and
+// references are derived from the payment ID. It enforces the rules of a
+// real provider: captures up to the authorized amount, no void after
+// capture and refunds up to the captured amount. Its state is lost on restart.
+type FakeGateway struct {
+	mu             sync.Mutex
+	authorizations map[string]*fakeAuthorization
+	refunds        int
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{authorizations: make(map[string]*fakeAuthorization)}
+}
+
+// Name identifies the gateway in the payments it processed.
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+// Authorize approves the payment unless its method is FakeMethodDeclined or
+// FakeMethodError. The reference is "fake_auth_" followed by the payment ID.
+func (g *FakeGateway) Authorize(ctx context.Context, req domain.AuthorizationRequest) (string, error) {
+	switch req.PaymentMethod {
+	case FakeMethodDeclined:
+		return "", fmt.Errorf("%w: insufficient funds", domain.ErrPaymentDeclined)
+	case FakeMethodError:
+		return "", ErrFakeUnavailable
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	reference := fmt.Sprintf("fake_auth_%d", req.PaymentID)
+	g.authorizations[reference] = &fakeAuthorization{amount: domain.Cents(req.Amount)}
+	return reference, nil
+}
+
+// Capture captures amount of an authorization, at most what remains of it.
+func (g *FakeGateway) Capture(ctx context.Context, reference string, amount float64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, ok := g.authorizations[reference]
+	if !ok {
+		return fmt.Errorf("unknown authorization %q", reference)
+	}
+	if auth.voided {
+		return fmt.Errorf("authorization %q is voided", reference)
+	}
+	if auth.captured+domain.Cents(amount) > auth.amount {
+		return fmt.Errorf("capture exceeds authorization %q", reference)
+	}
+	auth.captured += domain.Cents(amount)
+	return nil
+}
+
+// Void releases an authorization that has not been captured.
+func (g *FakeGateway) Void(ctx context.Context, reference string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, ok := g.authorizations[reference]
+	if !ok {
+		return fmt.Errorf("unknown authorization %q", reference)
+	}
+	if auth.captured > 0 {
+		return fmt.Errorf("authorization %q is already captured", reference)
+	}
+	auth.voided = true
+	return nil
+}
+
+// Refund returns amount of the captured funds, at most what was captured and
+// not refunded yet. Refund references are numbered in order.
+func (g *FakeGateway) Refund(ctx context.Context, reference string, amount float64) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, ok := g.authorizations[reference]
+	if !ok {
+		return "", fmt.Errorf("unknown authorization %q", reference)
+	}
+	if auth.refunded+domain.Cents(amount) > auth.captured {
+		return "", fmt.Errorf("refund exceeds the captured amount of %q", reference)
+	}
+	auth.refunded += domain.Cents(amount)
+	g.refunds++
+	return fmt.Sprintf("fake_refund_%d", g.refunds), nil
+}
@@ -0,0 +1,52 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+func TestFakeGateway(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Authorize, capture and refund", func(t *testing.T) {
+		gateway := NewFakeGateway()
+
+		reference, err := gateway.Authorize(ctx, domain.AuthorizationRequest{PaymentID: 7, Amount: 10.0})
+		require.NoError(t, err)
+		assert.Equal(t, "fake_auth_7", reference)
+
+		assert.Error(t, gateway.Capture(ctx, reference, 10.01))
+		require.NoError(t, gateway.Capture(ctx, reference, 10.0))
+		assert.Error(t, gateway.Void(ctx, reference))
+
+		refund, err := gateway.Refund(ctx, reference, 4.0)
+		require.NoError(t, err)
+		assert.Equal(t, "fake_refund_1", refund)
+		_, err = gateway.Refund(ctx, reference, 6.01)
+		assert.Error(t, err)
+	})
+
+	t.Run("Outcome depends on the payment method", func(t *testing.T) {
+		gateway := NewFakeGateway()
+
+		_, err := gateway.Authorize(ctx, domain.AuthorizationRequest{PaymentID: 1, Amount: 1.0, PaymentMethod: FakeMethodDeclined})
+		assert.ErrorIs(t, err, domain.ErrPaymentDeclined)
+
+		_, err = gateway.Authorize(ctx, domain.AuthorizationRequest{PaymentID: 2, Amount: 1.0, PaymentMethod: FakeMethodError})
+		assert.ErrorIs(t, err, ErrFakeUnavailable)
+	})
+
+	t.Run("Voided authorization cannot be captured", func(t *testing.T) {
+		gateway := NewFakeGateway()
+
+		reference, err := gateway.Authorize(ctx, domain.AuthorizationRequest{PaymentID: 1, Amount: 5.0})
+		require.NoError(t, err)
+		require.NoError(t, gateway.Void(ctx, reference))
+
+		assert.Error(t, gateway.Capture(ctx, reference, 5.0))
+	})
+}
@@ -0,0 +1,84 @@
+// Package quote signs and verifies quote tokens, which let CreateOrder honour
+// the prices shown to a client by the quote endpoint.
+package quote
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// claims is the signed content of a quote token.
+type claims struct {
+	Items     []domain.OrderItem `json:"items"`
+	ExpiresAt int64              `json:"exp"`
+}
+
+// Signer issues and verifies stateless quote tokens. A token is the
+// base64url encoded JSON claims followed by a dot and the base64url encoded
+// HMAC-SHA256 of the encoded claims, so every instance sharing the secret
+// can verify tokens issued by the others.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	return &Signer{
+		secret: secret,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Sign returns a token for the priced items, valid for the signer's TTL,
+// and its expiry time.
+func (s *Signer) Sign(items []domain.OrderItem) (string, time.Time, error) {
+	expiresAt := s.now().Add(s.ttl).Truncate(time.Second)
+
+	payload, err := json.Marshal(claims{Items: items, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signature(encoded), expiresAt, nil
+}
+
+// Verify checks the token signature and expiry and returns the priced items
+// it was issued for. It returns domain.ErrInvalidQuote for malformed or
+// tampered tokens and domain.ErrQuoteExpired once the validity window is over.
+func (s *Signer) Verify(token string) ([]domain.OrderItem, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(s.signature(encoded)), []byte(signature)) {
+		return nil, domain.ErrInvalidQuote
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, domain.ErrInvalidQuote
+	}
+
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, domain.ErrInvalidQuote
+	}
+
+	if !s.now().Before(time.Unix(c.ExpiresAt, 0)) {
+		return nil, domain.ErrQuoteExpired
+	}
+
+	return c.Items, nil
+}
+
+func (s *Signer) signature(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package quote
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+func TestSigner(t *testing.T) {
+	items := []domain.OrderItem{
+		{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0},
+		{ProductID: 2, Quantity: 3, Price: 4.5, VAT: 0.45},
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	newSigner := func(secret string) *Signer {
+		signer := NewSigner([]byte(secret), 15*time.Minute)
+		signer.now = func() time.Time { return now }
+		return signer
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		signer := newSigner("secret")
+
+		token, expiresAt, err := signer.Sign(items)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(15*time.Minute), expiresAt)
+
+		quoted, err := signer.Verify(token)
+
+		require.NoError(t, err)
+		assert.Equal(t, items, quoted)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		signer := newSigner("secret")
+		token, _, err := signer.Sign(items)
+		require.NoError(t, err)
+
+		signer.now = func() time.Time { return now.Add(15 * time.Minute) }
+		_, err = signer.Verify(token)
+
+		assert.ErrorIs(t, err, domain.ErrQuoteExpired)
+	})
+
+	t.Run("Tampered or foreign tokens are rejected", func(t *testing.T) {
+		signer := newSigner("secret")
+		token, _, err := signer.Sign(items)
+		require.NoError(t, err)
+
+		other, _, err := newSigner("other").Sign([]domain.OrderItem{{ProductID: 1, Quantity: 2, Price: 0.01}})
+		require.NoError(t, err)
+		payload, _, _ := strings.Cut(other, ".")
+		_, signature, _ := strings.Cut(token, ".")
+
+		for _, tampered := range []string{"", "garbage", other, payload + "." + signature} {
+			_, err := signer.Verify(tampered)
+			assert.ErrorIs(t, err, domain.ErrInvalidQuote, tampered)
+		}
+	})
+}
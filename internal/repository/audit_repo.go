@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/valeriouberti/order-service-test/internal/actor"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+type AuditRepo struct {
+	db *sql.DB
+}
+
+func NewAuditRepo(db *sql.DB) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+// insertAuditEntry records a change to an entity in the audit log, as part
+// of the transaction making the change, so that no change is committed
+// without its entry. The actor and request ID are taken from ctx. before is
+// nil for created entities.
+func insertAuditEntry(ctx context.Context, tx *sql.Tx, entityType string, entityID int64, action string, before, after any) error {
+	beforeDiff, afterDiff, err := domain.AuditDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO audit_log (entity_type, entity_id, action, actor, request_id, before, after)
+        VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+    `, entityType, entityID, action, actor.FromContext(ctx), requestid.FromContext(ctx), nullJSON(beforeDiff), []byte(afterDiff))
+	return err
+}
+
+// nullJSON returns a nil JSON document as SQL NULL.
+func nullJSON(doc []byte) any {
+	if doc == nil {
+		return nil
+	}
+	return doc
+}
+
+// List retrieves the audit entries matching filter, ordered by ascending ID,
+// with at most filter.Limit entries.
+func (r *AuditRepo) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	query := `
+        SELECT id, entity_type, entity_id, action, COALESCE(actor, ''), COALESCE(request_id, ''),
+               before, after, created_at
+        FROM audit_log
+        WHERE id > $1
+          AND ($2 = '' OR entity_type = $2)
+          AND ($3 = 0 OR entity_id = $3)
+          AND ($4 = '' OR action = $4)
+          AND ($5 = '' OR actor = $5)
+          AND ($6 = '' OR request_id = $6)
+          AND ($7::timestamptz IS NULL OR created_at >= $7)
+          AND ($8::timestamptz IS NULL OR created_at < $8)
+        ORDER BY id
+        LIMIT $9
+    `
+
+	from := sql.NullTime{Time: filter.From, Valid: !filter.From.IsZero()}
+	to := sql.NullTime{Time: filter.To, Valid: !filter.To.IsZero()}
+	rows, err := r.db.QueryContext(ctx, query, filter.AfterID, filter.EntityType, filter.EntityID, filter.Action,
+		filter.Actor, filter.RequestID, from, to, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.AuditEntry{}
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&entry.ID, &entry.EntityType, &entry.EntityID, &entry.Action, &entry.Actor,
+			&entry.RequestID, &before, &after, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.Before, entry.After = before, after
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type CartRepo struct {
+	db *sql.DB
+}
+
+func NewCartRepo(db *sql.DB) *CartRepo {
+	return &CartRepo{db: db}
+}
+
+// Create persists a new empty cart and populates its creation time.
+func (r *CartRepo) Create(ctx context.Context, cart *domain.Cart) (*domain.Cart, error) {
+	query := `
+        INSERT INTO carts (id, customer_id, status, expires_at)
+        VALUES ($1, NULLIF($2, ''), $3, $4)
+        RETURNING created_at
+    `
+
+	err := r.db.QueryRowContext(ctx, query, cart.ID, cart.CustomerID, cart.Status, cart.ExpiresAt).
+		Scan(&cart.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	cart.Items = []domain.CartItem{}
+	return cart, nil
+}
+
+// GetByID returns a cart with its items, in the order they were added.
+// Expired carts are returned until they are deleted; callers check expiry.
+func (r *CartRepo) GetByID(ctx context.Context, id string) (*domain.Cart, error) {
+	query := `
+        SELECT id, COALESCE(customer_id, ''), status, COALESCE(order_id, 0), expires_at, created_at
+        FROM carts
+        WHERE id = $1
+    `
+
+	var cart domain.Cart
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&cart.ID,
+		&cart.CustomerID,
+		&cart.Status,
+		&cart.OrderID,
+		&cart.ExpiresAt,
+		&cart.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrCartNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT product_id, quantity FROM cart_items WHERE cart_id = $1 ORDER BY created_at, product_id
+    `, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cart.Items = []domain.CartItem{}
+	for rows.Next() {
+		var item domain.CartItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		cart.Items = append(cart.Items, item)
+	}
+
+	return &cart, rows.Err()
+}
+
+// AddItem adds quantity units of a product to an open cart, merging them into
+// the existing line for that product, and extends the cart expiry.
+func (r *CartRepo) AddItem(ctx context.Context, cartID string, item domain.CartItem, expiresAt time.Time) error {
+	return r.updateItems(ctx, cartID, expiresAt, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, `
+            INSERT INTO cart_items (cart_id, product_id, quantity)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (cart_id, product_id)
+            DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()
+        `, cartID, item.ProductID, item.Quantity)
+	})
+}
+
+// SetItemQuantity replaces the quantity of an existing line of an open cart
+// and extends the cart expiry.
+func (r *CartRepo) SetItemQuantity(ctx context.Context, cartID string, item domain.CartItem, expiresAt time.Time) error {
+	return r.updateItems(ctx, cartID, expiresAt, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, `
+            UPDATE cart_items SET quantity = $3, updated_at = NOW()
+            WHERE cart_id = $1 AND product_id = $2
+        `, cartID, item.ProductID, item.Quantity)
+	})
+}
+
+// RemoveItem removes the line of a product from an open cart and extends
+// the cart expiry.
+func (r *CartRepo) RemoveItem(ctx context.Context, cartID string, productID int64, expiresAt time.Time) error {
+	return r.updateItems(ctx, cartID, expiresAt, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, `DELETE FROM cart_items WHERE cart_id = $1 AND product_id = $2`, cartID, productID)
+	})
+}
+
+// updateItems runs change in a transaction after locking the cart and
+// extending its expiry. It returns domain.ErrCartNotFound if the cart is
+// missing or expired, domain.ErrCartCheckedOut if it is no longer open and
+// domain.ErrCartItemNotFound if change affected no line.
+func (r *CartRepo) updateItems(ctx context.Context, cartID string, expiresAt time.Time, change func(tx *sql.Tx) (sql.Result, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the cart so a concurrent checkout sees either all or none of the change
+	var status string
+	var expired bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT status, expires_at <= NOW() FROM carts WHERE id = $1 FOR UPDATE
+    `, cartID).Scan(&status, &expired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrCartNotFound
+		}
+		return err
+	}
+	if status != domain.CartStatusOpen {
+		return domain.ErrCartCheckedOut
+	}
+	if expired {
+		return domain.ErrCartNotFound
+	}
+
+	result, err := change(tx)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrCartItemNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE carts SET expires_at = $2, updated_at = NOW() WHERE id = $1`, cartID, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpired deletes the open carts that expired before now and returns
+// how many were deleted. Checked out carts are kept with their order.
+func (r *CartRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+        DELETE FROM carts WHERE status = $1 AND expires_at <= $2
+    `, domain.CartStatusOpen, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
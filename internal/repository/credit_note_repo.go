@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type CreditNoteRepo struct {
+	db *sql.DB
+}
+
+func NewCreditNoteRepo(db *sql.DB) *CreditNoteRepo {
+	return &CreditNoteRepo{db: db}
+}
+
+// refundablePayment is a captured payment with the amount that can still be
+// refunded on it.
+type refundablePayment struct {
+	id        int64
+	reference string
+	remaining int64
+}
+
+// refundablePayments returns the captured payments of an order, oldest
+// first, with the cents not yet refunded or being refunded on each. The
+// caller holds the order's row lock.
+func refundablePayments(ctx context.Context, tx *sql.Tx, orderID int64) ([]refundablePayment, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT p.id, COALESCE(p.reference, ''), p.amount - COALESCE(SUM(r.amount) FILTER (WHERE r.status <> $3), 0)
+        FROM payments p
+        LEFT JOIN refunds r ON r.payment_id = p.id
+        WHERE p.order_id = $1 AND p.status = $2
+        GROUP BY p.id
+        ORDER BY p.id
+    `, orderID, domain.PaymentStatusCaptured, domain.RefundStatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []refundablePayment
+	for rows.Next() {
+		var payment refundablePayment
+		var remaining float64
+		if err := rows.Scan(&payment.id, &payment.reference, &remaining); err != nil {
+			return nil, err
+		}
+		payment.remaining = domain.Cents(remaining)
+		if payment.remaining > 0 {
+			payments = append(payments, payment)
+		}
+	}
+
+	return payments, rows.Err()
+}
+
+// Refundable returns the amount captured for an order and not yet refunded
+// or being refunded.
+func (r *CreditNoteRepo) Refundable(ctx context.Context, orderID int64) (float64, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	payments, err := refundablePayments(ctx, tx, orderID)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, payment := range payments {
+		total += payment.remaining
+	}
+	return domain.FromCents(total), nil
+}
+
+// Create records a pending credit note with its lines and the refunds it is
+// made of, taken from the captured payments oldest first.
+//
+// The order is locked while the refund is checked, so concurrent refunds can
+// never together exceed what was captured: it returns
+// domain.ErrRefundExceedsCaptured if the amount exceeds the captured amount
+// not yet refunded and domain.ErrRefundExceedsQuantity if a line is credited
+// for more than its ordered quantity, counting earlier credit notes that
+// did not fail. Credit notes are numbered per order without gaps.
+func (r *CreditNoteRepo) Create(ctx context.Context, note *domain.CreditNote) (*domain.CreditNote, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockOrder(ctx, tx, note.OrderID); err != nil {
+		return nil, err
+	}
+
+	// Allocate the amount to the captured payments
+	payments, err := refundablePayments(ctx, tx, note.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	left := domain.Cents(note.Amount)
+	var refunds []domain.Refund
+	for _, payment := range payments {
+		if left == 0 {
+			break
+		}
+		amount := min(left, payment.remaining)
+		refunds = append(refunds, domain.Refund{
+			PaymentID:        payment.id,
+			Amount:           domain.FromCents(amount),
+			Status:           domain.RefundStatusPending,
+			PaymentReference: payment.reference,
+		})
+		left -= amount
+	}
+	if left > 0 {
+		return nil, domain.ErrRefundExceedsCaptured
+	}
+
+	// Check the credited quantities
+	for _, item := range note.Items {
+		var ordered, credited int
+		err := tx.QueryRowContext(ctx, `
+            SELECT
+                COALESCE((SELECT SUM(quantity) FROM order_items WHERE order_id = $1 AND product_id = $2), 0),
+                COALESCE((SELECT SUM(ci.quantity) FROM credit_note_items ci
+                          JOIN credit_notes cn ON cn.id = ci.credit_note_id
+                          WHERE cn.order_id = $1 AND ci.product_id = $2 AND cn.status <> $3), 0)
+        `, note.OrderID, item.ProductID, domain.CreditNoteStatusFailed).Scan(&ordered, &credited)
+		if err != nil {
+			return nil, err
+		}
+		if credited+item.Quantity > ordered {
+			return nil, fmt.Errorf("%w: product %d has %d of %d units left to credit",
+				domain.ErrRefundExceedsQuantity, item.ProductID, max(ordered-credited, 0), ordered)
+		}
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM credit_notes WHERE order_id = $1`, note.OrderID).Scan(&count); err != nil {
+		return nil, err
+	}
+	note.Number = fmt.Sprintf("CN-%d-%d", note.OrderID, count+1)
+	note.Status = domain.CreditNoteStatusPending
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO credit_notes (order_id, number, status, amount, price, vat, reason, request_id)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))
+        RETURNING id, created_at
+    `, note.OrderID, note.Number, note.Status, note.Amount, note.Price, note.VAT, note.Reason, note.RequestID,
+	).Scan(&note.ID, &note.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range note.Items {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO credit_note_items (credit_note_id, product_id, quantity, price, vat)
+            VALUES ($1, $2, $3, $4, $5)
+        `, note.ID, item.ProductID, item.Quantity, item.Price, item.VAT)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range refunds {
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO refunds (credit_note_id, payment_id, amount, status)
+            VALUES ($1, $2, $3, $4)
+            RETURNING id
+        `, note.ID, refunds[i].PaymentID, refunds[i].Amount, refunds[i].Status).Scan(&refunds[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	note.Refunds = refunds
+
+	return note, tx.Commit()
+}
+
+// CompleteRefund records the outcome of a pending refund.
+func (r *CreditNoteRepo) CompleteRefund(ctx context.Context, id int64, status, reference, failureReason string) error {
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE refunds SET status = $3, reference = NULLIF($4, ''), failure_reason = NULLIF($5, ''), updated_at = NOW()
+        WHERE id = $1 AND status = $2
+    `, id, domain.RefundStatusPending, status, reference, failureReason)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("refund %d is not pending", id)
+	}
+	return nil
+}
+
+// becomesRefunded reports whether an order in status becomes refunded once
+// everything captured for it has been refunded: it took payments, whether
+// in full or not, and has not been refunded already.
+func becomesRefunded(status string) bool {
+	return status == domain.OrderStatusPartiallyPaid || domain.IsPaidStatus(status)
+}
+
+// Finish sets the final status of a pending credit note, with its amount,
+// price and VAT, which a partially issued note reduces to what was
+// refunded. Issuing it, fully or partially, changes the order in the same
+// transaction: its version is incremented, it becomes refunded once
+// everything captured for it has been refunded, whether it is partially
+// paid, paid or shipped, and an
+// order.refunded event is written to the outbox and the change to the audit
+// log. The order is returned as it is after the change. It fails if the
+// note is no longer pending, so that it is finished only once.
+func (r *CreditNoteRepo) Finish(ctx context.Context, note *domain.CreditNote, status string) (*domain.Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	orderStatus, _, err := lockOrder(ctx, tx, note.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+        UPDATE credit_notes SET status = $2, amount = $3, price = $4, vat = $5, updated_at = NOW()
+        WHERE id = $1 AND status = $6
+    `, note.ID, status, note.Amount, note.Price, note.VAT, domain.CreditNoteStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, fmt.Errorf("credit note %d is not pending", note.ID)
+	}
+
+	var before *domain.Order
+	if domain.IsIssuedCreditNote(status) {
+		if before, err = getOrder(ctx, tx, note.OrderID); err != nil {
+			return nil, err
+		}
+
+		var captured, refunded float64
+		err := tx.QueryRowContext(ctx, `
+            SELECT
+                COALESCE((SELECT SUM(amount) FROM payments WHERE order_id = $1 AND status = $2), 0),
+                COALESCE((SELECT SUM(r.amount) FROM refunds r JOIN payments p ON p.id = r.payment_id
+                          WHERE p.order_id = $1 AND r.status = $3), 0)
+        `, note.OrderID, domain.PaymentStatusCaptured, domain.RefundStatusSucceeded).Scan(&captured, &refunded)
+		if err != nil {
+			return nil, err
+		}
+		if becomesRefunded(orderStatus) && domain.Cents(captured) > 0 && domain.Cents(refunded) >= domain.Cents(captured) {
+			orderStatus = domain.OrderStatusRefunded
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE orders SET status = $2, version = version + 1, updated_at = NOW() WHERE id = $1
+        `, note.OrderID, orderStatus)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := getOrder(ctx, tx, note.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if domain.IsIssuedCreditNote(status) {
+		event, err := domain.NewOrderEvent(domain.EventOrderRefunded, order)
+		if err != nil {
+			return nil, err
+		}
+		if err := insertOutboxEvent(ctx, tx, event); err != nil {
+			return nil, err
+		}
+		if err := insertAuditEntry(ctx, tx, domain.AuditEntityOrder, order.ID, domain.AuditActionOrderUpdated, before, order); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	note.Status = status
+	return order, nil
+}
+
+// ClaimStale returns a credit note that has been pending for longer than
+// staleAfter, which means the refund creating it was interrupted. The note
+// is claimed by touching it, so that it is not returned again for another
+// staleAfter. It returns domain.ErrCreditNoteNotFound if there is none.
+func (r *CreditNoteRepo) ClaimStale(ctx context.Context, staleAfter time.Duration) (*domain.CreditNote, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+        UPDATE credit_notes SET updated_at = NOW()
+        WHERE id = (
+            SELECT id FROM credit_notes
+            WHERE status = $1 AND updated_at < NOW() - make_interval(secs => $2)
+            ORDER BY id
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id
+    `, domain.CreditNoteStatusPending, staleAfter.Seconds()).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrCreditNoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCreditNote(r.db.QueryRowContext(ctx, creditNoteQuery+`WHERE cn.id = $1`, id))
+}
+
+// creditNoteQuery selects credit notes with their items and refunds; the
+// caller appends the WHERE clause.
+const creditNoteQuery = `
+    SELECT cn.id, cn.number, cn.order_id, cn.status, cn.amount, cn.price, cn.vat,
+           COALESCE(cn.reason, ''), COALESCE(cn.request_id, ''), cn.created_at,
+           COALESCE((SELECT json_agg(json_build_object(
+                         'product_id', ci.product_id, 'quantity', ci.quantity,
+                         'price', ci.price, 'vat', ci.vat) ORDER BY ci.id)
+                     FROM credit_note_items ci WHERE ci.credit_note_id = cn.id), '[]'),
+           COALESCE((SELECT json_agg(json_build_object(
+                         'id', r.id, 'payment_id', r.payment_id, 'amount', r.amount, 'status', r.status,
+                         'reference', COALESCE(r.reference, ''), 'failure_reason', COALESCE(r.failure_reason, '')) ORDER BY r.id)
+                     FROM refunds r WHERE r.credit_note_id = cn.id), '[]')
+    FROM credit_notes cn
+`
+
+func scanCreditNote(row interface{ Scan(...any) error }) (*domain.CreditNote, error) {
+	var note domain.CreditNote
+	var items, refunds string
+	err := row.Scan(
+		&note.ID,
+		&note.Number,
+		&note.OrderID,
+		&note.Status,
+		&note.Amount,
+		&note.Price,
+		&note.VAT,
+		&note.Reason,
+		&note.RequestID,
+		&note.CreatedAt,
+		&items,
+		&refunds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(items), &note.Items); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(refunds), &note.Refunds); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetByID returns a credit note of an order.
+func (r *CreditNoteRepo) GetByID(ctx context.Context, orderID, id int64) (*domain.CreditNote, error) {
+	note, err := scanCreditNote(r.db.QueryRowContext(ctx, creditNoteQuery+`WHERE cn.id = $1 AND cn.order_id = $2`, id, orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrCreditNoteNotFound
+	}
+	return note, err
+}
+
+// ListByOrder returns the credit notes of an order, oldest first.
+func (r *CreditNoteRepo) ListByOrder(ctx context.Context, orderID int64) ([]domain.CreditNote, error) {
+	rows, err := r.db.QueryContext(ctx, creditNoteQuery+`WHERE cn.order_id = $1 ORDER BY cn.id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []domain.CreditNote{}
+	for rows.Next() {
+		note, err := scanCreditNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, *note)
+	}
+
+	return notes, rows.Err()
+}
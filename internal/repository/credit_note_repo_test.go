@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+func TestCreditNoteRepoFinish(t *testing.T) {
+	ctx := context.Background()
+	note := &domain.CreditNote{ID: 7, OrderID: 1, Amount: 30.0, Price: 27.27, VAT: 2.73}
+
+	// finishDB answers the queries of issuing a credit note on an order in
+	// status with 30.00 captured and refunded refunded so far.
+	finishDB := func(t *testing.T, status string, refunded float64) (*sql.DB, *fakeDB) {
+		return newFakeDB(t,
+			rows("price + vat FROM orders", []string{"status", "total"}, []driver.Value{status, 30.0}),
+			rows("GROUP BY o.id", orderColumnNames, orderRow(status, 2)),
+			rows("FROM refunds r", []string{"captured", "refunded"}, []driver.Value{30.0, refunded}),
+			rows("GROUP BY o.id", orderColumnNames, orderRow(status, 3)),
+			rows("INSERT INTO outbox", []string{"id", "created_at"}, []driver.Value{int64(1), time.Now()}),
+		)
+	}
+
+	for _, status := range []string{
+		domain.OrderStatusPartiallyPaid,
+		domain.OrderStatusPaid,
+		domain.OrderStatusPartiallyShipped,
+		domain.OrderStatusShipped,
+	} {
+		t.Run("Fully refunded "+status+" order becomes refunded", func(t *testing.T) {
+			// Setup
+			db, fake := finishDB(t, status, 30.0)
+			repo := NewCreditNoteRepo(db)
+
+			_, err := repo.Finish(ctx, note, domain.CreditNoteStatusIssued)
+
+			// Assertions
+			require.NoError(t, err)
+			assert.True(t, fake.committed)
+			updates := fake.executed("UPDATE orders")
+			require.Len(t, updates, 1)
+			assert.Equal(t, domain.OrderStatusRefunded, updates[0].args[1])
+		})
+	}
+
+	t.Run("Partially refunded order keeps its status", func(t *testing.T) {
+		// Setup
+		db, fake := finishDB(t, domain.OrderStatusShipped, 29.99)
+		repo := NewCreditNoteRepo(db)
+
+		_, err := repo.Finish(ctx, note, domain.CreditNoteStatusPartiallyIssued)
+
+		// Assertions
+		require.NoError(t, err)
+		updates := fake.executed("UPDATE orders")
+		require.Len(t, updates, 1)
+		assert.Equal(t, domain.OrderStatusShipped, updates[0].args[1])
+	})
+
+	t.Run("Failed credit note leaves the order alone", func(t *testing.T) {
+		// Setup
+		db, fake := newFakeDB(t,
+			rows("price + vat FROM orders", []string{"status", "total"}, []driver.Value{domain.OrderStatusPaid, 30.0}),
+			rows("GROUP BY o.id", orderColumnNames, orderRow(domain.OrderStatusPaid, 2)),
+		)
+		repo := NewCreditNoteRepo(db)
+
+		_, err := repo.Finish(ctx, note, domain.CreditNoteStatusFailed)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Empty(t, fake.executed("UPDATE orders"))
+		assert.Empty(t, fake.executed("INSERT INTO outbox"))
+	})
+}
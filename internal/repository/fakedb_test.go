@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a scripted database/sql driver for repository tests. Queries
+// are answered with the rows of the first unused fakeResult whose match is
+// a substring of the query; statements without a result affect one row.
+// Every statement is recorded with its arguments and whether it ran in a
+// transaction.
+type fakeDB struct {
+	mu         sync.Mutex
+	results    []*fakeResult
+	statements []fakeStatement
+	inTx       bool
+	committed  bool
+}
+
+type fakeResult struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+	used    bool
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+	inTx  bool
+}
+
+// newFakeDB returns a *sql.DB answering queries with results.
+func newFakeDB(t *testing.T, results ...*fakeResult) (*sql.DB, *fakeDB) {
+	fake := &fakeDB{results: results}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// rows returns a fakeResult answering the first query containing match.
+func rows(match string, columns []string, values ...[]driver.Value) *fakeResult {
+	return &fakeResult{match: match, columns: columns, rows: values}
+}
+
+// executed returns the recorded statements containing match.
+func (f *fakeDB) executed(match string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var matched []fakeStatement
+	for _, statement := range f.statements {
+		if strings.Contains(statement.query, match) {
+			matched = append(matched, statement)
+		}
+	}
+	return matched
+}
+
+func (f *fakeDB) record(query string, args []driver.NamedValue) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.statements = append(f.statements, fakeStatement{query: query, args: values, inTx: f.inTx})
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("fakedb: prepare") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.inTx = true
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.inTx, c.db.committed = false, true
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.inTx = false
+	return nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.record(query, args)
+	for _, result := range c.db.results {
+		if !result.used && strings.Contains(query, result.match) {
+			result.used = true
+			return &fakeRows{columns: result.columns, rows: result.rows}, nil
+		}
+	}
+	return nil, errors.New("fakedb: no result for query: " + query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// importInterrupted is the error of jobs whose worker stopped reporting.
+const importInterrupted = "import interrupted, the rows without a result may have been created"
+
+type ImportRepo struct {
+	db *sql.DB
+}
+
+func NewImportRepo(db *sql.DB) *ImportRepo {
+	return &ImportRepo{
+		db: db,
+	}
+}
+
+// Create persists a job with the rows it imports and populates its
+// timestamps. The rows are kept until the job finishes.
+func (r *ImportRepo) Create(ctx context.Context, job *domain.ImportJob, rows []domain.ImportRow) (*domain.ImportJob, error) {
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        INSERT INTO import_jobs (id, status, format, mode, dry_run, total, rows, request_id, actor)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''))
+        RETURNING created_at, updated_at
+    `
+
+	err = r.db.QueryRowContext(ctx, query, job.ID, job.Status, job.Format, job.Mode, job.DryRun,
+		job.Total, payload, job.RequestID, job.Actor).Scan(&job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Results = []domain.ImportResult{}
+	return job, nil
+}
+
+// Claim starts the oldest queued job and returns it with its rows, or
+// domain.ErrImportJobNotFound when no job is queued. Concurrent workers claim
+// different jobs.
+//
+// Running jobs that have not reported progress for staleAfter are failed
+// first: their worker stopped, and resuming them could create orders twice.
+func (r *ImportRepo) Claim(ctx context.Context, staleAfter time.Duration) (*domain.ImportJob, []domain.ImportRow, error) {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE import_jobs
+        SET status = $1, error = $2, rows = NULL, updated_at = NOW(), finished_at = NOW()
+        WHERE status = $3 AND updated_at < NOW() - make_interval(secs => $4)
+    `, domain.ImportStatusFailed, importInterrupted, domain.ImportStatusRunning, staleAfter.Seconds())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+        UPDATE import_jobs SET status = $1, updated_at = NOW()
+        WHERE id = (
+            SELECT id FROM import_jobs
+            WHERE status = $2
+            ORDER BY created_at, id
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + importJobColumns + `, rows
+    `
+
+	var payload []byte
+	job, err := scanImportJob(r.db.QueryRowContext(ctx, query, domain.ImportStatusRunning, domain.ImportStatusQueued), &payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []domain.ImportRow
+	if err := json.Unmarshal(payload, &rows); err != nil {
+		return nil, nil, err
+	}
+
+	return job, rows, nil
+}
+
+// RecordResults stores the results of rows of a running job and adds them to
+// its progress.
+func (r *ImportRepo) RecordResults(ctx context.Context, id string, results []domain.ImportResult) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	succeeded, failed := 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case domain.ImportRowCreated, domain.ImportRowValid:
+			succeeded++
+		default:
+			failed++
+		}
+
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO import_results (job_id, line, ref, status, order_id, price, vat, error)
+            VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, 0), $6, $7, NULLIF($8, ''))
+        `, id, result.Line, result.Ref, result.Status, result.OrderID, result.Price, result.VAT, result.Error)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE import_jobs
+        SET processed = processed + $2, succeeded = succeeded + $3, failed = failed + $4, updated_at = NOW()
+        WHERE id = $1
+    `, id, len(results), succeeded, failed)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Finish ends a job with the given status and error message and drops its
+// rows.
+func (r *ImportRepo) Finish(ctx context.Context, id, status, message string) error {
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE import_jobs
+        SET status = $2, error = NULLIF($3, ''), rows = NULL, updated_at = NOW(), finished_at = NOW()
+        WHERE id = $1
+    `, id, status, message)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrImportJobNotFound
+	}
+	return nil
+}
+
+// GetByID returns a job with the results recorded so far, in file order.
+func (r *ImportRepo) GetByID(ctx context.Context, id string) (*domain.ImportJob, error) {
+	query := `SELECT ` + importJobColumns + ` FROM import_jobs WHERE id = $1`
+
+	job, err := scanImportJob(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT line, COALESCE(ref, ''), status, COALESCE(order_id, 0),
+               COALESCE(price, 0), COALESCE(vat, 0), COALESCE(error, '')
+        FROM import_results
+        WHERE job_id = $1
+        ORDER BY line
+    `, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result domain.ImportResult
+		if err := rows.Scan(&result.Line, &result.Ref, &result.Status, &result.OrderID,
+			&result.Price, &result.VAT, &result.Error); err != nil {
+			return nil, err
+		}
+		job.Results = append(job.Results, result)
+	}
+
+	return job, rows.Err()
+}
+
+// importJobColumns are the columns scanned by scanImportJob.
+const importJobColumns = `id, status, format, mode, dry_run, total, processed, succeeded, failed,
+        COALESCE(error, ''), COALESCE(request_id, ''), COALESCE(actor, ''), created_at, updated_at, finished_at`
+
+// scanImportJob scans the importJobColumns of a job, followed by extra.
+func scanImportJob(row *sql.Row, extra ...any) (*domain.ImportJob, error) {
+	job := &domain.ImportJob{Results: []domain.ImportResult{}}
+	var finishedAt sql.NullTime
+
+	dest := []any{
+		&job.ID, &job.Status, &job.Format, &job.Mode, &job.DryRun, &job.Total, &job.Processed,
+		&job.Succeeded, &job.Failed, &job.Error, &job.RequestID, &job.Actor, &job.CreatedAt, &job.UpdatedAt, &finishedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrImportJobNotFound
+		}
+		return nil, err
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+
+	return job, nil
+}
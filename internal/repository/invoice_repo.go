@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type InvoiceRepo struct {
+	db *sql.DB
+}
+
+func NewInvoiceRepo(db *sql.DB) *InvoiceRepo {
+	return &InvoiceRepo{db: db}
+}
+
+type queryer interface {
+	rowQuerier
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// GetByOrder returns the invoice of an order with its lines.
+func (r *InvoiceRepo) GetByOrder(ctx context.Context, orderID int64) (*domain.Invoice, error) {
+	return getInvoice(ctx, r.db, orderID)
+}
+
+// Issue issues the invoice of an order, numbering it with the next number
+// of invoice.FiscalYear. The number is taken in the same transaction that
+// records the invoice, so a failed issue leaves no gap, and concurrent
+// issues wait for each other on the fiscal year's sequence.
+//
+// The order is locked while the invoice is issued: if another request
+// issued it meanwhile, that invoice is returned. It returns
+// domain.ErrOrderNotInvoiceable unless the order has been paid: it is paid,
+// shipped, partially shipped or refunded.
+func (r *InvoiceRepo) Issue(ctx context.Context, invoice *domain.Invoice) (*domain.Invoice, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockOrder(ctx, tx, invoice.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := getInvoice(ctx, tx, invoice.OrderID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, domain.ErrInvoiceNotFound) {
+		return nil, err
+	}
+	if !domain.IsPaidStatus(status) && status != domain.OrderStatusRefunded {
+		return nil, domain.ErrOrderNotInvoiceable
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO invoice_sequences (fiscal_year, last_number) VALUES ($1, 1)
+        ON CONFLICT (fiscal_year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+        RETURNING last_number
+    `, invoice.FiscalYear).Scan(&invoice.Sequence)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Number = fmt.Sprintf("%d-%06d", invoice.FiscalYear, invoice.Sequence)
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO invoices (order_id, fiscal_year, sequence, number, customer_id, currency, price, vat, issued_at)
+        SELECT id, $2, $3, $4, customer_id, $5, price, vat, $6 FROM orders WHERE id = $1
+        RETURNING id
+    `, invoice.OrderID, invoice.FiscalYear, invoice.Sequence, invoice.Number, invoice.Currency, invoice.IssuedAt,
+	).Scan(&invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the lines, so that the invoice never changes once issued
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO invoice_lines (invoice_id, product_id, description, quantity, price, vat)
+        SELECT $1, oi.product_id, p.name, oi.quantity, oi.price, oi.vat
+        FROM order_items oi
+        JOIN products p ON p.id = oi.product_id
+        WHERE oi.order_id = $2
+        ORDER BY oi.id
+    `, invoice.ID, invoice.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	issued, err := getInvoice(ctx, tx, invoice.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return issued, tx.Commit()
+}
+
+// getInvoice loads the invoice of an order with the lines copied when it
+// was issued.
+func getInvoice(ctx context.Context, q queryer, orderID int64) (*domain.Invoice, error) {
+	var invoice domain.Invoice
+	err := q.QueryRowContext(ctx, `
+        SELECT id, number, fiscal_year, sequence, order_id, COALESCE(customer_id, ''), currency, price, vat, issued_at
+        FROM invoices
+        WHERE order_id = $1
+    `, orderID).Scan(
+		&invoice.ID,
+		&invoice.Number,
+		&invoice.FiscalYear,
+		&invoice.Sequence,
+		&invoice.OrderID,
+		&invoice.CustomerID,
+		&invoice.Currency,
+		&invoice.Price,
+		&invoice.VAT,
+		&invoice.IssuedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+        SELECT product_id, description, quantity, price, vat
+        FROM invoice_lines
+        WHERE invoice_id = $1
+        ORDER BY id
+    `, invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line domain.InvoiceLine
+		if err := rows.Scan(&line.ProductID, &line.Description, &line.Quantity, &line.Price, &line.VAT); err != nil {
+			return nil, err
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+
+	return &invoice, rows.Err()
+}
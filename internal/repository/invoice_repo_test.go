@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+var invoiceColumnNames = []string{"id", "number", "fiscal_year", "sequence", "order_id", "customer_id", "currency", "price", "vat", "issued_at"}
+
+var invoiceLineColumnNames = []string{"product_id", "description", "quantity", "price", "vat"}
+
+func TestInvoiceRepoIssue(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	issuedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db, fake := newFakeDB(t,
+		rows("price + vat FROM orders", []string{"status", "total"}, []driver.Value{domain.OrderStatusPaid, 30.0}),
+		rows("FROM invoices", invoiceColumnNames),
+		rows("INSERT INTO invoice_sequences", []string{"last_number"}, []driver.Value{int64(42)}),
+		rows("INSERT INTO invoices", []string{"id"}, []driver.Value{int64(5)}),
+		rows("FROM invoices", invoiceColumnNames,
+			[]driver.Value{int64(5), "2024-000042", int64(2024), int64(42), int64(1), "", "EUR", 27.27, 2.73, issuedAt}),
+		rows("FROM invoice_lines", invoiceLineColumnNames, []driver.Value{int64(5), "Widget", int64(1), 27.27, 2.73}),
+	)
+	repo := NewInvoiceRepo(db)
+
+	invoice, err := repo.Issue(ctx, &domain.Invoice{OrderID: 1, FiscalYear: 2024, Currency: "EUR", IssuedAt: issuedAt})
+
+	// Assertions
+	require.NoError(t, err)
+	assert.Equal(t, "2024-000042", invoice.Number)
+	require.Len(t, invoice.Lines, 1)
+	assert.Equal(t, "Widget", invoice.Lines[0].Description)
+	assert.True(t, fake.committed)
+
+	copied := fake.executed("INSERT INTO invoice_lines")
+	require.Len(t, copied, 1)
+	assert.True(t, copied[0].inTx)
+	assert.Equal(t, []driver.Value{int64(5), int64(1)}, copied[0].args)
+
+	// Lines are rendered from the copy, never from the products
+	for _, statement := range fake.executed("FROM invoice_lines") {
+		assert.NotContains(t, statement.query, "products")
+	}
+}
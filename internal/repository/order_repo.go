@@ -5,10 +5,23 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 
+	"github.com/lib/pq"
 	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
 )
 
+// uniqueViolation is the PostgreSQL error code of unique constraint violations.
+const uniqueViolation = "23505"
+
+// refundedTotals selects the price and VAT of the issued and partially
+// issued credit notes of the order aliased o.
+const refundedTotals = `
+               COALESCE((SELECT SUM(cn.price) FROM credit_notes cn WHERE cn.order_id = o.id AND cn.status IN ('issued', 'partially_issued')), 0),
+               COALESCE((SELECT SUM(cn.vat) FROM credit_notes cn WHERE cn.order_id = o.id AND cn.status IN ('issued', 'partially_issued')), 0)`
+
 type OrderRepo struct {
 	db *sql.DB
 }
@@ -23,7 +36,9 @@ func NewOrderRepo(db *sql.DB) *OrderRepo {
 // The method:
 // 1. Inserts the order record and retrieves its generated ID and creation timestamp
 // 2. Inserts all associated order items using the newly generated order ID
-// 3. Commits the transaction if everything succeeds
+// 3. For a cart checkout, marks the cart as checked out
+// 4. Records an order.created event in the outbox and an entry in the audit log
+// 5. Commits the transaction if everything succeeds
 //
 // Parameters:
 //   - ctx: The context for database operations, allows for cancellation and timeouts
@@ -33,7 +48,9 @@ func NewOrderRepo(db *sql.DB) *OrderRepo {
 //   - A pointer to the domain.Order with ID and CreatedAt populated from the database
 //   - An error if any database operation fails
 //
-// The method will roll back the transaction on any error.
+// The method will roll back the transaction on any error. It returns
+// domain.ErrCartCheckedOut if the cart being checked out is no longer open,
+// so that a cart is converted into at most one order.
 func (r *OrderRepo) Create(ctx context.Context, order *domain.Order) (*domain.Order, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -41,22 +58,75 @@ func (r *OrderRepo) Create(ctx context.Context, order *domain.Order) (*domain.Or
 	}
 	defer tx.Rollback()
 
+	if err = insertOrder(ctx, tx, order); err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		logging.FromContext(ctx).Debug("commit order failed", "order_id", order.ID, "error", err)
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Debug("order persisted", "order_id", order.ID, "items", len(order.Items))
+
+	return order, nil
+}
+
+// CreateBatch persists several orders in a single transaction, like Create
+// does for one: either all of them are created or none is.
+func (r *OrderRepo) CreateBatch(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, order := range orders {
+		if err = insertOrder(ctx, tx, order); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logging.FromContext(ctx).Debug("commit order batch failed", "orders", len(orders), "error", err)
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Debug("order batch persisted", "orders", len(orders))
+
+	return orders, nil
+}
+
+// insertOrder inserts an order with its items, its OrderCreated outbox event
+// and its audit entry, closing the cart it checks out if any.
+func insertOrder(ctx context.Context, tx *sql.Tx, order *domain.Order) error {
 	// Insert the order
 	query := `
-        INSERT INTO orders (price, vat, created_at)
-        VALUES ($1, $2, NOW())
-        RETURNING id, created_at
+        INSERT INTO orders (price, vat, request_id, customer_id, status, cart_id, created_at)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, NULLIF($6, ''), NOW())
+        RETURNING id, version, created_at
     `
 
-	err = tx.QueryRowContext(
+	err := tx.QueryRowContext(
 		ctx,
 		query,
 		order.Price,
 		order.VAT,
-	).Scan(&order.ID, &order.CreatedAt)
+		order.RequestID,
+		order.CustomerID,
+		order.Status,
+		order.CartID,
+	).Scan(&order.ID, &order.Version, &order.CreatedAt)
 
 	if err != nil {
-		return nil, err
+		logging.FromContext(ctx).Debug("insert order failed", "error", err)
+		// A concurrent checkout of the same cart committed first
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == "orders_cart_id_key" {
+			return domain.ErrCartCheckedOut
+		}
+		return err
 	}
 
 	// Insert order items
@@ -77,19 +147,48 @@ func (r *OrderRepo) Create(ctx context.Context, order *domain.Order) (*domain.Or
 		)
 
 		if err != nil {
-			return nil, err
+			logging.FromContext(ctx).Debug("insert order item failed",
+				"order_id", order.ID, "product_id", item.ProductID, "error", err)
+			return err
 		}
 
 		// Update the item in our order with the database values
 		order.Items[i] = item
 	}
 
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		return nil, err
+	// Close the cart being checked out; the row lock makes concurrent
+	// checkouts of the same cart wait and then fail
+	if order.CartID != "" {
+		result, err := tx.ExecContext(ctx, `
+            UPDATE carts SET status = $2, order_id = $3, updated_at = NOW()
+            WHERE id = $1 AND status = $4 AND expires_at > NOW()
+        `, order.CartID, domain.CartStatusCheckedOut, order.ID, domain.CartStatusOpen)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return domain.ErrCartCheckedOut
+		}
 	}
 
-	return order, nil
+	// Record the OrderCreated event in the same transaction
+	event, err := domain.NewOrderEvent(domain.EventOrderCreated, order)
+	if err != nil {
+		return err
+	}
+	if err = insertOutboxEvent(ctx, tx, event); err != nil {
+		logging.FromContext(ctx).Debug("insert outbox event failed", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	if err = insertAuditEntry(ctx, tx, domain.AuditEntityOrder, order.ID, domain.AuditActionOrderCreated, nil, order); err != nil {
+		logging.FromContext(ctx).Debug("insert audit entry failed", "order_id", order.ID, "error", err)
+		return err
+	}
+
+	return nil
 }
 
 // GetByID retrieves an order by its ID from the database.
@@ -108,15 +207,27 @@ func (r *OrderRepo) Create(ctx context.Context, order *domain.Order) (*domain.Or
 //   - Returns "order not found" error if no order exists with the given ID
 //   - Returns unmarshaling errors if the JSON data for items is malformed
 func (r *OrderRepo) GetByID(ctx context.Context, id int64) (*domain.Order, error) {
+	return getOrder(ctx, r.db, id)
+}
+
+// rowQuerier is implemented by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// getOrder loads an order with its items, in the order they were inserted.
+func getOrder(ctx context.Context, q rowQuerier, id int64) (*domain.Order, error) {
 	query := `
-        SELECT o.id, o.price, o.vat, o.created_at, 
+        SELECT o.id, COALESCE(o.customer_id, ''), o.status, o.price, o.vat,
+               COALESCE(o.request_id, ''), o.version, o.created_at,
+               ` + refundedTotals + `,
                COALESCE(json_agg(
                    json_build_object(
                        'product_id', oi.product_id,
                        'quantity', oi.quantity,
                        'price', oi.price,
                        'vat', oi.vat
-                   )
+                   ) ORDER BY oi.id
                ) FILTER (WHERE oi.id IS NOT NULL), '[]') as items
         FROM orders o
         LEFT JOIN order_items oi ON o.id = oi.order_id
@@ -127,17 +238,23 @@ func (r *OrderRepo) GetByID(ctx context.Context, id int64) (*domain.Order, error
 	var order domain.Order
 	var itemsJSON string
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := q.QueryRowContext(ctx, query, id).Scan(
 		&order.ID,
+		&order.CustomerID,
+		&order.Status,
 		&order.Price,
 		&order.VAT,
+		&order.RequestID,
+		&order.Version,
 		&order.CreatedAt,
+		&order.RefundedPrice,
+		&order.RefundedVAT,
 		&itemsJSON,
 	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("order not found")
+			return nil, domain.ErrOrderNotFound
 		}
 		return nil, err
 	}
@@ -149,3 +266,349 @@ func (r *OrderRepo) GetByID(ctx context.Context, id int64) (*domain.Order, error
 
 	return &order, nil
 }
+
+// listOrders selects the orders matching a listing filter, ordered by
+// ascending ID, with their items: orders after ID $1 of customer $2 and in
+// status $3, where empty values match every order, limited to $4 orders.
+const listOrders = `
+        SELECT o.id, COALESCE(o.customer_id, ''), o.status, o.price, o.vat,
+               COALESCE(o.request_id, ''), o.version, o.created_at,
+               ` + refundedTotals + `,
+               COALESCE(json_agg(
+                   json_build_object(
+                       'product_id', oi.product_id,
+                       'quantity', oi.quantity,
+                       'price', oi.price,
+                       'vat', oi.vat
+                   ) ORDER BY oi.id
+               ) FILTER (WHERE oi.id IS NOT NULL), '[]') as items
+        FROM orders o
+        LEFT JOIN order_items oi ON o.id = oi.order_id
+        WHERE o.id > $1
+          AND ($2 = '' OR o.customer_id = $2)
+          AND ($3 = '' OR o.status = $3)
+        GROUP BY o.id
+        ORDER BY o.id
+        LIMIT $4
+    `
+
+// exportFetchSize is the number of orders fetched from the export cursor at once.
+const exportFetchSize = 500
+
+// List retrieves the orders matching filter, ordered by ascending ID, with
+// their items. Empty filter fields match every order and at most filter.Limit
+// orders are returned.
+func (r *OrderRepo) List(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, error) {
+	rows, err := r.db.QueryContext(ctx, listOrders, filter.AfterID, filter.CustomerID, filter.Status, filter.Limit)
+	if err != nil {
+		logging.FromContext(ctx).Debug("list orders failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []domain.Order{}
+	for rows.Next() {
+		order, err := scanListedOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+
+	return orders, rows.Err()
+}
+
+// Export calls fn with every order matching filter, in the order and with
+// the filters of List, except that a zero limit exports every order. The
+// orders are read from a server-side cursor in batches, so memory use does
+// not grow with the number of orders, and all of them come from the same
+// snapshot. Export stops at the first error returned by fn.
+func (r *OrderRepo) Export(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.Order) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	limit := sql.NullInt64{Int64: int64(filter.Limit), Valid: filter.Limit > 0}
+	_, err = tx.ExecContext(ctx, `DECLARE export_orders NO SCROLL CURSOR FOR `+listOrders,
+		filter.AfterID, filter.CustomerID, filter.Status, limit)
+	if err != nil {
+		logging.FromContext(ctx).Debug("declare export cursor failed", "error", err)
+		return err
+	}
+
+	for {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`FETCH %d FROM export_orders`, exportFetchSize))
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+		for rows.Next() {
+			order, err := scanListedOrder(rows)
+			if err == nil {
+				err = fn(order)
+			}
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		if fetched < exportFetchSize {
+			return tx.Commit()
+		}
+	}
+}
+
+// scanListedOrder scans an order selected by listOrders.
+func scanListedOrder(rows *sql.Rows) (*domain.Order, error) {
+	var order domain.Order
+	var itemsJSON string
+
+	if err := rows.Scan(
+		&order.ID,
+		&order.CustomerID,
+		&order.Status,
+		&order.Price,
+		&order.VAT,
+		&order.RequestID,
+		&order.Version,
+		&order.CreatedAt,
+		&order.RefundedPrice,
+		&order.RefundedVAT,
+		&itemsJSON,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(itemsJSON), &order.Items); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// Update modifies an order in a transaction, provided it is still at the
+// given version. The version is compared and incremented first, which also
+// locks the order, so concurrent updates made against the same version
+// cannot overwrite each other: all but the first fail with
+// domain.ErrVersionMismatch.
+//
+// update receives the current order and changes its items, price and VAT in
+// place; returning an error aborts the transaction, as does a new total,
+// Price plus VAT, below the payments pending, authorized or captured on the
+// order, which fails with domain.ErrOrderNotEditable. The returned revision is
+// recorded in the order's history with the next revision number, and an
+// order.updated event is written to the outbox and the changed fields to the
+// audit log in the same transaction.
+//
+// It returns domain.ErrOrderNotFound if the order does not exist.
+func (r *OrderRepo) Update(ctx context.Context, id int64, version int, update func(order *domain.Order) (*domain.OrderRevision, error)) (*domain.Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Compare and swap the version
+	result, err := tx.ExecContext(ctx, `
+        UPDATE orders SET version = version + 1, updated_at = NOW() WHERE id = $1 AND version = $2
+    `, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, domain.ErrOrderNotFound
+		}
+		return nil, domain.ErrVersionMismatch
+	}
+
+	order, err := getOrder(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The order as it was, for the audit log
+	before := *order
+	before.Version = version
+	before.Items = slices.Clone(order.Items)
+
+	revision, err := update(order)
+	if err != nil {
+		return nil, err
+	}
+
+	// The payments reserved on the order must stay within its new total
+	var reserved float64
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(amount), 0) FROM payments WHERE order_id = $1 AND status IN ($2, $3, $4)
+    `, id, domain.PaymentStatusPending, domain.PaymentStatusAuthorized, domain.PaymentStatusCaptured).Scan(&reserved)
+	if err != nil {
+		return nil, err
+	}
+	if domain.Cents(reserved) > domain.Cents(order.Price)+domain.Cents(order.VAT) {
+		return nil, fmt.Errorf("%w: its payments of %.2f exceed the new total", domain.ErrOrderNotEditable, reserved)
+	}
+
+	// Replace the items
+	if _, err := tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = $1`, id); err != nil {
+		return nil, err
+	}
+	for _, item := range order.Items {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO order_items (order_id, product_id, quantity, price, vat)
+            VALUES ($1, $2, $3, $4, $5)
+        `, id, item.ProductID, item.Quantity, item.Price, item.VAT)
+		if err != nil {
+			logging.FromContext(ctx).Debug("insert order item failed",
+				"order_id", id, "product_id", item.ProductID, "error", err)
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE orders SET price = $2, vat = $3, updated_at = NOW() WHERE id = $1
+    `, id, order.Price, order.VAT)
+	if err != nil {
+		return nil, err
+	}
+
+	if revision != nil {
+		revision.Version = order.Version
+		if err := insertRevision(ctx, tx, id, revision); err != nil {
+			logging.FromContext(ctx).Debug("insert order revision failed", "order_id", id, "error", err)
+			return nil, err
+		}
+	}
+
+	// Record the OrderUpdated event in the same transaction
+	event, err := domain.NewOrderEvent(domain.EventOrderUpdated, order)
+	if err != nil {
+		return nil, err
+	}
+	if err = insertOutboxEvent(ctx, tx, event); err != nil {
+		logging.FromContext(ctx).Debug("insert outbox event failed", "order_id", id, "error", err)
+		return nil, err
+	}
+
+	if err = insertAuditEntry(ctx, tx, domain.AuditEntityOrder, id, domain.AuditActionOrderUpdated, &before, order); err != nil {
+		logging.FromContext(ctx).Debug("insert audit entry failed", "order_id", id, "error", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logging.FromContext(ctx).Debug("commit order update failed", "order_id", id, "error", err)
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// insertRevision records revision with the next revision number of the
+// order and populates its number and creation time. The caller holds the
+// order's row lock, so revision numbers are gap-free per order.
+func insertRevision(ctx context.Context, tx *sql.Tx, orderID int64, revision *domain.OrderRevision) error {
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return err
+	}
+	before, err := json.Marshal(revision.ItemsBefore)
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(revision.ItemsAfter)
+	if err != nil {
+		return err
+	}
+
+	return tx.QueryRowContext(ctx, `
+        INSERT INTO order_revisions (order_id, revision, version, changes, items_before, items_after,
+                                     price_before, vat_before, price_after, vat_after, request_id)
+        SELECT $1, COALESCE(MAX(revision), 0) + 1, $10, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')
+        FROM order_revisions WHERE order_id = $1
+        RETURNING revision, created_at
+    `,
+		orderID,
+		string(changes),
+		string(before),
+		string(after),
+		revision.PriceBefore,
+		revision.VATBefore,
+		revision.PriceAfter,
+		revision.VATAfter,
+		revision.RequestID,
+		revision.Version,
+	).Scan(&revision.Revision, &revision.CreatedAt)
+}
+
+// ListRevisions returns the revision history of an order, oldest first.
+// It returns domain.ErrOrderNotFound if the order does not exist.
+func (r *OrderRepo) ListRevisions(ctx context.Context, orderID int64) ([]domain.OrderRevision, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT revision, COALESCE(version, 0), changes, items_before, items_after,
+               price_before, vat_before, price_after, vat_after,
+               COALESCE(request_id, ''), created_at
+        FROM order_revisions
+        WHERE order_id = $1
+        ORDER BY revision
+    `, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []domain.OrderRevision{}
+	for rows.Next() {
+		var revision domain.OrderRevision
+		var changes, before, after string
+		if err := rows.Scan(
+			&revision.Revision,
+			&revision.Version,
+			&changes,
+			&before,
+			&after,
+			&revision.PriceBefore,
+			&revision.VATBefore,
+			&revision.PriceAfter,
+			&revision.VATAfter,
+			&revision.RequestID,
+			&revision.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(changes), &revision.Changes); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(before), &revision.ItemsBefore); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(after), &revision.ItemsAfter); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+func TestOrderRepoUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	// halve drops the order total from 30.00 to 15.00
+	halve := func(order *domain.Order) (*domain.OrderRevision, error) {
+		order.Price, order.VAT = 13.64, 1.36
+		return nil, nil
+	}
+
+	t.Run("Total below the reserved payments", func(t *testing.T) {
+		// Setup
+		db, fake := newFakeDB(t,
+			rows("GROUP BY o.id", orderColumnNames, orderRow(domain.OrderStatusPending, 2)),
+			rows("FROM payments", []string{"reserved"}, []driver.Value{15.01}),
+		)
+		repo := NewOrderRepo(db)
+
+		_, err := repo.Update(ctx, 1, 1, halve)
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrOrderNotEditable)
+		assert.False(t, fake.committed)
+		assert.Empty(t, fake.executed("DELETE FROM order_items"))
+	})
+
+	t.Run("Total covering the reserved payments", func(t *testing.T) {
+		// Setup
+		db, fake := newFakeDB(t,
+			rows("GROUP BY o.id", orderColumnNames, orderRow(domain.OrderStatusPending, 2)),
+			rows("FROM payments", []string{"reserved"}, []driver.Value{15.0}),
+			rows("INSERT INTO outbox", []string{"id", "created_at"}, []driver.Value{int64(1), time.Now()}),
+		)
+		repo := NewOrderRepo(db)
+
+		order, err := repo.Update(ctx, 1, 1, halve)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, 13.64, order.Price)
+		assert.True(t, fake.committed)
+	})
+}
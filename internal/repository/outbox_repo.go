@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type OutboxRepo struct {
+	db *sql.DB
+}
+
+func NewOutboxRepo(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+// insertOutboxEvent records an event in the outbox as part of tx, so the event
+// is stored if and only if the change that produced it is committed.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, event *domain.Event) error {
+	query := `
+        INSERT INTO outbox (event_type, aggregate_type, aggregate_id, payload, request_id)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+        RETURNING id, created_at
+    `
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		event.Type,
+		event.AggregateType,
+		event.AggregateID,
+		string(event.Payload),
+		event.RequestID,
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+// ProcessBatch locks up to limit unpublished events, oldest first, and passes
+// them to publish one at a time. Events that were published successfully are
+// marked as such when the batch transaction commits.
+//
+// Rows are locked with FOR UPDATE SKIP LOCKED, so several relays can run
+// concurrently without publishing the same event twice at the same time.
+// Processing stops at the first publish error to preserve event order; the
+// failure is recorded on the event and returned, and the event is retried on
+// the next batch.
+//
+// Returns the number of events published.
+func (r *OutboxRepo) ProcessBatch(ctx context.Context, limit int, publish func(context.Context, domain.Event) error) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        SELECT id, event_type, aggregate_type, aggregate_id, payload, COALESCE(request_id, ''), created_at
+        FROM outbox
+        WHERE published_at IS NULL
+        ORDER BY id
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    `
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var events []domain.Event
+	for rows.Next() {
+		var event domain.Event
+		var payload []byte
+		if err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.AggregateType,
+			&event.AggregateID,
+			&payload,
+			&event.RequestID,
+			&event.CreatedAt,
+		); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var publishErr error
+	for _, event := range events {
+		if publishErr = publish(ctx, event); publishErr != nil {
+			_, err := tx.ExecContext(ctx,
+				`UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`,
+				event.ID, publishErr.Error())
+			if err != nil {
+				return 0, err
+			}
+			publishErr = fmt.Errorf("failed to publish event %d: %w", event.ID, publishErr)
+			break
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`UPDATE outbox SET published_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1`,
+			event.ID)
+		if err != nil {
+			return 0, err
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return published, publishErr
+}
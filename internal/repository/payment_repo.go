@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type PaymentRepo struct {
+	db *sql.DB
+}
+
+func NewPaymentRepo(db *sql.DB) *PaymentRepo {
+	return &PaymentRepo{db: db}
+}
+
+const paymentColumns = `
+    id, order_id, amount, status, provider, COALESCE(reference, ''),
+    COALESCE(failure_reason, ''), COALESCE(request_id, ''), created_at, updated_at
+`
+
+func scanPayment(row interface{ Scan(...any) error }) (*domain.Payment, error) {
+	var payment domain.Payment
+	err := row.Scan(
+		&payment.ID,
+		&payment.OrderID,
+		&payment.Amount,
+		&payment.Status,
+		&payment.Provider,
+		&payment.Reference,
+		&payment.FailureReason,
+		&payment.RequestID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// lockOrder locks an order row for the rest of the transaction and returns
+// its status and total. It returns domain.ErrOrderNotFound if it does not exist.
+func lockOrder(ctx context.Context, tx *sql.Tx, orderID int64) (status string, total float64, err error) {
+	err = tx.QueryRowContext(ctx, `
+        SELECT status, price + vat FROM orders WHERE id = $1 FOR UPDATE
+    `, orderID).Scan(&status, &total)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, domain.ErrOrderNotFound
+	}
+	return status, total, err
+}
+
+// lockPayment locks a payment row for the rest of the transaction and
+// returns it as it is before the change, for the audit log. It returns
+// sql.ErrNoRows if the payment does not exist.
+func lockPayment(ctx context.Context, tx *sql.Tx, id int64) (*domain.Payment, error) {
+	return scanPayment(tx.QueryRowContext(ctx, `
+        SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE
+    `, id))
+}
+
+// Create records a pending payment for an order. The order is locked while
+// its balance is checked, so concurrent payments can never together exceed
+// the order total: the payments that are pending, authorized or captured
+// plus the new one must not exceed Price plus VAT. The payment is recorded
+// in the audit log in the same transaction.
+//
+// It returns domain.ErrOrderNotFound for unknown orders,
+// domain.ErrOrderNotPayable if the order is already paid and
+// domain.ErrPaymentExceedsBalance if the amount exceeds the balance.
+func (r *PaymentRepo) Create(ctx context.Context, payment *domain.Payment) (*domain.Payment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, total, err := lockOrder(ctx, tx, payment.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if status != domain.OrderStatusPending && status != domain.OrderStatusPartiallyPaid {
+		return nil, domain.ErrOrderNotPayable
+	}
+
+	var reserved float64
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(amount), 0) FROM payments WHERE order_id = $1 AND status IN ($2, $3, $4)
+    `, payment.OrderID, domain.PaymentStatusPending, domain.PaymentStatusAuthorized, domain.PaymentStatusCaptured).Scan(&reserved)
+	if err != nil {
+		return nil, err
+	}
+	if domain.Cents(reserved)+domain.Cents(payment.Amount) > domain.Cents(total) {
+		return nil, domain.ErrPaymentExceedsBalance
+	}
+
+	created, err := scanPayment(tx.QueryRowContext(ctx, `
+        INSERT INTO payments (order_id, amount, status, provider, request_id)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+        RETURNING `+paymentColumns,
+		payment.OrderID, payment.Amount, payment.Status, payment.Provider, payment.RequestID,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if err := insertAuditEntry(ctx, tx, domain.AuditEntityPayment, created.ID, domain.AuditActionPaymentCreated, nil, created); err != nil {
+		return nil, err
+	}
+
+	return created, tx.Commit()
+}
+
+// GetByID returns a payment of an order.
+func (r *PaymentRepo) GetByID(ctx context.Context, orderID, id int64) (*domain.Payment, error) {
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, `
+        SELECT `+paymentColumns+` FROM payments WHERE id = $1 AND order_id = $2
+    `, id, orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrPaymentNotFound
+	}
+	return payment, err
+}
+
+// ListByOrder returns the payments of an order, oldest first.
+func (r *PaymentRepo) ListByOrder(ctx context.Context, orderID int64) ([]domain.Payment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+paymentColumns+` FROM payments WHERE order_id = $1 ORDER BY id
+    `, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []domain.Payment{}
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, *payment)
+	}
+
+	return payments, rows.Err()
+}
+
+// Transition moves a payment from one status to another, recording the
+// gateway reference and failure reason when they are set. The status is
+// compared and swapped, so of two concurrent transitions from the same
+// status only one succeeds; the other gets domain.ErrPaymentStatus. The
+// change is recorded in the audit log in the same transaction.
+func (r *PaymentRepo) Transition(ctx context.Context, id int64, from, to, reference, failureReason string) (*domain.Payment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockPayment(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && before.Status != from {
+		return nil, domain.ErrPaymentStatus
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := scanPayment(tx.QueryRowContext(ctx, `
+        UPDATE payments
+        SET status = $2, reference = COALESCE(NULLIF($3, ''), reference),
+            failure_reason = NULLIF($4, ''), updated_at = NOW()
+        WHERE id = $1
+        RETURNING `+paymentColumns,
+		id, to, reference, failureReason,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if err := insertAuditEntry(ctx, tx, domain.AuditEntityPayment, id, domain.AuditActionPaymentUpdated, before, payment); err != nil {
+		return nil, err
+	}
+
+	return payment, tx.Commit()
+}
+
+// Capture marks an authorized payment as captured and, in the same
+// transaction, moves the order to partially_paid or, once the captured
+// payments net of their succeeded refunds cover Price plus VAT, to paid. The order version is incremented
+// and an order.updated or order.paid event is written to the outbox and the
+// changes of the payment and the order to the audit log.
+//
+// It returns the captured payment and the updated order,
+// domain.ErrOrderNotPayable unless the order is pending or partially_paid,
+// or domain.ErrPaymentStatus if the payment is not authorized.
+func (r *PaymentRepo) Capture(ctx context.Context, orderID, id int64) (*domain.Payment, *domain.Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	orderStatus, total, err := lockOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if orderStatus != domain.OrderStatusPending && orderStatus != domain.OrderStatusPartiallyPaid {
+		return nil, nil, domain.ErrOrderNotPayable
+	}
+
+	beforePayment, err := lockPayment(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && (beforePayment.OrderID != orderID || beforePayment.Status != domain.PaymentStatusAuthorized) {
+		return nil, nil, domain.ErrPaymentStatus
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payment, err := scanPayment(tx.QueryRowContext(ctx, `
+        UPDATE payments SET status = $2, updated_at = NOW() WHERE id = $1
+        RETURNING `+paymentColumns,
+		id, domain.PaymentStatusCaptured,
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := insertAuditEntry(ctx, tx, domain.AuditEntityPayment, id, domain.AuditActionPaymentUpdated, beforePayment, payment); err != nil {
+		return nil, nil, err
+	}
+
+	var captured, refunded float64
+	err = tx.QueryRowContext(ctx, `
+        SELECT
+            COALESCE((SELECT SUM(amount) FROM payments WHERE order_id = $1 AND status = $2), 0),
+            COALESCE((SELECT SUM(r.amount) FROM refunds r JOIN payments p ON p.id = r.payment_id
+                      WHERE p.order_id = $1 AND r.status = $3), 0)
+    `, orderID, domain.PaymentStatusCaptured, domain.RefundStatusSucceeded).Scan(&captured, &refunded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, eventType := domain.OrderStatusPartiallyPaid, domain.EventOrderUpdated
+	if domain.Cents(captured)-domain.Cents(refunded) >= domain.Cents(total) {
+		status, eventType = domain.OrderStatusPaid, domain.EventOrderPaid
+	}
+	before, err := getOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+        UPDATE orders SET status = $2, version = version + 1, updated_at = NOW() WHERE id = $1
+    `, orderID, status)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order, err := getOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event, err := domain.NewOrderEvent(eventType, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := insertOutboxEvent(ctx, tx, event); err != nil {
+		return nil, nil, err
+	}
+	if err := insertAuditEntry(ctx, tx, domain.AuditEntityOrder, orderID, domain.AuditActionOrderUpdated, before, order); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return payment, order, nil
+}
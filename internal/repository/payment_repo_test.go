@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+var paymentColumnNames = []string{"id", "order_id", "amount", "status", "provider", "reference", "failure_reason", "request_id", "created_at", "updated_at"}
+
+func paymentRow(status, reference string) []driver.Value {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []driver.Value{int64(3), int64(1), 22.0, status, "fake", reference, "", "", created, created}
+}
+
+func TestPaymentRepoTransition(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Audits the change", func(t *testing.T) {
+		// Setup
+		db, fake := newFakeDB(t,
+			rows("FOR UPDATE", paymentColumnNames, paymentRow(domain.PaymentStatusPending, "")),
+			rows("UPDATE payments", paymentColumnNames, paymentRow(domain.PaymentStatusAuthorized, "ref-1")),
+		)
+		repo := NewPaymentRepo(db)
+
+		payment, err := repo.Transition(ctx, 3, domain.PaymentStatusPending, domain.PaymentStatusAuthorized, "ref-1", "")
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.PaymentStatusAuthorized, payment.Status)
+		assert.True(t, fake.committed)
+
+		entries := fake.executed("INSERT INTO audit_log")
+		require.Len(t, entries, 1)
+		assert.True(t, entries[0].inTx)
+		assert.Equal(t, []driver.Value{domain.AuditEntityPayment, int64(3), domain.AuditActionPaymentUpdated},
+			entries[0].args[:3])
+		assert.JSONEq(t, `{"status": "pending"}`, string(entries[0].args[5].([]byte)))
+		assert.JSONEq(t, `{"status": "authorized", "reference": "ref-1"}`, string(entries[0].args[6].([]byte)))
+	})
+
+	t.Run("Wrong status", func(t *testing.T) {
+		// Setup
+		db, fake := newFakeDB(t, rows("FOR UPDATE", paymentColumnNames, paymentRow(domain.PaymentStatusFailed, "")))
+		repo := NewPaymentRepo(db)
+
+		_, err := repo.Transition(ctx, 3, domain.PaymentStatusPending, domain.PaymentStatusAuthorized, "ref-1", "")
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrPaymentStatus)
+		assert.Empty(t, fake.executed("UPDATE payments"))
+	})
+}
+
+var orderColumnNames = []string{"id", "customer_id", "status", "price", "vat", "request_id", "version", "created_at",
+	"refunded_price", "refunded_vat", "items"}
+
+func orderRow(status string, version int64) []driver.Value {
+	return []driver.Value{int64(1), "", status, 27.27, 2.73, "", version, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		0.0, 0.0, `[{"product_id": 5, "quantity": 1, "price": 27.27, "vat": 2.73}]`}
+}
+
+func TestPaymentRepoCapture(t *testing.T) {
+	ctx := context.Background()
+
+	// captureDB answers the queries of capturing a payment on an order
+	// totalling 30.00, with 52.00 captured and refunded refunded so far.
+	captureDB := func(t *testing.T, refunded float64) (*sql.DB, *fakeDB) {
+		return newFakeDB(t,
+			rows("price + vat FROM orders", []string{"status", "total"}, []driver.Value{domain.OrderStatusPartiallyPaid, 30.0}),
+			rows("FOR UPDATE", paymentColumnNames, paymentRow(domain.PaymentStatusAuthorized, "ref-1")),
+			rows("UPDATE payments", paymentColumnNames, paymentRow(domain.PaymentStatusCaptured, "ref-1")),
+			rows("FROM refunds r", []string{"captured", "refunded"}, []driver.Value{52.0, refunded}),
+			rows("GROUP BY o.id", orderColumnNames, orderRow(domain.OrderStatusPartiallyPaid, 2)),
+			rows("GROUP BY o.id", orderColumnNames, orderRow(domain.OrderStatusPartiallyPaid, 3)),
+			rows("INSERT INTO outbox", []string{"id", "created_at"}, []driver.Value{int64(1), time.Now()}),
+		)
+	}
+
+	t.Run("Captures net of refunds covering the total pay the order", func(t *testing.T) {
+		// Setup
+		db, fake := captureDB(t, 22.0)
+		repo := NewPaymentRepo(db)
+
+		_, _, err := repo.Capture(ctx, 1, 3)
+
+		// Assertions
+		require.NoError(t, err)
+		statusUpdates := fake.executed("UPDATE orders SET status")
+		require.Len(t, statusUpdates, 1)
+		assert.Equal(t, domain.OrderStatusPaid, statusUpdates[0].args[1])
+		assert.True(t, fake.committed)
+	})
+
+	t.Run("Refunded captures do not count toward paying the order", func(t *testing.T) {
+		// Setup
+		db, fake := captureDB(t, 22.01)
+		repo := NewPaymentRepo(db)
+
+		_, _, err := repo.Capture(ctx, 1, 3)
+
+		// Assertions
+		require.NoError(t, err)
+		statusUpdates := fake.executed("UPDATE orders SET status")
+		require.Len(t, statusUpdates, 1)
+		assert.Equal(t, domain.OrderStatusPartiallyPaid, statusUpdates[0].args[1])
+	})
+
+	for _, status := range []string{domain.OrderStatusPaid, domain.OrderStatusRefunded, domain.OrderStatusShipped} {
+		t.Run("Order "+status+" is not payable", func(t *testing.T) {
+			// Setup
+			db, fake := newFakeDB(t,
+				rows("price + vat FROM orders", []string{"status", "total"}, []driver.Value{status, 30.0}))
+			repo := NewPaymentRepo(db)
+
+			_, _, err := repo.Capture(ctx, 1, 3)
+
+			// Assertions
+			assert.ErrorIs(t, err, domain.ErrOrderNotPayable)
+			assert.Empty(t, fake.executed("UPDATE payments"))
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/lib/pq"
 	"github.com/valeriouberti/order-service-test/internal/domain"
 )
 
@@ -29,10 +30,38 @@ func (r *ProductRepo) GetByID(ctx context.Context, id int64) (*domain.Product, e
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("product not found")
+			return nil, domain.ErrProductNotFound
 		}
 		return nil, err
 	}
 
 	return &product, nil
 }
+
+// GetByIDs retrieves the products with the given IDs in a single query,
+// ordered by ID. IDs without a product are left out of the result.
+func (r *ProductRepo) GetByIDs(ctx context.Context, ids []int64) ([]domain.Product, error) {
+	query := `SELECT id, name, price, vat FROM products WHERE id = ANY($1) ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []domain.Product{}
+	for rows.Next() {
+		var product domain.Product
+		if err := rows.Scan(
+			&product.ID,
+			&product.Name,
+			&product.Price,
+			&product.VAT,
+		); err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+
+	return products, rows.Err()
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+// Amounts are summed in cents, as integers, so the reports add up exactly
+// like the order totals do.
+
+type ReportRepo struct {
+	db *sql.DB
+}
+
+func NewReportRepo(db *sql.DB) *ReportRepo {
+	return &ReportRepo{db: db}
+}
+
+// Sales aggregates the orders selected by filter per period, oldest period
+// first. Only periods with orders are returned; Period is the first day of
+// the period in the time zone of the filter. Refunds are the issued and
+// partially issued credit notes of the orders, whenever they were issued, so that the totals match
+// those of the orders. AverageOrderValue is left zero.
+func (r *ReportRepo) Sales(ctx context.Context, filter domain.ReportFilter) ([]domain.SalesPeriod, error) {
+	query := `
+        SELECT to_char(date_trunc($1, o.created_at AT TIME ZONE $2), 'YYYY-MM-DD'),
+               COUNT(*),
+               SUM(o.price * 100)::bigint,
+               SUM(o.vat * 100)::bigint,
+               SUM(r.price * 100)::bigint,
+               SUM(r.vat * 100)::bigint
+        FROM orders o
+        CROSS JOIN LATERAL (
+            SELECT COALESCE(SUM(cn.price), 0) AS price, COALESCE(SUM(cn.vat), 0) AS vat
+            FROM credit_notes cn
+            WHERE cn.order_id = o.id AND cn.status IN ('issued', 'partially_issued')
+        ) r
+        WHERE o.created_at >= $3 AND o.created_at < $4
+          AND o.status = ANY($5)
+        GROUP BY 1
+        ORDER BY 1
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, filter.Granularity, filter.Location.String(), filter.From, filter.To, pq.Array(filter.Statuses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periods []domain.SalesPeriod
+	for rows.Next() {
+		var period domain.SalesPeriod
+		var price, vat, refundedPrice, refundedVAT int64
+		if err := rows.Scan(&period.Period, &period.Orders, &price, &vat, &refundedPrice, &refundedVAT); err != nil {
+			return nil, err
+		}
+		period.GrossPrice = domain.FromCents(price)
+		period.GrossVAT = domain.FromCents(vat)
+		period.RefundedPrice = domain.FromCents(refundedPrice)
+		period.RefundedVAT = domain.FromCents(refundedVAT)
+		period.Revenue = domain.FromCents(price - refundedPrice)
+		period.VAT = domain.FromCents(vat - refundedVAT)
+		periods = append(periods, period)
+	}
+
+	return periods, rows.Err()
+}
+
+// TopProducts returns the products with the most units sold, or the most
+// revenue when by is domain.ReportByRevenue, in the orders selected by
+// filter. Ties are broken by product ID.
+func (r *ReportRepo) TopProducts(ctx context.Context, filter domain.ReportFilter, by string, limit int) ([]domain.ProductSales, error) {
+	orderBy := "3 DESC, 4 DESC"
+	if by == domain.ReportByRevenue {
+		orderBy = "4 DESC, 3 DESC"
+	}
+
+	query := `
+        SELECT oi.product_id, COALESCE(p.name, ''),
+               SUM(oi.quantity),
+               SUM(oi.price * 100)::bigint,
+               SUM(oi.vat * 100)::bigint
+        FROM order_items oi
+        JOIN orders o ON o.id = oi.order_id
+        LEFT JOIN products p ON p.id = oi.product_id
+        WHERE o.created_at >= $1 AND o.created_at < $2
+          AND o.status = ANY($3)
+        GROUP BY oi.product_id, p.name
+        ORDER BY ` + orderBy + `, oi.product_id
+        LIMIT $4
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, filter.From, filter.To, pq.Array(filter.Statuses), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []domain.ProductSales
+	for rows.Next() {
+		var product domain.ProductSales
+		var price, vat int64
+		if err := rows.Scan(&product.ProductID, &product.Name, &product.Quantity, &price, &vat); err != nil {
+			return nil, err
+		}
+		product.Revenue = domain.FromCents(price)
+		product.VAT = domain.FromCents(vat)
+		products = append(products, product)
+	}
+
+	return products, rows.Err()
+}
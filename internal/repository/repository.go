@@ -2,15 +2,100 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/valeriouberti/order-service-test/internal/domain"
 )
 
 type ProductRepository interface {
 	GetByID(ctx context.Context, id int64) (*domain.Product, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]domain.Product, error)
 }
 
 type OrderRepository interface {
 	Create(ctx context.Context, order *domain.Order) (*domain.Order, error)
+	CreateBatch(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error)
 	GetByID(ctx context.Context, id int64) (*domain.Order, error)
+	List(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, error)
+	Export(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.Order) error) error
+	Update(ctx context.Context, id int64, version int, update func(order *domain.Order) (*domain.OrderRevision, error)) (*domain.Order, error)
+	ListRevisions(ctx context.Context, orderID int64) ([]domain.OrderRevision, error)
+}
+
+type CartRepository interface {
+	Create(ctx context.Context, cart *domain.Cart) (*domain.Cart, error)
+	GetByID(ctx context.Context, id string) (*domain.Cart, error)
+	AddItem(ctx context.Context, cartID string, item domain.CartItem, expiresAt time.Time) error
+	SetItemQuantity(ctx context.Context, cartID string, item domain.CartItem, expiresAt time.Time) error
+	RemoveItem(ctx context.Context, cartID string, productID int64, expiresAt time.Time) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *domain.Payment) (*domain.Payment, error)
+	GetByID(ctx context.Context, orderID, id int64) (*domain.Payment, error)
+	ListByOrder(ctx context.Context, orderID int64) ([]domain.Payment, error)
+	Transition(ctx context.Context, id int64, from, to, reference, failureReason string) (*domain.Payment, error)
+	Capture(ctx context.Context, orderID, id int64) (*domain.Payment, *domain.Order, error)
+}
+
+type CreditNoteRepository interface {
+	Refundable(ctx context.Context, orderID int64) (float64, error)
+	Create(ctx context.Context, note *domain.CreditNote) (*domain.CreditNote, error)
+	CompleteRefund(ctx context.Context, id int64, status, reference, failureReason string) error
+	Finish(ctx context.Context, note *domain.CreditNote, status string) (*domain.Order, error)
+	ClaimStale(ctx context.Context, staleAfter time.Duration) (*domain.CreditNote, error)
+	GetByID(ctx context.Context, orderID, id int64) (*domain.CreditNote, error)
+	ListByOrder(ctx context.Context, orderID int64) ([]domain.CreditNote, error)
+}
+
+type InvoiceRepository interface {
+	GetByOrder(ctx context.Context, orderID int64) (*domain.Invoice, error)
+	Issue(ctx context.Context, invoice *domain.Invoice) (*domain.Invoice, error)
+}
+
+type ReturnRepository interface {
+	Create(ctx context.Context, ret *domain.Return) (*domain.Return, error)
+	Transition(ctx context.Context, orderID, id int64, from, to string) (*domain.Return, error)
+	LinkCreditNote(ctx context.Context, id, creditNoteID int64) error
+	GetByID(ctx context.Context, orderID, id int64) (*domain.Return, error)
+	ListByOrder(ctx context.Context, orderID int64) ([]domain.Return, error)
+}
+
+type ShipmentRepository interface {
+	Create(ctx context.Context, shipment *domain.Shipment) (*domain.Shipment, *domain.Order, error)
+	ListByOrder(ctx context.Context, orderID int64) ([]domain.Shipment, error)
+}
+
+type ImportRepository interface {
+	Create(ctx context.Context, job *domain.ImportJob, rows []domain.ImportRow) (*domain.ImportJob, error)
+	Claim(ctx context.Context, staleAfter time.Duration) (*domain.ImportJob, []domain.ImportRow, error)
+	RecordResults(ctx context.Context, id string, results []domain.ImportResult) error
+	Finish(ctx context.Context, id, status, message string) error
+	GetByID(ctx context.Context, id string) (*domain.ImportJob, error)
+}
+
+type ReportRepository interface {
+	Sales(ctx context.Context, filter domain.ReportFilter) ([]domain.SalesPeriod, error)
+	TopProducts(ctx context.Context, filter domain.ReportFilter, by string, limit int) ([]domain.ProductSales, error)
+}
+
+type AuditRepository interface {
+	List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+}
+
+type OutboxRepository interface {
+	ProcessBatch(ctx context.Context, limit int, publish func(context.Context, domain.Event) error) (int, error)
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	EnqueueDeliveries(ctx context.Context, event domain.Event) (int, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, leaseSeconds int) ([]domain.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, attempt domain.WebhookAttempt) error
+	ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, subscriptionID, deliveryID int64) error
 }
@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type ReturnRepo struct {
+	db *sql.DB
+}
+
+func NewReturnRepo(db *sql.DB) *ReturnRepo {
+	return &ReturnRepo{db: db}
+}
+
+// Create records a requested return. The order is locked while the
+// quantities are checked, so concurrent returns can never together exceed
+// the order: for every product, the returned quantity plus the quantities
+// of the returns that were not rejected must not exceed the ordered one.
+//
+// The return is recorded in the audit log in the same transaction. It
+// returns domain.ErrOrderNotReturnable unless the order is paid, shipped or
+// partially shipped, and domain.ErrReturnExceedsQuantity if a quantity is
+// too large.
+func (r *ReturnRepo) Create(ctx context.Context, ret *domain.Return) (*domain.Return, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockOrder(ctx, tx, ret.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if !domain.IsPaidStatus(status) {
+		return nil, domain.ErrOrderNotReturnable
+	}
+
+	for _, item := range ret.Items {
+		var ordered, returned int
+		err := tx.QueryRowContext(ctx, `
+            SELECT
+                COALESCE((SELECT SUM(quantity) FROM order_items WHERE order_id = $1 AND product_id = $2), 0),
+                COALESCE((SELECT SUM(ri.quantity) FROM return_items ri
+                          JOIN returns rt ON rt.id = ri.return_id
+                          WHERE rt.order_id = $1 AND ri.product_id = $2 AND rt.status <> $3), 0)
+        `, ret.OrderID, item.ProductID, domain.ReturnStatusRejected).Scan(&ordered, &returned)
+		if err != nil {
+			return nil, err
+		}
+		if returned+item.Quantity > ordered {
+			return nil, fmt.Errorf("%w: product %d has %d of %d units left to return",
+				domain.ErrReturnExceedsQuantity, item.ProductID, max(ordered-returned, 0), ordered)
+		}
+	}
+
+	ret.Status = domain.ReturnStatusRequested
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO returns (order_id, status, reason, request_id)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+        RETURNING id, created_at, updated_at
+    `, ret.OrderID, ret.Status, ret.Reason, ret.RequestID).Scan(&ret.ID, &ret.CreatedAt, &ret.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range ret.Items {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO return_items (return_id, product_id, quantity) VALUES ($1, $2, $3)
+        `, ret.ID, item.ProductID, item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := insertAuditEntry(ctx, tx, domain.AuditEntityReturn, ret.ID, domain.AuditActionReturnCreated, nil, ret); err != nil {
+		return nil, err
+	}
+
+	return ret, tx.Commit()
+}
+
+// productStock is the part of a product changed by restocking, as recorded
+// in the audit log.
+type productStock struct {
+	Stock int `json:"stock"`
+}
+
+// Transition moves a return of an order from one status to another,
+// failing with domain.ErrReturnStatus if it is no longer in from. Receiving
+// a return adds its quantities back to the products' stock in the same
+// transaction. The changes of the return and of each product are recorded
+// in the audit log in the same transaction.
+func (r *ReturnRepo) Transition(ctx context.Context, orderID, id int64, from, to string) (*domain.Return, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockReturn(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if before.OrderID != orderID {
+		return nil, domain.ErrReturnNotFound
+	}
+	if before.Status != from {
+		return nil, domain.ErrReturnStatus
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE returns SET status = $2, updated_at = NOW() WHERE id = $1
+    `, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == domain.ReturnStatusReceived {
+		if err := restock(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	ret, err := getReturn(ctx, tx, orderID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertAuditEntry(ctx, tx, domain.AuditEntityReturn, id, domain.AuditActionReturnUpdated, before, ret); err != nil {
+		return nil, err
+	}
+
+	return ret, tx.Commit()
+}
+
+// restock adds the quantities of a return back to the products' stock and
+// records the stock of each product before and after in the audit log.
+func restock(ctx context.Context, tx *sql.Tx, returnID int64) error {
+	rows, err := tx.QueryContext(ctx, `
+        UPDATE products p SET stock = p.stock + ri.quantity, updated_at = NOW()
+        FROM (SELECT product_id, SUM(quantity) AS quantity FROM return_items WHERE return_id = $1 GROUP BY product_id) ri
+        WHERE p.id = ri.product_id
+        RETURNING p.id, p.stock - ri.quantity, p.stock
+    `, returnID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type restocked struct {
+		productID     int64
+		before, after productStock
+	}
+	var products []restocked
+	for rows.Next() {
+		var p restocked
+		if err := rows.Scan(&p.productID, &p.before.Stock, &p.after.Stock); err != nil {
+			return err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, p := range products {
+		if err := insertAuditEntry(ctx, tx, domain.AuditEntityProduct, p.productID, domain.AuditActionProductRestocked, p.before, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LinkCreditNote records the credit note that refunded a return, and the
+// change in the audit log.
+func (r *ReturnRepo) LinkCreditNote(ctx context.Context, id, creditNoteID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockReturn(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE returns SET credit_note_id = $2, updated_at = NOW() WHERE id = $1
+    `, id, creditNoteID)
+	if err != nil {
+		return err
+	}
+
+	ret, err := getReturn(ctx, tx, before.OrderID, id)
+	if err != nil {
+		return err
+	}
+	if err := insertAuditEntry(ctx, tx, domain.AuditEntityReturn, id, domain.AuditActionReturnUpdated, before, ret); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// returnQuery selects returns with their items; the caller appends the
+// WHERE clause.
+const returnQuery = `
+    SELECT rt.id, rt.order_id, rt.status, COALESCE(rt.reason, ''), rt.credit_note_id,
+           COALESCE(rt.request_id, ''), rt.created_at, rt.updated_at,
+           COALESCE((SELECT json_agg(json_build_object(
+                         'product_id', ri.product_id, 'quantity', ri.quantity) ORDER BY ri.id)
+                     FROM return_items ri WHERE ri.return_id = rt.id), '[]')
+    FROM returns rt
+`
+
+func scanReturn(row interface{ Scan(...any) error }) (*domain.Return, error) {
+	var ret domain.Return
+	var creditNoteID sql.NullInt64
+	var items string
+	err := row.Scan(
+		&ret.ID,
+		&ret.OrderID,
+		&ret.Status,
+		&ret.Reason,
+		&creditNoteID,
+		&ret.RequestID,
+		&ret.CreatedAt,
+		&ret.UpdatedAt,
+		&items,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if creditNoteID.Valid {
+		ret.CreditNoteID = &creditNoteID.Int64
+	}
+	if err := json.Unmarshal([]byte(items), &ret.Items); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func getReturn(ctx context.Context, q rowQuerier, orderID, id int64) (*domain.Return, error) {
+	ret, err := scanReturn(q.QueryRowContext(ctx, returnQuery+`WHERE rt.id = $1 AND rt.order_id = $2`, id, orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrReturnNotFound
+	}
+	return ret, err
+}
+
+// lockReturn locks a return row for the rest of the transaction and returns
+// it as it is before the change, for the audit log.
+func lockReturn(ctx context.Context, tx *sql.Tx, id int64) (*domain.Return, error) {
+	ret, err := scanReturn(tx.QueryRowContext(ctx, returnQuery+`WHERE rt.id = $1 FOR UPDATE OF rt`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrReturnNotFound
+	}
+	return ret, err
+}
+
+// GetByID returns a return of an order.
+func (r *ReturnRepo) GetByID(ctx context.Context, orderID, id int64) (*domain.Return, error) {
+	return getReturn(ctx, r.db, orderID, id)
+}
+
+// ListByOrder returns the returns of an order, oldest first.
+func (r *ReturnRepo) ListByOrder(ctx context.Context, orderID int64) ([]domain.Return, error) {
+	rows, err := r.db.QueryContext(ctx, returnQuery+`WHERE rt.order_id = $1 ORDER BY rt.id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	returns := []domain.Return{}
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, *ret)
+	}
+
+	return returns, rows.Err()
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/actor"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+var returnColumns = []string{"id", "order_id", "status", "reason", "credit_note_id", "request_id", "created_at", "updated_at", "items"}
+
+func returnRow(status string) []driver.Value {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []driver.Value{int64(4), int64(1), status, "", nil, "", created, created,
+		`[{"product_id": 5, "quantity": 2}, {"product_id": 6, "quantity": 1}]`}
+}
+
+func TestReturnRepoTransition(t *testing.T) {
+	ctx := actor.NewContext(context.Background(), "alice")
+
+	t.Run("Receiving restocks the products and audits every change", func(t *testing.T) {
+		// Setup
+		db, fake := newFakeDB(t,
+			rows("FOR UPDATE OF rt", returnColumns, returnRow(domain.ReturnStatusApproved)),
+			rows("UPDATE products", []string{"id", "before", "after"},
+				[]driver.Value{int64(5), int64(3), int64(5)},
+				[]driver.Value{int64(6), int64(0), int64(1)}),
+			rows("FROM returns rt", returnColumns, returnRow(domain.ReturnStatusReceived)),
+		)
+		repo := NewReturnRepo(db)
+
+		ret, err := repo.Transition(ctx, 1, 4, domain.ReturnStatusApproved, domain.ReturnStatusReceived)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReturnStatusReceived, ret.Status)
+		assert.True(t, fake.committed)
+
+		entries := fake.executed("INSERT INTO audit_log")
+		require.Len(t, entries, 3)
+		for _, entry := range entries {
+			assert.True(t, entry.inTx)
+			assert.Equal(t, "alice", entry.args[3])
+		}
+		assert.Equal(t, []driver.Value{domain.AuditEntityProduct, int64(5), domain.AuditActionProductRestocked},
+			entries[0].args[:3])
+		assert.JSONEq(t, `{"stock": 3}`, string(entries[0].args[5].([]byte)))
+		assert.JSONEq(t, `{"stock": 5}`, string(entries[0].args[6].([]byte)))
+		assert.Equal(t, int64(6), entries[1].args[1])
+		assert.JSONEq(t, `{"stock": 1}`, string(entries[1].args[6].([]byte)))
+		assert.Equal(t, []driver.Value{domain.AuditEntityReturn, int64(4), domain.AuditActionReturnUpdated},
+			entries[2].args[:3])
+		assert.JSONEq(t, `{"status": "approved"}`, string(entries[2].args[5].([]byte)))
+		assert.JSONEq(t, `{"status": "received"}`, string(entries[2].args[6].([]byte)))
+	})
+
+	t.Run("Other transitions leave the stock alone", func(t *testing.T) {
+		// Setup
+		db, fake := newFakeDB(t,
+			rows("FOR UPDATE OF rt", returnColumns, returnRow(domain.ReturnStatusRequested)),
+			rows("FROM returns rt", returnColumns, returnRow(domain.ReturnStatusApproved)),
+		)
+		repo := NewReturnRepo(db)
+
+		_, err := repo.Transition(ctx, 1, 4, domain.ReturnStatusRequested, domain.ReturnStatusApproved)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Empty(t, fake.executed("UPDATE products"))
+		assert.Len(t, fake.executed("INSERT INTO audit_log"), 1)
+	})
+
+	t.Run("Wrong status", func(t *testing.T) {
+		// Setup
+		db, fake := newFakeDB(t, rows("FOR UPDATE OF rt", returnColumns, returnRow(domain.ReturnStatusRejected)))
+		repo := NewReturnRepo(db)
+
+		_, err := repo.Transition(ctx, 1, 4, domain.ReturnStatusApproved, domain.ReturnStatusReceived)
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrReturnStatus)
+		assert.False(t, fake.committed)
+		assert.Empty(t, fake.executed("INSERT INTO audit_log"))
+	})
+}
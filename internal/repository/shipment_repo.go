@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type ShipmentRepo struct {
+	db *sql.DB
+}
+
+func NewShipmentRepo(db *sql.DB) *ShipmentRepo {
+	return &ShipmentRepo{db: db}
+}
+
+// Create records a shipment of a paid order and derives the order status
+// from what has been shipped: shipped once every ordered unit is in a
+// shipment, partially_shipped before that. The order's version is
+// incremented and an order.updated or order.shipped event is written to the
+// outbox and the status change to the audit log in the same transaction.
+// The order is returned as it is after the change.
+//
+// The order is locked while the quantities are checked, so concurrent
+// shipments can never ship a unit twice. A shipment without items ships
+// every unit not shipped yet. It returns domain.ErrOrderNotShippable unless
+// the order is paid or partially shipped, domain.ErrShipmentExceedsQuantity
+// if a quantity exceeds what is left to ship and
+// domain.ErrDuplicateShipment if the tracking number is already recorded
+// for the carrier.
+func (r *ShipmentRepo) Create(ctx context.Context, shipment *domain.Shipment) (*domain.Shipment, *domain.Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockOrder(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if status != domain.OrderStatusPaid && status != domain.OrderStatusPartiallyShipped {
+		return nil, nil, domain.ErrOrderNotShippable
+	}
+
+	remaining, err := unshipped(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(shipment.Items) == 0 {
+		for _, item := range remaining {
+			if item.Quantity > 0 {
+				shipment.Items = append(shipment.Items, item)
+			}
+		}
+		if len(shipment.Items) == 0 {
+			return nil, nil, fmt.Errorf("%w: everything has been shipped", domain.ErrShipmentExceedsQuantity)
+		}
+	}
+
+	left := make(map[int64]int, len(remaining))
+	for _, item := range remaining {
+		left[item.ProductID] = item.Quantity
+	}
+	for _, item := range shipment.Items {
+		if _, ok := left[item.ProductID]; !ok {
+			return nil, nil, fmt.Errorf("%w: product %d is not in the order", domain.ErrShipmentExceedsQuantity, item.ProductID)
+		}
+		if item.Quantity > left[item.ProductID] {
+			return nil, nil, fmt.Errorf("%w: product %d has %d units left to ship",
+				domain.ErrShipmentExceedsQuantity, item.ProductID, left[item.ProductID])
+		}
+		left[item.ProductID] -= item.Quantity
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO shipments (order_id, carrier, tracking_number, request_id)
+        VALUES ($1, $2, $3, NULLIF($4, ''))
+        RETURNING id, created_at
+    `, shipment.OrderID, shipment.Carrier, shipment.TrackingNumber, shipment.RequestID).Scan(&shipment.ID, &shipment.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == "shipments_carrier_tracking_number_key" {
+			return nil, nil, domain.ErrDuplicateShipment
+		}
+		return nil, nil, err
+	}
+
+	for _, item := range shipment.Items {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO shipment_items (shipment_id, product_id, quantity) VALUES ($1, $2, $3)
+        `, shipment.ID, item.ProductID, item.Quantity)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Derive the order status from the units left to ship
+	status, eventType := domain.OrderStatusShipped, domain.EventOrderShipped
+	for _, quantity := range left {
+		if quantity > 0 {
+			status, eventType = domain.OrderStatusPartiallyShipped, domain.EventOrderUpdated
+			break
+		}
+	}
+	before, err := getOrder(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+        UPDATE orders SET status = $2, version = version + 1, updated_at = NOW() WHERE id = $1
+    `, shipment.OrderID, status)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order, err := getOrder(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event, err := domain.NewOrderEvent(eventType, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := insertOutboxEvent(ctx, tx, event); err != nil {
+		return nil, nil, err
+	}
+	if err := insertAuditEntry(ctx, tx, domain.AuditEntityOrder, order.ID, domain.AuditActionOrderUpdated, before, order); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return shipment, order, nil
+}
+
+// unshipped returns, per product of an order, the ordered units that are
+// not in a shipment yet, in the order the products were first ordered.
+func unshipped(ctx context.Context, tx *sql.Tx, orderID int64) ([]domain.OrderItem, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT oi.product_id, SUM(oi.quantity) - COALESCE((
+                   SELECT SUM(si.quantity) FROM shipment_items si
+                   JOIN shipments s ON s.id = si.shipment_id
+                   WHERE s.order_id = $1 AND si.product_id = oi.product_id), 0)
+        FROM order_items oi
+        WHERE oi.order_id = $1
+        GROUP BY oi.product_id
+        ORDER BY MIN(oi.id)
+    `, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.OrderItem
+	for rows.Next() {
+		var item domain.OrderItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// ListByOrder returns the shipments of an order, oldest first.
+func (r *ShipmentRepo) ListByOrder(ctx context.Context, orderID int64) ([]domain.Shipment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT s.id, s.order_id, s.carrier, s.tracking_number, COALESCE(s.request_id, ''), s.created_at,
+               COALESCE((SELECT json_agg(json_build_object(
+                             'product_id', si.product_id, 'quantity', si.quantity) ORDER BY si.id)
+                         FROM shipment_items si WHERE si.shipment_id = s.id), '[]')
+        FROM shipments s
+        WHERE s.order_id = $1
+        ORDER BY s.id
+    `, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipments := []domain.Shipment{}
+	for rows.Next() {
+		var shipment domain.Shipment
+		var items string
+		if err := rows.Scan(
+			&shipment.ID,
+			&shipment.OrderID,
+			&shipment.Carrier,
+			&shipment.TrackingNumber,
+			&shipment.RequestID,
+			&shipment.CreatedAt,
+			&items,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(items), &shipment.Items); err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, shipment)
+	}
+
+	return shipments, rows.Err()
+}
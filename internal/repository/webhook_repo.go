@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type WebhookRepo struct {
+	db *sql.DB
+}
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+// CreateSubscription persists a new webhook subscription and populates its ID and creation time.
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+	query := `
+        INSERT INTO webhook_subscriptions (url, event_types, secret, active)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `
+
+	err := r.db.QueryRowContext(ctx, query, sub.URL, pq.Array(sub.EventTypes), sub.Secret, sub.Active).
+		Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// ListSubscriptions returns every subscription, oldest first. Secrets are not loaded.
+func (r *WebhookRepo) ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	query := `SELECT id, url, event_types, active, created_at FROM webhook_subscriptions ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []domain.WebhookSubscription{}
+	for rows.Next() {
+		var sub domain.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, pq.Array(&sub.EventTypes), &sub.Active, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// GetSubscription returns a subscription by ID. Its secret is not loaded.
+func (r *WebhookRepo) GetSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error) {
+	query := `SELECT id, url, event_types, active, created_at FROM webhook_subscriptions WHERE id = $1`
+
+	var sub domain.WebhookSubscription
+	err := r.db.QueryRowContext(ctx, query, id).
+		Scan(&sub.ID, &sub.URL, pq.Array(&sub.EventTypes), &sub.Active, &sub.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+// DeleteSubscription removes a subscription together with its deliveries.
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// EnqueueDeliveries queues event for every active subscription that listens to
+// its type. Enqueuing the same event twice is a no-op, which makes the outbox
+// relay's at-least-once delivery safe.
+//
+// Returns the number of deliveries created.
+func (r *WebhookRepo) EnqueueDeliveries(ctx context.Context, event domain.Event) (int, error) {
+	query := `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+        SELECT id, $1, $2::text, $3
+        FROM webhook_subscriptions
+        WHERE active AND (cardinality(event_types) = 0 OR $2::text = ANY(event_types))
+        ON CONFLICT (subscription_id, event_id) DO NOTHING
+    `
+
+	// The whole event is the webhook body
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, event.ID, event.Type, string(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next attempt
+// is due, together with their target URL and secret.
+//
+// Claimed deliveries are leased by pushing next_attempt_at forward by
+// leaseSeconds, so concurrent dispatchers don't send the same delivery twice.
+// If a dispatcher dies mid-send the delivery becomes due again after the lease.
+// The returned NextAttemptAt is the end of the lease.
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, limit int, leaseSeconds int) ([]domain.WebhookDelivery, error) {
+	query := `
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = NOW() + make_interval(secs => $2), updated_at = NOW()
+        FROM webhook_subscriptions s
+        WHERE s.id = d.subscription_id
+          AND d.id IN (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at, id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+          )
+        RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status,
+                  d.attempts, d.next_attempt_at, d.created_at, s.url, s.secret
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, limit, leaseSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(
+			&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status,
+			&d.Attempts, &d.NextAttemptAt, &d.CreatedAt, &d.URL, &d.Secret,
+		); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt stores the outcome of sending a delivery and adds it to the
+// delivery's history, provided the delivery is still held under the claim
+// the attempt was made under. It returns
+// domain.ErrDeliveryLeaseLost if the lease expired and the delivery was
+// claimed again, or if it was redelivered meanwhile.
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, attempt domain.WebhookAttempt) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET attempts = attempts + 1,
+            status = $2,
+            last_status_code = NULLIF($3, 0),
+            last_error = NULLIF($4, ''),
+            next_attempt_at = $5,
+            delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE delivered_at END,
+            updated_at = NOW()
+        WHERE id = $1 AND status = 'pending' AND next_attempt_at = $6
+    `
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query,
+		attempt.DeliveryID, attempt.Status, attempt.StatusCode, attempt.Error, attempt.NextAttemptAt, attempt.LeasedUntil)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrDeliveryLeaseLost
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO webhook_attempts (delivery_id, status_code, error)
+        VALUES ($1, NULLIF($2, 0), NULLIF($3, ''))
+    `, attempt.DeliveryID, attempt.StatusCode, attempt.Error)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription, newest
+// first, with the history of their attempts.
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]domain.WebhookDelivery, error) {
+	query := `
+        SELECT id, subscription_id, event_id, event_type, payload, status, attempts,
+               next_attempt_at, COALESCE(last_status_code, 0), COALESCE(last_error, ''),
+               delivered_at, created_at,
+               COALESCE((SELECT json_agg(json_build_object(
+                             'status_code', COALESCE(a.status_code, 0), 'error', COALESCE(a.error, ''),
+                             'attempted_at', a.attempted_at) ORDER BY a.id)
+                         FROM webhook_attempts a WHERE a.delivery_id = webhook_deliveries.id), '[]')
+        FROM webhook_deliveries
+        WHERE subscription_id = $1
+        ORDER BY id DESC
+        LIMIT $2
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var payload []byte
+		var deliveredAt sql.NullTime
+		var history string
+		if err := rows.Scan(
+			&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &deliveredAt, &d.CreatedAt, &history,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(history), &d.History); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Redeliver makes a delivery pending again and due immediately, whatever its
+// current status. Its attempt counter is reset so it gets a full retry budget.
+func (r *WebhookRepo) Redeliver(ctx context.Context, subscriptionID, deliveryID int64) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+        WHERE id = $1 AND subscription_id = $2
+    `
+
+	result, err := r.db.ExecContext(ctx, query, deliveryID, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+func TestWebhookRepoRecordAttempt(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	leasedUntil := time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC)
+	db, fake := newFakeDB(t)
+	repo := NewWebhookRepo(db)
+
+	err := repo.RecordAttempt(ctx, domain.WebhookAttempt{
+		DeliveryID:    7,
+		StatusCode:    503,
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: leasedUntil.Add(time.Minute),
+		LeasedUntil:   leasedUntil,
+	})
+
+	// Assertions
+	require.NoError(t, err)
+	assert.True(t, fake.committed)
+
+	updates := fake.executed("UPDATE webhook_deliveries")
+	require.Len(t, updates, 1)
+	assert.Contains(t, updates[0].query, "next_attempt_at = $6")
+	assert.Equal(t, leasedUntil, updates[0].args[5])
+
+	attempts := fake.executed("INSERT INTO webhook_attempts")
+	require.Len(t, attempts, 1)
+	assert.True(t, attempts[0].inTx)
+	assert.Equal(t, []driver.Value{int64(7), int64(503), ""}, attempts[0].args)
+}
+
+func TestWebhookRepoListDeliveries(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "subscription_id", "event_id", "event_type", "payload", "status", "attempts",
+		"next_attempt_at", "last_status_code", "last_error", "delivered_at", "created_at", "history"}
+	db, _ := newFakeDB(t, rows("FROM webhook_deliveries", columns, []driver.Value{
+		int64(7), int64(1), int64(3), domain.EventOrderCreated, []byte(`{}`), domain.DeliveryDelivered, int64(1),
+		created, int64(200), "", created, created,
+		`[{"status_code": 503, "error": "", "attempted_at": "2024-05-01T12:00:00+00:00"},
+		  {"status_code": 200, "error": "", "attempted_at": "2024-05-01T12:05:00.5+00:00"}]`,
+	}))
+	repo := NewWebhookRepo(db)
+
+	deliveries, err := repo.ListDeliveries(ctx, 1, 50)
+
+	// Assertions
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Len(t, deliveries[0].History, 2)
+	assert.Equal(t, 503, deliveries[0].History[0].StatusCode)
+	assert.Equal(t, 200, deliveries[0].History[1].StatusCode)
+	assert.True(t, deliveries[0].History[1].AttemptedAt.Equal(created.Add(5*time.Minute+500*time.Millisecond)))
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// AuditService reads the audit log. Entries are written by the repositories,
+// in the transaction of each change, and are never modified.
+type AuditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// ListAuditEntries returns a page of audit entries matching filter, oldest
+// first. A zero limit selects domain.DefaultAuditListLimit and larger limits
+// are capped at domain.MaxAuditListLimit. When the page is full,
+// NextAfterID is set to the cursor of the following page.
+func (s *AuditService) ListAuditEntries(ctx context.Context, filter domain.AuditFilter) (*domain.AuditListResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = domain.DefaultAuditListLimit
+	}
+	if filter.Limit > domain.MaxAuditListLimit {
+		filter.Limit = domain.MaxAuditListLimit
+	}
+
+	entries, err := s.auditRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+
+	if entries == nil {
+		entries = []domain.AuditEntry{}
+	}
+	response := &domain.AuditListResponse{Entries: entries}
+	if len(entries) == filter.Limit {
+		response.NextAfterID = entries[len(entries)-1].ID
+	}
+
+	return response, nil
+}
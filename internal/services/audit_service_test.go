@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.AuditEntry), args.Error(1)
+}
+
+func TestListAuditEntries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Default limit", func(t *testing.T) {
+		// Setup
+		auditRepo := new(MockAuditRepository)
+		auditService := NewAuditService(auditRepo)
+
+		auditRepo.On("List", ctx, domain.AuditFilter{EntityType: domain.AuditEntityOrder, Limit: domain.DefaultAuditListLimit}).
+			Return([]domain.AuditEntry{{ID: 1}, {ID: 2}}, nil)
+
+		response, err := auditService.ListAuditEntries(ctx, domain.AuditFilter{EntityType: domain.AuditEntityOrder})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Len(t, response.Entries, 2)
+		assert.Zero(t, response.NextAfterID)
+		auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("Full page returns the next cursor", func(t *testing.T) {
+		// Setup
+		auditRepo := new(MockAuditRepository)
+		auditService := NewAuditService(auditRepo)
+
+		auditRepo.On("List", ctx, domain.AuditFilter{AfterID: 3, Limit: 2}).
+			Return([]domain.AuditEntry{{ID: 4}, {ID: 7}}, nil)
+
+		response, err := auditService.ListAuditEntries(ctx, domain.AuditFilter{AfterID: 3, Limit: 2})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), response.NextAfterID)
+	})
+
+	t.Run("Limit is capped", func(t *testing.T) {
+		// Setup
+		auditRepo := new(MockAuditRepository)
+		auditService := NewAuditService(auditRepo)
+
+		auditRepo.On("List", ctx, domain.AuditFilter{Limit: domain.MaxAuditListLimit}).Return([]domain.AuditEntry{}, nil)
+
+		response, err := auditService.ListAuditEntries(ctx, domain.AuditFilter{Limit: 10000})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Empty(t, response.Entries)
+		auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		// Setup
+		auditRepo := new(MockAuditRepository)
+		auditService := NewAuditService(auditRepo)
+
+		auditRepo.On("List", ctx, mock.Anything).Return(nil, errors.New("db error"))
+
+		response, err := auditService.ListAuditEntries(ctx, domain.AuditFilter{})
+
+		// Assertions
+		assert.Error(t, err)
+		assert.Nil(t, response)
+		assert.Contains(t, err.Error(), "failed to list audit entries")
+	})
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// ErrInvalidCart is returned when a cart change or checkout fails validation.
+var ErrInvalidCart = errors.New("invalid cart")
+
+type CartService struct {
+	cartRepo     repository.CartRepository
+	productRepo  repository.ProductRepository
+	orderService OrderServiceInterface
+	ttl          time.Duration
+	now          func() time.Time
+}
+
+// NewCartService creates a CartService. Open carts expire ttl after their
+// last change; checkout goes through orderService.
+func NewCartService(cartRepo repository.CartRepository, productRepo repository.ProductRepository, orderService OrderServiceInterface, ttl time.Duration) *CartService {
+	return &CartService{
+		cartRepo:     cartRepo,
+		productRepo:  productRepo,
+		orderService: orderService,
+		ttl:          ttl,
+		now:          time.Now,
+	}
+}
+
+// CreateCart creates an empty open cart. Cart IDs are random so that they
+// cannot be guessed by other clients.
+func (s *CartService) CreateCart(ctx context.Context, req *domain.CreateCartRequest) (*domain.CartResponse, error) {
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.cartRepo.Create(ctx, &domain.Cart{
+		ID:         id,
+		CustomerID: req.CustomerID,
+		Status:     domain.CartStatusOpen,
+		ExpiresAt:  s.now().Add(s.ttl),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cart: %w", err)
+	}
+
+	return s.price(ctx, cart)
+}
+
+// GetCart returns a cart priced with the current product prices.
+// Expired carts are reported as not found.
+func (s *CartService) GetCart(ctx context.Context, id string) (*domain.CartResponse, error) {
+	cart, err := s.getCart(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.price(ctx, cart)
+}
+
+// AddItem adds a product to an open cart, merging it with an existing line
+// for the same product, and returns the updated cart.
+func (s *CartService) AddItem(ctx context.Context, id string, item domain.CartItem) (*domain.CartResponse, error) {
+	if item.Quantity < 1 {
+		return nil, fmt.Errorf("%w: quantity must be at least 1", ErrInvalidCart)
+	}
+	if _, err := s.productRepo.GetByID(ctx, item.ProductID); err != nil {
+		return nil, err
+	}
+
+	if err := s.cartRepo.AddItem(ctx, id, item, s.now().Add(s.ttl)); err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, id)
+}
+
+// UpdateItem sets the quantity of a product already in an open cart and
+// returns the updated cart.
+func (s *CartService) UpdateItem(ctx context.Context, id string, item domain.CartItem) (*domain.CartResponse, error) {
+	if item.Quantity < 1 {
+		return nil, fmt.Errorf("%w: quantity must be at least 1, remove the item instead", ErrInvalidCart)
+	}
+
+	if err := s.cartRepo.SetItemQuantity(ctx, id, item, s.now().Add(s.ttl)); err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, id)
+}
+
+// RemoveItem removes a product from an open cart and returns the updated cart.
+func (s *CartService) RemoveItem(ctx context.Context, id string, productID int64) (*domain.CartResponse, error) {
+	if err := s.cartRepo.RemoveItem(ctx, id, productID, s.now().Add(s.ttl)); err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, id)
+}
+
+// Checkout converts an open cart into an order through the order service.
+// The order is priced like any other order and the cart is closed in the
+// same transaction as the order is created, so a cart is checked out at most
+// once; later attempts fail with domain.ErrCartCheckedOut.
+func (s *CartService) Checkout(ctx context.Context, id string) (*domain.OrderResponse, error) {
+	cart, err := s.getCart(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Status != domain.CartStatusOpen {
+		return nil, domain.ErrCartCheckedOut
+	}
+	if len(cart.Items) == 0 {
+		return nil, fmt.Errorf("%w: cart is empty", ErrInvalidCart)
+	}
+
+	req := &domain.CreateOrderRequest{
+		Order:  domain.OrderInput{CustomerID: cart.CustomerID},
+		CartID: cart.ID,
+	}
+	for _, item := range cart.Items {
+		req.Order.Items = append(req.Order.Items, domain.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	order, err := s.orderService.CreateOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("cart checked out", "cart_id", cart.ID, "order_id", order.OrderID)
+	return order, nil
+}
+
+// DeleteExpired deletes the open carts whose expiry has passed.
+func (s *CartService) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.cartRepo.DeleteExpired(ctx, s.now())
+}
+
+// RunExpiry deletes expired carts every interval until ctx is cancelled.
+func (s *CartService) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.DeleteExpired(ctx)
+			if err != nil {
+				logging.FromContext(ctx).Error("failed to delete expired carts", "error", err)
+			} else if n > 0 {
+				logging.FromContext(ctx).Info("expired carts deleted", "count", n)
+			}
+		}
+	}
+}
+
+// getCart loads a cart, treating expired carts as not found.
+func (s *CartService) getCart(ctx context.Context, id string) (*domain.Cart, error) {
+	cart, err := s.cartRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Expired(s.now()) {
+		return nil, domain.ErrCartNotFound
+	}
+	return cart, nil
+}
+
+// price maps a cart to its response, pricing every line with the current
+// product prices loaded in a single query.
+func (s *CartService) price(ctx context.Context, cart *domain.Cart) (*domain.CartResponse, error) {
+	response := &domain.CartResponse{
+		CartID:     cart.ID,
+		CustomerID: cart.CustomerID,
+		Status:     cart.Status,
+		OrderID:    cart.OrderID,
+		Items:      []domain.OrderItem{},
+		ExpiresAt:  cart.ExpiresAt,
+		CreatedAt:  cart.CreatedAt,
+	}
+	if len(cart.Items) == 0 {
+		return response, nil
+	}
+
+	ids := make([]int64, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		ids = append(ids, item.ProductID)
+	}
+	products, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]domain.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for _, item := range cart.Items {
+		product, ok := byID[item.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("product with ID %d not found: %w", item.ProductID, domain.ErrProductNotFound)
+		}
+		response.Items = append(response.Items, domain.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     product.Price * float64(item.Quantity),
+			VAT:       product.VAT * float64(item.Quantity),
+		})
+	}
+	response.CartPrice, response.CartVAT = totals(response.Items)
+
+	return response, nil
+}
+
+// newRandomID returns a random 128-bit hex ID that cannot be guessed.
+func newRandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+type MockCartRepository struct {
+	mock.Mock
+	repository.CartRepository
+}
+
+func (m *MockCartRepository) Create(ctx context.Context, cart *domain.Cart) (*domain.Cart, error) {
+	args := m.Called(ctx, cart)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Cart), args.Error(1)
+}
+
+func (m *MockCartRepository) GetByID(ctx context.Context, id string) (*domain.Cart, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Cart), args.Error(1)
+}
+
+func (m *MockCartRepository) AddItem(ctx context.Context, id string, item domain.CartItem, expiresAt time.Time) error {
+	args := m.Called(ctx, id, item, expiresAt)
+	return args.Error(0)
+}
+
+type MockOrderService struct {
+	mock.Mock
+	OrderServiceInterface
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.OrderResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) CreateOrders(ctx context.Context, reqs []*domain.CreateOrderRequest) ([]*domain.OrderResponse, error) {
+	args := m.Called(ctx, reqs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) QuoteOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.QuoteResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.QuoteResponse), args.Error(1)
+}
+
+func newTestCartService(cartRepo *MockCartRepository, productRepo *MockProductRepository, orderService *MockOrderService, now time.Time) *CartService {
+	cartService := NewCartService(cartRepo, productRepo, orderService, time.Hour)
+	cartService.now = func() time.Time { return now }
+	return cartService
+}
+
+func TestCartService(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Create cart", func(t *testing.T) {
+		// Setup
+		cartRepo := new(MockCartRepository)
+		cartService := newTestCartService(cartRepo, new(MockProductRepository), new(MockOrderService), now)
+
+		cartRepo.On("Create", ctx, mock.MatchedBy(func(cart *domain.Cart) bool {
+			return len(cart.ID) == 32 && cart.Status == domain.CartStatusOpen && cart.ExpiresAt.Equal(now.Add(time.Hour))
+		})).Return(&domain.Cart{ID: "abc", CustomerID: "cust-42", Status: domain.CartStatusOpen}, nil)
+
+		cart, err := cartService.CreateCart(ctx, &domain.CreateCartRequest{CustomerID: "cust-42"})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, "abc", cart.CartID)
+		assert.Empty(t, cart.Items)
+		cartRepo.AssertExpectations(t)
+	})
+
+	t.Run("Get cart is priced with current prices", func(t *testing.T) {
+		// Setup
+		cartRepo := new(MockCartRepository)
+		productRepo := new(MockProductRepository)
+		cartService := newTestCartService(cartRepo, productRepo, new(MockOrderService), now)
+
+		cartRepo.On("GetByID", ctx, "abc").Return(&domain.Cart{
+			ID:        "abc",
+			Status:    domain.CartStatusOpen,
+			Items:     []domain.CartItem{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 3}},
+			ExpiresAt: now.Add(time.Minute),
+		}, nil)
+		productRepo.On("GetByIDs", ctx, []int64{1, 2}).Return([]domain.Product{
+			{ID: 2, Price: 5.0, VAT: 0.5},
+			{ID: 1, Price: 10.0, VAT: 1.0},
+		}, nil)
+
+		cart, err := cartService.GetCart(ctx, "abc")
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, 35.0, cart.CartPrice)
+		assert.Equal(t, 3.5, cart.CartVAT)
+		assert.Equal(t, []domain.OrderItem{
+			{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0},
+			{ProductID: 2, Quantity: 3, Price: 15.0, VAT: 1.5},
+		}, cart.Items)
+	})
+
+	t.Run("Expired cart is not found", func(t *testing.T) {
+		// Setup
+		cartRepo := new(MockCartRepository)
+		cartService := newTestCartService(cartRepo, new(MockProductRepository), new(MockOrderService), now)
+
+		cartRepo.On("GetByID", ctx, "abc").Return(&domain.Cart{ID: "abc", Status: domain.CartStatusOpen, ExpiresAt: now}, nil)
+
+		_, err := cartService.GetCart(ctx, "abc")
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrCartNotFound)
+	})
+
+	t.Run("Add item with unknown product", func(t *testing.T) {
+		// Setup
+		productRepo := new(MockProductRepository)
+		cartService := newTestCartService(new(MockCartRepository), productRepo, new(MockOrderService), now)
+
+		productRepo.On("GetByID", ctx, int64(9)).Return(nil, domain.ErrProductNotFound)
+
+		_, err := cartService.AddItem(ctx, "abc", domain.CartItem{ProductID: 9, Quantity: 1})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrProductNotFound)
+	})
+
+	t.Run("Add item with invalid quantity", func(t *testing.T) {
+		cartService := newTestCartService(new(MockCartRepository), new(MockProductRepository), new(MockOrderService), now)
+
+		_, err := cartService.AddItem(ctx, "abc", domain.CartItem{ProductID: 1, Quantity: 0})
+
+		assert.ErrorIs(t, err, ErrInvalidCart)
+	})
+
+	t.Run("Checkout creates the order for the cart", func(t *testing.T) {
+		// Setup
+		cartRepo := new(MockCartRepository)
+		orderService := new(MockOrderService)
+		cartService := newTestCartService(cartRepo, new(MockProductRepository), orderService, now)
+
+		cartRepo.On("GetByID", ctx, "abc").Return(&domain.Cart{
+			ID:         "abc",
+			CustomerID: "cust-42",
+			Status:     domain.CartStatusOpen,
+			Items:      []domain.CartItem{{ProductID: 1, Quantity: 2}},
+			ExpiresAt:  now.Add(time.Minute),
+		}, nil)
+		orderService.On("CreateOrder", ctx, &domain.CreateOrderRequest{
+			Order: domain.OrderInput{
+				CustomerID: "cust-42",
+				Items:      []domain.OrderItem{{ProductID: 1, Quantity: 2}},
+			},
+			CartID: "abc",
+		}).Return(&domain.OrderResponse{OrderID: 7}, nil)
+
+		order, err := cartService.Checkout(ctx, "abc")
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), order.OrderID)
+		orderService.AssertExpectations(t)
+	})
+
+	t.Run("Checkout of a checked out cart", func(t *testing.T) {
+		// Setup
+		cartRepo := new(MockCartRepository)
+		cartService := newTestCartService(cartRepo, new(MockProductRepository), new(MockOrderService), now)
+
+		cartRepo.On("GetByID", ctx, "abc").Return(&domain.Cart{
+			ID:      "abc",
+			Status:  domain.CartStatusCheckedOut,
+			OrderID: 7,
+			Items:   []domain.CartItem{{ProductID: 1, Quantity: 2}},
+		}, nil)
+
+		_, err := cartService.Checkout(ctx, "abc")
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrCartCheckedOut)
+	})
+
+	t.Run("Checkout of an empty cart", func(t *testing.T) {
+		// Setup
+		cartRepo := new(MockCartRepository)
+		cartService := newTestCartService(cartRepo, new(MockProductRepository), new(MockOrderService), now)
+
+		cartRepo.On("GetByID", ctx, "abc").Return(&domain.Cart{ID: "abc", Status: domain.CartStatusOpen, ExpiresAt: now.Add(time.Minute)}, nil)
+
+		_, err := cartService.Checkout(ctx, "abc")
+
+		// Assertions
+		assert.ErrorIs(t, err, ErrInvalidCart)
+	})
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/actor"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+// ErrInvalidImport is returned when an import request fails validation.
+var ErrInvalidImport = errors.New("invalid import")
+
+const (
+	// importStaleAfter is how long a running job may go without progress
+	// before it is considered interrupted
+	importStaleAfter = 10 * time.Minute
+	// importBatchSize is the number of dry run results recorded at once
+	importBatchSize = 100
+)
+
+type ImportService struct {
+	importRepo repository.ImportRepository
+	orders     OrderServiceInterface
+	syncRows   int
+	maxRows    int
+}
+
+// NewImportService creates an ImportService. Imports of up to syncRows rows
+// run within the request; larger ones are queued for RunJobs. Imports of
+// more than maxRows rows are rejected.
+func NewImportService(importRepo repository.ImportRepository, orders OrderServiceInterface, syncRows, maxRows int) *ImportService {
+	return &ImportService{
+		importRepo: importRepo,
+		orders:     orders,
+		syncRows:   syncRows,
+		maxRows:    maxRows,
+	}
+}
+
+// ImportOrders imports the orders of a parsed file. The mode defaults to
+// best effort.
+//
+// Small imports run at once and the finished job is returned with a result
+// per row. Larger ones are returned queued; GetImportJob reports their
+// progress. It returns ErrInvalidImport for unknown modes and files with no
+// rows or too many.
+func (s *ImportService) ImportOrders(ctx context.Context, req *domain.ImportRequest) (*domain.ImportJob, error) {
+	if req.Mode == "" {
+		req.Mode = domain.ImportModeBestEffort
+	}
+	if req.Mode != domain.ImportModeBestEffort && req.Mode != domain.ImportModeAllOrNothing {
+		return nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidImport, domain.ImportModeBestEffort, domain.ImportModeAllOrNothing)
+	}
+	if len(req.Rows) == 0 {
+		return nil, fmt.Errorf("%w: the file holds no orders", ErrInvalidImport)
+	}
+	if len(req.Rows) > s.maxRows {
+		return nil, fmt.Errorf("%w: the file holds %d orders, at most %d can be imported at once", ErrInvalidImport, len(req.Rows), s.maxRows)
+	}
+
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+
+	queued := len(req.Rows) > s.syncRows
+	job := &domain.ImportJob{
+		ID:        id,
+		Status:    domain.ImportStatusRunning,
+		Format:    req.Format,
+		Mode:      req.Mode,
+		DryRun:    req.DryRun,
+		Total:     len(req.Rows),
+		RequestID: requestid.FromContext(ctx),
+		Actor:     actor.FromContext(ctx),
+	}
+	if queued {
+		job.Status = domain.ImportStatusQueued
+	}
+
+	job, err = s.importRepo.Create(ctx, job, req.Rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("import started",
+		"job_id", job.ID,
+		"rows", job.Total,
+		"mode", job.Mode,
+		"dry_run", job.DryRun,
+		"queued", queued,
+	)
+
+	if queued {
+		return job, nil
+	}
+
+	// Finish the job even if the client goes away
+	ctx = context.WithoutCancel(ctx)
+	s.run(ctx, job, req.Rows)
+
+	return s.importRepo.GetByID(ctx, job.ID)
+}
+
+// GetImportJob returns an import job with the results of the rows processed
+// so far.
+func (s *ImportService) GetImportJob(ctx context.Context, id string) (*domain.ImportJob, error) {
+	return s.importRepo.GetByID(ctx, id)
+}
+
+// RunJobs processes the queued import jobs every interval until ctx is
+// cancelled. Jobs run with the request ID of the request that queued them.
+func (s *ImportService) RunJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				job, rows, err := s.importRepo.Claim(ctx, importStaleAfter)
+				if errors.Is(err, domain.ErrImportJobNotFound) {
+					break
+				}
+				if err != nil {
+					logging.FromContext(ctx).Error("failed to claim import job", "error", err)
+					break
+				}
+				// Orders are created on behalf of whoever submitted the job
+				jobCtx := actor.NewContext(requestid.NewContext(ctx, job.RequestID), job.Actor)
+				s.run(jobCtx, job, rows)
+			}
+		}
+	}
+}
+
+// run processes the rows of a running job and finishes it. A job stopped by
+// the cancellation of ctx is finished as failed.
+func (s *ImportService) run(ctx context.Context, job *domain.ImportJob, rows []domain.ImportRow) {
+	logger := logging.FromContext(ctx).With("job_id", job.ID)
+
+	var err error
+	switch {
+	case job.DryRun:
+		err = s.validate(ctx, job, rows)
+	case job.Mode == domain.ImportModeAllOrNothing:
+		err = s.createAll(ctx, job, rows)
+	default:
+		err = s.createEach(ctx, job, rows)
+	}
+
+	status, message := domain.ImportStatusCompleted, ""
+	if err != nil {
+		status, message = domain.ImportStatusFailed, err.Error()
+	}
+	if err := s.importRepo.Finish(context.WithoutCancel(ctx), job.ID, status, message); err != nil {
+		logger.Error("failed to finish import job", "error", err)
+		return
+	}
+
+	logger.Info("import finished", "status", status, "error", message)
+}
+
+// validate checks and prices every row without creating orders.
+func (s *ImportService) validate(ctx context.Context, job *domain.ImportJob, rows []domain.ImportRow) error {
+	results := make([]domain.ImportResult, 0, importBatchSize)
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result, _ := s.check(ctx, row)
+		results = append(results, result)
+
+		if len(results) == importBatchSize || i == len(rows)-1 {
+			if err := s.importRepo.RecordResults(ctx, job.ID, results); err != nil {
+				return fmt.Errorf("failed to record import results: %w", err)
+			}
+			results = results[:0]
+		}
+	}
+	return nil
+}
+
+// createEach creates the order of every valid row on its own, recording
+// each result as soon as the order is created.
+func (s *ImportService) createEach(ctx context.Context, job *domain.ImportJob, rows []domain.ImportRow) error {
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result := domain.ImportResult{Line: row.Line, Ref: row.Ref, Status: domain.ImportRowFailed}
+		if err := checkRow(row); err != nil {
+			result.Error = err.Error()
+		} else if order, err := s.orders.CreateOrder(ctx, &domain.CreateOrderRequest{Order: row.Order}); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Status = domain.ImportRowCreated
+			result.OrderID, result.Price, result.VAT = order.OrderID, order.OrderPrice, order.OrderVAT
+		}
+
+		if err := s.importRepo.RecordResults(ctx, job.ID, []domain.ImportResult{result}); err != nil {
+			return fmt.Errorf("failed to record import results: %w", err)
+		}
+	}
+	return nil
+}
+
+// createAll checks every row and creates all the orders in one transaction
+// if they are all valid. Otherwise nothing is created, the valid rows are
+// skipped and the job fails.
+func (s *ImportService) createAll(ctx context.Context, job *domain.ImportJob, rows []domain.ImportRow) error {
+	results := make([]domain.ImportResult, len(rows))
+	reqs := make([]*domain.CreateOrderRequest, len(rows))
+	invalid := 0
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var ok bool
+		results[i], ok = s.check(ctx, row)
+		if !ok {
+			invalid++
+		}
+		reqs[i] = &domain.CreateOrderRequest{Order: row.Order}
+	}
+
+	var failure error
+	if invalid > 0 {
+		failure = fmt.Errorf("%d of %d rows are invalid, no order was created", invalid, len(rows))
+		for i := range results {
+			if results[i].Status == domain.ImportRowValid {
+				results[i].Status = domain.ImportRowSkipped
+			}
+		}
+	} else if orders, err := s.orders.CreateOrders(ctx, reqs); err != nil {
+		failure = fmt.Errorf("no order was created: %w", err)
+		for i := range results {
+			results[i] = domain.ImportResult{Line: rows[i].Line, Ref: rows[i].Ref, Status: domain.ImportRowFailed, Error: err.Error()}
+		}
+	} else {
+		for i, order := range orders {
+			results[i].Status = domain.ImportRowCreated
+			results[i].OrderID, results[i].Price, results[i].VAT = order.OrderID, order.OrderPrice, order.OrderVAT
+		}
+	}
+
+	if err := s.importRepo.RecordResults(context.WithoutCancel(ctx), job.ID, results); err != nil {
+		return fmt.Errorf("failed to record import results: %w", err)
+	}
+	return failure
+}
+
+// check validates a row and prices it with a quote. The result is valid
+// with the quoted totals, or failed.
+func (s *ImportService) check(ctx context.Context, row domain.ImportRow) (domain.ImportResult, bool) {
+	result := domain.ImportResult{Line: row.Line, Ref: row.Ref, Status: domain.ImportRowFailed}
+
+	if err := checkRow(row); err != nil {
+		result.Error = err.Error()
+		return result, false
+	}
+	quote, err := s.orders.QuoteOrder(ctx, &domain.CreateOrderRequest{Order: row.Order})
+	if err != nil {
+		result.Error = err.Error()
+		return result, false
+	}
+
+	result.Status = domain.ImportRowValid
+	result.Price, result.VAT = quote.OrderPrice, quote.OrderVAT
+	return result, true
+}
+
+// checkRow checks a row was read and orders at least one item, each with a
+// positive quantity.
+func checkRow(row domain.ImportRow) error {
+	if row.Error != "" {
+		return errors.New(row.Error)
+	}
+	if len(row.Order.Items) == 0 {
+		return errors.New("order must contain at least one item")
+	}
+	for _, item := range row.Order.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("quantity of product %d must be positive", item.ProductID)
+		}
+	}
+	return nil
+}
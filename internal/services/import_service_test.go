@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+type MockImportRepository struct {
+	mock.Mock
+}
+
+func (m *MockImportRepository) Create(ctx context.Context, job *domain.ImportJob, rows []domain.ImportRow) (*domain.ImportJob, error) {
+	args := m.Called(ctx, job, rows)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ImportJob), args.Error(1)
+}
+
+func (m *MockImportRepository) Claim(ctx context.Context, staleAfter time.Duration) (*domain.ImportJob, []domain.ImportRow, error) {
+	args := m.Called(ctx, staleAfter)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*domain.ImportJob), args.Get(1).([]domain.ImportRow), args.Error(2)
+}
+
+func (m *MockImportRepository) RecordResults(ctx context.Context, id string, results []domain.ImportResult) error {
+	// Copy the results, the caller may reuse the slice
+	args := m.Called(ctx, id, append([]domain.ImportResult(nil), results...))
+	return args.Error(0)
+}
+
+func (m *MockImportRepository) Finish(ctx context.Context, id, status, message string) error {
+	args := m.Called(ctx, id, status, message)
+	return args.Error(0)
+}
+
+func (m *MockImportRepository) GetByID(ctx context.Context, id string) (*domain.ImportJob, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ImportJob), args.Error(1)
+}
+
+// importRows returns n valid rows ordering one unit of product 1 each.
+func importRows(n int) []domain.ImportRow {
+	rows := make([]domain.ImportRow, n)
+	for i := range rows {
+		rows[i] = domain.ImportRow{Line: i + 1, Order: domain.OrderInput{Items: []domain.OrderItem{{ProductID: 1, Quantity: 1}}}}
+	}
+	return rows
+}
+
+func TestImportOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Best effort creates the valid rows", func(t *testing.T) {
+		// Setup
+		importRepo := new(MockImportRepository)
+		orders := new(MockOrderService)
+		importService := NewImportService(importRepo, orders, 10, 100)
+
+		rows := importRows(3)
+		rows[1].Error = "line 2: invalid quantity"
+		importRepo.On("Create", ctx, mock.MatchedBy(func(job *domain.ImportJob) bool {
+			return len(job.ID) == 32 && job.Status == domain.ImportStatusRunning && job.Mode == domain.ImportModeBestEffort && job.Total == 3
+		}), rows).Return(&domain.ImportJob{ID: "job", Status: domain.ImportStatusRunning}, nil)
+		orders.On("CreateOrder", mock.Anything, mock.Anything).Return(&domain.OrderResponse{OrderID: 7, OrderPrice: 10.0, OrderVAT: 1.0}, nil).Once()
+		orders.On("CreateOrder", mock.Anything, mock.Anything).Return(nil, domain.ErrProductNotFound).Once()
+		importRepo.On("RecordResults", mock.Anything, "job", []domain.ImportResult{
+			{Line: 1, Status: domain.ImportRowCreated, OrderID: 7, Price: 10.0, VAT: 1.0},
+		}).Return(nil)
+		importRepo.On("RecordResults", mock.Anything, "job", []domain.ImportResult{
+			{Line: 2, Status: domain.ImportRowFailed, Error: "line 2: invalid quantity"},
+		}).Return(nil)
+		importRepo.On("RecordResults", mock.Anything, "job", []domain.ImportResult{
+			{Line: 3, Status: domain.ImportRowFailed, Error: domain.ErrProductNotFound.Error()},
+		}).Return(nil)
+		importRepo.On("Finish", mock.Anything, "job", domain.ImportStatusCompleted, "").Return(nil)
+		importRepo.On("GetByID", mock.Anything, "job").Return(&domain.ImportJob{ID: "job", Status: domain.ImportStatusCompleted}, nil)
+
+		job, err := importService.ImportOrders(ctx, &domain.ImportRequest{Format: domain.ImportFormatCSV, Rows: rows})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.ImportStatusCompleted, job.Status)
+		importRepo.AssertExpectations(t)
+		orders.AssertNumberOfCalls(t, "CreateOrder", 2)
+	})
+
+	t.Run("All or nothing creates nothing when a row is invalid", func(t *testing.T) {
+		// Setup
+		importRepo := new(MockImportRepository)
+		orders := new(MockOrderService)
+		importService := NewImportService(importRepo, orders, 10, 100)
+
+		rows := importRows(2)
+		rows[1].Order.Items[0].Quantity = 0
+		importRepo.On("Create", ctx, mock.Anything, rows).Return(&domain.ImportJob{ID: "job", Mode: domain.ImportModeAllOrNothing}, nil)
+		orders.On("QuoteOrder", mock.Anything, mock.Anything).Return(&domain.QuoteResponse{OrderPrice: 10.0, OrderVAT: 1.0}, nil)
+		importRepo.On("RecordResults", mock.Anything, "job", []domain.ImportResult{
+			{Line: 1, Status: domain.ImportRowSkipped, Price: 10.0, VAT: 1.0},
+			{Line: 2, Status: domain.ImportRowFailed, Error: "quantity of product 1 must be positive"},
+		}).Return(nil)
+		importRepo.On("Finish", mock.Anything, "job", domain.ImportStatusFailed, "1 of 2 rows are invalid, no order was created").Return(nil)
+		importRepo.On("GetByID", mock.Anything, "job").Return(&domain.ImportJob{ID: "job", Status: domain.ImportStatusFailed}, nil)
+
+		_, err := importService.ImportOrders(ctx, &domain.ImportRequest{Mode: domain.ImportModeAllOrNothing, Rows: rows})
+
+		// Assertions
+		require.NoError(t, err)
+		importRepo.AssertExpectations(t)
+		orders.AssertNotCalled(t, "CreateOrders", mock.Anything, mock.Anything)
+	})
+
+	t.Run("All or nothing creates the orders in one batch", func(t *testing.T) {
+		// Setup
+		importRepo := new(MockImportRepository)
+		orders := new(MockOrderService)
+		importService := NewImportService(importRepo, orders, 10, 100)
+
+		rows := importRows(2)
+		importRepo.On("Create", ctx, mock.Anything, rows).Return(&domain.ImportJob{ID: "job", Mode: domain.ImportModeAllOrNothing}, nil)
+		orders.On("QuoteOrder", mock.Anything, mock.Anything).Return(&domain.QuoteResponse{OrderPrice: 10.0, OrderVAT: 1.0}, nil)
+		orders.On("CreateOrders", mock.Anything, mock.MatchedBy(func(reqs []*domain.CreateOrderRequest) bool {
+			return len(reqs) == 2
+		})).Return([]*domain.OrderResponse{{OrderID: 3, OrderPrice: 10.0}, {OrderID: 4, OrderPrice: 10.0}}, nil)
+		importRepo.On("RecordResults", mock.Anything, "job", []domain.ImportResult{
+			{Line: 1, Status: domain.ImportRowCreated, OrderID: 3, Price: 10.0},
+			{Line: 2, Status: domain.ImportRowCreated, OrderID: 4, Price: 10.0},
+		}).Return(nil)
+		importRepo.On("Finish", mock.Anything, "job", domain.ImportStatusCompleted, "").Return(nil)
+		importRepo.On("GetByID", mock.Anything, "job").Return(&domain.ImportJob{ID: "job"}, nil)
+
+		_, err := importService.ImportOrders(ctx, &domain.ImportRequest{Mode: domain.ImportModeAllOrNothing, Rows: rows})
+
+		// Assertions
+		require.NoError(t, err)
+		importRepo.AssertExpectations(t)
+	})
+
+	t.Run("Dry run only prices the rows", func(t *testing.T) {
+		// Setup
+		importRepo := new(MockImportRepository)
+		orders := new(MockOrderService)
+		importService := NewImportService(importRepo, orders, 10, 100)
+
+		rows := importRows(1)
+		importRepo.On("Create", ctx, mock.Anything, rows).Return(&domain.ImportJob{ID: "job", DryRun: true}, nil)
+		orders.On("QuoteOrder", mock.Anything, mock.Anything).Return(&domain.QuoteResponse{OrderPrice: 10.0, OrderVAT: 1.0}, nil)
+		importRepo.On("RecordResults", mock.Anything, "job", []domain.ImportResult{
+			{Line: 1, Status: domain.ImportRowValid, Price: 10.0, VAT: 1.0},
+		}).Return(nil)
+		importRepo.On("Finish", mock.Anything, "job", domain.ImportStatusCompleted, "").Return(nil)
+		importRepo.On("GetByID", mock.Anything, "job").Return(&domain.ImportJob{ID: "job"}, nil)
+
+		_, err := importService.ImportOrders(ctx, &domain.ImportRequest{DryRun: true, Rows: rows})
+
+		// Assertions
+		require.NoError(t, err)
+		importRepo.AssertExpectations(t)
+		orders.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Large imports are queued", func(t *testing.T) {
+		// Setup
+		importRepo := new(MockImportRepository)
+		importService := NewImportService(importRepo, new(MockOrderService), 2, 100)
+
+		importRepo.On("Create", ctx, mock.MatchedBy(func(job *domain.ImportJob) bool {
+			return job.Status == domain.ImportStatusQueued
+		}), mock.Anything).Return(&domain.ImportJob{ID: "job", Status: domain.ImportStatusQueued}, nil)
+
+		job, err := importService.ImportOrders(ctx, &domain.ImportRequest{Rows: importRows(3)})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.ImportStatusQueued, job.Status)
+		importRepo.AssertNotCalled(t, "Finish", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		importService := NewImportService(new(MockImportRepository), new(MockOrderService), 10, 2)
+
+		cases := []*domain.ImportRequest{
+			{Mode: "sometimes", Rows: importRows(1)},
+			{},
+			{Rows: importRows(3)},
+		}
+		for _, req := range cases {
+			_, err := importService.ImportOrders(ctx, req)
+
+			// Assertions
+			assert.ErrorIs(t, err, ErrInvalidImport)
+		}
+	})
+}
+
+func TestRunImportJobs(t *testing.T) {
+	// Setup
+	importRepo := new(MockImportRepository)
+	orders := new(MockOrderService)
+	importService := NewImportService(importRepo, orders, 10, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	importRepo.On("Claim", mock.Anything, importStaleAfter).
+		Return(&domain.ImportJob{ID: "job", Mode: domain.ImportModeBestEffort, RequestID: "req-1"}, importRows(1), nil).Once()
+	importRepo.On("Claim", mock.Anything, importStaleAfter).Return(nil, nil, domain.ErrImportJobNotFound)
+	orders.On("CreateOrder", mock.MatchedBy(func(ctx context.Context) bool {
+		return requestid.FromContext(ctx) == "req-1"
+	}), mock.Anything).Return(&domain.OrderResponse{OrderID: 7}, nil)
+	importRepo.On("RecordResults", mock.Anything, "job", mock.Anything).Return(nil)
+	importRepo.On("Finish", mock.Anything, "job", domain.ImportStatusCompleted, "").
+		Run(func(mock.Arguments) { cancel() }).Return(nil)
+
+	done := make(chan struct{})
+	go func() {
+		importService.RunJobs(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	// Assertions
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("import worker did not stop")
+	}
+	importRepo.AssertExpectations(t)
+	orders.AssertNumberOfCalls(t, "CreateOrder", 1)
+}
+
+func TestImportJobNotFound(t *testing.T) {
+	// Setup
+	importRepo := new(MockImportRepository)
+	importService := NewImportService(importRepo, new(MockOrderService), 10, 100)
+	importRepo.On("GetByID", mock.Anything, "missing").Return(nil, domain.ErrImportJobNotFound)
+
+	_, err := importService.GetImportJob(context.Background(), "missing")
+
+	// Assertions
+	assert.True(t, errors.Is(err, domain.ErrImportJobNotFound))
+}
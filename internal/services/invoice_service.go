@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/invoice"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// InvoiceSettings are the details printed on every invoice and how fiscal
+// years are counted. A fiscal year starts on the first day of
+// FiscalYearStart in Location and is named after the calendar year it
+// starts in.
+type InvoiceSettings struct {
+	Seller          domain.InvoiceParty
+	Currency        string
+	Location        *time.Location
+	FiscalYearStart time.Month
+}
+
+type InvoiceService struct {
+	invoiceRepo repository.InvoiceRepository
+	settings    InvoiceSettings
+	now         func() time.Time
+}
+
+func NewInvoiceService(invoiceRepo repository.InvoiceRepository, settings InvoiceSettings) *InvoiceService {
+	if settings.Location == nil {
+		settings.Location = time.UTC
+	}
+	if settings.FiscalYearStart == 0 {
+		settings.FiscalYearStart = time.January
+	}
+	return &InvoiceService{
+		invoiceRepo: invoiceRepo,
+		settings:    settings,
+		now:         time.Now,
+	}
+}
+
+// GetInvoice returns the invoice of a paid order, issuing it on the first
+// request with the next number of the current fiscal year. Later requests
+// return the same invoice. Orders that are not paid or refunded return
+// domain.ErrOrderNotInvoiceable.
+func (s *InvoiceService) GetInvoice(ctx context.Context, orderID int64) (*domain.Invoice, error) {
+	inv, err := s.invoiceRepo.GetByOrder(ctx, orderID)
+	if errors.Is(err, domain.ErrInvoiceNotFound) {
+		now := s.now().In(s.settings.Location)
+		inv, err = s.invoiceRepo.Issue(ctx, &domain.Invoice{
+			OrderID:    orderID,
+			FiscalYear: fiscalYear(now, s.settings.FiscalYearStart),
+			Currency:   s.settings.Currency,
+			IssuedAt:   now,
+		})
+		if err == nil {
+			logging.FromContext(ctx).Info("invoice issued", "order_id", orderID, "invoice", inv.Number)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.complete(inv), nil
+}
+
+// complete adds the seller and the amounts derived from the stored ones.
+func (s *InvoiceService) complete(inv *domain.Invoice) *domain.Invoice {
+	inv.Seller = s.settings.Seller
+	inv.IssuedAt = inv.IssuedAt.In(s.settings.Location)
+	inv.Total = domain.FromCents(domain.Cents(inv.Price) + domain.Cents(inv.VAT))
+	for i := range inv.Lines {
+		line := &inv.Lines[i]
+		if line.Quantity > 0 {
+			line.UnitPrice = domain.FromCents(int64(math.Round(float64(domain.Cents(line.Price)) / float64(line.Quantity))))
+		}
+		line.VATRate = invoice.VATRate(line.Price, line.VAT)
+	}
+	if inv.Lines == nil {
+		inv.Lines = []domain.InvoiceLine{}
+	}
+	return inv
+}
+
+// fiscalYear returns the fiscal year t falls in, named after the calendar
+// year it starts in.
+func fiscalYear(t time.Time, start time.Month) int {
+	if t.Month() < start {
+		return t.Year() - 1
+	}
+	return t.Year()
+}
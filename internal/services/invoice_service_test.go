@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type MockInvoiceRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceRepository) GetByOrder(ctx context.Context, orderID int64) (*domain.Invoice, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) Issue(ctx context.Context, invoice *domain.Invoice) (*domain.Invoice, error) {
+	args := m.Called(ctx, invoice)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invoice), args.Error(1)
+}
+
+func TestGetInvoice(t *testing.T) {
+	ctx := context.Background()
+	rome := time.FixedZone("CEST", 2*60*60)
+	settings := InvoiceSettings{
+		Seller:          domain.InvoiceParty{Name: "Shop"},
+		Currency:        "EUR",
+		Location:        rome,
+		FiscalYearStart: time.April,
+	}
+
+	t.Run("First request issues the invoice", func(t *testing.T) {
+		// Setup
+		invoiceRepo := new(MockInvoiceRepository)
+		invoiceService := NewInvoiceService(invoiceRepo, settings)
+		// 1 April 2025 in Rome, still March in UTC
+		invoiceService.now = func() time.Time { return time.Date(2025, 3, 31, 22, 30, 0, 0, time.UTC) }
+
+		invoiceRepo.On("GetByOrder", ctx, int64(1)).Return(nil, domain.ErrInvoiceNotFound)
+		invoiceRepo.On("Issue", ctx, mock.MatchedBy(func(inv *domain.Invoice) bool {
+			return inv.OrderID == 1 && inv.FiscalYear == 2025 && inv.Currency == "EUR"
+		})).Return(&domain.Invoice{
+			Number: "2025-000001", OrderID: 1, Price: 35.0, VAT: 3.5,
+			Lines: []domain.InvoiceLine{
+				{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0},
+				{ProductID: 2, Quantity: 3, Price: 15.0, VAT: 1.5},
+			},
+			IssuedAt: time.Date(2025, 3, 31, 22, 30, 0, 0, time.UTC),
+		}, nil)
+
+		inv, err := invoiceService.GetInvoice(ctx, 1)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, "Shop", inv.Seller.Name)
+		assert.Equal(t, 38.5, inv.Total)
+		assert.Equal(t, 10.0, inv.Lines[0].UnitPrice)
+		assert.Equal(t, 5.0, inv.Lines[1].UnitPrice)
+		assert.Equal(t, 10.0, inv.Lines[1].VATRate)
+		assert.Equal(t, "2025-04-01", inv.IssuedAt.Format("2006-01-02"))
+		invoiceRepo.AssertExpectations(t)
+	})
+
+	t.Run("Issued invoice is returned", func(t *testing.T) {
+		// Setup
+		invoiceRepo := new(MockInvoiceRepository)
+		invoiceService := NewInvoiceService(invoiceRepo, settings)
+		invoiceRepo.On("GetByOrder", ctx, int64(1)).Return(&domain.Invoice{Number: "2024-000007", OrderID: 1}, nil)
+
+		inv, err := invoiceService.GetInvoice(ctx, 1)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, "2024-000007", inv.Number)
+		assert.NotNil(t, inv.Lines)
+		invoiceRepo.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unpaid order", func(t *testing.T) {
+		// Setup
+		invoiceRepo := new(MockInvoiceRepository)
+		invoiceService := NewInvoiceService(invoiceRepo, settings)
+		invoiceRepo.On("GetByOrder", ctx, int64(1)).Return(nil, domain.ErrInvoiceNotFound)
+		invoiceRepo.On("Issue", ctx, mock.Anything).Return(nil, domain.ErrOrderNotInvoiceable)
+
+		_, err := invoiceService.GetInvoice(ctx, 1)
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrOrderNotInvoiceable)
+	})
+}
+
+func TestFiscalYear(t *testing.T) {
+	assert.Equal(t, 2024, fiscalYear(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), time.January))
+	assert.Equal(t, 2023, fiscalYear(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), time.April))
+	assert.Equal(t, 2024, fiscalYear(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.April))
+}
@@ -2,39 +2,53 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
 	"github.com/valeriouberti/order-service-test/internal/repository"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
 )
 
+// ErrInvalidOrderUpdate is returned when the changes of an order update fail validation.
+var ErrInvalidOrderUpdate = errors.New("invalid order update")
+
 type OrderService struct {
 	orderRepo   repository.OrderRepository
 	productRepo repository.ProductRepository
+	events      EventPublisher
+	quotes      QuoteSigner
 }
 
-func NewOrderService(orderRepo repository.OrderRepository, productRepo repository.ProductRepository) *OrderService {
+// NewOrderService creates an OrderService. events may be nil when nothing
+// needs to be notified of order changes, and quotes may be nil when quote
+// tokens are not issued.
+func NewOrderService(orderRepo repository.OrderRepository, productRepo repository.ProductRepository, events EventPublisher, quotes QuoteSigner) *OrderService {
 	return &OrderService{
 		orderRepo:   orderRepo,
 		productRepo: productRepo,
+		events:      events,
+		quotes:      quotes,
 	}
 }
 
 // CreateOrder creates a new order based on the provided request.
 //
 // It performs the following steps:
-// 1. Initializes an order with items from the request
-// 2. For each item:
-//   - Retrieves the product details from repository
-//   - Calculates the price and VAT based on product information and quantity
-//   - Updates the item with calculated values
+// 1. Initializes a pending order with the customer and items from the request and the caller's request ID
+// 2. Prices the items:
+//   - With a quote token, verifies it and uses the quoted prices
+//   - Otherwise retrieves each product and calculates the price and VAT based on product information and quantity
 //
 // 3. Calculates total price and VAT for the entire order
 // 4. Persists the order in the database
-// 5. Maps the created order to a response object
+// 5. Publishes an order.created event to the event publisher
+// 6. Maps the created order to a response object
 //
 // The function returns the order response containing ID, price, VAT, and items.
-// If a product is not found or if there's an error saving the order, an error is returned.
+// If a product is not found, the quote token is invalid, expired or does not
+// match the items, or if there's an error saving the order, an error is returned.
 //
 // Parameters:
 //   - ctx: context.Context for the operation
@@ -44,53 +58,181 @@ func NewOrderService(orderRepo repository.OrderRepository, productRepo repositor
 //   - *domain.OrderResponse: the created order with calculated totals
 //   - error: if any step fails during order creation
 func (s *OrderService) CreateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.OrderResponse, error) {
-	// Initialize order
+	logger := logging.FromContext(ctx)
+
+	// Price the items, honouring a quote if there is one
+	var items []domain.OrderItem
+	var err error
+	if req.QuoteToken != "" {
+		items, err = s.quotedItems(req.QuoteToken, req.Order.Items)
+		if err != nil {
+			logger.Warn("quote rejected", "error", err)
+			return nil, err
+		}
+	} else {
+		items, err = s.priceItems(ctx, req.Order.Items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Initialize order with its totals
 	order := &domain.Order{
-		Items: req.Order.Items,
+		CustomerID: req.Order.CustomerID,
+		Status:     domain.OrderStatusPending,
+		Items:      items,
+		RequestID:  requestid.FromContext(ctx),
+		CartID:     req.CartID,
+	}
+	order.Price, order.VAT = totals(items)
+
+	// Save order to database
+	createdOrder, err := s.orderRepo.Create(ctx, order)
+	if err != nil {
+		logger.Error("failed to create order", "error", err)
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	logger.Info("order created",
+		"order_id", createdOrder.ID,
+		"items", len(createdOrder.Items),
+		"order_price", createdOrder.Price,
+		"quoted", req.QuoteToken != "",
+	)
+
+	// Notify subscribers
+	s.publish(ctx, domain.EventOrderCreated, createdOrder)
+
+	// Map to response
+	return toOrderResponse(createdOrder), nil
+}
+
+// CreateOrders creates several orders in a single transaction: either all of
+// them are created or none is. Each order is priced like by CreateOrder and
+// an order.created event is published for each once they are all persisted.
+// A pricing error names the position of the failing request.
+func (s *OrderService) CreateOrders(ctx context.Context, reqs []*domain.CreateOrderRequest) ([]*domain.OrderResponse, error) {
+	logger := logging.FromContext(ctx)
+
+	orders := make([]*domain.Order, len(reqs))
+	for i, req := range reqs {
+		var items []domain.OrderItem
+		var err error
+		if req.QuoteToken != "" {
+			items, err = s.quotedItems(req.QuoteToken, req.Order.Items)
+		} else {
+			items, err = s.priceItems(ctx, req.Order.Items)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("order %d: %w", i+1, err)
+		}
+
+		orders[i] = &domain.Order{
+			CustomerID: req.Order.CustomerID,
+			Status:     domain.OrderStatusPending,
+			Items:      items,
+			RequestID:  requestid.FromContext(ctx),
+		}
+		orders[i].Price, orders[i].VAT = totals(items)
+	}
+
+	created, err := s.orderRepo.CreateBatch(ctx, orders)
+	if err != nil {
+		logger.Error("failed to create orders", "orders", len(orders), "error", err)
+		return nil, fmt.Errorf("failed to create orders: %w", err)
 	}
 
-	// Calculate price and VAT for each item
-	var totalPrice, totalVAT float64
+	logger.Info("orders created", "orders", len(created))
+
+	responses := make([]*domain.OrderResponse, len(created))
+	for i, order := range created {
+		s.publish(ctx, domain.EventOrderCreated, order)
+		responses[i] = toOrderResponse(order)
+	}
+
+	return responses, nil
+}
 
-	for i, item := range order.Items {
+// QuoteOrder prices an order exactly like CreateOrder without persisting it.
+// The response carries a signed token that CreateOrder accepts until it
+// expires, charging the quoted prices even if the catalog changed meanwhile.
+func (s *OrderService) QuoteOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.QuoteResponse, error) {
+	items, err := s.priceItems(ctx, req.Order.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.QuoteResponse{Items: items}
+	response.OrderPrice, response.OrderVAT = totals(items)
+
+	if s.quotes != nil {
+		token, expiresAt, err := s.quotes.Sign(items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign quote: %w", err)
+		}
+		response.QuoteToken = token
+		response.ExpiresAt = &expiresAt
+	}
+
+	return response, nil
+}
+
+// priceItems looks up the product of every item and returns a copy of the
+// items with their line price and VAT.
+func (s *OrderService) priceItems(ctx context.Context, items []domain.OrderItem) ([]domain.OrderItem, error) {
+	priced := make([]domain.OrderItem, len(items))
+
+	for i, item := range items {
 		// Get product details
 		product, err := s.productRepo.GetByID(ctx, item.ProductID)
 		if err != nil {
+			logging.FromContext(ctx).Warn("product lookup failed", "product_id", item.ProductID, "error", err)
 			return nil, fmt.Errorf("product with ID %d not found: %w", item.ProductID, err)
 		}
 
 		// Calculate item price and VAT
-		itemPrice := product.Price * float64(item.Quantity)
-		itemVAT := product.VAT * float64(item.Quantity)
+		priced[i] = domain.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     product.Price * float64(item.Quantity),
+			VAT:       product.VAT * float64(item.Quantity),
+		}
+	}
 
-		// Update the item with price and VAT
-		order.Items[i].Price = itemPrice
-		order.Items[i].VAT = itemVAT
+	return priced, nil
+}
 
-		// Add to totals
-		totalPrice += itemPrice
-		totalVAT += itemVAT
+// quotedItems verifies a quote token and returns the items it priced, which
+// must be the requested items: same products and quantities, in the same order.
+func (s *OrderService) quotedItems(token string, items []domain.OrderItem) ([]domain.OrderItem, error) {
+	if s.quotes == nil {
+		return nil, domain.ErrInvalidQuote
 	}
 
-	// Set order totals
-	order.Price = totalPrice
-	order.VAT = totalVAT
-
-	// Save order to database
-	createdOrder, err := s.orderRepo.Create(ctx, order)
+	quoted, err := s.quotes.Verify(token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+		return nil, err
 	}
 
-	// Map to response
-	response := &domain.OrderResponse{
-		OrderID:    createdOrder.ID,
-		OrderPrice: createdOrder.Price,
-		OrderVAT:   createdOrder.VAT,
-		Items:      createdOrder.Items,
+	if len(quoted) != len(items) {
+		return nil, domain.ErrQuoteMismatch
+	}
+	for i := range items {
+		if quoted[i].ProductID != items[i].ProductID || quoted[i].Quantity != items[i].Quantity {
+			return nil, domain.ErrQuoteMismatch
+		}
 	}
 
-	return response, nil
+	return quoted, nil
+}
+
+// totals sums the price and VAT of the items.
+func totals(items []domain.OrderItem) (price, vat float64) {
+	for _, item := range items {
+		price += item.Price
+		vat += item.VAT
+	}
+	return price, vat
 }
 
 // GetOrder retrieves an order by its ID.
@@ -109,12 +251,233 @@ func (s *OrderService) GetOrder(ctx context.Context, id int64) (*domain.OrderRes
 		return nil, err
 	}
 
-	response := &domain.OrderResponse{
-		OrderID:    order.ID,
-		OrderPrice: order.Price,
-		OrderVAT:   order.VAT,
-		Items:      order.Items,
+	return toOrderResponse(order), nil
+}
+
+// ListOrders returns a page of orders matching filter, ordered by ID.
+// A zero limit selects domain.DefaultOrderListLimit and larger limits are
+// capped at domain.MaxOrderListLimit. When the page is full, NextAfterID is
+// set to the cursor of the following page.
+func (s *OrderService) ListOrders(ctx context.Context, filter domain.OrderFilter) (*domain.OrderListResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = domain.DefaultOrderListLimit
+	}
+	if filter.Limit > domain.MaxOrderListLimit {
+		filter.Limit = domain.MaxOrderListLimit
+	}
+
+	orders, err := s.orderRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+
+	response := &domain.OrderListResponse{Orders: make([]domain.OrderResponse, 0, len(orders))}
+	for i := range orders {
+		response.Orders = append(response.Orders, *toOrderResponse(&orders[i]))
+	}
+	if len(orders) == filter.Limit {
+		response.NextAfterID = orders[len(orders)-1].ID
 	}
 
 	return response, nil
 }
+
+// ExportOrders calls fn with every order matching filter, oldest first, in
+// the representation returned by GetOrder. Unlike ListOrders the orders are
+// not paged: a zero limit exports all of them. The orders are streamed from
+// the repository and the export stops at the first error returned by fn.
+func (s *OrderService) ExportOrders(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.OrderResponse) error) error {
+	if filter.Limit < 0 {
+		filter.Limit = 0
+	}
+
+	err := s.orderRepo.Export(ctx, filter, func(order *domain.Order) error {
+		return fn(toOrderResponse(order))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export orders: %w", err)
+	}
+	return nil
+}
+
+// UpdateOrder adds, removes or changes lines of a pending order.
+//
+// Every requested item sets the quantity of its product: products not in the
+// order are added, a zero quantity removes the product's line and products
+// that are not listed keep their line and price. Added and changed lines are
+// priced with the current product prices, like at creation, and the totals
+// are recomputed. The change is recorded as a revision of the order and
+// published as an order.updated event.
+//
+// The changes apply only if the order is still at req.Version; otherwise
+// domain.ErrVersionMismatch is returned and nothing changes. It returns
+// ErrInvalidOrderUpdate for invalid changes or changes that would leave the
+// order empty, domain.ErrOrderNotFound for unknown orders and
+// domain.ErrOrderNotEditable once the order is no longer pending.
+func (s *OrderService) UpdateOrder(ctx context.Context, id int64, req *domain.UpdateOrderRequest) (*domain.OrderResponse, error) {
+	logger := logging.FromContext(ctx)
+
+	changes, err := validateItemChanges(req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Price the added and changed lines before the order is locked
+	var set []domain.OrderItem
+	for _, change := range changes {
+		if change.Quantity > 0 {
+			set = append(set, change)
+		}
+	}
+	priced, err := s.priceItems(ctx, set)
+	if err != nil {
+		return nil, err
+	}
+	lines := make(map[int64]domain.OrderItem, len(changes))
+	for _, change := range changes {
+		lines[change.ProductID] = change
+	}
+	for _, item := range priced {
+		lines[item.ProductID] = item
+	}
+
+	updated, err := s.orderRepo.Update(ctx, id, req.Version, func(order *domain.Order) (*domain.OrderRevision, error) {
+		if order.Status != domain.OrderStatusPending {
+			return nil, domain.ErrOrderNotEditable
+		}
+
+		items := applyItemChanges(order.Items, changes, lines)
+		if len(items) == 0 {
+			return nil, fmt.Errorf("%w: an order must keep at least one item", ErrInvalidOrderUpdate)
+		}
+
+		revision := &domain.OrderRevision{
+			Changes:     changes,
+			ItemsBefore: order.Items,
+			PriceBefore: order.Price,
+			VATBefore:   order.VAT,
+			ItemsAfter:  items,
+			RequestID:   requestid.FromContext(ctx),
+		}
+		order.Items = items
+		order.Price, order.VAT = totals(items)
+		revision.PriceAfter, revision.VATAfter = order.Price, order.VAT
+
+		return revision, nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrOrderNotFound) || errors.Is(err, domain.ErrOrderNotEditable) ||
+			errors.Is(err, domain.ErrVersionMismatch) || errors.Is(err, ErrInvalidOrderUpdate) {
+			return nil, err
+		}
+		logger.Error("failed to update order", "order_id", id, "error", err)
+		return nil, fmt.Errorf("failed to update order: %w", err)
+	}
+
+	logger.Info("order updated",
+		"order_id", updated.ID,
+		"changes", len(changes),
+		"order_price", updated.Price,
+	)
+
+	// Notify subscribers
+	s.publish(ctx, domain.EventOrderUpdated, updated)
+
+	return toOrderResponse(updated), nil
+}
+
+// ListOrderRevisions returns the revision history of an order, oldest first.
+func (s *OrderService) ListOrderRevisions(ctx context.Context, id int64) ([]domain.OrderRevision, error) {
+	return s.orderRepo.ListRevisions(ctx, id)
+}
+
+// validateItemChanges checks the requested line changes and returns them
+// without prices: at least one change, non-negative quantities and each
+// product at most once.
+func validateItemChanges(items []domain.OrderItem) ([]domain.OrderItem, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item change is required", ErrInvalidOrderUpdate)
+	}
+
+	changes := make([]domain.OrderItem, 0, len(items))
+	seen := make(map[int64]bool, len(items))
+	for _, item := range items {
+		if item.Quantity < 0 {
+			return nil, fmt.Errorf("%w: quantity of product %d must not be negative", ErrInvalidOrderUpdate, item.ProductID)
+		}
+		if seen[item.ProductID] {
+			return nil, fmt.Errorf("%w: product %d is listed more than once", ErrInvalidOrderUpdate, item.ProductID)
+		}
+		seen[item.ProductID] = true
+		changes = append(changes, domain.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	return changes, nil
+}
+
+// applyItemChanges returns the items of an order after the changes. Lines
+// keep their position; a changed product is replaced by its line in lines,
+// or dropped when its quantity is zero, and added products are appended in
+// the order they were requested.
+func applyItemChanges(items, changes []domain.OrderItem, lines map[int64]domain.OrderItem) []domain.OrderItem {
+	result := make([]domain.OrderItem, 0, len(items)+len(changes))
+	placed := make(map[int64]bool, len(changes))
+
+	for _, item := range items {
+		line, changed := lines[item.ProductID]
+		switch {
+		case !changed:
+			result = append(result, item)
+		case !placed[item.ProductID] && line.Quantity > 0:
+			// Further lines of the same product are merged into this one
+			result = append(result, line)
+			placed[item.ProductID] = true
+		default:
+			placed[item.ProductID] = true
+		}
+	}
+
+	for _, change := range changes {
+		if !placed[change.ProductID] && change.Quantity > 0 {
+			result = append(result, lines[change.ProductID])
+		}
+	}
+
+	return result
+}
+
+// publish sends an order event to the event publisher, if any. Failing to
+// build the event is logged but never fails the operation: the change is
+// already committed and the outbox carries the durable copy of the event.
+func (s *OrderService) publish(ctx context.Context, eventType string, order *domain.Order) {
+	if s.events == nil {
+		return
+	}
+
+	event, err := domain.NewOrderEvent(eventType, order)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to build order event", "order_id", order.ID, "error", err)
+		return
+	}
+	s.events.Publish(*event)
+}
+
+// toOrderResponse maps an order to its API representation, with its totals
+// net of refunds.
+func toOrderResponse(order *domain.Order) *domain.OrderResponse {
+	return &domain.OrderResponse{
+		OrderID:       order.ID,
+		CustomerID:    order.CustomerID,
+		Status:        order.Status,
+		OrderPrice:    order.Price,
+		OrderVAT:      order.VAT,
+		RefundedPrice: order.RefundedPrice,
+		RefundedVAT:   order.RefundedVAT,
+		NetPrice:      domain.FromCents(domain.Cents(order.Price) - domain.Cents(order.RefundedPrice)),
+		NetVAT:        domain.FromCents(domain.Cents(order.VAT) - domain.Cents(order.RefundedVAT)),
+		RequestID:     order.RequestID,
+		Version:       order.Version,
+		CreatedAt:     order.CreatedAt,
+		Items:         order.Items,
+	}
+}
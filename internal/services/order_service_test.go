@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/quote"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
 )
 
 // Mock repositories
@@ -23,6 +27,26 @@ func (m *MockOrderRepository) Create(ctx context.Context, order *domain.Order) (
 	return args.Get(0).(*domain.Order), args.Error(1)
 }
 
+func (m *MockOrderRepository) CreateBatch(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error) {
+	args := m.Called(ctx, orders)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) Export(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.Order) error) error {
+	args := m.Called(ctx, filter, fn)
+	if orders, ok := args.Get(0).([]*domain.Order); ok {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
 func (m *MockOrderRepository) GetByID(ctx context.Context, id int64) (*domain.Order, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -31,6 +55,36 @@ func (m *MockOrderRepository) GetByID(ctx context.Context, id int64) (*domain.Or
 	return args.Get(0).(*domain.Order), args.Error(1)
 }
 
+func (m *MockOrderRepository) List(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Order), args.Error(1)
+}
+
+// Update applies update to the order returned by the expectation, like the
+// repository does to the locked order.
+func (m *MockOrderRepository) Update(ctx context.Context, id int64, version int, update func(order *domain.Order) (*domain.OrderRevision, error)) (*domain.Order, error) {
+	args := m.Called(ctx, id, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	order := args.Get(0).(*domain.Order)
+	if _, err := update(order); err != nil {
+		return nil, err
+	}
+	return order, args.Error(1)
+}
+
+func (m *MockOrderRepository) ListRevisions(ctx context.Context, orderID int64) ([]domain.OrderRevision, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.OrderRevision), args.Error(1)
+}
+
 type MockProductRepository struct {
 	mock.Mock
 }
@@ -43,12 +97,20 @@ func (m *MockProductRepository) GetByID(ctx context.Context, id int64) (*domain.
 	return args.Get(0).(*domain.Product), args.Error(1)
 }
 
+func (m *MockProductRepository) GetByIDs(ctx context.Context, ids []int64) ([]domain.Product, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
 // Test CreateOrder
 func TestCreateOrder(t *testing.T) {
 	// Setup
 	mockOrderRepo := new(MockOrderRepository)
 	mockProductRepo := new(MockProductRepository)
-	orderService := NewOrderService(mockOrderRepo, mockProductRepo)
+	orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, nil)
 
 	ctx := context.Background()
 
@@ -56,9 +118,7 @@ func TestCreateOrder(t *testing.T) {
 	t.Run("Successful order creation", func(t *testing.T) {
 		// Mock input
 		req := &domain.CreateOrderRequest{
-			Order: struct {
-				Items []domain.OrderItem `json:"items"`
-			}{
+			Order: domain.OrderInput{
 				Items: []domain.OrderItem{
 					{ProductID: 1, Quantity: 2},
 					{ProductID: 2, Quantity: 3},
@@ -106,9 +166,7 @@ func TestCreateOrder(t *testing.T) {
 	t.Run("Product not found", func(t *testing.T) {
 		// Mock input
 		req := &domain.CreateOrderRequest{
-			Order: struct {
-				Items []domain.OrderItem `json:"items"`
-			}{
+			Order: domain.OrderInput{
 				Items: []domain.OrderItem{
 					{ProductID: 999, Quantity: 1},
 				},
@@ -131,12 +189,62 @@ func TestCreateOrder(t *testing.T) {
 	})
 }
 
+func TestCreateOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Creates all orders in one batch", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		mockProductRepo := new(MockProductRepository)
+		events := &recordingPublisher{}
+		orderService := NewOrderService(mockOrderRepo, mockProductRepo, events, nil)
+
+		mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 10.0, VAT: 1.0}, nil)
+		mockOrderRepo.On("CreateBatch", ctx, mock.MatchedBy(func(orders []*domain.Order) bool {
+			return len(orders) == 2 && orders[0].CustomerID == "c1" && orders[0].Price == 20.0 &&
+				orders[1].Price == 10.0 && orders[1].Status == domain.OrderStatusPending
+		})).Return([]*domain.Order{{ID: 1, Price: 20.0, VAT: 2.0}, {ID: 2, Price: 10.0, VAT: 1.0}}, nil)
+
+		responses, err := orderService.CreateOrders(ctx, []*domain.CreateOrderRequest{
+			{Order: domain.OrderInput{CustomerID: "c1", Items: []domain.OrderItem{{ProductID: 1, Quantity: 2}}}},
+			{Order: domain.OrderInput{Items: []domain.OrderItem{{ProductID: 1, Quantity: 1}}}},
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		require.Len(t, responses, 2)
+		assert.Equal(t, int64(2), responses[1].OrderID)
+		assert.Len(t, events.events, 2)
+		mockOrderRepo.AssertExpectations(t)
+	})
+
+	t.Run("Pricing error creates nothing", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		mockProductRepo := new(MockProductRepository)
+		orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, nil)
+
+		mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 10.0, VAT: 1.0}, nil)
+		mockProductRepo.On("GetByID", ctx, int64(9)).Return(nil, domain.ErrProductNotFound)
+
+		_, err := orderService.CreateOrders(ctx, []*domain.CreateOrderRequest{
+			{Order: domain.OrderInput{Items: []domain.OrderItem{{ProductID: 1, Quantity: 1}}}},
+			{Order: domain.OrderInput{Items: []domain.OrderItem{{ProductID: 9, Quantity: 1}}}},
+		})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrProductNotFound)
+		assert.Contains(t, err.Error(), "order 2")
+		mockOrderRepo.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+	})
+}
+
 // Test GetOrder
 func TestGetOrder(t *testing.T) {
 	// Setup
 	mockOrderRepo := new(MockOrderRepository)
 	mockProductRepo := new(MockProductRepository)
-	orderService := NewOrderService(mockOrderRepo, mockProductRepo)
+	orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, nil)
 
 	ctx := context.Background()
 
@@ -188,3 +296,357 @@ func TestGetOrder(t *testing.T) {
 		mockOrderRepo.AssertExpectations(t)
 	})
 }
+
+// Test that the request ID in the context is recorded on the order
+func TestCreateOrderRecordsRequestID(t *testing.T) {
+	mockOrderRepo := new(MockOrderRepository)
+	mockProductRepo := new(MockProductRepository)
+	orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, nil)
+
+	ctx := requestid.NewContext(context.Background(), "req-123")
+
+	req := &domain.CreateOrderRequest{
+		Order: domain.OrderInput{
+			Items: []domain.OrderItem{
+				{ProductID: 1, Quantity: 1},
+			},
+		},
+	}
+
+	mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 10.0, VAT: 1.0}, nil)
+	mockOrderRepo.On("Create", ctx, mock.MatchedBy(func(order *domain.Order) bool {
+		return order.RequestID == "req-123"
+	})).Return(&domain.Order{ID: 7, Price: 10.0, VAT: 1.0, RequestID: "req-123"}, nil)
+
+	result, err := orderService.CreateOrder(ctx, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "req-123", result.RequestID)
+	mockOrderRepo.AssertExpectations(t)
+}
+
+func TestListOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Full page returns a cursor", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		orderService := NewOrderService(mockOrderRepo, new(MockProductRepository), nil, nil)
+
+		mockOrderRepo.On("List", ctx, domain.OrderFilter{Status: "pending", Limit: 2}).Return([]domain.Order{
+			{ID: 3, Status: "pending"},
+			{ID: 7, Status: "pending"},
+		}, nil)
+
+		result, err := orderService.ListOrders(ctx, domain.OrderFilter{Status: "pending", Limit: 2})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Len(t, result.Orders, 2)
+		assert.Equal(t, int64(7), result.NextAfterID)
+		mockOrderRepo.AssertExpectations(t)
+	})
+
+	t.Run("Last page and default limit", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		orderService := NewOrderService(mockOrderRepo, new(MockProductRepository), nil, nil)
+
+		mockOrderRepo.On("List", ctx, domain.OrderFilter{Limit: domain.DefaultOrderListLimit}).Return([]domain.Order{{ID: 1}}, nil)
+
+		result, err := orderService.ListOrders(ctx, domain.OrderFilter{})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Len(t, result.Orders, 1)
+		assert.Zero(t, result.NextAfterID)
+		mockOrderRepo.AssertExpectations(t)
+	})
+
+	t.Run("Limit is capped", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		orderService := NewOrderService(mockOrderRepo, new(MockProductRepository), nil, nil)
+
+		mockOrderRepo.On("List", ctx, domain.OrderFilter{Limit: domain.MaxOrderListLimit}).Return([]domain.Order{}, nil)
+
+		result, err := orderService.ListOrders(ctx, domain.OrderFilter{Limit: 10000})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Empty(t, result.Orders)
+		mockOrderRepo.AssertExpectations(t)
+	})
+}
+
+func TestExportOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Streams every order as a response", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		orderService := NewOrderService(mockOrderRepo, new(MockProductRepository), nil, nil)
+
+		mockOrderRepo.On("Export", ctx, domain.OrderFilter{CustomerID: "cust-1"}, mock.Anything).Return([]*domain.Order{
+			{ID: 1, CustomerID: "cust-1", Price: 20.0, VAT: 2.0, RefundedPrice: 5.0, RefundedVAT: 0.5},
+			{ID: 2, CustomerID: "cust-1", Price: 10.0, VAT: 1.0},
+		}, nil)
+
+		var exported []*domain.OrderResponse
+		err := orderService.ExportOrders(ctx, domain.OrderFilter{CustomerID: "cust-1"}, func(order *domain.OrderResponse) error {
+			exported = append(exported, order)
+			return nil
+		})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Len(t, exported, 2)
+		assert.Equal(t, 15.0, exported[0].NetPrice)
+		assert.Equal(t, 1.5, exported[0].NetVAT)
+		mockOrderRepo.AssertExpectations(t)
+	})
+
+	t.Run("Stops at the first error", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		orderService := NewOrderService(mockOrderRepo, new(MockProductRepository), nil, nil)
+
+		mockOrderRepo.On("Export", ctx, domain.OrderFilter{}, mock.Anything).Return([]*domain.Order{{ID: 1}, {ID: 2}}, nil)
+
+		calls := 0
+		err := orderService.ExportOrders(ctx, domain.OrderFilter{Limit: -1}, func(order *domain.OrderResponse) error {
+			calls++
+			return errors.New("client gone")
+		})
+
+		// Assertions
+		assert.ErrorContains(t, err, "client gone")
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestQuoteOrder(t *testing.T) {
+	ctx := context.Background()
+	items := []domain.OrderItem{{ProductID: 1, Quantity: 2}}
+
+	t.Run("Quote is priced like an order but not persisted", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		mockProductRepo := new(MockProductRepository)
+		orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, quote.NewSigner([]byte("secret"), time.Minute))
+
+		mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 10.0, VAT: 1.0}, nil)
+
+		result, err := orderService.QuoteOrder(ctx, &domain.CreateOrderRequest{Order: domain.OrderInput{Items: items}})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, 20.0, result.OrderPrice)
+		assert.Equal(t, 2.0, result.OrderVAT)
+		assert.NotEmpty(t, result.QuoteToken)
+		assert.NotNil(t, result.ExpiresAt)
+		mockOrderRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Order honours quoted prices after a catalog change", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		mockProductRepo := new(MockProductRepository)
+		orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, quote.NewSigner([]byte("secret"), time.Minute))
+
+		mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 10.0, VAT: 1.0}, nil).Once()
+		quoted, err := orderService.QuoteOrder(ctx, &domain.CreateOrderRequest{Order: domain.OrderInput{Items: items}})
+		require.NoError(t, err)
+
+		// The price changes; the product must not be looked up again
+		mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 99.0, VAT: 9.9}, nil)
+		mockOrderRepo.On("Create", ctx, mock.MatchedBy(func(order *domain.Order) bool {
+			return order.Price == 20.0 && order.VAT == 2.0 && order.Items[0].Price == 20.0
+		})).Return(&domain.Order{ID: 1, Price: 20.0, VAT: 2.0}, nil)
+
+		result, err := orderService.CreateOrder(ctx, &domain.CreateOrderRequest{
+			Order:      domain.OrderInput{Items: []domain.OrderItem{{ProductID: 1, Quantity: 2}}},
+			QuoteToken: quoted.QuoteToken,
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, 20.0, result.OrderPrice)
+		assert.Equal(t, 2.0, result.OrderVAT)
+		mockProductRepo.AssertNumberOfCalls(t, "GetByID", 1)
+	})
+
+	t.Run("Items must match the quote", func(t *testing.T) {
+		// Setup
+		signer := quote.NewSigner([]byte("secret"), time.Minute)
+		orderService := NewOrderService(new(MockOrderRepository), new(MockProductRepository), nil, signer)
+
+		token, _, err := signer.Sign([]domain.OrderItem{{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0}})
+		require.NoError(t, err)
+
+		_, err = orderService.CreateOrder(ctx, &domain.CreateOrderRequest{
+			Order:      domain.OrderInput{Items: []domain.OrderItem{{ProductID: 1, Quantity: 5}}},
+			QuoteToken: token,
+		})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrQuoteMismatch)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		// Setup
+		orderService := NewOrderService(new(MockOrderRepository), new(MockProductRepository), nil, quote.NewSigner([]byte("secret"), time.Minute))
+
+		_, err := orderService.CreateOrder(ctx, &domain.CreateOrderRequest{
+			Order:      domain.OrderInput{Items: items},
+			QuoteToken: "forged",
+		})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrInvalidQuote)
+	})
+}
+
+func TestUpdateOrder(t *testing.T) {
+	ctx := context.Background()
+
+	pendingOrder := func() *domain.Order {
+		return &domain.Order{
+			ID:     1,
+			Status: domain.OrderStatusPending,
+			Items: []domain.OrderItem{
+				{ProductID: 1, Quantity: 2, Price: 20.0, VAT: 2.0},
+				{ProductID: 2, Quantity: 3, Price: 15.0, VAT: 1.5},
+			},
+			Price: 35.0,
+			VAT:   3.5,
+		}
+	}
+
+	t.Run("Lines are added, changed and removed", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		mockProductRepo := new(MockProductRepository)
+		orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, nil)
+
+		mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 12.0, VAT: 1.2}, nil)
+		mockProductRepo.On("GetByID", ctx, int64(3)).Return(&domain.Product{ID: 3, Price: 4.0, VAT: 0.4}, nil)
+		mockOrderRepo.On("Update", ctx, int64(1), 1).Return(pendingOrder(), nil)
+
+		result, err := orderService.UpdateOrder(ctx, 1, &domain.UpdateOrderRequest{Version: 1, Items: []domain.OrderItem{
+			{ProductID: 1, Quantity: 1},
+			{ProductID: 2, Quantity: 0},
+			{ProductID: 3, Quantity: 5},
+		}})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, []domain.OrderItem{
+			{ProductID: 1, Quantity: 1, Price: 12.0, VAT: 1.2},
+			{ProductID: 3, Quantity: 5, Price: 20.0, VAT: 2.0},
+		}, result.Items)
+		assert.InDelta(t, 32.0, result.OrderPrice, 1e-9)
+		assert.InDelta(t, 3.2, result.OrderVAT, 1e-9)
+		mockOrderRepo.AssertExpectations(t)
+	})
+
+	t.Run("Revision records the change", func(t *testing.T) {
+		// Setup
+		mockProductRepo := new(MockProductRepository)
+		mockProductRepo.On("GetByID", mock.Anything, int64(2)).Return(&domain.Product{ID: 2, Price: 5.0, VAT: 0.5}, nil)
+		var revision *domain.OrderRevision
+		repo := &updateRecorder{order: pendingOrder(), revision: &revision}
+		orderService := NewOrderService(repo, mockProductRepo, nil, nil)
+
+		_, err := orderService.UpdateOrder(requestid.NewContext(ctx, "req-1"), 1, &domain.UpdateOrderRequest{
+			Version: 1,
+			Items:   []domain.OrderItem{{ProductID: 2, Quantity: 4, Price: 1.0}},
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		require.NotNil(t, revision)
+		assert.Equal(t, []domain.OrderItem{{ProductID: 2, Quantity: 4}}, revision.Changes)
+		assert.Equal(t, pendingOrder().Items, revision.ItemsBefore)
+		assert.Equal(t, 35.0, revision.PriceBefore)
+		assert.Equal(t, 40.0, revision.PriceAfter)
+		assert.Equal(t, "req-1", revision.RequestID)
+	})
+
+	t.Run("Order that has progressed cannot be edited", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		mockProductRepo := new(MockProductRepository)
+		orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, nil)
+
+		order := pendingOrder()
+		order.Status = "paid"
+		mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 10.0, VAT: 1.0}, nil)
+		mockOrderRepo.On("Update", ctx, int64(1), 1).Return(order, nil)
+
+		_, err := orderService.UpdateOrder(ctx, 1, &domain.UpdateOrderRequest{Version: 1, Items: []domain.OrderItem{{ProductID: 1, Quantity: 1}}})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrOrderNotEditable)
+	})
+
+	t.Run("Removing every line is rejected", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		orderService := NewOrderService(mockOrderRepo, new(MockProductRepository), nil, nil)
+		mockOrderRepo.On("Update", ctx, int64(1), 1).Return(pendingOrder(), nil)
+
+		_, err := orderService.UpdateOrder(ctx, 1, &domain.UpdateOrderRequest{Version: 1, Items: []domain.OrderItem{
+			{ProductID: 1, Quantity: 0},
+			{ProductID: 2, Quantity: 0},
+		}})
+
+		// Assertions
+		assert.ErrorIs(t, err, ErrInvalidOrderUpdate)
+	})
+
+	t.Run("Concurrent change", func(t *testing.T) {
+		// Setup
+		mockOrderRepo := new(MockOrderRepository)
+		mockProductRepo := new(MockProductRepository)
+		orderService := NewOrderService(mockOrderRepo, mockProductRepo, nil, nil)
+
+		mockProductRepo.On("GetByID", ctx, int64(1)).Return(&domain.Product{ID: 1, Price: 10.0, VAT: 1.0}, nil)
+		mockOrderRepo.On("Update", ctx, int64(1), 1).Return(nil, domain.ErrVersionMismatch)
+
+		_, err := orderService.UpdateOrder(ctx, 1, &domain.UpdateOrderRequest{Version: 1, Items: []domain.OrderItem{{ProductID: 1, Quantity: 1}}})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrVersionMismatch)
+	})
+
+	t.Run("Invalid changes", func(t *testing.T) {
+		orderService := NewOrderService(new(MockOrderRepository), new(MockProductRepository), nil, nil)
+
+		for _, items := range [][]domain.OrderItem{
+			nil,
+			{{ProductID: 1, Quantity: -1}},
+			{{ProductID: 1, Quantity: 1}, {ProductID: 1, Quantity: 2}},
+		} {
+			_, err := orderService.UpdateOrder(ctx, 1, &domain.UpdateOrderRequest{Items: items})
+			assert.ErrorIs(t, err, ErrInvalidOrderUpdate)
+		}
+	})
+}
+
+// updateRecorder is an OrderRepository that applies updates to a fixed
+// order and keeps the recorded revision.
+type updateRecorder struct {
+	MockOrderRepository
+	order    *domain.Order
+	revision **domain.OrderRevision
+}
+
+func (r *updateRecorder) Update(ctx context.Context, id int64, version int, update func(order *domain.Order) (*domain.OrderRevision, error)) (*domain.Order, error) {
+	revision, err := update(r.order)
+	if err != nil {
+		return nil, err
+	}
+	*r.revision = revision
+	return r.order, nil
+}
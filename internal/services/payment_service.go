@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+var (
+	// ErrInvalidPayment is returned when a payment request fails validation.
+	ErrInvalidPayment = errors.New("invalid payment")
+	// ErrPaymentGateway is returned when the payment gateway fails to
+	// process an operation for a reason other than a decline.
+	ErrPaymentGateway = errors.New("payment gateway error")
+)
+
+type PaymentService struct {
+	paymentRepo repository.PaymentRepository
+	orderRepo   repository.OrderRepository
+	gateway     PaymentGateway
+	events      EventPublisher
+}
+
+// NewPaymentService creates a PaymentService processing payments through
+// gateway. events may be nil when nothing needs to be notified of orders
+// being paid.
+func NewPaymentService(paymentRepo repository.PaymentRepository, orderRepo repository.OrderRepository, gateway PaymentGateway, events EventPublisher) *PaymentService {
+	return &PaymentService{
+		paymentRepo: paymentRepo,
+		orderRepo:   orderRepo,
+		gateway:     gateway,
+		events:      events,
+	}
+}
+
+// CreatePayment authorizes a payment for an order and, if req.Capture is
+// set, captures it.
+//
+// The payment is recorded as pending before the gateway is called, which
+// reserves its amount: the payments of an order never exceed its total,
+// Price plus VAT, even when they are made concurrently. A zero amount pays
+// the remaining balance. A declined payment is kept as failed and
+// domain.ErrPaymentDeclined is returned.
+func (s *PaymentService) CreatePayment(ctx context.Context, orderID int64, req *domain.CreatePaymentRequest) (*domain.Payment, error) {
+	logger := logging.FromContext(ctx)
+
+	if req.Amount < 0 || (req.Amount > 0 && domain.Cents(req.Amount) == 0) {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidPayment)
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		summary, err := s.ListPayments(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if domain.Cents(summary.Balance) <= 0 {
+			return nil, domain.ErrPaymentExceedsBalance
+		}
+		amount = summary.Balance
+	}
+
+	payment, err := s.paymentRepo.Create(ctx, &domain.Payment{
+		OrderID:   orderID,
+		Amount:    domain.FromCents(domain.Cents(amount)),
+		Status:    domain.PaymentStatusPending,
+		Provider:  s.gateway.Name(),
+		RequestID: requestid.FromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reference, err := s.gateway.Authorize(ctx, domain.AuthorizationRequest{
+		OrderID:       orderID,
+		PaymentID:     payment.ID,
+		Amount:        payment.Amount,
+		PaymentMethod: req.PaymentMethod,
+	})
+	if err != nil {
+		// Release the reserved amount
+		if _, failErr := s.paymentRepo.Transition(ctx, payment.ID, domain.PaymentStatusPending, domain.PaymentStatusFailed, "", err.Error()); failErr != nil {
+			logger.Error("failed to record failed payment", "payment_id", payment.ID, "error", failErr)
+		}
+		if errors.Is(err, domain.ErrPaymentDeclined) {
+			logger.Info("payment declined", "order_id", orderID, "payment_id", payment.ID, "error", err)
+			return nil, err
+		}
+		logger.Error("payment authorization failed", "order_id", orderID, "payment_id", payment.ID, "error", err)
+		return nil, fmt.Errorf("%w: %v", ErrPaymentGateway, err)
+	}
+
+	payment, err = s.paymentRepo.Transition(ctx, payment.ID, domain.PaymentStatusPending, domain.PaymentStatusAuthorized, reference, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to record authorization: %w", err)
+	}
+
+	logger.Info("payment authorized", "order_id", orderID, "payment_id", payment.ID, "amount", payment.Amount)
+
+	if req.Capture {
+		return s.capture(ctx, payment)
+	}
+	return payment, nil
+}
+
+// ListPayments returns the payments of an order reconciled against its
+// total: the amounts authorized and captured and the remaining balance.
+func (s *PaymentService) ListPayments(ctx context.Context, orderID int64) (*domain.PaymentSummary, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.paymentRepo.ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return summarizePayments(order, payments), nil
+}
+
+// CapturePayment captures an authorized payment. Once the captured payments
+// cover the order total the order becomes paid, before that partially_paid.
+func (s *PaymentService) CapturePayment(ctx context.Context, orderID, paymentID int64) (*domain.Payment, error) {
+	payment, err := s.paymentRepo.GetByID(ctx, orderID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != domain.PaymentStatusAuthorized {
+		return nil, domain.ErrPaymentStatus
+	}
+
+	return s.capture(ctx, payment)
+}
+
+// VoidPayment releases an authorized payment, returning its amount to the
+// order balance.
+func (s *PaymentService) VoidPayment(ctx context.Context, orderID, paymentID int64) (*domain.Payment, error) {
+	payment, err := s.paymentRepo.GetByID(ctx, orderID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != domain.PaymentStatusAuthorized {
+		return nil, domain.ErrPaymentStatus
+	}
+
+	if err := s.gateway.Void(ctx, payment.Reference); err != nil {
+		logging.FromContext(ctx).Error("payment void failed", "payment_id", payment.ID, "error", err)
+		return nil, fmt.Errorf("%w: %v", ErrPaymentGateway, err)
+	}
+
+	payment, err = s.paymentRepo.Transition(ctx, payment.ID, domain.PaymentStatusAuthorized, domain.PaymentStatusVoided, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("payment voided", "order_id", orderID, "payment_id", payment.ID)
+	return payment, nil
+}
+
+// capture captures an authorized payment with the gateway and records it,
+// updating the order status in the same transaction.
+func (s *PaymentService) capture(ctx context.Context, payment *domain.Payment) (*domain.Payment, error) {
+	logger := logging.FromContext(ctx)
+
+	if err := s.gateway.Capture(ctx, payment.Reference, payment.Amount); err != nil {
+		logger.Error("payment capture failed", "payment_id", payment.ID, "error", err)
+		return nil, fmt.Errorf("%w: %v", ErrPaymentGateway, err)
+	}
+
+	captured, order, err := s.paymentRepo.Capture(ctx, payment.OrderID, payment.ID)
+	if err != nil {
+		logger.Error("failed to record capture", "payment_id", payment.ID, "error", err)
+		return nil, err
+	}
+
+	logger.Info("payment captured",
+		"order_id", order.ID,
+		"payment_id", captured.ID,
+		"amount", captured.Amount,
+		"order_status", order.Status,
+	)
+
+	// Notify subscribers
+	if s.events != nil {
+		eventType := domain.EventOrderUpdated
+		if order.Status == domain.OrderStatusPaid {
+			eventType = domain.EventOrderPaid
+		}
+		event, err := domain.NewOrderEvent(eventType, order)
+		if err != nil {
+			logger.Warn("failed to build order event", "order_id", order.ID, "error", err)
+		} else {
+			s.events.Publish(*event)
+		}
+	}
+
+	return captured, nil
+}
+
+// summarizePayments reconciles payments against the order total. Amounts
+// are summed in cents.
+func summarizePayments(order *domain.Order, payments []domain.Payment) *domain.PaymentSummary {
+	total := domain.Cents(order.Price) + domain.Cents(order.VAT)
+	var authorized, captured int64
+	for _, payment := range payments {
+		switch payment.Status {
+		case domain.PaymentStatusPending, domain.PaymentStatusAuthorized:
+			authorized += domain.Cents(payment.Amount)
+		case domain.PaymentStatusCaptured:
+			captured += domain.Cents(payment.Amount)
+		}
+	}
+
+	return &domain.PaymentSummary{
+		OrderID:    order.ID,
+		OrderTotal: domain.FromCents(total),
+		Authorized: domain.FromCents(authorized),
+		Captured:   domain.FromCents(captured),
+		Balance:    domain.FromCents(total - authorized - captured),
+		Payments:   payments,
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/payments"
+)
+
+type MockPaymentRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRepository) Create(ctx context.Context, payment *domain.Payment) (*domain.Payment, error) {
+	args := m.Called(ctx, payment)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetByID(ctx context.Context, orderID, id int64) (*domain.Payment, error) {
+	args := m.Called(ctx, orderID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ListByOrder(ctx context.Context, orderID int64) ([]domain.Payment, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) Transition(ctx context.Context, id int64, from, to, reference, failureReason string) (*domain.Payment, error) {
+	args := m.Called(ctx, id, from, to, reference, failureReason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) Capture(ctx context.Context, orderID, id int64) (*domain.Payment, *domain.Order, error) {
+	args := m.Called(ctx, orderID, id)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*domain.Payment), args.Get(1).(*domain.Order), args.Error(2)
+}
+
+// recordingPublisher is an EventPublisher keeping the published events.
+type recordingPublisher struct {
+	events []domain.Event
+}
+
+func (p *recordingPublisher) Publish(event domain.Event) {
+	p.events = append(p.events, event)
+}
+
+func TestCreatePayment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Authorize and capture pays the order", func(t *testing.T) {
+		// Setup
+		paymentRepo := new(MockPaymentRepository)
+		events := &recordingPublisher{}
+		paymentService := NewPaymentService(paymentRepo, new(MockOrderRepository), payments.NewFakeGateway(), events)
+
+		paymentRepo.On("Create", ctx, mock.MatchedBy(func(p *domain.Payment) bool {
+			return p.OrderID == 1 && p.Amount == 38.5 && p.Status == domain.PaymentStatusPending && p.Provider == "fake"
+		})).Return(&domain.Payment{ID: 3, OrderID: 1, Amount: 38.5, Status: domain.PaymentStatusPending}, nil)
+		paymentRepo.On("Transition", ctx, int64(3), domain.PaymentStatusPending, domain.PaymentStatusAuthorized, "fake_auth_3", "").
+			Return(&domain.Payment{ID: 3, OrderID: 1, Amount: 38.5, Status: domain.PaymentStatusAuthorized, Reference: "fake_auth_3"}, nil)
+		paymentRepo.On("Capture", ctx, int64(1), int64(3)).
+			Return(&domain.Payment{ID: 3, OrderID: 1, Amount: 38.5, Status: domain.PaymentStatusCaptured}, &domain.Order{ID: 1, Status: domain.OrderStatusPaid}, nil)
+
+		payment, err := paymentService.CreatePayment(ctx, 1, &domain.CreatePaymentRequest{Amount: 38.5, Capture: true})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.PaymentStatusCaptured, payment.Status)
+		require.Len(t, events.events, 1)
+		assert.Equal(t, domain.EventOrderPaid, events.events[0].Type)
+		paymentRepo.AssertExpectations(t)
+	})
+
+	t.Run("Zero amount pays the balance", func(t *testing.T) {
+		// Setup
+		paymentRepo := new(MockPaymentRepository)
+		orderRepo := new(MockOrderRepository)
+		paymentService := NewPaymentService(paymentRepo, orderRepo, payments.NewFakeGateway(), nil)
+
+		orderRepo.On("GetByID", ctx, int64(1)).Return(&domain.Order{ID: 1, Price: 35.0, VAT: 3.5}, nil)
+		paymentRepo.On("ListByOrder", ctx, int64(1)).Return([]domain.Payment{
+			{Amount: 10.0, Status: domain.PaymentStatusCaptured},
+			{Amount: 5.0, Status: domain.PaymentStatusFailed},
+		}, nil)
+		paymentRepo.On("Create", ctx, mock.MatchedBy(func(p *domain.Payment) bool { return p.Amount == 28.5 })).
+			Return(&domain.Payment{ID: 4, OrderID: 1, Amount: 28.5}, nil)
+		paymentRepo.On("Transition", ctx, int64(4), domain.PaymentStatusPending, domain.PaymentStatusAuthorized, "fake_auth_4", "").
+			Return(&domain.Payment{ID: 4, Status: domain.PaymentStatusAuthorized}, nil)
+
+		payment, err := paymentService.CreatePayment(ctx, 1, &domain.CreatePaymentRequest{})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.PaymentStatusAuthorized, payment.Status)
+		paymentRepo.AssertExpectations(t)
+	})
+
+	t.Run("Declined payment is recorded as failed", func(t *testing.T) {
+		// Setup
+		paymentRepo := new(MockPaymentRepository)
+		paymentService := NewPaymentService(paymentRepo, new(MockOrderRepository), payments.NewFakeGateway(), nil)
+
+		paymentRepo.On("Create", ctx, mock.Anything).Return(&domain.Payment{ID: 5, OrderID: 1, Amount: 10.0}, nil)
+		paymentRepo.On("Transition", ctx, int64(5), domain.PaymentStatusPending, domain.PaymentStatusFailed, "", mock.Anything).
+			Return(&domain.Payment{ID: 5, Status: domain.PaymentStatusFailed}, nil)
+
+		_, err := paymentService.CreatePayment(ctx, 1, &domain.CreatePaymentRequest{Amount: 10.0, PaymentMethod: payments.FakeMethodDeclined})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrPaymentDeclined)
+		paymentRepo.AssertExpectations(t)
+	})
+
+	t.Run("Gateway failure", func(t *testing.T) {
+		// Setup
+		paymentRepo := new(MockPaymentRepository)
+		paymentService := NewPaymentService(paymentRepo, new(MockOrderRepository), payments.NewFakeGateway(), nil)
+
+		paymentRepo.On("Create", ctx, mock.Anything).Return(&domain.Payment{ID: 6, OrderID: 1, Amount: 10.0}, nil)
+		paymentRepo.On("Transition", ctx, int64(6), domain.PaymentStatusPending, domain.PaymentStatusFailed, "", mock.Anything).
+			Return(&domain.Payment{ID: 6, Status: domain.PaymentStatusFailed}, nil)
+
+		_, err := paymentService.CreatePayment(ctx, 1, &domain.CreatePaymentRequest{Amount: 10.0, PaymentMethod: payments.FakeMethodError})
+
+		// Assertions
+		assert.ErrorIs(t, err, ErrPaymentGateway)
+	})
+
+	t.Run("Negative amount", func(t *testing.T) {
+		paymentService := NewPaymentService(new(MockPaymentRepository), new(MockOrderRepository), payments.NewFakeGateway(), nil)
+
+		_, err := paymentService.CreatePayment(ctx, 1, &domain.CreatePaymentRequest{Amount: -1})
+
+		assert.ErrorIs(t, err, ErrInvalidPayment)
+	})
+}
+
+func TestListPayments(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	paymentRepo := new(MockPaymentRepository)
+	orderRepo := new(MockOrderRepository)
+	paymentService := NewPaymentService(paymentRepo, orderRepo, payments.NewFakeGateway(), nil)
+
+	orderRepo.On("GetByID", ctx, int64(1)).Return(&domain.Order{ID: 1, Price: 0.1, VAT: 0.2}, nil)
+	paymentRepo.On("ListByOrder", ctx, int64(1)).Return([]domain.Payment{
+		{Amount: 0.1, Status: domain.PaymentStatusCaptured},
+		{Amount: 0.1, Status: domain.PaymentStatusAuthorized},
+		{Amount: 0.1, Status: domain.PaymentStatusVoided},
+	}, nil)
+
+	summary, err := paymentService.ListPayments(ctx, 1)
+
+	// Assertions
+	require.NoError(t, err)
+	assert.Equal(t, 0.3, summary.OrderTotal)
+	assert.Equal(t, 0.1, summary.Captured)
+	assert.Equal(t, 0.1, summary.Authorized)
+	assert.Equal(t, 0.1, summary.Balance)
+	assert.Len(t, summary.Payments, 3)
+}
+
+func TestCapturePayment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Only authorized payments can be captured", func(t *testing.T) {
+		// Setup
+		paymentRepo := new(MockPaymentRepository)
+		paymentService := NewPaymentService(paymentRepo, new(MockOrderRepository), payments.NewFakeGateway(), nil)
+		paymentRepo.On("GetByID", ctx, int64(1), int64(2)).Return(&domain.Payment{ID: 2, Status: domain.PaymentStatusVoided}, nil)
+
+		_, err := paymentService.CapturePayment(ctx, 1, 2)
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrPaymentStatus)
+	})
+
+	t.Run("Partial capture", func(t *testing.T) {
+		// Setup
+		paymentRepo := new(MockPaymentRepository)
+		gateway := payments.NewFakeGateway()
+		reference, err := gateway.Authorize(ctx, domain.AuthorizationRequest{PaymentID: 2, Amount: 10.0})
+		require.NoError(t, err)
+		events := &recordingPublisher{}
+		paymentService := NewPaymentService(paymentRepo, new(MockOrderRepository), gateway, events)
+
+		paymentRepo.On("GetByID", ctx, int64(1), int64(2)).
+			Return(&domain.Payment{ID: 2, OrderID: 1, Amount: 10.0, Status: domain.PaymentStatusAuthorized, Reference: reference}, nil)
+		paymentRepo.On("Capture", ctx, int64(1), int64(2)).
+			Return(&domain.Payment{ID: 2, Status: domain.PaymentStatusCaptured}, &domain.Order{ID: 1, Status: domain.OrderStatusPartiallyPaid}, nil)
+
+		payment, err := paymentService.CapturePayment(ctx, 1, 2)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.PaymentStatusCaptured, payment.Status)
+		require.Len(t, events.events, 1)
+		assert.Equal(t, domain.EventOrderUpdated, events.events[0].Type)
+	})
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+// ErrInvalidRefund is returned when a refund request fails validation.
+var ErrInvalidRefund = errors.New("invalid refund")
+
+const (
+	// refundStaleAfter is how long a credit note may stay pending before
+	// its refund is considered interrupted
+	refundStaleAfter = 10 * time.Minute
+	// refundInterrupted is the failure reason of interrupted refunds
+	refundInterrupted = "interrupted before the gateway outcome was recorded"
+)
+
+type RefundService struct {
+	creditNoteRepo repository.CreditNoteRepository
+	orderRepo      repository.OrderRepository
+	gateway        PaymentGateway
+	events         EventPublisher
+}
+
+// NewRefundService creates a RefundService returning money through gateway.
+// events may be nil when nothing needs to be notified of refunds.
+func NewRefundService(creditNoteRepo repository.CreditNoteRepository, orderRepo repository.OrderRepository, gateway PaymentGateway, events EventPublisher) *RefundService {
+	return &RefundService{
+		creditNoteRepo: creditNoteRepo,
+		orderRepo:      orderRepo,
+		gateway:        gateway,
+		events:         events,
+	}
+}
+
+// CreateRefund refunds order lines or an amount and returns the credit note
+// documenting it.
+//
+// Lines are credited at the price they were charged, prorated when only
+// part of a line's quantity is refunded; amounts are split into price and
+// VAT in the proportion of the order totals. The credit note is recorded
+// as pending before the gateway is called, which reserves its amount on the
+// captured payments: refunds never exceed what was captured, even when they
+// are made concurrently. The note is issued once every refund succeeded.
+// If only some did, it is partially issued for the amount refunded, split
+// into price and VAT in its own proportion, and returned without error so
+// that its lines are not credited again; the rest can be refunded by
+// amount. If every refund failed, it is failed and an error wrapping
+// ErrPaymentGateway is returned. Once the note is recorded, the refunds and
+// their outcome are completed even if the client goes away; notes left
+// pending by a crash are finished by RunRecovery.
+func (s *RefundService) CreateRefund(ctx context.Context, orderID int64, req *domain.CreateRefundRequest) (*domain.CreditNote, error) {
+	logger := logging.FromContext(ctx)
+
+	if err := validateRefund(req); err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	note := &domain.CreditNote{
+		OrderID:   orderID,
+		Reason:    req.Reason,
+		RequestID: requestid.FromContext(ctx),
+	}
+	if len(req.Items) > 0 {
+		note.Items, err = creditItems(order.Items, req.Items)
+		if err != nil {
+			return nil, err
+		}
+		note.Price, note.VAT = totals(note.Items)
+		note.Amount = domain.FromCents(domain.Cents(note.Price) + domain.Cents(note.VAT))
+	} else {
+		amount := req.Amount
+		if amount == 0 {
+			amount, err = s.creditNoteRepo.Refundable(ctx, orderID)
+			if err != nil {
+				return nil, err
+			}
+			if domain.Cents(amount) == 0 {
+				return nil, domain.ErrRefundExceedsCaptured
+			}
+		}
+		note.Amount = domain.FromCents(domain.Cents(amount))
+		note.Price, note.VAT = splitAmount(note.Amount, order.Price, order.VAT)
+	}
+
+	note, err = s.creditNoteRepo.Create(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+
+	// Money may move from here on, so finish even if the client goes away
+	ctx = context.WithoutCancel(ctx)
+
+	var refundErr error
+	for i := range note.Refunds {
+		refund := &note.Refunds[i]
+		reference, err := s.gateway.Refund(ctx, refund.PaymentReference, refund.Amount)
+		if err != nil {
+			logger.Error("refund failed", "order_id", orderID, "payment_id", refund.PaymentID, "refund_id", refund.ID, "error", err)
+			refund.Status, refund.FailureReason = domain.RefundStatusFailed, err.Error()
+			refundErr = err
+		} else {
+			refund.Status, refund.Reference = domain.RefundStatusSucceeded, reference
+		}
+		if err := s.creditNoteRepo.CompleteRefund(ctx, refund.ID, refund.Status, refund.Reference, refund.FailureReason); err != nil {
+			return nil, fmt.Errorf("failed to record refund: %w", err)
+		}
+	}
+
+	status, err := s.finish(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+	if status == domain.CreditNoteStatusFailed {
+		return nil, fmt.Errorf("%w: %v", ErrPaymentGateway, refundErr)
+	}
+
+	return note, nil
+}
+
+// finish sets the status of a pending credit note from the outcome of its
+// refunds and notifies subscribers if it was issued. It returns the status.
+func (s *RefundService) finish(ctx context.Context, note *domain.CreditNote) (string, error) {
+	logger := logging.FromContext(ctx)
+
+	var refunded int64
+	failed := false
+	for _, refund := range note.Refunds {
+		if refund.Status == domain.RefundStatusSucceeded {
+			refunded += domain.Cents(refund.Amount)
+		} else {
+			failed = true
+		}
+	}
+
+	status := domain.CreditNoteStatusIssued
+	switch {
+	case failed && refunded == 0:
+		status = domain.CreditNoteStatusFailed
+	case failed:
+		// Credit only what the gateway refunded
+		status = domain.CreditNoteStatusPartiallyIssued
+		note.Amount = domain.FromCents(refunded)
+		note.Price, note.VAT = splitAmount(note.Amount, note.Price, note.VAT)
+	}
+
+	order, err := s.creditNoteRepo.Finish(ctx, note, status)
+	if err != nil {
+		logger.Error("failed to record credit note", "credit_note_id", note.ID, "error", err)
+		return "", err
+	}
+	if status == domain.CreditNoteStatusFailed {
+		return status, nil
+	}
+
+	logger.Info("credit note issued",
+		"order_id", note.OrderID,
+		"credit_note", note.Number,
+		"status", status,
+		"amount", note.Amount,
+		"order_status", order.Status,
+	)
+
+	// Notify subscribers
+	if s.events != nil {
+		event, err := domain.NewOrderEvent(domain.EventOrderRefunded, order)
+		if err != nil {
+			logger.Warn("failed to build order event", "order_id", order.ID, "error", err)
+		} else {
+			s.events.Publish(*event)
+		}
+	}
+
+	return status, nil
+}
+
+// RunRecovery finishes the credit notes left pending by an interrupted
+// refund every interval until ctx is cancelled. Their refunds still pending
+// never had their gateway outcome recorded: they are failed, which releases
+// their amount, and logged so that they can be reconciled with the gateway.
+func (s *RefundService) RunRecovery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				note, err := s.creditNoteRepo.ClaimStale(ctx, refundStaleAfter)
+				if errors.Is(err, domain.ErrCreditNoteNotFound) {
+					break
+				}
+				if err != nil {
+					logging.FromContext(ctx).Error("failed to claim stale credit note", "error", err)
+					break
+				}
+				if err := s.recover(ctx, note); err != nil {
+					logging.FromContext(ctx).Error("failed to recover credit note", "credit_note_id", note.ID, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// recover fails the pending refunds of a stale credit note and finishes it.
+func (s *RefundService) recover(ctx context.Context, note *domain.CreditNote) error {
+	logger := logging.FromContext(ctx)
+
+	for i := range note.Refunds {
+		refund := &note.Refunds[i]
+		if refund.Status != domain.RefundStatusPending {
+			continue
+		}
+		logger.Error("refund interrupted, reconcile it with the payment gateway",
+			"order_id", note.OrderID, "payment_id", refund.PaymentID, "refund_id", refund.ID, "amount", refund.Amount)
+		refund.Status, refund.FailureReason = domain.RefundStatusFailed, refundInterrupted
+		if err := s.creditNoteRepo.CompleteRefund(ctx, refund.ID, refund.Status, "", refund.FailureReason); err != nil {
+			return fmt.Errorf("failed to record refund: %w", err)
+		}
+	}
+
+	_, err := s.finish(ctx, note)
+	return err
+}
+
+// ListCreditNotes returns the credit notes of an order, oldest first.
+func (s *RefundService) ListCreditNotes(ctx context.Context, orderID int64) ([]domain.CreditNote, error) {
+	if _, err := s.orderRepo.GetByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.creditNoteRepo.ListByOrder(ctx, orderID)
+}
+
+// GetCreditNote returns a credit note of an order.
+func (s *RefundService) GetCreditNote(ctx context.Context, orderID, id int64) (*domain.CreditNote, error) {
+	return s.creditNoteRepo.GetByID(ctx, orderID, id)
+}
+
+// validateRefund checks a refund request: lines or an amount but not both,
+// a non-negative amount and each product at most once with a positive
+// quantity.
+func validateRefund(req *domain.CreateRefundRequest) error {
+	if len(req.Items) > 0 && req.Amount != 0 {
+		return fmt.Errorf("%w: items and amount are mutually exclusive", ErrInvalidRefund)
+	}
+	if req.Amount < 0 || (req.Amount > 0 && domain.Cents(req.Amount) == 0) {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidRefund)
+	}
+
+	seen := make(map[int64]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("%w: quantity of product %d must be positive", ErrInvalidRefund, item.ProductID)
+		}
+		if seen[item.ProductID] {
+			return fmt.Errorf("%w: product %d is listed more than once", ErrInvalidRefund, item.ProductID)
+		}
+		seen[item.ProductID] = true
+	}
+
+	return nil
+}
+
+// creditItems prices the refunded lines from the order lines of the same
+// product: the charged price and VAT prorated to the refunded quantity and
+// rounded to the cent. Refunding a line's whole quantity credits exactly
+// what was charged for it.
+func creditItems(lines, items []domain.OrderItem) ([]domain.OrderItem, error) {
+	ordered := make(map[int64]domain.OrderItem, len(lines))
+	for _, line := range lines {
+		total := ordered[line.ProductID]
+		total.Quantity += line.Quantity
+		total.Price += line.Price
+		total.VAT += line.VAT
+		ordered[line.ProductID] = total
+	}
+
+	credited := make([]domain.OrderItem, 0, len(items))
+	for _, item := range items {
+		line, ok := ordered[item.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("%w: product %d is not in the order", ErrInvalidRefund, item.ProductID)
+		}
+		if item.Quantity > line.Quantity {
+			return nil, fmt.Errorf("%w: product %d was ordered %d times", domain.ErrRefundExceedsQuantity, item.ProductID, line.Quantity)
+		}
+		credited = append(credited, domain.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     prorate(line.Price, item.Quantity, line.Quantity),
+			VAT:       prorate(line.VAT, item.Quantity, line.Quantity),
+		})
+	}
+
+	return credited, nil
+}
+
+// prorate returns the share of amount for part of total units, rounded to
+// the cent.
+func prorate(amount float64, part, total int) float64 {
+	return domain.FromCents(int64(math.Round(float64(domain.Cents(amount)) * float64(part) / float64(total))))
+}
+
+// splitAmount splits an amount including VAT into price and VAT in the
+// proportion of totalPrice and totalVAT.
+func splitAmount(amount, totalPrice, totalVAT float64) (price, vat float64) {
+	cents := domain.Cents(amount)
+	total := domain.Cents(totalPrice) + domain.Cents(totalVAT)
+	if total == 0 {
+		return amount, 0
+	}
+	vatCents := int64(math.Round(float64(cents) * float64(domain.Cents(totalVAT)) / float64(total)))
+	return domain.FromCents(cents - vatCents), domain.FromCents(vatCents)
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/payments"
+)
+
+type MockCreditNoteRepository struct {
+	mock.Mock
+}
+
+func (m *MockCreditNoteRepository) Refundable(ctx context.Context, orderID int64) (float64, error) {
+	args := m.Called(ctx, orderID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockCreditNoteRepository) Create(ctx context.Context, note *domain.CreditNote) (*domain.CreditNote, error) {
+	args := m.Called(ctx, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CreditNote), args.Error(1)
+}
+
+func (m *MockCreditNoteRepository) CompleteRefund(ctx context.Context, id int64, status, reference, failureReason string) error {
+	args := m.Called(ctx, id, status, reference, failureReason)
+	return args.Error(0)
+}
+
+func (m *MockCreditNoteRepository) Finish(ctx context.Context, note *domain.CreditNote, status string) (*domain.Order, error) {
+	args := m.Called(ctx, note, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockCreditNoteRepository) ClaimStale(ctx context.Context, staleAfter time.Duration) (*domain.CreditNote, error) {
+	args := m.Called(ctx, staleAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CreditNote), args.Error(1)
+}
+
+func (m *MockCreditNoteRepository) GetByID(ctx context.Context, orderID, id int64) (*domain.CreditNote, error) {
+	args := m.Called(ctx, orderID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CreditNote), args.Error(1)
+}
+
+func (m *MockCreditNoteRepository) ListByOrder(ctx context.Context, orderID int64) ([]domain.CreditNote, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.CreditNote), args.Error(1)
+}
+
+// capturedGateway returns a fake gateway where payment 3 authorized and
+// captured amount.
+func capturedGateway(t *testing.T, amount float64) *payments.FakeGateway {
+	gateway := payments.NewFakeGateway()
+	reference, err := gateway.Authorize(context.Background(), domain.AuthorizationRequest{OrderID: 1, PaymentID: 3, Amount: amount})
+	require.NoError(t, err)
+	require.NoError(t, gateway.Capture(context.Background(), reference, amount))
+	return gateway
+}
+
+func TestCreateRefund(t *testing.T) {
+	ctx := context.Background()
+	order := &domain.Order{
+		ID:     1,
+		Status: domain.OrderStatusPaid,
+		Price:  35.0,
+		VAT:    3.5,
+		Items: []domain.OrderItem{
+			{ProductID: 1, Quantity: 3, Price: 10.0, VAT: 1.0},
+			{ProductID: 2, Quantity: 1, Price: 25.0, VAT: 2.5},
+		},
+	}
+
+	t.Run("Partial line refund is prorated and issued", func(t *testing.T) {
+		// Setup
+		noteRepo := new(MockCreditNoteRepository)
+		orderRepo := new(MockOrderRepository)
+		events := &recordingPublisher{}
+		refundService := NewRefundService(noteRepo, orderRepo, capturedGateway(t, 38.5), events)
+
+		orderRepo.On("GetByID", ctx, int64(1)).Return(order, nil)
+		noteRepo.On("Create", ctx, mock.MatchedBy(func(note *domain.CreditNote) bool {
+			return note.OrderID == 1 && note.Price == 6.67 && note.VAT == 0.67 && note.Amount == 7.34 &&
+				len(note.Items) == 1 && note.Items[0].Quantity == 2 && note.Reason == "damaged"
+		})).Return(&domain.CreditNote{
+			ID: 7, Number: "CN-1-1", OrderID: 1, Status: domain.CreditNoteStatusPending, Amount: 7.34,
+			Refunds: []domain.Refund{{ID: 9, PaymentID: 3, Amount: 7.34, Status: domain.RefundStatusPending, PaymentReference: "fake_auth_3"}},
+		}, nil)
+		noteRepo.On("CompleteRefund", mock.Anything, int64(9), domain.RefundStatusSucceeded, "fake_refund_1", "").Return(nil)
+		noteRepo.On("Finish", mock.Anything, mock.Anything, domain.CreditNoteStatusIssued).
+			Return(&domain.Order{ID: 1, Status: domain.OrderStatusPaid, RefundedPrice: 6.67, RefundedVAT: 0.67}, nil).
+			Run(func(args mock.Arguments) { args.Get(1).(*domain.CreditNote).Status = domain.CreditNoteStatusIssued })
+
+		note, err := refundService.CreateRefund(ctx, 1, &domain.CreateRefundRequest{
+			Items:  []domain.OrderItem{{ProductID: 1, Quantity: 2}},
+			Reason: "damaged",
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.CreditNoteStatusIssued, note.Status)
+		assert.Equal(t, domain.RefundStatusSucceeded, note.Refunds[0].Status)
+		assert.Equal(t, "fake_refund_1", note.Refunds[0].Reference)
+		require.Len(t, events.events, 1)
+		assert.Equal(t, domain.EventOrderRefunded, events.events[0].Type)
+		noteRepo.AssertExpectations(t)
+	})
+
+	t.Run("Amount is split into price and VAT", func(t *testing.T) {
+		// Setup
+		noteRepo := new(MockCreditNoteRepository)
+		orderRepo := new(MockOrderRepository)
+		refundService := NewRefundService(noteRepo, orderRepo, capturedGateway(t, 38.5), nil)
+
+		orderRepo.On("GetByID", ctx, int64(1)).Return(order, nil)
+		noteRepo.On("Create", ctx, mock.MatchedBy(func(note *domain.CreditNote) bool {
+			return note.Amount == 11.0 && note.Price == 10.0 && note.VAT == 1.0 && len(note.Items) == 0
+		})).Return(&domain.CreditNote{ID: 7, OrderID: 1, Amount: 11.0}, nil)
+		noteRepo.On("Finish", mock.Anything, mock.Anything, domain.CreditNoteStatusIssued).Return(&domain.Order{ID: 1}, nil)
+
+		_, err := refundService.CreateRefund(ctx, 1, &domain.CreateRefundRequest{Amount: 11.0})
+
+		// Assertions
+		require.NoError(t, err)
+		noteRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty request refunds everything refundable", func(t *testing.T) {
+		// Setup
+		noteRepo := new(MockCreditNoteRepository)
+		orderRepo := new(MockOrderRepository)
+		refundService := NewRefundService(noteRepo, orderRepo, capturedGateway(t, 38.5), nil)
+
+		orderRepo.On("GetByID", ctx, int64(1)).Return(order, nil)
+		noteRepo.On("Refundable", ctx, int64(1)).Return(38.5, nil)
+		noteRepo.On("Create", ctx, mock.MatchedBy(func(note *domain.CreditNote) bool {
+			return note.Amount == 38.5 && note.Price == 35.0 && note.VAT == 3.5
+		})).Return(&domain.CreditNote{ID: 7, OrderID: 1, Amount: 38.5}, nil)
+		noteRepo.On("Finish", mock.Anything, mock.Anything, domain.CreditNoteStatusIssued).Return(&domain.Order{ID: 1, Status: domain.OrderStatusRefunded}, nil)
+
+		_, err := refundService.CreateRefund(ctx, 1, &domain.CreateRefundRequest{})
+
+		// Assertions
+		require.NoError(t, err)
+		noteRepo.AssertExpectations(t)
+	})
+
+	t.Run("Nothing left to refund", func(t *testing.T) {
+		// Setup
+		noteRepo := new(MockCreditNoteRepository)
+		orderRepo := new(MockOrderRepository)
+		refundService := NewRefundService(noteRepo, orderRepo, payments.NewFakeGateway(), nil)
+
+		orderRepo.On("GetByID", ctx, int64(1)).Return(order, nil)
+		noteRepo.On("Refundable", ctx, int64(1)).Return(0.0, nil)
+
+		_, err := refundService.CreateRefund(ctx, 1, &domain.CreateRefundRequest{})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrRefundExceedsCaptured)
+		noteRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed gateway refund fails the credit note", func(t *testing.T) {
+		// Setup
+		noteRepo := new(MockCreditNoteRepository)
+		orderRepo := new(MockOrderRepository)
+		events := &recordingPublisher{}
+		refundService := NewRefundService(noteRepo, orderRepo, payments.NewFakeGateway(), events)
+
+		orderRepo.On("GetByID", ctx, int64(1)).Return(order, nil)
+		noteRepo.On("Create", ctx, mock.Anything).Return(&domain.CreditNote{
+			ID: 7, OrderID: 1, Amount: 5.0,
+			Refunds: []domain.Refund{{ID: 9, PaymentID: 3, Amount: 5.0, PaymentReference: "unknown"}},
+		}, nil)
+		noteRepo.On("CompleteRefund", mock.Anything, int64(9), domain.RefundStatusFailed, "", mock.Anything).Return(nil)
+		noteRepo.On("Finish", mock.Anything, mock.Anything, domain.CreditNoteStatusFailed).Return(&domain.Order{ID: 1}, nil)
+
+		_, err := refundService.CreateRefund(ctx, 1, &domain.CreateRefundRequest{Amount: 5.0})
+
+		// Assertions
+		assert.ErrorIs(t, err, ErrPaymentGateway)
+		assert.Empty(t, events.events)
+		noteRepo.AssertExpectations(t)
+	})
+
+	t.Run("Refund failing on one of two payments partially issues the credit note", func(t *testing.T) {
+		// Setup
+		noteRepo := new(MockCreditNoteRepository)
+		orderRepo := new(MockOrderRepository)
+		events := &recordingPublisher{}
+		refundService := NewRefundService(noteRepo, orderRepo, capturedGateway(t, 20.0), events)
+
+		orderRepo.On("GetByID", ctx, int64(1)).Return(order, nil)
+		noteRepo.On("Create", ctx, mock.Anything).Return(&domain.CreditNote{
+			ID: 7, OrderID: 1, Amount: 27.5, Price: 25.0, VAT: 2.5,
+			Items: []domain.OrderItem{{ProductID: 2, Quantity: 1, Price: 25.0, VAT: 2.5}},
+			Refunds: []domain.Refund{
+				{ID: 9, PaymentID: 3, Amount: 20.0, PaymentReference: "fake_auth_3"},
+				{ID: 10, PaymentID: 4, Amount: 7.5, PaymentReference: "unknown"},
+			},
+		}, nil)
+		noteRepo.On("CompleteRefund", mock.Anything, int64(9), domain.RefundStatusSucceeded, "fake_refund_1", "").Return(nil)
+		noteRepo.On("CompleteRefund", mock.Anything, int64(10), domain.RefundStatusFailed, "", mock.Anything).Return(nil)
+		noteRepo.On("Finish", mock.Anything, mock.MatchedBy(func(note *domain.CreditNote) bool {
+			return note.Amount == 20.0 && note.Price == 18.18 && note.VAT == 1.82
+		}), domain.CreditNoteStatusPartiallyIssued).
+			Return(&domain.Order{ID: 1, Status: domain.OrderStatusPaid, RefundedPrice: 18.18, RefundedVAT: 1.82}, nil).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*domain.CreditNote).Status = domain.CreditNoteStatusPartiallyIssued
+			})
+
+		note, err := refundService.CreateRefund(ctx, 1, &domain.CreateRefundRequest{
+			Items: []domain.OrderItem{{ProductID: 2, Quantity: 1}},
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.CreditNoteStatusPartiallyIssued, note.Status)
+		assert.Equal(t, domain.RefundStatusSucceeded, note.Refunds[0].Status)
+		assert.Equal(t, domain.RefundStatusFailed, note.Refunds[1].Status)
+		assert.NotEmpty(t, note.Refunds[1].FailureReason)
+		require.Len(t, events.events, 1)
+		assert.Equal(t, domain.EventOrderRefunded, events.events[0].Type)
+		noteRepo.AssertExpectations(t)
+	})
+
+	invalid := []struct {
+		name string
+		req  domain.CreateRefundRequest
+		err  error
+	}{
+		{"Items and amount", domain.CreateRefundRequest{Items: []domain.OrderItem{{ProductID: 1, Quantity: 1}}, Amount: 1}, ErrInvalidRefund},
+		{"Negative amount", domain.CreateRefundRequest{Amount: -1}, ErrInvalidRefund},
+		{"Zero quantity", domain.CreateRefundRequest{Items: []domain.OrderItem{{ProductID: 1}}}, ErrInvalidRefund},
+		{"Duplicate product", domain.CreateRefundRequest{Items: []domain.OrderItem{{ProductID: 1, Quantity: 1}, {ProductID: 1, Quantity: 1}}}, ErrInvalidRefund},
+		{"Product not in the order", domain.CreateRefundRequest{Items: []domain.OrderItem{{ProductID: 9, Quantity: 1}}}, ErrInvalidRefund},
+		{"More than ordered", domain.CreateRefundRequest{Items: []domain.OrderItem{{ProductID: 2, Quantity: 2}}}, domain.ErrRefundExceedsQuantity},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			orderRepo := new(MockOrderRepository)
+			orderRepo.On("GetByID", ctx, int64(1)).Return(order, nil)
+			refundService := NewRefundService(new(MockCreditNoteRepository), orderRepo, payments.NewFakeGateway(), nil)
+
+			_, err := refundService.CreateRefund(ctx, 1, &tc.req)
+
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestRecoverCreditNote(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Interrupted refund is failed and the note partially issued", func(t *testing.T) {
+		// Setup
+		noteRepo := new(MockCreditNoteRepository)
+		refundService := NewRefundService(noteRepo, new(MockOrderRepository), payments.NewFakeGateway(), nil)
+
+		note := &domain.CreditNote{
+			ID: 7, OrderID: 1, Status: domain.CreditNoteStatusPending, Amount: 27.5, Price: 25.0, VAT: 2.5,
+			Refunds: []domain.Refund{
+				{ID: 9, PaymentID: 3, Amount: 20.0, Status: domain.RefundStatusSucceeded, Reference: "fake_refund_1"},
+				{ID: 10, PaymentID: 4, Amount: 7.5, Status: domain.RefundStatusPending},
+			},
+		}
+		noteRepo.On("CompleteRefund", ctx, int64(10), domain.RefundStatusFailed, "", refundInterrupted).Return(nil)
+		noteRepo.On("Finish", ctx, mock.MatchedBy(func(note *domain.CreditNote) bool {
+			return note.Amount == 20.0 && note.Price == 18.18 && note.VAT == 1.82
+		}), domain.CreditNoteStatusPartiallyIssued).Return(&domain.Order{ID: 1, Status: domain.OrderStatusPaid}, nil)
+
+		err := refundService.recover(ctx, note)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.RefundStatusFailed, note.Refunds[1].Status)
+		noteRepo.AssertExpectations(t)
+		noteRepo.AssertNumberOfCalls(t, "CompleteRefund", 1)
+	})
+
+	t.Run("Note without a recorded refund is failed", func(t *testing.T) {
+		// Setup
+		noteRepo := new(MockCreditNoteRepository)
+		refundService := NewRefundService(noteRepo, new(MockOrderRepository), payments.NewFakeGateway(), nil)
+
+		note := &domain.CreditNote{
+			ID: 7, OrderID: 1, Status: domain.CreditNoteStatusPending, Amount: 5.0,
+			Refunds: []domain.Refund{{ID: 9, PaymentID: 3, Amount: 5.0, Status: domain.RefundStatusPending}},
+		}
+		noteRepo.On("CompleteRefund", ctx, int64(9), domain.RefundStatusFailed, "", refundInterrupted).Return(nil)
+		noteRepo.On("Finish", ctx, note, domain.CreditNoteStatusFailed).Return(&domain.Order{ID: 1}, nil)
+
+		err := refundService.recover(ctx, note)
+
+		// Assertions
+		require.NoError(t, err)
+		noteRepo.AssertExpectations(t)
+	})
+}
+
+func TestCreditItems(t *testing.T) {
+	lines := []domain.OrderItem{
+		{ProductID: 1, Quantity: 1, Price: 0.1, VAT: 0.01},
+		{ProductID: 1, Quantity: 2, Price: 0.2, VAT: 0.02},
+	}
+
+	items, err := creditItems(lines, []domain.OrderItem{{ProductID: 1, Quantity: 3}})
+
+	// Assertions
+	require.NoError(t, err)
+	assert.Equal(t, []domain.OrderItem{{ProductID: 1, Quantity: 3, Price: 0.3, VAT: 0.03}}, items)
+}
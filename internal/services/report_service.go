@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// ErrInvalidReport is returned when the parameters of a report are invalid.
+var ErrInvalidReport = errors.New("invalid report")
+
+const (
+	// defaultReportDays is the length of the date range of reports that do
+	// not set one, ending today.
+	defaultReportDays = 30
+	// maxReportPeriods bounds the number of periods of a sales report.
+	maxReportPeriods = 1000
+
+	DefaultTopProducts = 10
+	MaxTopProducts     = 100
+)
+
+const reportDate = "2006-01-02"
+
+type ReportService struct {
+	reportRepo repository.ReportRepository
+	now        func() time.Time
+}
+
+func NewReportService(reportRepo repository.ReportRepository) *ReportService {
+	return &ReportService{
+		reportRepo: reportRepo,
+		now:        time.Now,
+	}
+}
+
+// SalesReport aggregates the orders created in the requested date range by
+// day, week or month (the default is day). It reports the number of orders,
+// their gross and net totals, refunds and the average order value.
+//
+// Every total is the exact sum of the corresponding totals of the orders in
+// the order API, so a report can be reconciled with a listing or an export
+// of the same orders. Orders count in the period they were created in, in
+// the time zone of the report, and so do their refunds.
+func (s *ReportService) SalesReport(ctx context.Context, req *domain.ReportRequest) (*domain.SalesReport, error) {
+	filter, err := s.filter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Granularity = req.Granularity
+	switch filter.Granularity {
+	case "":
+		filter.Granularity = domain.ReportGranularityDay
+	case domain.ReportGranularityDay, domain.ReportGranularityWeek, domain.ReportGranularityMonth:
+	default:
+		return nil, fmt.Errorf("%w: granularity must be %s, %s or %s", ErrInvalidReport,
+			domain.ReportGranularityDay, domain.ReportGranularityWeek, domain.ReportGranularityMonth)
+	}
+
+	// Every period of the range, including those without orders
+	var starts []time.Time
+	for start := periodStart(filter.From, filter.Granularity); start.Before(filter.To); start = nextPeriod(start, filter.Granularity) {
+		if len(starts) == maxReportPeriods {
+			return nil, fmt.Errorf("%w: the date range has more than %d periods", ErrInvalidReport, maxReportPeriods)
+		}
+		starts = append(starts, start)
+	}
+
+	periods, err := s.reportRepo.Sales(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate sales: %w", err)
+	}
+	byPeriod := make(map[string]domain.SalesPeriod, len(periods))
+	for _, period := range periods {
+		byPeriod[period.Period] = period
+	}
+
+	report := &domain.SalesReport{
+		From:        filter.From.Format(reportDate),
+		To:          filter.To.AddDate(0, 0, -1).Format(reportDate),
+		TimeZone:    filter.Location.String(),
+		Granularity: filter.Granularity,
+		Statuses:    filter.Statuses,
+		Periods:     make([]domain.SalesPeriod, len(starts)),
+	}
+	var total salesCents
+	for i, start := range starts {
+		period, ok := byPeriod[start.Format(reportDate)]
+		if !ok {
+			period.Period = start.Format(reportDate)
+		}
+		period.AverageOrderValue = averageOrderValue(period.Revenue, period.Orders)
+		report.Periods[i] = period
+		total.add(period.SalesTotals)
+	}
+	report.Total = total.totals()
+
+	return report, nil
+}
+
+// TopProducts ranks the products sold in the requested date range by units
+// sold, or by revenue when By is "revenue". Limit sets the number of
+// products returned, DefaultTopProducts by default and at most
+// MaxTopProducts.
+func (s *ReportService) TopProducts(ctx context.Context, req *domain.ReportRequest) (*domain.TopProductsReport, error) {
+	filter, err := s.filter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	by := req.By
+	switch by {
+	case "":
+		by = domain.ReportByQuantity
+	case domain.ReportByQuantity, domain.ReportByRevenue:
+	default:
+		return nil, fmt.Errorf("%w: by must be %s or %s", ErrInvalidReport, domain.ReportByQuantity, domain.ReportByRevenue)
+	}
+
+	limit := req.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultTopProducts
+	case limit < 0 || limit > MaxTopProducts:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidReport, MaxTopProducts)
+	}
+
+	products, err := s.reportRepo.TopProducts(ctx, filter, by, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank products: %w", err)
+	}
+	if products == nil {
+		products = []domain.ProductSales{}
+	}
+
+	return &domain.TopProductsReport{
+		From:     filter.From.Format(reportDate),
+		To:       filter.To.AddDate(0, 0, -1).Format(reportDate),
+		TimeZone: filter.Location.String(),
+		Statuses: filter.Statuses,
+		By:       by,
+		Products: products,
+	}, nil
+}
+
+// filter validates the date range, time zone and statuses of a report. The
+// range defaults to the defaultReportDays days ending today, in UTC unless a
+// time zone is requested.
+func (s *ReportService) filter(req *domain.ReportRequest) (domain.ReportFilter, error) {
+	var filter domain.ReportFilter
+
+	// "Local" is the zone of the server, which PostgreSQL does not know
+	location, err := time.LoadLocation(req.TimeZone)
+	if err != nil || req.TimeZone == "Local" {
+		return filter, fmt.Errorf("%w: unknown time zone %q", ErrInvalidReport, req.TimeZone)
+	}
+	filter.Location = location
+
+	today := s.now().In(location)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, location)
+	if req.To != "" {
+		if to, err = time.ParseInLocation(reportDate, req.To, location); err != nil {
+			return filter, fmt.Errorf("%w: to must be a date (YYYY-MM-DD)", ErrInvalidReport)
+		}
+	}
+	from := to.AddDate(0, 0, 1-defaultReportDays)
+	if req.From != "" {
+		if from, err = time.ParseInLocation(reportDate, req.From, location); err != nil {
+			return filter, fmt.Errorf("%w: from must be a date (YYYY-MM-DD)", ErrInvalidReport)
+		}
+	}
+	if to.Before(from) {
+		return filter, fmt.Errorf("%w: from is after to", ErrInvalidReport)
+	}
+	filter.From, filter.To = from, to.AddDate(0, 0, 1)
+
+	for _, status := range strings.Split(req.Statuses, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	if filter.Statuses == nil {
+		filter.Statuses = domain.SalesStatuses
+	}
+
+	return filter, nil
+}
+
+// periodStart returns the start of the period of granularity t is in.
+// Weeks start on Monday, like in PostgreSQL.
+func periodStart(t time.Time, granularity string) time.Time {
+	year, month, day := t.Date()
+	switch granularity {
+	case domain.ReportGranularityWeek:
+		day -= (int(t.Weekday()) + 6) % 7
+	case domain.ReportGranularityMonth:
+		day = 1
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// nextPeriod returns the start of the period following the one starting at
+// start.
+func nextPeriod(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case domain.ReportGranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case domain.ReportGranularityMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// averageOrderValue returns revenue per order, rounded to the cent.
+func averageOrderValue(revenue float64, orders int64) float64 {
+	if orders == 0 {
+		return 0
+	}
+	return domain.FromCents(int64(math.Round(float64(domain.Cents(revenue)) / float64(orders))))
+}
+
+// salesCents sums sales totals in cents, so that totals over many periods
+// stay exact.
+type salesCents struct {
+	orders, grossPrice, grossVAT, refundedPrice, refundedVAT, revenue, vat int64
+}
+
+func (c *salesCents) add(t domain.SalesTotals) {
+	c.orders += t.Orders
+	c.grossPrice += domain.Cents(t.GrossPrice)
+	c.grossVAT += domain.Cents(t.GrossVAT)
+	c.refundedPrice += domain.Cents(t.RefundedPrice)
+	c.refundedVAT += domain.Cents(t.RefundedVAT)
+	c.revenue += domain.Cents(t.Revenue)
+	c.vat += domain.Cents(t.VAT)
+}
+
+func (c *salesCents) totals() domain.SalesTotals {
+	revenue := domain.FromCents(c.revenue)
+	return domain.SalesTotals{
+		Orders:            c.orders,
+		GrossPrice:        domain.FromCents(c.grossPrice),
+		GrossVAT:          domain.FromCents(c.grossVAT),
+		RefundedPrice:     domain.FromCents(c.refundedPrice),
+		RefundedVAT:       domain.FromCents(c.refundedVAT),
+		Revenue:           revenue,
+		VAT:               domain.FromCents(c.vat),
+		AverageOrderValue: averageOrderValue(revenue, c.orders),
+	}
+}
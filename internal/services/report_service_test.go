@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type MockReportRepository struct {
+	mock.Mock
+}
+
+func (m *MockReportRepository) Sales(ctx context.Context, filter domain.ReportFilter) ([]domain.SalesPeriod, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.SalesPeriod), args.Error(1)
+}
+
+func (m *MockReportRepository) TopProducts(ctx context.Context, filter domain.ReportFilter, by string, limit int) ([]domain.ProductSales, error) {
+	args := m.Called(ctx, filter, by, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ProductSales), args.Error(1)
+}
+
+func newTestReportService(repo *MockReportRepository) *ReportService {
+	s := NewReportService(repo)
+	s.now = func() time.Time { return time.Date(2024, 5, 15, 22, 30, 0, 0, time.UTC) }
+	return s
+}
+
+func TestSalesReport(t *testing.T) {
+	ctx := context.Background()
+	rome, err := time.LoadLocation("Europe/Rome")
+	require.NoError(t, err)
+
+	t.Run("Weekly periods in a time zone", func(t *testing.T) {
+		// Setup
+		reportRepo := new(MockReportRepository)
+		reportService := newTestReportService(reportRepo)
+
+		reportRepo.On("Sales", ctx, mock.MatchedBy(func(f domain.ReportFilter) bool {
+			return f.From.Equal(time.Date(2024, 4, 30, 0, 0, 0, 0, rome)) &&
+				f.To.Equal(time.Date(2024, 5, 16, 0, 0, 0, 0, rome)) &&
+				f.Location.String() == "Europe/Rome" &&
+				f.Granularity == domain.ReportGranularityWeek &&
+				assert.ObjectsAreEqual([]string{"paid", "shipped"}, f.Statuses)
+		})).Return([]domain.SalesPeriod{
+			{Period: "2024-04-29", SalesTotals: domain.SalesTotals{Orders: 3, GrossPrice: 10.1, GrossVAT: 1.01, Revenue: 10.1, VAT: 1.01}},
+			{Period: "2024-05-13", SalesTotals: domain.SalesTotals{Orders: 1, GrossPrice: 20.2, GrossVAT: 2.02, RefundedPrice: 5, RefundedVAT: 0.5, Revenue: 15.2, VAT: 1.52}},
+		}, nil)
+
+		report, err := reportService.SalesReport(ctx, &domain.ReportRequest{
+			From:        "2024-04-30",
+			To:          "2024-05-15",
+			TimeZone:    "Europe/Rome",
+			Granularity: "week",
+			Statuses:    "paid, shipped",
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, "2024-04-30", report.From)
+		assert.Equal(t, "2024-05-15", report.To)
+		require.Len(t, report.Periods, 3)
+		assert.Equal(t, "2024-04-29", report.Periods[0].Period)
+		assert.Equal(t, 3.37, report.Periods[0].AverageOrderValue)
+		assert.Equal(t, domain.SalesPeriod{Period: "2024-05-06"}, report.Periods[1])
+		assert.Equal(t, domain.SalesTotals{
+			Orders: 4, GrossPrice: 30.3, GrossVAT: 3.03, RefundedPrice: 5, RefundedVAT: 0.5,
+			Revenue: 25.3, VAT: 2.53, AverageOrderValue: 6.33,
+		}, report.Total)
+		reportRepo.AssertExpectations(t)
+	})
+
+	t.Run("Defaults to the last 30 days in UTC", func(t *testing.T) {
+		// Setup
+		reportRepo := new(MockReportRepository)
+		reportService := newTestReportService(reportRepo)
+
+		reportRepo.On("Sales", ctx, mock.MatchedBy(func(f domain.ReportFilter) bool {
+			return f.From.Equal(time.Date(2024, 4, 16, 0, 0, 0, 0, time.UTC)) &&
+				f.To.Equal(time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)) &&
+				f.Granularity == domain.ReportGranularityDay &&
+				assert.ObjectsAreEqual(domain.SalesStatuses, f.Statuses)
+		})).Return(nil, nil)
+
+		report, err := reportService.SalesReport(ctx, &domain.ReportRequest{})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Len(t, report.Periods, 30)
+		assert.Equal(t, "2024-05-15", report.Periods[29].Period)
+		assert.Equal(t, "UTC", report.TimeZone)
+		assert.Zero(t, report.Total)
+	})
+
+	t.Run("Totals stay exact in cents", func(t *testing.T) {
+		// Setup
+		reportRepo := new(MockReportRepository)
+		reportService := newTestReportService(reportRepo)
+
+		reportRepo.On("Sales", ctx, mock.Anything).Return([]domain.SalesPeriod{
+			{Period: "2024-05-01", SalesTotals: domain.SalesTotals{Orders: 1, Revenue: 0.1}},
+			{Period: "2024-05-02", SalesTotals: domain.SalesTotals{Orders: 1, Revenue: 0.2}},
+		}, nil)
+
+		report, err := reportService.SalesReport(ctx, &domain.ReportRequest{From: "2024-05-01", To: "2024-05-02"})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, 0.3, report.Total.Revenue)
+		assert.Equal(t, 0.15, report.Total.AverageOrderValue)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		reportService := newTestReportService(new(MockReportRepository))
+
+		for _, req := range []domain.ReportRequest{
+			{TimeZone: "Mars/Olympus"},
+			{TimeZone: "Local"},
+			{From: "01/05/2024"},
+			{To: "2024-13-01"},
+			{From: "2024-05-02", To: "2024-05-01"},
+			{Granularity: "year"},
+			{From: "2020-01-01", To: "2024-01-01"},
+		} {
+			_, err := reportService.SalesReport(ctx, &req)
+
+			// Assertions
+			assert.ErrorIs(t, err, ErrInvalidReport, "%+v", req)
+		}
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		// Setup
+		reportRepo := new(MockReportRepository)
+		reportService := newTestReportService(reportRepo)
+
+		reportRepo.On("Sales", ctx, mock.Anything).Return(nil, errors.New("database error"))
+
+		_, err := reportService.SalesReport(ctx, &domain.ReportRequest{})
+
+		// Assertions
+		assert.ErrorContains(t, err, "failed to aggregate sales")
+	})
+}
+
+func TestTopProducts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Defaults to the top 10 by quantity", func(t *testing.T) {
+		// Setup
+		reportRepo := new(MockReportRepository)
+		reportService := newTestReportService(reportRepo)
+
+		reportRepo.On("TopProducts", ctx, mock.Anything, domain.ReportByQuantity, DefaultTopProducts).Return(nil, nil)
+
+		report, err := reportService.TopProducts(ctx, &domain.ReportRequest{})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReportByQuantity, report.By)
+		assert.NotNil(t, report.Products)
+		reportRepo.AssertExpectations(t)
+	})
+
+	t.Run("Ranks by revenue", func(t *testing.T) {
+		// Setup
+		reportRepo := new(MockReportRepository)
+		reportService := newTestReportService(reportRepo)
+
+		reportRepo.On("TopProducts", ctx, mock.Anything, domain.ReportByRevenue, 3).Return([]domain.ProductSales{
+			{ProductID: 5, Name: "Product 5", Quantity: 2, Revenue: 20.0, VAT: 2.0},
+		}, nil)
+
+		report, err := reportService.TopProducts(ctx, &domain.ReportRequest{By: "revenue", Limit: 3})
+
+		// Assertions
+		require.NoError(t, err)
+		require.Len(t, report.Products, 1)
+		assert.Equal(t, int64(5), report.Products[0].ProductID)
+		reportRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		reportService := newTestReportService(new(MockReportRepository))
+
+		for _, req := range []domain.ReportRequest{
+			{By: "margin"},
+			{Limit: -1},
+			{Limit: MaxTopProducts + 1},
+		} {
+			_, err := reportService.TopProducts(ctx, &req)
+
+			// Assertions
+			assert.ErrorIs(t, err, ErrInvalidReport, "%+v", req)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+// ErrInvalidReturn is returned when a return request fails validation.
+var ErrInvalidReturn = errors.New("invalid return")
+
+type ReturnService struct {
+	returnRepo repository.ReturnRepository
+	orderRepo  repository.OrderRepository
+	refunds    RefundServiceInterface
+}
+
+// NewReturnService creates a ReturnService refunding received returns
+// through refunds.
+func NewReturnService(returnRepo repository.ReturnRepository, orderRepo repository.OrderRepository, refunds RefundServiceInterface) *ReturnService {
+	return &ReturnService{
+		returnRepo: returnRepo,
+		orderRepo:  orderRepo,
+		refunds:    refunds,
+	}
+}
+
+// CreateReturn opens a return for quantities of the lines of a paid order.
+// A product can never be returned more times than it was ordered, counting
+// the returns that were not rejected.
+func (s *ReturnService) CreateReturn(ctx context.Context, orderID int64, req *domain.CreateReturnRequest) (*domain.Return, error) {
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrInvalidReturn)
+	}
+
+	items := make([]domain.OrderItem, 0, len(req.Items))
+	seen := make(map[int64]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity of product %d must be positive", ErrInvalidReturn, item.ProductID)
+		}
+		if seen[item.ProductID] {
+			return nil, fmt.Errorf("%w: product %d is listed more than once", ErrInvalidReturn, item.ProductID)
+		}
+		seen[item.ProductID] = true
+		items = append(items, domain.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	ret, err := s.returnRepo.Create(ctx, &domain.Return{
+		OrderID:   orderID,
+		Reason:    req.Reason,
+		Items:     items,
+		RequestID: requestid.FromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("return requested", "order_id", orderID, "return_id", ret.ID)
+	return ret, nil
+}
+
+// ListReturns returns the returns of an order, oldest first.
+func (s *ReturnService) ListReturns(ctx context.Context, orderID int64) ([]domain.Return, error) {
+	if _, err := s.orderRepo.GetByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.returnRepo.ListByOrder(ctx, orderID)
+}
+
+// GetReturn returns a return of an order.
+func (s *ReturnService) GetReturn(ctx context.Context, orderID, id int64) (*domain.Return, error) {
+	return s.returnRepo.GetByID(ctx, orderID, id)
+}
+
+// ApproveReturn approves a requested return.
+func (s *ReturnService) ApproveReturn(ctx context.Context, orderID, id int64) (*domain.Return, error) {
+	return s.transition(ctx, orderID, id, domain.ReturnStatusRequested, domain.ReturnStatusApproved)
+}
+
+// RejectReturn rejects a return that has not been received, releasing its
+// quantities for other returns.
+func (s *ReturnService) RejectReturn(ctx context.Context, orderID, id int64) (*domain.Return, error) {
+	ret, err := s.returnRepo.GetByID(ctx, orderID, id)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != domain.ReturnStatusRequested && ret.Status != domain.ReturnStatusApproved {
+		return nil, domain.ErrReturnStatus
+	}
+	return s.transition(ctx, orderID, id, ret.Status, domain.ReturnStatusRejected)
+}
+
+// ReceiveReturn records that the goods of an approved return arrived and
+// puts them back in stock.
+func (s *ReturnService) ReceiveReturn(ctx context.Context, orderID, id int64) (*domain.Return, error) {
+	return s.transition(ctx, orderID, id, domain.ReturnStatusApproved, domain.ReturnStatusReceived)
+}
+
+// RefundReturn refunds the lines of a received return with a credit note.
+//
+// The return is marked refunded before the refund is made, so concurrent
+// requests cannot refund it twice; if the refund fails it goes back to
+// received and can be retried.
+func (s *ReturnService) RefundReturn(ctx context.Context, orderID, id int64) (*domain.Return, error) {
+	logger := logging.FromContext(ctx)
+
+	ret, err := s.transition(ctx, orderID, id, domain.ReturnStatusReceived, domain.ReturnStatusRefunded)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := s.refunds.CreateRefund(ctx, orderID, &domain.CreateRefundRequest{
+		Items:  ret.Items,
+		Reason: fmt.Sprintf("return %d", ret.ID),
+	})
+	if err != nil {
+		if _, revertErr := s.returnRepo.Transition(ctx, orderID, id, domain.ReturnStatusRefunded, domain.ReturnStatusReceived); revertErr != nil {
+			logger.Error("failed to revert return after failed refund", "return_id", id, "error", revertErr)
+		}
+		return nil, err
+	}
+
+	if err := s.returnRepo.LinkCreditNote(ctx, ret.ID, note.ID); err != nil {
+		return nil, fmt.Errorf("failed to record credit note of return: %w", err)
+	}
+	ret.CreditNoteID = &note.ID
+
+	logger.Info("return refunded", "order_id", orderID, "return_id", id, "credit_note", note.Number)
+	return ret, nil
+}
+
+func (s *ReturnService) transition(ctx context.Context, orderID, id int64, from, to string) (*domain.Return, error) {
+	ret, err := s.returnRepo.Transition(ctx, orderID, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("return status changed", "order_id", orderID, "return_id", id, "status", to)
+	return ret, nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type MockReturnRepository struct {
+	mock.Mock
+}
+
+func (m *MockReturnRepository) Create(ctx context.Context, ret *domain.Return) (*domain.Return, error) {
+	args := m.Called(ctx, ret)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Return), args.Error(1)
+}
+
+func (m *MockReturnRepository) Transition(ctx context.Context, orderID, id int64, from, to string) (*domain.Return, error) {
+	args := m.Called(ctx, orderID, id, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Return), args.Error(1)
+}
+
+func (m *MockReturnRepository) LinkCreditNote(ctx context.Context, id, creditNoteID int64) error {
+	args := m.Called(ctx, id, creditNoteID)
+	return args.Error(0)
+}
+
+func (m *MockReturnRepository) GetByID(ctx context.Context, orderID, id int64) (*domain.Return, error) {
+	args := m.Called(ctx, orderID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Return), args.Error(1)
+}
+
+func (m *MockReturnRepository) ListByOrder(ctx context.Context, orderID int64) ([]domain.Return, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Return), args.Error(1)
+}
+
+type MockRefundService struct {
+	mock.Mock
+	RefundServiceInterface
+}
+
+func (m *MockRefundService) CreateRefund(ctx context.Context, orderID int64, req *domain.CreateRefundRequest) (*domain.CreditNote, error) {
+	args := m.Called(ctx, orderID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CreditNote), args.Error(1)
+}
+
+func TestCreateReturn(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		// Setup
+		returnRepo := new(MockReturnRepository)
+		returnService := NewReturnService(returnRepo, new(MockOrderRepository), new(MockRefundService))
+		returnRepo.On("Create", ctx, mock.MatchedBy(func(ret *domain.Return) bool {
+			return ret.OrderID == 1 && len(ret.Items) == 1 && ret.Items[0] == domain.OrderItem{ProductID: 2, Quantity: 1}
+		})).Return(&domain.Return{ID: 4, OrderID: 1, Status: domain.ReturnStatusRequested}, nil)
+
+		ret, err := returnService.CreateReturn(ctx, 1, &domain.CreateReturnRequest{
+			Items:  []domain.OrderItem{{ProductID: 2, Quantity: 1, Price: 99}},
+			Reason: "wrong size",
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReturnStatusRequested, ret.Status)
+		returnRepo.AssertExpectations(t)
+	})
+
+	invalid := []struct {
+		name  string
+		items []domain.OrderItem
+	}{
+		{"No items", nil},
+		{"Zero quantity", []domain.OrderItem{{ProductID: 1}}},
+		{"Duplicate product", []domain.OrderItem{{ProductID: 1, Quantity: 1}, {ProductID: 1, Quantity: 2}}},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			returnService := NewReturnService(new(MockReturnRepository), new(MockOrderRepository), new(MockRefundService))
+
+			_, err := returnService.CreateReturn(ctx, 1, &domain.CreateReturnRequest{Items: tc.items})
+
+			assert.ErrorIs(t, err, ErrInvalidReturn)
+		})
+	}
+}
+
+func TestRejectReturn(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Approved return can be rejected", func(t *testing.T) {
+		// Setup
+		returnRepo := new(MockReturnRepository)
+		returnService := NewReturnService(returnRepo, new(MockOrderRepository), new(MockRefundService))
+		returnRepo.On("GetByID", ctx, int64(1), int64(4)).Return(&domain.Return{ID: 4, Status: domain.ReturnStatusApproved}, nil)
+		returnRepo.On("Transition", ctx, int64(1), int64(4), domain.ReturnStatusApproved, domain.ReturnStatusRejected).
+			Return(&domain.Return{ID: 4, Status: domain.ReturnStatusRejected}, nil)
+
+		ret, err := returnService.RejectReturn(ctx, 1, 4)
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReturnStatusRejected, ret.Status)
+	})
+
+	t.Run("Received return cannot be rejected", func(t *testing.T) {
+		// Setup
+		returnRepo := new(MockReturnRepository)
+		returnService := NewReturnService(returnRepo, new(MockOrderRepository), new(MockRefundService))
+		returnRepo.On("GetByID", ctx, int64(1), int64(4)).Return(&domain.Return{ID: 4, Status: domain.ReturnStatusReceived}, nil)
+
+		_, err := returnService.RejectReturn(ctx, 1, 4)
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrReturnStatus)
+		returnRepo.AssertNotCalled(t, "Transition", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRefundReturn(t *testing.T) {
+	ctx := context.Background()
+	items := []domain.OrderItem{{ProductID: 2, Quantity: 1}}
+
+	t.Run("Refunds the returned lines", func(t *testing.T) {
+		// Setup
+		returnRepo := new(MockReturnRepository)
+		refunds := new(MockRefundService)
+		returnService := NewReturnService(returnRepo, new(MockOrderRepository), refunds)
+
+		returnRepo.On("Transition", ctx, int64(1), int64(4), domain.ReturnStatusReceived, domain.ReturnStatusRefunded).
+			Return(&domain.Return{ID: 4, OrderID: 1, Status: domain.ReturnStatusRefunded, Items: items}, nil)
+		refunds.On("CreateRefund", ctx, int64(1), &domain.CreateRefundRequest{Items: items, Reason: "return 4"}).
+			Return(&domain.CreditNote{ID: 7, Number: "CN-1-1"}, nil)
+		returnRepo.On("LinkCreditNote", ctx, int64(4), int64(7)).Return(nil)
+
+		ret, err := returnService.RefundReturn(ctx, 1, 4)
+
+		// Assertions
+		require.NoError(t, err)
+		require.NotNil(t, ret.CreditNoteID)
+		assert.Equal(t, int64(7), *ret.CreditNoteID)
+		returnRepo.AssertExpectations(t)
+		refunds.AssertExpectations(t)
+	})
+
+	t.Run("Failed refund goes back to received", func(t *testing.T) {
+		// Setup
+		returnRepo := new(MockReturnRepository)
+		refunds := new(MockRefundService)
+		returnService := NewReturnService(returnRepo, new(MockOrderRepository), refunds)
+
+		returnRepo.On("Transition", ctx, int64(1), int64(4), domain.ReturnStatusReceived, domain.ReturnStatusRefunded).
+			Return(&domain.Return{ID: 4, OrderID: 1, Items: items}, nil)
+		refunds.On("CreateRefund", ctx, int64(1), mock.Anything).Return(nil, ErrPaymentGateway)
+		returnRepo.On("Transition", ctx, int64(1), int64(4), domain.ReturnStatusRefunded, domain.ReturnStatusReceived).
+			Return(&domain.Return{ID: 4, Status: domain.ReturnStatusReceived}, nil)
+
+		_, err := returnService.RefundReturn(ctx, 1, 4)
+
+		// Assertions
+		assert.ErrorIs(t, err, ErrPaymentGateway)
+		returnRepo.AssertExpectations(t)
+	})
+}
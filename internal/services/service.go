@@ -2,11 +2,105 @@ package services
 
 import (
 	"context"
+	"time"
 
 	"github.com/valeriouberti/order-service-test/internal/domain"
 )
 
+// EventPublisher receives the domain events emitted by the services,
+// e.g. the in-process event bus feeding the order stream.
+type EventPublisher interface {
+	Publish(event domain.Event)
+}
+
+// QuoteSigner issues and verifies the tokens returned with order quotes.
+type QuoteSigner interface {
+	Sign(items []domain.OrderItem) (string, time.Time, error)
+	Verify(token string) ([]domain.OrderItem, error)
+}
+
+// PaymentGateway is a payment provider. Authorize returns the provider's
+// reference of the authorization, used by the other operations; declined
+// payments are reported with an error wrapping domain.ErrPaymentDeclined.
+type PaymentGateway interface {
+	Name() string
+	Authorize(ctx context.Context, req domain.AuthorizationRequest) (reference string, err error)
+	Capture(ctx context.Context, reference string, amount float64) error
+	Void(ctx context.Context, reference string) error
+	Refund(ctx context.Context, reference string, amount float64) (refundReference string, err error)
+}
+
 type OrderServiceInterface interface {
 	CreateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.OrderResponse, error)
+	CreateOrders(ctx context.Context, reqs []*domain.CreateOrderRequest) ([]*domain.OrderResponse, error)
+	QuoteOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.QuoteResponse, error)
 	GetOrder(ctx context.Context, id int64) (*domain.OrderResponse, error)
+	ListOrders(ctx context.Context, filter domain.OrderFilter) (*domain.OrderListResponse, error)
+	ExportOrders(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.OrderResponse) error) error
+	UpdateOrder(ctx context.Context, id int64, req *domain.UpdateOrderRequest) (*domain.OrderResponse, error)
+	ListOrderRevisions(ctx context.Context, id int64) ([]domain.OrderRevision, error)
+}
+
+type CartServiceInterface interface {
+	CreateCart(ctx context.Context, req *domain.CreateCartRequest) (*domain.CartResponse, error)
+	GetCart(ctx context.Context, id string) (*domain.CartResponse, error)
+	AddItem(ctx context.Context, id string, item domain.CartItem) (*domain.CartResponse, error)
+	UpdateItem(ctx context.Context, id string, item domain.CartItem) (*domain.CartResponse, error)
+	RemoveItem(ctx context.Context, id string, productID int64) (*domain.CartResponse, error)
+	Checkout(ctx context.Context, id string) (*domain.OrderResponse, error)
+}
+
+type PaymentServiceInterface interface {
+	CreatePayment(ctx context.Context, orderID int64, req *domain.CreatePaymentRequest) (*domain.Payment, error)
+	ListPayments(ctx context.Context, orderID int64) (*domain.PaymentSummary, error)
+	CapturePayment(ctx context.Context, orderID, paymentID int64) (*domain.Payment, error)
+	VoidPayment(ctx context.Context, orderID, paymentID int64) (*domain.Payment, error)
+}
+
+type RefundServiceInterface interface {
+	CreateRefund(ctx context.Context, orderID int64, req *domain.CreateRefundRequest) (*domain.CreditNote, error)
+	ListCreditNotes(ctx context.Context, orderID int64) ([]domain.CreditNote, error)
+	GetCreditNote(ctx context.Context, orderID, id int64) (*domain.CreditNote, error)
+}
+
+type ReturnServiceInterface interface {
+	CreateReturn(ctx context.Context, orderID int64, req *domain.CreateReturnRequest) (*domain.Return, error)
+	ListReturns(ctx context.Context, orderID int64) ([]domain.Return, error)
+	GetReturn(ctx context.Context, orderID, id int64) (*domain.Return, error)
+	ApproveReturn(ctx context.Context, orderID, id int64) (*domain.Return, error)
+	RejectReturn(ctx context.Context, orderID, id int64) (*domain.Return, error)
+	ReceiveReturn(ctx context.Context, orderID, id int64) (*domain.Return, error)
+	RefundReturn(ctx context.Context, orderID, id int64) (*domain.Return, error)
+}
+
+type ShipmentServiceInterface interface {
+	CreateShipment(ctx context.Context, orderID int64, req *domain.CreateShipmentRequest) (*domain.Shipment, error)
+	ListShipments(ctx context.Context, orderID int64) ([]domain.Shipment, error)
+}
+
+type ImportServiceInterface interface {
+	ImportOrders(ctx context.Context, req *domain.ImportRequest) (*domain.ImportJob, error)
+	GetImportJob(ctx context.Context, id string) (*domain.ImportJob, error)
+}
+
+type ReportServiceInterface interface {
+	SalesReport(ctx context.Context, req *domain.ReportRequest) (*domain.SalesReport, error)
+	TopProducts(ctx context.Context, req *domain.ReportRequest) (*domain.TopProductsReport, error)
+}
+
+type AuditServiceInterface interface {
+	ListAuditEntries(ctx context.Context, filter domain.AuditFilter) (*domain.AuditListResponse, error)
+}
+
+type InvoiceServiceInterface interface {
+	GetInvoice(ctx context.Context, orderID int64) (*domain.Invoice, error)
+}
+
+type WebhookServiceInterface interface {
+	CreateSubscription(ctx context.Context, req *domain.CreateWebhookRequest) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, subscriptionID int64) ([]domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, subscriptionID, deliveryID int64) error
 }
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/logging"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+	"github.com/valeriouberti/order-service-test/internal/requestid"
+)
+
+// ErrInvalidShipment is returned when a shipment request fails validation.
+var ErrInvalidShipment = errors.New("invalid shipment")
+
+type ShipmentService struct {
+	shipmentRepo repository.ShipmentRepository
+	orderRepo    repository.OrderRepository
+	events       EventPublisher
+}
+
+// NewShipmentService creates a ShipmentService. events may be nil when
+// nothing needs to be notified of orders being shipped.
+func NewShipmentService(shipmentRepo repository.ShipmentRepository, orderRepo repository.OrderRepository, events EventPublisher) *ShipmentService {
+	return &ShipmentService{
+		shipmentRepo: shipmentRepo,
+		orderRepo:    orderRepo,
+		events:       events,
+	}
+}
+
+// CreateShipment records a parcel sent for a paid order. No unit is ever
+// shipped twice, even by concurrent requests: quantities above what is left
+// to ship are rejected with domain.ErrShipmentExceedsQuantity. The order
+// becomes partially_shipped, or shipped once every unit has been shipped,
+// publishing an order.shipped event.
+func (s *ShipmentService) CreateShipment(ctx context.Context, orderID int64, req *domain.CreateShipmentRequest) (*domain.Shipment, error) {
+	logger := logging.FromContext(ctx)
+
+	carrier, trackingNumber := strings.TrimSpace(req.Carrier), strings.TrimSpace(req.TrackingNumber)
+	if carrier == "" || trackingNumber == "" {
+		return nil, fmt.Errorf("%w: carrier and tracking_number are required", ErrInvalidShipment)
+	}
+
+	items := make([]domain.OrderItem, 0, len(req.Items))
+	seen := make(map[int64]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity of product %d must be positive", ErrInvalidShipment, item.ProductID)
+		}
+		if seen[item.ProductID] {
+			return nil, fmt.Errorf("%w: product %d is listed more than once", ErrInvalidShipment, item.ProductID)
+		}
+		seen[item.ProductID] = true
+		items = append(items, domain.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	shipment, order, err := s.shipmentRepo.Create(ctx, &domain.Shipment{
+		OrderID:        orderID,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+		Items:          items,
+		RequestID:      requestid.FromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("shipment created",
+		"order_id", orderID,
+		"shipment_id", shipment.ID,
+		"carrier", shipment.Carrier,
+		"order_status", order.Status,
+	)
+
+	// Notify subscribers
+	if s.events != nil {
+		eventType := domain.EventOrderUpdated
+		if order.Status == domain.OrderStatusShipped {
+			eventType = domain.EventOrderShipped
+		}
+		event, err := domain.NewOrderEvent(eventType, order)
+		if err != nil {
+			logger.Warn("failed to build order event", "order_id", order.ID, "error", err)
+		} else {
+			s.events.Publish(*event)
+		}
+	}
+
+	return shipment, nil
+}
+
+// ListShipments returns the shipments of an order, oldest first.
+func (s *ShipmentService) ListShipments(ctx context.Context, orderID int64) ([]domain.Shipment, error) {
+	if _, err := s.orderRepo.GetByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.shipmentRepo.ListByOrder(ctx, orderID)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+)
+
+type MockShipmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockShipmentRepository) Create(ctx context.Context, shipment *domain.Shipment) (*domain.Shipment, *domain.Order, error) {
+	args := m.Called(ctx, shipment)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*domain.Shipment), args.Get(1).(*domain.Order), args.Error(2)
+}
+
+func (m *MockShipmentRepository) ListByOrder(ctx context.Context, orderID int64) ([]domain.Shipment, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Shipment), args.Error(1)
+}
+
+func TestCreateShipment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Last parcel ships the order", func(t *testing.T) {
+		// Setup
+		shipmentRepo := new(MockShipmentRepository)
+		events := &recordingPublisher{}
+		shipmentService := NewShipmentService(shipmentRepo, new(MockOrderRepository), events)
+
+		shipmentRepo.On("Create", ctx, mock.MatchedBy(func(s *domain.Shipment) bool {
+			return s.OrderID == 1 && s.Carrier == "UPS" && s.TrackingNumber == "1Z999" &&
+				len(s.Items) == 1 && s.Items[0] == domain.OrderItem{ProductID: 2, Quantity: 3}
+		})).Return(&domain.Shipment{ID: 5, OrderID: 1, Carrier: "UPS"}, &domain.Order{ID: 1, Status: domain.OrderStatusShipped}, nil)
+
+		shipment, err := shipmentService.CreateShipment(ctx, 1, &domain.CreateShipmentRequest{
+			Carrier:        " UPS ",
+			TrackingNumber: "1Z999",
+			Items:          []domain.OrderItem{{ProductID: 2, Quantity: 3}},
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), shipment.ID)
+		require.Len(t, events.events, 1)
+		assert.Equal(t, domain.EventOrderShipped, events.events[0].Type)
+		shipmentRepo.AssertExpectations(t)
+	})
+
+	t.Run("Partial shipment updates the order", func(t *testing.T) {
+		// Setup
+		shipmentRepo := new(MockShipmentRepository)
+		events := &recordingPublisher{}
+		shipmentService := NewShipmentService(shipmentRepo, new(MockOrderRepository), events)
+
+		shipmentRepo.On("Create", ctx, mock.Anything).
+			Return(&domain.Shipment{ID: 5}, &domain.Order{ID: 1, Status: domain.OrderStatusPartiallyShipped}, nil)
+
+		_, err := shipmentService.CreateShipment(ctx, 1, &domain.CreateShipmentRequest{
+			Carrier:        "UPS",
+			TrackingNumber: "1Z999",
+			Items:          []domain.OrderItem{{ProductID: 2, Quantity: 1}},
+		})
+
+		// Assertions
+		require.NoError(t, err)
+		require.Len(t, events.events, 1)
+		assert.Equal(t, domain.EventOrderUpdated, events.events[0].Type)
+	})
+
+	t.Run("Repository errors are returned", func(t *testing.T) {
+		// Setup
+		shipmentRepo := new(MockShipmentRepository)
+		shipmentService := NewShipmentService(shipmentRepo, new(MockOrderRepository), nil)
+		shipmentRepo.On("Create", ctx, mock.Anything).Return(nil, nil, domain.ErrShipmentExceedsQuantity)
+
+		_, err := shipmentService.CreateShipment(ctx, 1, &domain.CreateShipmentRequest{Carrier: "UPS", TrackingNumber: "1Z999"})
+
+		// Assertions
+		assert.ErrorIs(t, err, domain.ErrShipmentExceedsQuantity)
+	})
+
+	invalid := []struct {
+		name string
+		req  domain.CreateShipmentRequest
+	}{
+		{"Missing carrier", domain.CreateShipmentRequest{TrackingNumber: "1Z999"}},
+		{"Missing tracking number", domain.CreateShipmentRequest{Carrier: "UPS", TrackingNumber: " "}},
+		{"Zero quantity", domain.CreateShipmentRequest{Carrier: "UPS", TrackingNumber: "1Z999", Items: []domain.OrderItem{{ProductID: 1}}}},
+		{"Duplicate product", domain.CreateShipmentRequest{Carrier: "UPS", TrackingNumber: "1Z999", Items: []domain.OrderItem{{ProductID: 1, Quantity: 1}, {ProductID: 1, Quantity: 1}}}},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			shipmentService := NewShipmentService(new(MockShipmentRepository), new(MockOrderRepository), nil)
+
+			_, err := shipmentService.CreateShipment(ctx, 1, &tc.req)
+
+			assert.ErrorIs(t, err, ErrInvalidShipment)
+		})
+	}
+}
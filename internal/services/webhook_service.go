@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// ErrInvalidWebhook is returned when a subscription request fails validation.
+var ErrInvalidWebhook = errors.New("invalid webhook subscription")
+
+// deliveryLogLimit bounds the number of deliveries returned by the delivery log.
+const deliveryLogLimit = 100
+
+// webhookEventTypes lists the event types partners can subscribe to.
+var webhookEventTypes = map[string]bool{
+	domain.EventOrderCreated:  true,
+	domain.EventOrderUpdated:  true,
+	domain.EventOrderPaid:     true,
+	domain.EventOrderRefunded: true,
+	domain.EventOrderShipped:  true,
+}
+
+type WebhookService struct {
+	webhookRepo repository.WebhookRepository
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+	}
+}
+
+// CreateSubscription validates and stores a new webhook subscription.
+//
+// The URL must be an absolute http or https URL and every event type must be
+// known; an empty list subscribes to all events. When no secret is supplied a
+// random one is generated. The secret is only ever returned by this call.
+func (s *WebhookService) CreateSubscription(ctx context.Context, req *domain.CreateWebhookRequest) (*domain.WebhookSubscription, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+
+	eventTypes := []string{}
+	for _, eventType := range req.EventTypes {
+		if !webhookEventTypes[eventType] {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, eventType)
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.webhookRepo.CreateSubscription(ctx, &domain.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: eventTypes,
+		Secret:     secret,
+		Active:     true,
+	})
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return s.webhookRepo.ListSubscriptions(ctx)
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error) {
+	return s.webhookRepo.GetSubscription(ctx, id)
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id int64) error {
+	return s.webhookRepo.DeleteSubscription(ctx, id)
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first.
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID int64) ([]domain.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, subscriptionID, deliveryLogLimit)
+}
+
+// Redeliver schedules a delivery to be sent again immediately, including
+// deliveries that already succeeded or were dead-lettered.
+func (s *WebhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID int64) error {
+	return s.webhookRepo.Redeliver(ctx, subscriptionID, deliveryID)
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+type MockWebhookRepository struct {
+	mock.Mock
+	repository.WebhookRepository
+}
+
+func (m *MockWebhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+	args := m.Called(ctx, sub)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebhookSubscription), args.Error(1)
+}
+
+func TestCreateSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Secret is generated when missing", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		webhookService := NewWebhookService(mockRepo)
+
+		mockRepo.On("CreateSubscription", ctx, mock.MatchedBy(func(sub *domain.WebhookSubscription) bool {
+			return len(sub.Secret) == 64 && sub.Active && sub.EventTypes[0] == domain.EventOrderCreated
+		})).Return(&domain.WebhookSubscription{ID: 1}, nil)
+
+		sub, err := webhookService.CreateSubscription(ctx, &domain.CreateWebhookRequest{
+			URL:        "https://partner.example.com/hooks",
+			EventTypes: []string{domain.EventOrderCreated},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), sub.ID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid URL", func(t *testing.T) {
+		webhookService := NewWebhookService(new(MockWebhookRepository))
+
+		_, err := webhookService.CreateSubscription(ctx, &domain.CreateWebhookRequest{URL: "ftp://partner.example.com"})
+
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+	})
+
+	t.Run("Unknown event type", func(t *testing.T) {
+		webhookService := NewWebhookService(new(MockWebhookRepository))
+
+		_, err := webhookService.CreateSubscription(ctx, &domain.CreateWebhookRequest{
+			URL:        "https://partner.example.com/hooks",
+			EventTypes: []string{"order.exploded"},
+		})
+
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+		assert.Contains(t, err.Error(), "order.exploded")
+	})
+}
@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// orderRepository decorates a repository.OrderRepository with one span per query.
+type orderRepository struct {
+	next   repository.OrderRepository
+	tracer trace.Tracer
+}
+
+// NewOrderRepository wraps next so every call is traced.
+func NewOrderRepository(next repository.OrderRepository, tp trace.TracerProvider) repository.OrderRepository {
+	return &orderRepository{next: next, tracer: tp.Tracer(InstrumentationName)}
+}
+
+func (r *orderRepository) Create(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	ctx, span := r.tracer.Start(ctx, "OrderRepository.Create",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("INSERT", "orders")...),
+		trace.WithAttributes(attribute.Int("order.items", len(order.Items))),
+	)
+	created, err := r.next.Create(ctx, order)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("order.id", created.ID))
+	}
+	endSpan(span, err)
+	return created, err
+}
+
+func (r *orderRepository) CreateBatch(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error) {
+	ctx, span := r.tracer.Start(ctx, "OrderRepository.CreateBatch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("INSERT", "orders")...),
+		trace.WithAttributes(attribute.Int("order.count", len(orders))),
+	)
+	created, err := r.next.CreateBatch(ctx, orders)
+	endSpan(span, err)
+	return created, err
+}
+
+func (r *orderRepository) GetByID(ctx context.Context, id int64) (*domain.Order, error) {
+	ctx, span := r.tracer.Start(ctx, "OrderRepository.GetByID",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("SELECT", "orders")...),
+		trace.WithAttributes(attribute.Int64("order.id", id)),
+	)
+	order, err := r.next.GetByID(ctx, id)
+	endSpan(span, err)
+	return order, err
+}
+
+func (r *orderRepository) List(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, error) {
+	ctx, span := r.tracer.Start(ctx, "OrderRepository.List",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("SELECT", "orders")...),
+		trace.WithAttributes(attribute.Int("order.limit", filter.Limit)),
+	)
+	orders, err := r.next.List(ctx, filter)
+	if err == nil {
+		span.SetAttributes(attribute.Int("order.count", len(orders)))
+	}
+	endSpan(span, err)
+	return orders, err
+}
+
+func (r *orderRepository) Export(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.Order) error) error {
+	ctx, span := r.tracer.Start(ctx, "OrderRepository.Export",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("SELECT", "orders")...),
+	)
+	count := 0
+	err := r.next.Export(ctx, filter, func(order *domain.Order) error {
+		count++
+		return fn(order)
+	})
+	span.SetAttributes(attribute.Int("order.count", count))
+	endSpan(span, err)
+	return err
+}
+
+func (r *orderRepository) Update(ctx context.Context, id int64, version int, update func(order *domain.Order) (*domain.OrderRevision, error)) (*domain.Order, error) {
+	ctx, span := r.tracer.Start(ctx, "OrderRepository.Update",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("UPDATE", "orders")...),
+		trace.WithAttributes(attribute.Int64("order.id", id), attribute.Int("order.version", version)),
+	)
+	order, err := r.next.Update(ctx, id, version, update)
+	endSpan(span, err)
+	return order, err
+}
+
+func (r *orderRepository) ListRevisions(ctx context.Context, orderID int64) ([]domain.OrderRevision, error) {
+	ctx, span := r.tracer.Start(ctx, "OrderRepository.ListRevisions",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("SELECT", "order_revisions")...),
+		trace.WithAttributes(attribute.Int64("order.id", orderID)),
+	)
+	revisions, err := r.next.ListRevisions(ctx, orderID)
+	endSpan(span, err)
+	return revisions, err
+}
+
+// productRepository decorates a repository.ProductRepository with one span per query.
+type productRepository struct {
+	next   repository.ProductRepository
+	tracer trace.Tracer
+}
+
+// NewProductRepository wraps next so every call is traced.
+func NewProductRepository(next repository.ProductRepository, tp trace.TracerProvider) repository.ProductRepository {
+	return &productRepository{next: next, tracer: tp.Tracer(InstrumentationName)}
+}
+
+func (r *productRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
+	ctx, span := r.tracer.Start(ctx, "ProductRepository.GetByID",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("SELECT", "products")...),
+		trace.WithAttributes(attribute.Int64("product.id", id)),
+	)
+	product, err := r.next.GetByID(ctx, id)
+	endSpan(span, err)
+	return product, err
+}
+
+func (r *productRepository) GetByIDs(ctx context.Context, ids []int64) ([]domain.Product, error) {
+	ctx, span := r.tracer.Start(ctx, "ProductRepository.GetByIDs",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("SELECT", "products")...),
+		trace.WithAttributes(attribute.Int("product.ids", len(ids))),
+	)
+	products, err := r.next.GetByIDs(ctx, ids)
+	endSpan(span, err)
+	return products, err
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// orderService decorates a services.OrderServiceInterface with one span per method.
+type orderService struct {
+	next   services.OrderServiceInterface
+	tracer trace.Tracer
+}
+
+// NewOrderService wraps next so every call is traced.
+func NewOrderService(next services.OrderServiceInterface, tp trace.TracerProvider) services.OrderServiceInterface {
+	return &orderService{next: next, tracer: tp.Tracer(InstrumentationName)}
+}
+
+func (s *orderService) CreateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.OrderResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "OrderService.CreateOrder",
+		trace.WithAttributes(attribute.Int("order.items", len(req.Order.Items))),
+	)
+	response, err := s.next.CreateOrder(ctx, req)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("order.id", response.OrderID))
+	}
+	endSpan(span, err)
+	return response, err
+}
+
+func (s *orderService) CreateOrders(ctx context.Context, reqs []*domain.CreateOrderRequest) ([]*domain.OrderResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "OrderService.CreateOrders",
+		trace.WithAttributes(attribute.Int("order.count", len(reqs))),
+	)
+	responses, err := s.next.CreateOrders(ctx, reqs)
+	endSpan(span, err)
+	return responses, err
+}
+
+func (s *orderService) QuoteOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.QuoteResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "OrderService.QuoteOrder",
+		trace.WithAttributes(attribute.Int("order.items", len(req.Order.Items))),
+	)
+	response, err := s.next.QuoteOrder(ctx, req)
+	endSpan(span, err)
+	return response, err
+}
+
+func (s *orderService) GetOrder(ctx context.Context, id int64) (*domain.OrderResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "OrderService.GetOrder",
+		trace.WithAttributes(attribute.Int64("order.id", id)),
+	)
+	response, err := s.next.GetOrder(ctx, id)
+	endSpan(span, err)
+	return response, err
+}
+
+func (s *orderService) UpdateOrder(ctx context.Context, id int64, req *domain.UpdateOrderRequest) (*domain.OrderResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "OrderService.UpdateOrder",
+		trace.WithAttributes(
+			attribute.Int64("order.id", id),
+			attribute.Int("order.changes", len(req.Items)),
+		),
+	)
+	response, err := s.next.UpdateOrder(ctx, id, req)
+	endSpan(span, err)
+	return response, err
+}
+
+func (s *orderService) ListOrderRevisions(ctx context.Context, id int64) ([]domain.OrderRevision, error) {
+	ctx, span := s.tracer.Start(ctx, "OrderService.ListOrderRevisions",
+		trace.WithAttributes(attribute.Int64("order.id", id)),
+	)
+	revisions, err := s.next.ListOrderRevisions(ctx, id)
+	endSpan(span, err)
+	return revisions, err
+}
+
+func (s *orderService) ExportOrders(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.OrderResponse) error) error {
+	ctx, span := s.tracer.Start(ctx, "OrderService.ExportOrders")
+	err := s.next.ExportOrders(ctx, filter, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (s *orderService) ListOrders(ctx context.Context, filter domain.OrderFilter) (*domain.OrderListResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "OrderService.ListOrders")
+	response, err := s.next.ListOrders(ctx, filter)
+	if err == nil {
+		span.SetAttributes(attribute.Int("order.count", len(response.Orders)))
+	}
+	endSpan(span, err)
+	return response, err
+}
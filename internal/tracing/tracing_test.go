@@ -0,0 +1,182 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/api/handlers"
+	"github.com/valeriouberti/order-service-test/internal/api/middleware"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/services"
+	"github.com/valeriouberti/order-service-test/internal/tracing"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type MockOrderRepository struct {
+	mock.Mock
+}
+
+func (m *MockOrderRepository) Create(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	args := m.Called(ctx, order)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) CreateBatch(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error) {
+	args := m.Called(ctx, orders)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) Export(ctx context.Context, filter domain.OrderFilter, fn func(order *domain.Order) error) error {
+	args := m.Called(ctx, filter, fn)
+	if orders, ok := args.Get(0).([]*domain.Order); ok {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockOrderRepository) GetByID(ctx context.Context, id int64) (*domain.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) List(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) Update(ctx context.Context, id int64, version int, update func(order *domain.Order) (*domain.OrderRevision, error)) (*domain.Order, error) {
+	args := m.Called(ctx, id, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) ListRevisions(ctx context.Context, orderID int64) ([]domain.OrderRevision, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.OrderRevision), args.Error(1)
+}
+
+type MockProductRepository struct {
+	mock.Mock
+}
+
+func (m *MockProductRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetByIDs(ctx context.Context, ids []int64) ([]domain.Product, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func TestCreateOrderSpans(t *testing.T) {
+	// Setup an in-memory exporter
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	mockOrderRepo := new(MockOrderRepository)
+	mockProductRepo := new(MockProductRepository)
+	mockProductRepo.On("GetByID", mock.Anything, int64(1)).Return(&domain.Product{ID: 1, Price: 10.0, VAT: 1.0}, nil)
+	mockProductRepo.On("GetByID", mock.Anything, int64(2)).Return(&domain.Product{ID: 2, Price: 5.0, VAT: 0.5}, nil)
+	mockOrderRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Order")).Return(&domain.Order{ID: 1}, nil)
+
+	orderService := tracing.NewOrderService(services.NewOrderService(
+		tracing.NewOrderRepository(mockOrderRepo, tp),
+		tracing.NewProductRepository(mockProductRepo, tp),
+		nil,
+		nil,
+	), tp)
+
+	r := mux.NewRouter()
+	r.Use(middleware.Tracing(tp, propagation.TraceContext{}))
+	r.HandleFunc("/api/orders", handlers.NewOrderHandler(orderService).CreateOrder).Methods("POST")
+
+	// Send a request carrying a W3C traceparent header
+	body := []byte(`{"order":{"items":[{"product_id":1,"quantity":2},{"product_id":2,"quantity":1}]}}`)
+	req := httptest.NewRequest("POST", "/api/orders", bytes.NewBuffer(body))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 5)
+
+	byName := map[string][]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+
+	// The handler span continues the client's trace
+	require.Len(t, byName["POST /api/orders"], 1)
+	handlerSpan := byName["POST /api/orders"][0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", handlerSpan.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", handlerSpan.Parent.SpanID().String())
+
+	// The service span is a child of the handler span
+	require.Len(t, byName["OrderService.CreateOrder"], 1)
+	serviceSpan := byName["OrderService.CreateOrder"][0]
+	assert.Equal(t, handlerSpan.SpanContext.SpanID(), serviceSpan.Parent.SpanID())
+
+	// Each repository query is a child of the service span
+	assert.Len(t, byName["ProductRepository.GetByID"], 2)
+	assert.Len(t, byName["OrderRepository.Create"], 1)
+	for _, name := range []string{"ProductRepository.GetByID", "OrderRepository.Create"} {
+		for _, span := range byName[name] {
+			assert.Equal(t, serviceSpan.SpanContext.SpanID(), span.Parent.SpanID())
+			assert.Equal(t, handlerSpan.SpanContext.TraceID(), span.SpanContext.TraceID())
+		}
+	}
+}
+
+func TestRepositoryErrorIsRecorded(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	mockOrderRepo := new(MockOrderRepository)
+	mockOrderRepo.On("GetByID", mock.Anything, int64(999)).Return(nil, domain.ErrOrderNotFound)
+
+	_, err := tracing.NewOrderRepository(mockOrderRepo, tp).GetByID(context.Background(), 999)
+	assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "Error", spans[0].Status.Code.String())
+	assert.Equal(t, "order not found", spans[0].Status.Description)
+}
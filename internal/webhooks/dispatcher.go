@@ -0,0 +1,169 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// maxBackoff caps the delay between two attempts of the same delivery.
+const maxBackoff = time.Hour
+
+// Config controls how deliveries are sent and retried.
+type Config struct {
+	// MaxAttempts is the number of failed attempts after which a delivery is dead-lettered.
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles after each failure.
+	Backoff time.Duration
+	// Timeout bounds a single HTTP request.
+	Timeout time.Duration
+	// BatchSize is the maximum number of deliveries claimed at once.
+	BatchSize int
+	// Interval is the time between two polls for due deliveries.
+	Interval time.Duration
+}
+
+// Dispatcher sends pending webhook deliveries, retrying failures with
+// exponential backoff until they succeed or are dead-lettered.
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func NewDispatcher(repo repository.WebhookRepository, cfg Config, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Run dispatches due deliveries every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Warn("webhook dispatch failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue claims one batch of due deliveries, sends them and records the
+// outcome of each. It returns the number of deliveries attempted.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// Deliveries are sent one after the other, so lease them for longer than
+	// the whole batch can take
+	lease := int((time.Duration(d.cfg.BatchSize)*d.cfg.Timeout + time.Minute).Seconds())
+
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, d.cfg.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		attempt := d.send(ctx, delivery)
+		attempt.LeasedUntil = delivery.NextAttemptAt
+
+		logger := d.logger.With(
+			"delivery_id", delivery.ID,
+			"subscription_id", delivery.SubscriptionID,
+			"event_type", delivery.EventType,
+			"status", attempt.Status,
+		)
+		if err := d.repo.RecordAttempt(ctx, attempt); errors.Is(err, domain.ErrDeliveryLeaseLost) {
+			// Whoever holds the delivery now records its outcome
+			logger.Warn("webhook delivery lease lost, attempt not recorded")
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+
+		switch attempt.Status {
+		case domain.DeliveryDelivered:
+			logger.Debug("webhook delivered")
+		case domain.DeliveryDead:
+			logger.Warn("webhook dead-lettered", "attempts", delivery.Attempts+1, "error", attempt.Error)
+		default:
+			logger.Info("webhook delivery failed, will retry", "next_attempt_at", attempt.NextAttemptAt, "error", attempt.Error)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// send performs one signed HTTP POST and returns the resulting attempt.
+// Any 2xx response counts as success.
+func (d *Dispatcher) send(ctx context.Context, delivery domain.WebhookDelivery) domain.WebhookAttempt {
+	now := d.now()
+	attempt := domain.WebhookAttempt{DeliveryID: delivery.ID, NextAttemptAt: now}
+
+	timestamp := now.Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "order-service-webhooks/1.0")
+		req.Header.Set(HeaderEvent, delivery.EventType)
+		req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+		var resp *http.Response
+		if resp, err = d.client.Do(req); err == nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+
+			attempt.StatusCode = resp.StatusCode
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				attempt.Status = domain.DeliveryDelivered
+				return attempt
+			}
+			err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+	}
+
+	attempt.Error = err.Error()
+	attempts := delivery.Attempts + 1
+	if attempts >= d.cfg.MaxAttempts {
+		attempt.Status = domain.DeliveryDead
+		return attempt
+	}
+
+	attempt.Status = domain.DeliveryPending
+	attempt.NextAttemptAt = now.Add(backoff(d.cfg.Backoff, attempts))
+	return attempt
+}
+
+// backoff returns the delay before the next attempt after the given number of
+// failed attempts: base, 2*base, 4*base, ... capped at maxBackoff.
+func backoff(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,199 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// fakeRepo is an in-memory webhook repository holding pending deliveries.
+type fakeRepo struct {
+	repository.WebhookRepository
+
+	mu         sync.Mutex
+	deliveries map[int64]*domain.WebhookDelivery
+	attempts   []domain.WebhookAttempt
+	lease      int
+}
+
+func newFakeRepo(deliveries ...domain.WebhookDelivery) *fakeRepo {
+	r := &fakeRepo{deliveries: map[int64]*domain.WebhookDelivery{}}
+	for i := range deliveries {
+		r.deliveries[deliveries[i].ID] = &deliveries[i]
+	}
+	return r
+}
+
+func (r *fakeRepo) ClaimDueDeliveries(ctx context.Context, limit int, leaseSeconds int) ([]domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lease = leaseSeconds
+	var due []domain.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == domain.DeliveryPending && len(due) < limit {
+			d.NextAttemptAt = d.NextAttemptAt.Add(time.Duration(leaseSeconds) * time.Second)
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeRepo) RecordAttempt(ctx context.Context, attempt domain.WebhookAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.deliveries[attempt.DeliveryID]
+	if d.Status != domain.DeliveryPending || !d.NextAttemptAt.Equal(attempt.LeasedUntil) {
+		return domain.ErrDeliveryLeaseLost
+	}
+	d.Attempts++
+	d.Status = attempt.Status
+	d.LastStatusCode = attempt.StatusCode
+	d.LastError = attempt.Error
+	d.NextAttemptAt = attempt.NextAttemptAt
+	r.attempts = append(r.attempts, attempt)
+	return nil
+}
+
+func testDelivery(url string) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:             7,
+		SubscriptionID: 1,
+		EventID:        42,
+		EventType:      domain.EventOrderCreated,
+		Payload:        json.RawMessage(`{"id":42,"type":"order.created"}`),
+		Status:         domain.DeliveryPending,
+		URL:            url,
+		Secret:         "s3cr3t",
+	}
+}
+
+func newTestDispatcher(repo repository.WebhookRepository, maxAttempts int) *Dispatcher {
+	d := NewDispatcher(repo, Config{
+		MaxAttempts: maxAttempts,
+		Backoff:     10 * time.Second,
+		Timeout:     time.Second,
+		BatchSize:   10,
+		Interval:    time.Second,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	d.now = func() time.Time { return time.Unix(1700000000, 0) }
+	return d
+}
+
+func TestDispatchDue(t *testing.T) {
+	t.Run("Successful delivery is signed", func(t *testing.T) {
+		var received *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		repo := newFakeRepo(testDelivery(server.URL))
+		n, err := newTestDispatcher(repo, 3).DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, domain.DeliveryDelivered, repo.deliveries[7].Status)
+		assert.Equal(t, http.StatusNoContent, repo.deliveries[7].LastStatusCode)
+
+		// The receiver can authenticate the payload
+		require.NotNil(t, received)
+		assert.JSONEq(t, `{"id":42,"type":"order.created"}`, string(body))
+		assert.Equal(t, "order.created", received.Header.Get(HeaderEvent))
+		assert.Equal(t, "7", received.Header.Get(HeaderDelivery))
+		timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		assert.True(t, Verify("s3cr3t", timestamp, body, received.Header.Get(HeaderSignature)))
+		assert.False(t, Verify("wrong", timestamp, body, received.Header.Get(HeaderSignature)))
+	})
+
+	t.Run("Failures are retried with exponential backoff then dead-lettered", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		repo := newFakeRepo(testDelivery(server.URL))
+		dispatcher := newTestDispatcher(repo, 3)
+
+		for i := 0; i < 4; i++ {
+			_, err := dispatcher.DispatchDue(context.Background())
+			require.NoError(t, err)
+		}
+
+		// The dead delivery is not attempted again
+		require.Len(t, repo.attempts, 3)
+		start := dispatcher.now()
+		assert.Equal(t, domain.DeliveryPending, repo.attempts[0].Status)
+		assert.Equal(t, start.Add(10*time.Second), repo.attempts[0].NextAttemptAt)
+		assert.Equal(t, domain.DeliveryPending, repo.attempts[1].Status)
+		assert.Equal(t, start.Add(20*time.Second), repo.attempts[1].NextAttemptAt)
+		assert.Equal(t, domain.DeliveryDead, repo.attempts[2].Status)
+		assert.Equal(t, "unexpected status 503", repo.deliveries[7].LastError)
+	})
+
+	t.Run("Deliveries are leased for the whole batch", func(t *testing.T) {
+		repo := newFakeRepo()
+		_, err := newTestDispatcher(repo, 3).DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 10*1+60, repo.lease)
+	})
+
+	t.Run("Attempt is not recorded once the delivery was redelivered", func(t *testing.T) {
+		var repo *fakeRepo
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Redelivered while the request is in flight
+			repo.mu.Lock()
+			repo.deliveries[7].Attempts, repo.deliveries[7].NextAttemptAt = 0, time.Unix(1700000100, 0)
+			repo.mu.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		repo = newFakeRepo(testDelivery(server.URL))
+		n, err := newTestDispatcher(repo, 3).DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Empty(t, repo.attempts)
+		assert.Equal(t, 0, repo.deliveries[7].Attempts)
+	})
+
+	t.Run("Unreachable endpoint counts as a failure", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close()
+
+		repo := newFakeRepo(testDelivery(url))
+		_, err := newTestDispatcher(repo, 3).DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.DeliveryPending, repo.deliveries[7].Status)
+		assert.Equal(t, 0, repo.deliveries[7].LastStatusCode)
+		assert.NotEmpty(t, repo.deliveries[7].LastError)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(30*time.Second, 1))
+	assert.Equal(t, 60*time.Second, backoff(30*time.Second, 2))
+	assert.Equal(t, 240*time.Second, backoff(30*time.Second, 4))
+	assert.Equal(t, maxBackoff, backoff(30*time.Second, 20))
+}
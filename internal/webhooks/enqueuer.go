@@ -0,0 +1,23 @@
+package webhooks
+
+import (
+	"context"
+
+	"github.com/valeriouberti/order-service-test/internal/domain"
+	"github.com/valeriouberti/order-service-test/internal/repository"
+)
+
+// Enqueuer is an outbox.Publisher that turns each event into one pending
+// delivery per matching subscription. The Dispatcher sends them later.
+type Enqueuer struct {
+	repo repository.WebhookRepository
+}
+
+func NewEnqueuer(repo repository.WebhookRepository) *Enqueuer {
+	return &Enqueuer{repo: repo}
+}
+
+func (e *Enqueuer) Publish(ctx context.Context, event domain.Event) error {
+	_, err := e.repo.EnqueueDeliveries(ctx, event)
+	return err
+}
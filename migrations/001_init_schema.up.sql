@@ -1,5 +1,3 @@
-BEGIN;
-
 -- Create products table
 CREATE TABLE IF NOT EXISTS products (
     id SERIAL PRIMARY KEY,
@@ -35,12 +33,14 @@ CREATE TABLE IF NOT EXISTS order_items (
 CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
 CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id);
 
--- Insert sample product data
-INSERT INTO products (name, price, vat) VALUES 
+-- Insert sample product data, unless the database was set up before
+-- migrations were versioned and already has products
+INSERT INTO products (name, price, vat)
+SELECT name, price, vat FROM (VALUES
     ('Product 1', 2.00, 0.20),
     ('Product 2', 1.50, 0.15),
     ('Product 3', 3.00, 0.30),
     ('Product 4', 4.25, 0.43),
-    ('Product 5', 10.00, 1.00);
-
-COMMIT;
\ No newline at end of file
+    ('Product 5', 10.00, 1.00)
+) AS sample (name, price, vat)
+WHERE NOT EXISTS (SELECT 1 FROM products);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_request_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS request_id;
@@ -0,0 +1,5 @@
+-- Record the correlation ID of the request that created each order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS request_id VARCHAR(128);
+
+-- Allow support to look up an order from a client's request ID
+CREATE INDEX IF NOT EXISTS idx_orders_request_id ON orders(request_id);
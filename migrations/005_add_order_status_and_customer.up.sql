@@ -0,0 +1,8 @@
+-- Track the lifecycle of each order; new orders start as pending
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'pending';
+
+-- Optional reference to the customer who placed the order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
@@ -0,0 +1,25 @@
+-- Server-side shopping carts, converted into orders at checkout
+CREATE TABLE IF NOT EXISTS carts (
+    id VARCHAR(32) PRIMARY KEY,
+    customer_id VARCHAR(64),
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    order_id INTEGER REFERENCES orders(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- One line per product; quantities of the same product are merged
+CREATE TABLE IF NOT EXISTS cart_items (
+    cart_id VARCHAR(32) NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cart_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_carts_expires_at ON carts(expires_at) WHERE status = 'open';
+
+-- A cart is converted into at most one order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cart_id VARCHAR(32) UNIQUE;
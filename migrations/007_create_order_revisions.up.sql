@@ -0,0 +1,16 @@
+-- History of the changes made to the lines of pending orders
+CREATE TABLE IF NOT EXISTS order_revisions (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    changes JSONB NOT NULL,
+    items_before JSONB NOT NULL,
+    items_after JSONB NOT NULL,
+    price_before DECIMAL(10, 2) NOT NULL,
+    vat_before DECIMAL(10, 2) NOT NULL,
+    price_after DECIMAL(10, 2) NOT NULL,
+    vat_after DECIMAL(10, 2) NOT NULL,
+    request_id VARCHAR(128),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (order_id, revision)
+);
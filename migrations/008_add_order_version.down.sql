@@ -0,0 +1,2 @@
+ALTER TABLE order_revisions DROP COLUMN IF EXISTS version;
+ALTER TABLE orders DROP COLUMN IF EXISTS version;
@@ -0,0 +1,5 @@
+-- Incremented on every change; used for optimistic concurrency control
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Version of the order each revision produced
+ALTER TABLE order_revisions ADD COLUMN IF NOT EXISTS version INTEGER;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS credit_note_items;
+DROP TABLE IF EXISTS credit_notes;
@@ -0,0 +1,44 @@
+-- Credit notes document the money refunded for an order
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    number VARCHAR(32) NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    price DECIMAL(10, 2) NOT NULL,
+    vat DECIMAL(10, 2) NOT NULL,
+    reason TEXT,
+    request_id VARCHAR(128),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_notes_order_id ON credit_notes(order_id);
+
+-- Order lines credited by line-level credit notes
+CREATE TABLE IF NOT EXISTS credit_note_items (
+    id SERIAL PRIMARY KEY,
+    credit_note_id INTEGER NOT NULL REFERENCES credit_notes(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    price DECIMAL(10, 2) NOT NULL,
+    vat DECIMAL(10, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_note_items_credit_note_id ON credit_note_items(credit_note_id);
+
+-- Gateway refunds, one per captured payment a credit note is taken from
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    credit_note_id INTEGER NOT NULL REFERENCES credit_notes(id) ON DELETE CASCADE,
+    payment_id INTEGER NOT NULL REFERENCES payments(id),
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL,
+    reference VARCHAR(128),
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_credit_note_id ON refunds(credit_note_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_payment_id ON refunds(payment_id);
@@ -0,0 +1,22 @@
+-- Last invoice number issued in each fiscal year. Incremented in the
+-- transaction issuing the invoice, so numbers have no gaps.
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    fiscal_year INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
+
+-- Invoices of paid orders, at most one per order
+CREATE TABLE IF NOT EXISTS invoices (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL UNIQUE REFERENCES orders(id),
+    fiscal_year INTEGER NOT NULL,
+    sequence INTEGER NOT NULL,
+    number VARCHAR(32) NOT NULL UNIQUE,
+    customer_id VARCHAR(64),
+    currency CHAR(3) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    vat DECIMAL(10, 2) NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (fiscal_year, sequence)
+);
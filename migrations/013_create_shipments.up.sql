@@ -0,0 +1,22 @@
+-- Parcels sent for orders
+CREATE TABLE IF NOT EXISTS shipments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    carrier VARCHAR(64) NOT NULL,
+    tracking_number VARCHAR(128) NOT NULL,
+    request_id VARCHAR(128),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (carrier, tracking_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments(order_id);
+
+-- Order lines and quantities in each parcel
+CREATE TABLE IF NOT EXISTS shipment_items (
+    id SERIAL PRIMARY KEY,
+    shipment_id INTEGER NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipment_items_shipment_id ON shipment_items(shipment_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS import_results;
+DROP TABLE IF EXISTS import_jobs;
@@ -0,0 +1,33 @@
+-- Bulk order imports; rows holds the parsed file until the job finishes
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id VARCHAR(64) PRIMARY KEY,
+    status VARCHAR(32) NOT NULL,
+    format VARCHAR(32) NOT NULL,
+    mode VARCHAR(32) NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    total INTEGER NOT NULL,
+    processed INTEGER NOT NULL DEFAULT 0,
+    succeeded INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    rows JSONB,
+    request_id VARCHAR(128),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs(status, created_at);
+
+-- Outcome of each row of an import
+CREATE TABLE IF NOT EXISTS import_results (
+    job_id VARCHAR(64) NOT NULL REFERENCES import_jobs(id) ON DELETE CASCADE,
+    line INTEGER NOT NULL,
+    ref VARCHAR(128),
+    status VARCHAR(32) NOT NULL,
+    order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+    price DECIMAL(10, 2),
+    vat DECIMAL(10, 2),
+    error TEXT,
+    PRIMARY KEY (job_id, line)
+);
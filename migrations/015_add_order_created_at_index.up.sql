@@ -0,0 +1,2 @@
+-- Sales reports select orders by creation time
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
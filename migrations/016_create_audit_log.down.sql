@@ -0,0 +1,3 @@
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS actor;
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
@@ -0,0 +1,22 @@
+-- Lines of issued invoices, copied from the order when the invoice is
+-- issued so that later changes to products do not alter it
+CREATE TABLE IF NOT EXISTS invoice_lines (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    vat DECIMAL(10, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_lines_invoice_id ON invoice_lines(invoice_id);
+
+-- Invoices issued before lines were stored get the lines as they read now
+INSERT INTO invoice_lines (invoice_id, product_id, description, quantity, price, vat)
+SELECT i.id, oi.product_id, p.name, oi.quantity, oi.price, oi.vat
+FROM invoices i
+JOIN order_items oi ON oi.order_id = i.order_id
+JOIN products p ON p.id = oi.product_id
+WHERE NOT EXISTS (SELECT 1 FROM invoice_lines il WHERE il.invoice_id = i.id)
+ORDER BY i.id, oi.id;
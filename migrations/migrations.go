@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL migration files into the binary.
+//
+// Each migration is a pair of files named NNN_description.up.sql and
+// NNN_description.down.sql, where NNN is the schema version. The files must
+// not contain BEGIN/COMMIT: the runner wraps every migration in a transaction.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
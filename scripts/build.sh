@@ -2,5 +2,5 @@
 set -e
 
 echo "Building the application..."
-go build -o order-service-test ./cmd/api/main.go
+go build -o order-service-test ./cmd/api
 echo "Build completed successfully!"
\ No newline at end of file
@@ -0,0 +1,14 @@
+#!/bin/sh
+set -e
+
+# Regenerates the gRPC code in internal/grpcapi/orderv1 from proto/.
+# Requires protoc, protoc-gen-go v1.35.1 and protoc-gen-go-grpc v1.5.1:
+#   go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.35.1
+#   go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.5.1
+
+echo "Generating protobuf code..."
+protoc -I proto \
+    --go_out=. --go_opt=module=github.com/valeriouberti/order-service-test \
+    --go-grpc_out=. --go-grpc_opt=module=github.com/valeriouberti/order-service-test \
+    order/v1/order.proto
+echo "Generation completed successfully!"
@@ -4,6 +4,9 @@ set -e
 echo "Running tests..."
 go test -v ./... -cover
 
+# Read the Parquet exports back with an independent Parquet implementation
+go test -mod=mod -tags parquetinterop ./internal/orderexport
+
 # If you want to generate a coverage report
 go test -coverprofile=coverage.out ./...
 go tool cover -html=coverage.out -o coverage.html